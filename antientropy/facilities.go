@@ -0,0 +1,23 @@
+package antientropy
+
+//go:generate mockgen -source=facilities.go -destination=facilities_mock_test.go -package=antientropy
+
+import (
+	"github.com/maxpoletaev/kv/clust"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/storage"
+)
+
+type Cluster interface {
+	Self() membership.Member
+	Members() []membership.Member
+	Conn(membership.NodeID) (clust.Conn, error)
+	SelfConn() clust.Conn
+}
+
+// Storage is the local storage engine the trees are built from. It must be able
+// to list its keys and to read the current values of the keys of a leaf.
+type Storage interface {
+	storage.Engine
+	storage.Scannable
+}
@@ -0,0 +1,107 @@
+package antientropy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/twmb/murmur3"
+
+	"github.com/maxpoletaev/kv/clust/ring"
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/storage"
+)
+
+// tree is a Merkle tree of a fixed depth over the keys of a range. The keys are assigned to
+// the leaves by their position on the hash ring, so that the same key falls into the same
+// leaf on all nodes. The hash of a leaf combines the digests of its keys regardless of their
+// order, and the hash of an inner node is the hash of its two children. Level 0 is the root,
+// and the level equal to the depth holds the leaves.
+type tree struct {
+	depth  int
+	levels [][]uint64
+}
+
+func newTree(depth int) *tree {
+	levels := make([][]uint64, depth+1)
+	for i := range levels {
+		levels[i] = make([]uint64, 1<<i)
+	}
+
+	return &tree{
+		depth:  depth,
+		levels: levels,
+	}
+}
+
+// leafIndex returns the leaf of a tree of the given depth the key belongs to.
+func leafIndex(key string, depth int) int {
+	return int(ring.KeyHash(key) >> (64 - depth))
+}
+
+// add adds the digest of the key to its leaf. The tree must be rebuilt afterwards.
+func (t *tree) add(key string, digest uint64) {
+	leaves := t.levels[t.depth]
+	leaves[leafIndex(key, t.depth)] ^= digest
+}
+
+// build computes the hashes of the inner nodes from the leaves.
+func (t *tree) build() {
+	buf := make([]byte, 16)
+
+	for level := t.depth - 1; level >= 0; level-- {
+		children := t.levels[level+1]
+
+		for i := range t.levels[level] {
+			binary.BigEndian.PutUint64(buf[:8], children[2*i])
+			binary.BigEndian.PutUint64(buf[8:], children[2*i+1])
+			t.levels[level][i] = murmur3.Sum64(buf)
+		}
+	}
+}
+
+// nodes returns the hashes of the nodes with the given indices at the given level.
+func (t *tree) nodes(level int, indices []uint32) ([]uint64, error) {
+	if level < 0 || level > t.depth {
+		return nil, fmt.Errorf("level %d is out of range", level)
+	}
+
+	hashes := make([]uint64, 0, len(indices))
+
+	for _, idx := range indices {
+		if int(idx) >= len(t.levels[level]) {
+			return nil, fmt.Errorf("node %d is out of range at level %d", idx, level)
+		}
+
+		hashes = append(hashes, t.levels[level][idx])
+	}
+
+	return hashes, nil
+}
+
+// digest returns the hash of the key and the versions of its values. The data is not
+// hashed, since the values with the same version always have the same data.
+func digest(key string, values []storage.Value) uint64 {
+	versions := make([]string, 0, len(values))
+
+	for _, v := range values {
+		version := dvv.MustEncode(v.DottedVersion())
+		if v.Tombstone {
+			version += "!"
+		}
+
+		versions = append(versions, version)
+	}
+
+	sort.Strings(versions)
+
+	h := murmur3.New64()
+	h.Write([]byte(key))
+
+	for _, version := range versions {
+		h.Write([]byte{0})
+		h.Write([]byte(version))
+	}
+
+	return h.Sum64()
+}
@@ -0,0 +1,32 @@
+package antientropy
+
+import (
+	"time"
+
+	"github.com/maxpoletaev/kv/clust/ring"
+)
+
+type option func(*Repairer)
+
+// WithInterval sets how often the Merkle trees are rebuilt and compared with the replicas.
+func WithInterval(d time.Duration) option {
+	return func(r *Repairer) {
+		r.interval = d
+	}
+}
+
+// WithTreeDepth sets the depth of the Merkle trees, so that each tree has 2^depth leaves.
+// Deeper trees find the differing keys more precisely, but take more round trips to compare.
+// All nodes of the cluster must use the same depth, otherwise their trees cannot be compared.
+func WithTreeDepth(depth int) option {
+	return func(r *Repairer) {
+		r.depth = depth
+	}
+}
+
+// WithPlacement sets the placement that decides which nodes each key is stored on.
+func WithPlacement(p *ring.Placement) option {
+	return func(r *Repairer) {
+		r.placement = p
+	}
+}
@@ -0,0 +1,221 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.19.4
+// source: antientropy/proto/antientropy.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AntiEntropyServiceClient is the client API for AntiEntropyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AntiEntropyServiceClient interface {
+	// TreeNodes returns the hashes of the Merkle tree nodes of a range at the given level.
+	TreeNodes(ctx context.Context, in *TreeNodesRequest, opts ...grpc.CallOption) (*TreeNodesResponse, error)
+	// LeafKeys returns the digests of the keys of a range that belong to the given leaves.
+	LeafKeys(ctx context.Context, in *LeafKeysRequest, opts ...grpc.CallOption) (*LeafKeysResponse, error)
+	// Trigger starts an anti-entropy round without waiting for the schedule.
+	Trigger(ctx context.Context, in *TriggerRequest, opts ...grpc.CallOption) (*TriggerResponse, error)
+	// Status returns the schedule and the results of the last round.
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+}
+
+type antiEntropyServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAntiEntropyServiceClient(cc grpc.ClientConnInterface) AntiEntropyServiceClient {
+	return &antiEntropyServiceClient{cc}
+}
+
+func (c *antiEntropyServiceClient) TreeNodes(ctx context.Context, in *TreeNodesRequest, opts ...grpc.CallOption) (*TreeNodesResponse, error) {
+	out := new(TreeNodesResponse)
+	err := c.cc.Invoke(ctx, "/antientropy.AntiEntropyService/TreeNodes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *antiEntropyServiceClient) LeafKeys(ctx context.Context, in *LeafKeysRequest, opts ...grpc.CallOption) (*LeafKeysResponse, error) {
+	out := new(LeafKeysResponse)
+	err := c.cc.Invoke(ctx, "/antientropy.AntiEntropyService/LeafKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *antiEntropyServiceClient) Trigger(ctx context.Context, in *TriggerRequest, opts ...grpc.CallOption) (*TriggerResponse, error) {
+	out := new(TriggerResponse)
+	err := c.cc.Invoke(ctx, "/antientropy.AntiEntropyService/Trigger", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *antiEntropyServiceClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, "/antientropy.AntiEntropyService/Status", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AntiEntropyServiceServer is the server API for AntiEntropyService service.
+// All implementations must embed UnimplementedAntiEntropyServiceServer
+// for forward compatibility
+type AntiEntropyServiceServer interface {
+	// TreeNodes returns the hashes of the Merkle tree nodes of a range at the given level.
+	TreeNodes(context.Context, *TreeNodesRequest) (*TreeNodesResponse, error)
+	// LeafKeys returns the digests of the keys of a range that belong to the given leaves.
+	LeafKeys(context.Context, *LeafKeysRequest) (*LeafKeysResponse, error)
+	// Trigger starts an anti-entropy round without waiting for the schedule.
+	Trigger(context.Context, *TriggerRequest) (*TriggerResponse, error)
+	// Status returns the schedule and the results of the last round.
+	Status(context.Context, *StatusRequest) (*StatusResponse, error)
+	mustEmbedUnimplementedAntiEntropyServiceServer()
+}
+
+// UnimplementedAntiEntropyServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAntiEntropyServiceServer struct {
+}
+
+func (UnimplementedAntiEntropyServiceServer) TreeNodes(context.Context, *TreeNodesRequest) (*TreeNodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TreeNodes not implemented")
+}
+func (UnimplementedAntiEntropyServiceServer) LeafKeys(context.Context, *LeafKeysRequest) (*LeafKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LeafKeys not implemented")
+}
+func (UnimplementedAntiEntropyServiceServer) Trigger(context.Context, *TriggerRequest) (*TriggerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Trigger not implemented")
+}
+func (UnimplementedAntiEntropyServiceServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedAntiEntropyServiceServer) mustEmbedUnimplementedAntiEntropyServiceServer() {}
+
+// UnsafeAntiEntropyServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AntiEntropyServiceServer will
+// result in compilation errors.
+type UnsafeAntiEntropyServiceServer interface {
+	mustEmbedUnimplementedAntiEntropyServiceServer()
+}
+
+func RegisterAntiEntropyServiceServer(s grpc.ServiceRegistrar, srv AntiEntropyServiceServer) {
+	s.RegisterService(&AntiEntropyService_ServiceDesc, srv)
+}
+
+func _AntiEntropyService_TreeNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TreeNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AntiEntropyServiceServer).TreeNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/antientropy.AntiEntropyService/TreeNodes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AntiEntropyServiceServer).TreeNodes(ctx, req.(*TreeNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AntiEntropyService_LeafKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeafKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AntiEntropyServiceServer).LeafKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/antientropy.AntiEntropyService/LeafKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AntiEntropyServiceServer).LeafKeys(ctx, req.(*LeafKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AntiEntropyService_Trigger_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriggerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AntiEntropyServiceServer).Trigger(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/antientropy.AntiEntropyService/Trigger",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AntiEntropyServiceServer).Trigger(ctx, req.(*TriggerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AntiEntropyService_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AntiEntropyServiceServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/antientropy.AntiEntropyService/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AntiEntropyServiceServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AntiEntropyService_ServiceDesc is the grpc.ServiceDesc for AntiEntropyService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AntiEntropyService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "antientropy.AntiEntropyService",
+	HandlerType: (*AntiEntropyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "TreeNodes",
+			Handler:    _AntiEntropyService_TreeNodes_Handler,
+		},
+		{
+			MethodName: "LeafKeys",
+			Handler:    _AntiEntropyService_LeafKeys_Handler,
+		},
+		{
+			MethodName: "Trigger",
+			Handler:    _AntiEntropyService_Trigger_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _AntiEntropyService_Status_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "antientropy/proto/antientropy.proto",
+}
@@ -0,0 +1,631 @@
+package antientropy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/maxpoletaev/kv/antientropy/proto"
+	"github.com/maxpoletaev/kv/clust"
+	"github.com/maxpoletaev/kv/clust/ring"
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/multierror"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/storage"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+const (
+	defaultInterval  = 10 * time.Minute
+	defaultTreeDepth = 10
+	handoffBatchSize = 100
+)
+
+var errRoundInProgress = errors.New("anti-entropy round is already in progress")
+
+// Status is a snapshot of the schedule and the results of the anti-entropy rounds.
+type Status struct {
+	// Running is true while a round is in progress.
+	Running bool
+	// Interval is the time between the scheduled rounds.
+	Interval time.Duration
+	// LastStartedAt and LastFinishedAt are the times the last round started and finished at.
+	LastStartedAt  time.Time
+	LastFinishedAt time.Time
+	// NextRunAt is the time the next scheduled round starts at.
+	NextRunAt time.Time
+	// RoundsCompleted is the number of rounds completed without errors since the start.
+	RoundsCompleted int64
+	// RangesCompared is the number of ranges compared with the replicas by the last round.
+	RangesCompared int64
+	// LeavesDiffered is the number of tree leaves that differed from the replicas in the last round.
+	LeavesDiffered int64
+	// KeysRepaired is the number of keys sent to or received from the replicas by the last round.
+	KeysRepaired int64
+	// LastError is the error the last round has failed with, if any.
+	LastError string
+}
+
+// KeyDigest is the digest of the versions of a key stored on this node.
+type KeyDigest struct {
+	Key    string
+	Digest uint64
+}
+
+// keyRange is the set of keys stored on the same replicas.
+type keyRange struct {
+	replicas []membership.NodeID
+	tree     *tree
+	leaves   map[int][]string
+}
+
+// Repairer brings the keys that are never read back in sync between the replicas. The keys
+// stored on this node are grouped into ranges by the replicas they belong to, and each range
+// has a Merkle tree. The trees are periodically rebuilt from a scan of the local storage and
+// compared with the trees of the same ranges on the other replicas, starting from the root
+// and descending only into the subtrees that differ. The keys of the differing leaves are then
+// exchanged with the replica in both directions, the same way as the keys moved by rebalancing,
+// so that the newer versions are never overwritten by the older ones.
+type Repairer struct {
+	cluster   Cluster
+	storage   Storage
+	logger    log.Logger
+	interval  time.Duration
+	depth     int
+	placement *ring.Placement
+	trigger   chan struct{}
+	empty     *tree
+
+	mut    sync.Mutex
+	ranges map[string]*keyRange
+	status Status
+}
+
+func New(cluster Cluster, s Storage, logger log.Logger, opts ...option) *Repairer {
+	r := &Repairer{
+		cluster:   cluster,
+		storage:   s,
+		logger:    logger,
+		interval:  defaultInterval,
+		depth:     defaultTreeDepth,
+		placement: ring.DefaultPlacement(),
+		trigger:   make(chan struct{}, 1),
+		ranges:    make(map[string]*keyRange),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.empty = newTree(r.depth)
+	r.empty.build()
+	r.status.Interval = r.interval
+
+	return r
+}
+
+func (r *Repairer) RunLoop(ctx context.Context) {
+	level.Info(r.logger).Log(
+		"msg", "anti-entropy loop started",
+		"interval", r.interval,
+		"tree_depth", r.depth,
+	)
+
+	for {
+		r.mut.Lock()
+		r.status.NextRunAt = time.Now().Add(r.interval)
+		r.mut.Unlock()
+
+		select {
+		case <-time.After(r.interval):
+			// noop
+		case <-r.trigger:
+			// noop
+		case <-ctx.Done():
+			return
+		}
+
+		if err := r.Sync(ctx); err != nil {
+			level.Error(r.logger).Log("msg", "anti-entropy round failed", "err", err)
+		}
+	}
+}
+
+// Trigger makes the loop start a round without waiting for the schedule. It returns
+// false if a round is already in progress or has already been triggered.
+func (r *Repairer) Trigger() bool {
+	if r.Status().Running {
+		return false
+	}
+
+	select {
+	case r.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Status returns the schedule and the results of the last round.
+func (r *Repairer) Status() Status {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	return r.status
+}
+
+// Sync rebuilds the trees and compares them with the trees of the other replicas, repairing
+// the keys that differ. The replicas that are not reachable are skipped until the next round.
+func (r *Repairer) Sync(ctx context.Context) error {
+	r.mut.Lock()
+
+	if r.status.Running {
+		r.mut.Unlock()
+		return errRoundInProgress
+	}
+
+	r.status.Running = true
+	r.status.LastStartedAt = time.Now()
+	r.mut.Unlock()
+
+	result := Status{}
+	err := r.sync(ctx, &result)
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	r.status.Running = false
+	r.status.LastFinishedAt = time.Now()
+	r.status.RangesCompared = result.RangesCompared
+	r.status.LeavesDiffered = result.LeavesDiffered
+	r.status.KeysRepaired = result.KeysRepaired
+	r.status.LastError = ""
+
+	if err != nil {
+		r.status.LastError = err.Error()
+		return err
+	}
+
+	r.status.RoundsCompleted++
+
+	return nil
+}
+
+func (r *Repairer) sync(ctx context.Context, result *Status) error {
+	if err := r.Rebuild(); err != nil {
+		return fmt.Errorf("failed to rebuild trees: %w", err)
+	}
+
+	r.mut.Lock()
+	ranges := make([]*keyRange, 0, len(r.ranges))
+
+	for _, rng := range r.ranges {
+		ranges = append(ranges, rng)
+	}
+	r.mut.Unlock()
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return rangeID(ranges[i].replicas) < rangeID(ranges[j].replicas)
+	})
+
+	self := r.cluster.Self()
+	errs := multierror.New[membership.NodeID]()
+
+	for _, peer := range r.cluster.Members() {
+		if peer.ID == self.ID || !peer.IsReacheable() {
+			continue
+		}
+
+		if err := r.syncPeer(ctx, self.ID, peer, ranges, result); err != nil {
+			errs.Add(peer.ID, err)
+		}
+	}
+
+	return errs.Ret()
+}
+
+// syncPeer compares the ranges replicated to both this node and the peer.
+func (r *Repairer) syncPeer(ctx context.Context, self membership.NodeID, peer membership.Member, ranges []*keyRange, result *Status) error {
+	conn, err := r.cluster.Conn(peer.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	for _, rng := range ranges {
+		if !containsNode(rng.replicas, self) || !containsNode(rng.replicas, peer.ID) {
+			continue
+		}
+
+		leaves, err := r.diff(ctx, conn, rng)
+		if err != nil {
+			return err
+		}
+
+		result.RangesCompared++
+
+		if len(leaves) == 0 {
+			continue
+		}
+
+		repaired, err := r.repair(ctx, conn, rng, leaves)
+		if err != nil {
+			return err
+		}
+
+		result.LeavesDiffered += int64(len(leaves))
+		result.KeysRepaired += int64(repaired)
+
+		level.Info(r.logger).Log(
+			"msg", "replica repaired",
+			"node", peer.Name,
+			"range", rangeID(rng.replicas),
+			"leaves", len(leaves),
+			"keys", repaired,
+		)
+	}
+
+	return nil
+}
+
+// diff returns the leaves of the range that differ between this node and the peer.
+func (r *Repairer) diff(ctx context.Context, conn clust.Conn, rng *keyRange) ([]uint32, error) {
+	indices := []uint32{0}
+
+	for lvl := 0; ; lvl++ {
+		resp, err := conn.TreeNodes(ctx, &proto.TreeNodesRequest{
+			Replicas: toProtoNodes(rng.replicas),
+			Level:    uint32(lvl),
+			Indices:  indices,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tree nodes: %w", err)
+		}
+
+		if int(resp.Depth) != rng.tree.depth {
+			return nil, fmt.Errorf("tree depth mismatch: %d != %d", resp.Depth, rng.tree.depth)
+		}
+
+		local, err := rng.tree.nodes(lvl, indices)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(resp.Hashes) != len(local) {
+			return nil, fmt.Errorf("expected %d tree nodes, got %d", len(local), len(resp.Hashes))
+		}
+
+		differ := make([]uint32, 0)
+
+		for i := range indices {
+			if local[i] != resp.Hashes[i] {
+				differ = append(differ, indices[i])
+			}
+		}
+
+		if lvl == rng.tree.depth || len(differ) == 0 {
+			return differ, nil
+		}
+
+		indices = make([]uint32, 0, 2*len(differ))
+		for _, idx := range differ {
+			indices = append(indices, 2*idx, 2*idx+1)
+		}
+	}
+}
+
+// repair exchanges the keys of the given leaves that differ between this node and the peer.
+// The local versions are sent to the peer, and the versions of the peer are stored locally.
+// It returns the number of keys that differed.
+func (r *Repairer) repair(ctx context.Context, conn clust.Conn, rng *keyRange, leaves []uint32) (int, error) {
+	resp, err := conn.LeafKeys(ctx, &proto.LeafKeysRequest{
+		Replicas: toProtoNodes(rng.replicas),
+		Leaves:   leaves,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get leaf keys: %w", err)
+	}
+
+	remote := make(map[string]uint64, len(resp.Keys))
+	for _, kd := range resp.Keys {
+		remote[kd.Key] = kd.Digest
+	}
+
+	local := make(map[string]uint64)
+	push := make([]*storagepb.HandoffEntry, 0)
+
+	err = r.readLeaves(rng.replicas, leaves, func(key string, values []storage.Value) {
+		d := digest(key, values)
+		local[key] = d
+
+		if remoteDigest, ok := remote[key]; !ok || remoteDigest != d {
+			push = append(push, &storagepb.HandoffEntry{
+				Key:    key,
+				Values: toProtoValues(values),
+			})
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read local keys: %w", err)
+	}
+
+	pull := make([]*storagepb.HandoffEntry, 0)
+
+	for _, kd := range resp.Keys {
+		if localDigest, ok := local[kd.Key]; ok && localDigest == kd.Digest {
+			continue
+		}
+
+		getResp, err := conn.Get(ctx, &storagepb.GetRequest{Key: kd.Key})
+		if err != nil {
+			return 0, fmt.Errorf("failed to get key %s: %w", kd.Key, err)
+		}
+
+		if len(getResp.Value) > 0 {
+			pull = append(pull, &storagepb.HandoffEntry{
+				Key:    kd.Key,
+				Values: getResp.Value,
+			})
+		}
+	}
+
+	if err := handoff(ctx, conn, push); err != nil {
+		return 0, fmt.Errorf("failed to send keys: %w", err)
+	}
+
+	if err := handoff(ctx, r.cluster.SelfConn(), pull); err != nil {
+		return 0, fmt.Errorf("failed to store received keys: %w", err)
+	}
+
+	repaired := make(map[string]bool, len(push)+len(pull))
+
+	for _, entry := range push {
+		repaired[entry.Key] = true
+	}
+
+	for _, entry := range pull {
+		repaired[entry.Key] = true
+	}
+
+	return len(repaired), nil
+}
+
+// handoff stores the entries on the node through the handoff stream, in batches.
+func handoff(ctx context.Context, conn clust.Conn, entries []*storagepb.HandoffEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	stream, err := conn.Handoff(ctx)
+	if err != nil {
+		return err
+	}
+
+	lastKeys := make([]string, 0)
+
+	for start := 0; start < len(entries); start += handoffBatchSize {
+		end := start + handoffBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		if err := stream.Send(&storagepb.HandoffRequest{Entries: entries[start:end]}); err != nil {
+			return err
+		}
+
+		lastKeys = append(lastKeys, entries[end-1].Key)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for _, lastKey := range lastKeys {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		if resp.LastKey != lastKey {
+			return fmt.Errorf("node confirmed key %q instead of %q", resp.LastKey, lastKey)
+		}
+	}
+
+	if _, err := stream.Recv(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// Rebuild rebuilds the Merkle trees from a scan of the local storage, and remembers the keys
+// of each leaf, so that the keys of the differing leaves can be found without another scan.
+// The trees are kept as they were if the scan fails, since the trees of a partial scan would
+// make the keys that have not been scanned look missing to the other replicas.
+func (r *Repairer) Rebuild() error {
+	hashRing := r.buildRing()
+	ranges := make(map[string]*keyRange)
+	it := storage.NewKeyIterator(r.storage.Scan())
+
+	for {
+		key, values, ok := it.Next()
+		if !ok {
+			break
+		}
+
+		replicas := sortedNodes(hashRing.PreferenceList(key, r.placement.ReplicationFactor()))
+		id := rangeID(replicas)
+
+		rng, ok := ranges[id]
+		if !ok {
+			rng = &keyRange{
+				replicas: replicas,
+				tree:     newTree(r.depth),
+				leaves:   make(map[int][]string),
+			}
+
+			ranges[id] = rng
+		}
+
+		leaf := leafIndex(key, r.depth)
+		rng.leaves[leaf] = append(rng.leaves[leaf], key)
+		rng.tree.add(key, digest(key, values))
+	}
+
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	for _, rng := range ranges {
+		rng.tree.build()
+	}
+
+	r.mut.Lock()
+	r.ranges = ranges
+	r.mut.Unlock()
+
+	return nil
+}
+
+// TreeNodes returns the depth of the trees and the hashes of the tree nodes of the range
+// stored on the given replicas. The range without local keys has an empty tree.
+func (r *Repairer) TreeNodes(replicas []membership.NodeID, lvl int, indices []uint32) (int, []uint64, error) {
+	r.mut.Lock()
+	t := r.empty
+
+	if rng, ok := r.ranges[rangeID(sortedNodes(replicas))]; ok {
+		t = rng.tree
+	}
+	r.mut.Unlock()
+
+	hashes, err := t.nodes(lvl, indices)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return t.depth, hashes, nil
+}
+
+// LeafKeys returns the digests of the local keys of the range stored on the given
+// replicas that belong to the given leaves. The keys are the ones the trees were built
+// from, but the digests are computed from their current values, so they reflect the
+// writes made since the trees were built. The keys added since then are left for the
+// next round.
+func (r *Repairer) LeafKeys(replicas []membership.NodeID, leaves []uint32) ([]KeyDigest, error) {
+	keys := make([]KeyDigest, 0)
+
+	err := r.readLeaves(sortedNodes(replicas), leaves, func(key string, values []storage.Value) {
+		keys = append(keys, KeyDigest{
+			Key:    key,
+			Digest: digest(key, values),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// readLeaves calls fn with the current values of each key of the range that belongs to one
+// of the leaves, as listed by the last rebuild. The keys that have been removed from this
+// node since then are skipped.
+func (r *Repairer) readLeaves(replicas []membership.NodeID, leaves []uint32, fn func(string, []storage.Value)) error {
+	r.mut.Lock()
+	rng, ok := r.ranges[rangeID(replicas)]
+	r.mut.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	for _, leaf := range leaves {
+		for _, key := range rng.leaves[int(leaf)] {
+			values, err := r.storage.Get(key)
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			} else if err != nil {
+				return fmt.Errorf("failed to get %s: %w", key, err)
+			}
+
+			fn(key, values)
+		}
+	}
+
+	return nil
+}
+
+func (r *Repairer) buildRing() *ring.Ring {
+	members := r.cluster.Members()
+	ids := make([]membership.NodeID, 0, len(members))
+
+	for i := range members {
+		ids = append(ids, members[i].ID)
+	}
+
+	return r.placement.Ring(ids)
+}
+
+// rangeID returns the identifier of the range stored on the given replicas, which must be sorted.
+func rangeID(replicas []membership.NodeID) string {
+	parts := make([]string, 0, len(replicas))
+	for _, id := range replicas {
+		parts = append(parts, strconv.FormatUint(uint64(id), 10))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func sortedNodes(ids []membership.NodeID) []membership.NodeID {
+	sorted := make([]membership.NodeID, len(ids))
+	copy(sorted, ids)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	return sorted
+}
+
+func containsNode(ids []membership.NodeID, id membership.NodeID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toProtoNodes(ids []membership.NodeID) []uint32 {
+	result := make([]uint32, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, uint32(id))
+	}
+
+	return result
+}
+
+func toProtoValues(values []storage.Value) []*storagepb.VersionedValue {
+	result := make([]*storagepb.VersionedValue, 0, len(values))
+
+	for _, value := range values {
+		result = append(result, &storagepb.VersionedValue{
+			Version:   dvv.MustEncode(value.DottedVersion()),
+			Data:      value.Data,
+			Timestamp: uint64(value.Timestamp),
+			Origin:    value.Origin,
+			Tombstone: value.Tombstone,
+		})
+	}
+
+	return result
+}
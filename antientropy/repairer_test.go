@@ -0,0 +1,373 @@
+package antientropy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/maxpoletaev/kv/antientropy/proto"
+	"github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/clust/ring"
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/inmemory"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+// fakeHandoffStream writes the received keys straight into the storage engine.
+type fakeHandoffStream struct {
+	grpc.ClientStream
+	engine storage.Engine
+	acks   []string
+}
+
+func (s *fakeHandoffStream) Send(req *storagepb.HandoffRequest) error {
+	for _, entry := range req.Entries {
+		for _, v := range entry.Values {
+			version, err := dvv.Decode(v.Version)
+			if err != nil {
+				return err
+			}
+
+			err = s.engine.Put(entry.Key, storage.Value{
+				Version: version.Context,
+				Dot:     version.Dot,
+				Data:    v.Data,
+			})
+			if err != nil && !errors.Is(err, storage.ErrObsoleteWrite) {
+				return err
+			}
+		}
+	}
+
+	s.acks = append(s.acks, req.Entries[len(req.Entries)-1].Key)
+
+	return nil
+}
+
+func (s *fakeHandoffStream) Recv() (*storagepb.HandoffResponse, error) {
+	if len(s.acks) == 0 {
+		return nil, io.EOF
+	}
+
+	resp := &storagepb.HandoffResponse{LastKey: s.acks[0]}
+	s.acks = s.acks[1:]
+
+	return resp, nil
+}
+
+func (s *fakeHandoffStream) CloseSend() error {
+	return nil
+}
+
+type testNode struct {
+	member   membership.Member
+	engine   *inmemory.InMemoryEngine
+	repairer *Repairer
+}
+
+// conn returns a connection that serves the requests with the storage and the trees of the node.
+func (n *testNode) conn(ctrl *gomock.Controller) *mock.MockClient {
+	conn := mock.NewMockClient(ctrl)
+
+	conn.EXPECT().TreeNodes(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *proto.TreeNodesRequest) (*proto.TreeNodesResponse, error) {
+			depth, hashes, err := n.repairer.TreeNodes(fromProtoNodes(req.Replicas), int(req.Level), req.Indices)
+			return &proto.TreeNodesResponse{Depth: uint32(depth), Hashes: hashes}, err
+		}).AnyTimes()
+
+	conn.EXPECT().LeafKeys(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *proto.LeafKeysRequest) (*proto.LeafKeysResponse, error) {
+			digests, err := n.repairer.LeafKeys(fromProtoNodes(req.Replicas), req.Leaves)
+			if err != nil {
+				return nil, err
+			}
+
+			resp := &proto.LeafKeysResponse{}
+			for _, kd := range digests {
+				resp.Keys = append(resp.Keys, &proto.KeyDigest{Key: kd.Key, Digest: kd.Digest})
+			}
+
+			return resp, nil
+		}).AnyTimes()
+
+	conn.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *storagepb.GetRequest) (*storagepb.GetResponse, error) {
+			values, err := n.engine.Get(req.Key)
+			if err != nil {
+				return nil, err
+			}
+
+			return &storagepb.GetResponse{Value: toProtoValues(values)}, nil
+		}).AnyTimes()
+
+	conn.EXPECT().Handoff(gomock.Any()).DoAndReturn(
+		func(ctx context.Context) (storagepb.StorageService_HandoffClient, error) {
+			return &fakeHandoffStream{engine: n.engine}, nil
+		}).AnyTimes()
+
+	return conn
+}
+
+func fromProtoNodes(ids []uint32) []membership.NodeID {
+	nodes := make([]membership.NodeID, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, membership.NodeID(id))
+	}
+
+	return nodes
+}
+
+func setupNodes(t *testing.T, ctrl *gomock.Controller, n int, opts ...option) []*testNode {
+	nodes := make([]*testNode, 0, n)
+	members := make([]membership.Member, 0, n)
+
+	for i := 1; i <= n; i++ {
+		members = append(members, membership.Member{
+			ID:     membership.NodeID(i),
+			Name:   fmt.Sprintf("node%d", i),
+			Status: membership.StatusHealthy,
+		})
+	}
+
+	for i := range members {
+		nodes = append(nodes, &testNode{
+			member: members[i],
+			engine: inmemory.New(),
+		})
+	}
+
+	for _, node := range nodes {
+		cluster := NewMockCluster(ctrl)
+		cluster.EXPECT().Self().Return(node.member).AnyTimes()
+		cluster.EXPECT().Members().Return(members).AnyTimes()
+		cluster.EXPECT().SelfConn().Return(node.conn(ctrl)).AnyTimes()
+
+		for _, peer := range nodes {
+			cluster.EXPECT().Conn(peer.member.ID).Return(peer.conn(ctrl), nil).AnyTimes()
+		}
+
+		node.repairer = New(cluster, node.engine, kitlog.NewNopLogger(), opts...)
+	}
+
+	return nodes
+}
+
+func put(t *testing.T, engine storage.Engine, key string, version vclock.V) {
+	require.NoError(t, engine.Put(key, storage.Value{
+		Version: vclock.New(version),
+		Data:    []byte(key),
+	}))
+}
+
+func versionOf(t *testing.T, engine storage.Engine, key string) *vclock.Vector {
+	values, err := engine.Get(key)
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+
+	return values[0].Version
+}
+
+func TestSync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nodes := setupNodes(t, ctrl, 2, WithPlacement(ring.NewPlacement(2, ring.DefaultVirtualNodes)), WithTreeDepth(6))
+	node1, node2 := nodes[0], nodes[1]
+
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("key%03d", i)
+		put(t, node1.engine, key, vclock.V{1: 1})
+		put(t, node2.engine, key, vclock.V{1: 1})
+	}
+
+	put(t, node1.engine, "only1", vclock.V{1: 1})
+	put(t, node2.engine, "only2", vclock.V{2: 1})
+	put(t, node1.engine, "diverged", vclock.V{1: 1})
+	put(t, node2.engine, "diverged", vclock.V{1: 1, 2: 1})
+
+	require.NoError(t, node2.repairer.Rebuild())
+	require.NoError(t, node1.repairer.Sync(context.Background()))
+
+	// The missing keys are copied in both directions, and the newer version wins.
+	assert.Equal(t, vclock.New(vclock.V{1: 1}), versionOf(t, node2.engine, "only1"))
+	assert.Equal(t, vclock.New(vclock.V{2: 1}), versionOf(t, node1.engine, "only2"))
+	assert.Equal(t, vclock.New(vclock.V{1: 1, 2: 1}), versionOf(t, node1.engine, "diverged"))
+	assert.Equal(t, vclock.New(vclock.V{1: 1, 2: 1}), versionOf(t, node2.engine, "diverged"))
+
+	status := node1.repairer.Status()
+	assert.Equal(t, int64(1), status.RoundsCompleted)
+	assert.Equal(t, int64(1), status.RangesCompared)
+	assert.Equal(t, int64(3), status.KeysRepaired)
+	assert.LessOrEqual(t, status.LeavesDiffered, int64(3))
+	assert.Greater(t, status.LeavesDiffered, int64(0))
+	assert.Empty(t, status.LastError)
+
+	// The trees are the same now, so nothing else is repaired.
+	require.NoError(t, node2.repairer.Rebuild())
+	require.NoError(t, node1.repairer.Sync(context.Background()))
+	assert.Equal(t, int64(0), node1.repairer.Status().KeysRepaired)
+	assert.Equal(t, int64(0), node1.repairer.Status().LeavesDiffered)
+}
+
+func TestSync_OnlySharedRanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nodes := setupNodes(t, ctrl, 3, WithPlacement(ring.NewPlacement(2, ring.DefaultVirtualNodes)))
+
+	for i := 0; i < 100; i++ {
+		put(t, nodes[0].engine, fmt.Sprintf("key%03d", i), vclock.V{1: 1})
+	}
+
+	require.NoError(t, nodes[0].repairer.Sync(context.Background()))
+
+	// Each node only gets the keys it is a replica of.
+	for _, node := range nodes[1:] {
+		it := storage.NewKeyIterator(node.engine.Scan())
+
+		for {
+			key, _, ok := it.Next()
+			if !ok {
+				break
+			}
+
+			replicas := node.repairer.buildRing().PreferenceList(key, 2)
+			assert.Contains(t, replicas, node.member.ID)
+			assert.Contains(t, replicas, membership.NodeID(1))
+		}
+	}
+
+	assert.Equal(t, int64(2), nodes[0].repairer.Status().RangesCompared)
+}
+
+// failingStorage is a storage whose scans fail after the first key.
+type failingStorage struct {
+	*inmemory.InMemoryEngine
+}
+
+func (s failingStorage) Scan() storage.ScanIterator {
+	return &failingIterator{ScanIterator: s.InMemoryEngine.Scan()}
+}
+
+type failingIterator struct {
+	storage.ScanIterator
+	read bool
+}
+
+func (i *failingIterator) HasNext() bool {
+	return !i.read && i.ScanIterator.HasNext()
+}
+
+func (i *failingIterator) Next() (string, storage.Value) {
+	i.read = true
+	return i.ScanIterator.Next()
+}
+
+func (i *failingIterator) Err() error {
+	if i.read {
+		return assert.AnError
+	}
+
+	return nil
+}
+
+func TestSync_ScanError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nodes := setupNodes(t, ctrl, 2)
+
+	for i := 0; i < 10; i++ {
+		put(t, nodes[0].engine, fmt.Sprintf("key%d", i), vclock.V{1: 1})
+	}
+
+	// The round must fail rather than treat the keys that have not been scanned as missing.
+	repairer := New(nodes[0].repairer.cluster, failingStorage{nodes[0].engine}, kitlog.NewNopLogger())
+	require.ErrorIs(t, repairer.Sync(context.Background()), assert.AnError)
+	assert.NotEmpty(t, repairer.Status().LastError)
+
+	// No trees have been built from the partial scan, so there are no keys to compare.
+	keys, err := repairer.LeafKeys([]membership.NodeID{1, 2}, []uint32{0})
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestLeafKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nodes := setupNodes(t, ctrl, 1, WithPlacement(ring.NewPlacement(1, ring.DefaultVirtualNodes)), WithTreeDepth(0))
+	node := nodes[0]
+
+	put(t, node.engine, "key1", vclock.V{1: 1})
+	put(t, node.engine, "key2", vclock.V{1: 1})
+	require.NoError(t, node.repairer.Rebuild())
+
+	// The keys come from the last rebuild, and their digests from the current values.
+	put(t, node.engine, "key2", vclock.V{1: 2})
+	put(t, node.engine, "key3", vclock.V{1: 1})
+
+	keys, err := node.repairer.LeafKeys([]membership.NodeID{1}, []uint32{0})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	values, err := node.engine.Get("key2")
+	require.NoError(t, err)
+
+	assert.Equal(t, "key1", keys[0].Key)
+	assert.Equal(t, "key2", keys[1].Key)
+	assert.Equal(t, digest("key2", values), keys[1].Digest)
+}
+
+func TestTree_Diff(t *testing.T) {
+	a, b := newTree(4), newTree(4)
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%03d", i)
+		a.add(key, uint64(i))
+		b.add(key, uint64(i))
+	}
+
+	a.build()
+	b.build()
+	assert.Equal(t, a.levels, b.levels)
+
+	// The order of the keys does not matter.
+	c := newTree(4)
+	for i := 99; i >= 0; i-- {
+		c.add(fmt.Sprintf("key%03d", i), uint64(i))
+	}
+
+	c.build()
+	assert.Equal(t, a.levels, c.levels)
+
+	// A changed key changes its leaf and the path to the root, but nothing else.
+	b.add("key042", 42)
+	b.add("key042", 4242)
+	b.build()
+
+	leaf := leafIndex("key042", 4)
+
+	for lvl := 4; lvl >= 0; lvl-- {
+		idx := leaf >> (4 - lvl)
+
+		for i := range a.levels[lvl] {
+			if i == idx {
+				assert.NotEqual(t, a.levels[lvl][i], b.levels[lvl][i])
+			} else {
+				assert.Equal(t, a.levels[lvl][i], b.levels[lvl][i])
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/antientropy"
+	"github.com/maxpoletaev/kv/antientropy/proto"
+	"github.com/maxpoletaev/kv/membership"
+)
+
+type Repairer interface {
+	TreeNodes(replicas []membership.NodeID, level int, indices []uint32) (int, []uint64, error)
+	LeafKeys(replicas []membership.NodeID, leaves []uint32) ([]antientropy.KeyDigest, error)
+	Trigger() bool
+	Status() antientropy.Status
+}
+
+// AntiEntropyService serves the Merkle trees of this node to the other replicas,
+// and exposes the schedule of the anti-entropy rounds.
+type AntiEntropyService struct {
+	proto.UnimplementedAntiEntropyServiceServer
+	repairer Repairer
+}
+
+func New(repairer Repairer) *AntiEntropyService {
+	return &AntiEntropyService{
+		repairer: repairer,
+	}
+}
+
+func (s *AntiEntropyService) TreeNodes(ctx context.Context, req *proto.TreeNodesRequest) (*proto.TreeNodesResponse, error) {
+	depth, hashes, err := s.repairer.TreeNodes(fromProtoNodes(req.Replicas), int(req.Level), req.Indices)
+	if err != nil {
+		return nil, status.New(codes.InvalidArgument, fmt.Sprintf("invalid tree nodes: %s", err)).Err()
+	}
+
+	return &proto.TreeNodesResponse{
+		Depth:  uint32(depth),
+		Hashes: hashes,
+	}, nil
+}
+
+func (s *AntiEntropyService) LeafKeys(ctx context.Context, req *proto.LeafKeysRequest) (*proto.LeafKeysResponse, error) {
+	digests, err := s.repairer.LeafKeys(fromProtoNodes(req.Replicas), req.Leaves)
+	if err != nil {
+		return nil, status.New(codes.Internal, fmt.Sprintf("failed to scan leaf keys: %s", err)).Err()
+	}
+
+	keys := make([]*proto.KeyDigest, 0, len(digests))
+
+	for _, kd := range digests {
+		keys = append(keys, &proto.KeyDigest{
+			Key:    kd.Key,
+			Digest: kd.Digest,
+		})
+	}
+
+	return &proto.LeafKeysResponse{Keys: keys}, nil
+}
+
+func (s *AntiEntropyService) Trigger(ctx context.Context, req *proto.TriggerRequest) (*proto.TriggerResponse, error) {
+	return &proto.TriggerResponse{
+		Started: s.repairer.Trigger(),
+	}, nil
+}
+
+func (s *AntiEntropyService) Status(ctx context.Context, req *proto.StatusRequest) (*proto.StatusResponse, error) {
+	st := s.repairer.Status()
+
+	return &proto.StatusResponse{
+		Running:         st.Running,
+		IntervalMs:      st.Interval.Milliseconds(),
+		LastStartedAt:   unixMilli(st.LastStartedAt),
+		LastFinishedAt:  unixMilli(st.LastFinishedAt),
+		NextRunAt:       unixMilli(st.NextRunAt),
+		RoundsCompleted: st.RoundsCompleted,
+		RangesCompared:  st.RangesCompared,
+		LeavesDiffered:  st.LeavesDiffered,
+		KeysRepaired:    st.KeysRepaired,
+		LastError:       st.LastError,
+	}, nil
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixMilli()
+}
+
+func fromProtoNodes(ids []uint32) []membership.NodeID {
+	nodes := make([]membership.NodeID, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, membership.NodeID(id))
+	}
+
+	return nodes
+}
@@ -43,6 +43,11 @@ func (c *Cluster) Conn(id membership.NodeID) (Conn, error) {
 	return c.conns.Get(id)
 }
 
+// MemberStats returns the latency and the errors of the calls made to the member by this node.
+func (c *Cluster) MemberStats(id membership.NodeID) (membership.Stats, bool) {
+	return c.conns.MemberStats(id)
+}
+
 // Self returns the local member which represents the current node.
 // The member registry must guarantee that the local member is always present.
 func (c *Cluster) Self() membership.Member {
@@ -5,6 +5,7 @@ package clust
 import (
 	"context"
 
+	antientropypb "github.com/maxpoletaev/kv/antientropy/proto"
 	faildetectorpb "github.com/maxpoletaev/kv/faildetector/proto"
 	membershippb "github.com/maxpoletaev/kv/membership/proto"
 	storagepb "github.com/maxpoletaev/kv/storage/proto"
@@ -16,6 +17,13 @@ type Conn interface {
 	Members(ctx context.Context) (*membershippb.MembersResponse, error)
 	Get(ctx context.Context, req *storagepb.GetRequest) (*storagepb.GetResponse, error)
 	Put(ctx context.Context, req *storagepb.PutRequest) (*storagepb.PutResponse, error)
+	Merge(ctx context.Context, req *storagepb.MergeRequest) (*storagepb.MergeResponse, error)
+	MultiGet(ctx context.Context, req *storagepb.MultiGetRequest) (*storagepb.MultiGetResponse, error)
+	MultiPut(ctx context.Context, req *storagepb.MultiPutRequest) (*storagepb.MultiPutResponse, error)
+	Repair(ctx context.Context, req *storagepb.RepairRequest) (*storagepb.RepairResponse, error)
+	Handoff(ctx context.Context) (storagepb.StorageService_HandoffClient, error)
+	TreeNodes(ctx context.Context, req *antientropypb.TreeNodesRequest) (*antientropypb.TreeNodesResponse, error)
+	LeafKeys(ctx context.Context, req *antientropypb.LeafKeysRequest) (*antientropypb.LeafKeysResponse, error)
 	PingDirect(ctx context.Context) (*faildetectorpb.PingResponse, error)
 	PingIndirect(ctx context.Context, req *faildetectorpb.PingRequest) (*faildetectorpb.PingResponse, error)
 	IsClosed() bool
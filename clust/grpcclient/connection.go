@@ -6,6 +6,7 @@ import (
 
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	antientropypb "github.com/maxpoletaev/kv/antientropy/proto"
 	faildetectorpb "github.com/maxpoletaev/kv/faildetector/proto"
 	"github.com/maxpoletaev/kv/internal/multierror"
 	membershippb "github.com/maxpoletaev/kv/membership/proto"
@@ -17,6 +18,7 @@ type GrpcClient struct {
 	faildetectorClient faildetectorpb.FailDetectorServiceClient
 	membershipClient   membershippb.MembershipServiceClient
 	storageClient      storagepb.StorageServiceClient
+	antientropyClient  antientropypb.AntiEntropyServiceClient
 	onClose            []func() error
 	closed             uint32
 }
@@ -57,6 +59,43 @@ func (c *GrpcClient) Put(ctx context.Context, req *storagepb.PutRequest) (*stora
 	return c.storageClient.Put(ctx, req)
 }
 
+// MultiGet returns the values of several keys at once, with the error of each key in its result.
+func (c *GrpcClient) MultiGet(ctx context.Context, req *storagepb.MultiGetRequest) (*storagepb.MultiGetResponse, error) {
+	return c.storageClient.MultiGet(ctx, req)
+}
+
+// MultiPut applies several puts at once, with the error of each put in its result.
+func (c *GrpcClient) MultiPut(ctx context.Context, req *storagepb.MultiPutRequest) (*storagepb.MultiPutResponse, error) {
+	return c.storageClient.MultiPut(ctx, req)
+}
+
+// Repair stores the given versions of the key on the node, keeping the versions it already has
+// unless they are covered by the given ones.
+func (c *GrpcClient) Repair(ctx context.Context, req *storagepb.RepairRequest) (*storagepb.RepairResponse, error) {
+	return c.storageClient.Repair(ctx, req)
+}
+
+// Merge adds a merge operand to the given key, which is combined with the value by the merge operator.
+func (c *GrpcClient) Merge(ctx context.Context, req *storagepb.MergeRequest) (*storagepb.MergeResponse, error) {
+	return c.storageClient.Merge(ctx, req)
+}
+
+// Handoff opens a stream to move the keys to the node. The batches of keys sent to the
+// stream are acknowledged by the node once they are stored.
+func (c *GrpcClient) Handoff(ctx context.Context) (storagepb.StorageService_HandoffClient, error) {
+	return c.storageClient.Handoff(ctx)
+}
+
+// TreeNodes returns the hashes of the Merkle tree nodes of a key range stored on the node.
+func (c *GrpcClient) TreeNodes(ctx context.Context, req *antientropypb.TreeNodesRequest) (*antientropypb.TreeNodesResponse, error) {
+	return c.antientropyClient.TreeNodes(ctx, req)
+}
+
+// LeafKeys returns the digests of the keys of a range that belong to the given Merkle tree leaves.
+func (c *GrpcClient) LeafKeys(ctx context.Context, req *antientropypb.LeafKeysRequest) (*antientropypb.LeafKeysResponse, error) {
+	return c.antientropyClient.LeafKeys(ctx, req)
+}
+
 // Join attempts to join the cluster. It returns the list of current cluster members before the join.
 func (c *GrpcClient) Join(ctx context.Context, req *membershippb.JoinRequest) (*membershippb.JoinResponse, error) {
 	return c.membershipClient.Join(ctx, req)
@@ -7,6 +7,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	antientropypb "github.com/maxpoletaev/kv/antientropy/proto"
 	"github.com/maxpoletaev/kv/clust"
 	faildetectorpb "github.com/maxpoletaev/kv/faildetector/proto"
 	membershippb "github.com/maxpoletaev/kv/membership/proto"
@@ -39,11 +40,13 @@ func (d *GrpcDialer) DialContext(ctx context.Context, addr string) (clust.Conn,
 	faildetectorClient := faildetectorpb.NewFailDetectorServiceClient(grpcConn)
 	membershipClient := membershippb.NewMembershipServiceClient(grpcConn)
 	storageClient := storagepb.NewStorageServiceClient(grpcConn)
+	antientropyClient := antientropypb.NewAntiEntropyServiceClient(grpcConn)
 
 	c := &GrpcClient{
 		faildetectorClient: faildetectorClient,
 		membershipClient:   membershipClient,
 		storageClient:      storageClient,
+		antientropyClient:  antientropyClient,
 	}
 
 	c.addOnCloseHook(func() error {
@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	proto2 "github.com/maxpoletaev/kv/antientropy/proto"
 	proto "github.com/maxpoletaev/kv/faildetector/proto"
 	proto0 "github.com/maxpoletaev/kv/membership/proto"
 	proto1 "github.com/maxpoletaev/kv/storage/proto"
@@ -66,6 +67,21 @@ func (mr *MockClientMockRecorder) Get(ctx, req interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockClient)(nil).Get), ctx, req)
 }
 
+// Handoff mocks base method.
+func (m *MockClient) Handoff(ctx context.Context) (proto1.StorageService_HandoffClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Handoff", ctx)
+	ret0, _ := ret[0].(proto1.StorageService_HandoffClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Handoff indicates an expected call of Handoff.
+func (mr *MockClientMockRecorder) Handoff(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Handoff", reflect.TypeOf((*MockClient)(nil).Handoff), ctx)
+}
+
 // IsClosed mocks base method.
 func (m *MockClient) IsClosed() bool {
 	m.ctrl.T.Helper()
@@ -95,6 +111,21 @@ func (mr *MockClientMockRecorder) Join(ctx, req interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Join", reflect.TypeOf((*MockClient)(nil).Join), ctx, req)
 }
 
+// LeafKeys mocks base method.
+func (m *MockClient) LeafKeys(ctx context.Context, req *proto2.LeafKeysRequest) (*proto2.LeafKeysResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LeafKeys", ctx, req)
+	ret0, _ := ret[0].(*proto2.LeafKeysResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LeafKeys indicates an expected call of LeafKeys.
+func (mr *MockClientMockRecorder) LeafKeys(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeafKeys", reflect.TypeOf((*MockClient)(nil).LeafKeys), ctx, req)
+}
+
 // Members mocks base method.
 func (m *MockClient) Members(ctx context.Context) (*proto0.MembersResponse, error) {
 	m.ctrl.T.Helper()
@@ -110,6 +141,51 @@ func (mr *MockClientMockRecorder) Members(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Members", reflect.TypeOf((*MockClient)(nil).Members), ctx)
 }
 
+// Merge mocks base method.
+func (m *MockClient) Merge(ctx context.Context, req *proto1.MergeRequest) (*proto1.MergeResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, req)
+	ret0, _ := ret[0].(*proto1.MergeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockClientMockRecorder) Merge(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockClient)(nil).Merge), ctx, req)
+}
+
+// MultiGet mocks base method.
+func (m *MockClient) MultiGet(ctx context.Context, req *proto1.MultiGetRequest) (*proto1.MultiGetResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MultiGet", ctx, req)
+	ret0, _ := ret[0].(*proto1.MultiGetResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MultiGet indicates an expected call of MultiGet.
+func (mr *MockClientMockRecorder) MultiGet(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MultiGet", reflect.TypeOf((*MockClient)(nil).MultiGet), ctx, req)
+}
+
+// MultiPut mocks base method.
+func (m *MockClient) MultiPut(ctx context.Context, req *proto1.MultiPutRequest) (*proto1.MultiPutResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MultiPut", ctx, req)
+	ret0, _ := ret[0].(*proto1.MultiPutResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MultiPut indicates an expected call of MultiPut.
+func (mr *MockClientMockRecorder) MultiPut(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MultiPut", reflect.TypeOf((*MockClient)(nil).MultiPut), ctx, req)
+}
+
 // PingDirect mocks base method.
 func (m *MockClient) PingDirect(ctx context.Context) (*proto.PingResponse, error) {
 	m.ctrl.T.Helper()
@@ -154,3 +230,33 @@ func (mr *MockClientMockRecorder) Put(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockClient)(nil).Put), ctx, req)
 }
+
+// Repair mocks base method.
+func (m *MockClient) Repair(ctx context.Context, req *proto1.RepairRequest) (*proto1.RepairResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Repair", ctx, req)
+	ret0, _ := ret[0].(*proto1.RepairResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Repair indicates an expected call of Repair.
+func (mr *MockClientMockRecorder) Repair(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Repair", reflect.TypeOf((*MockClient)(nil).Repair), ctx, req)
+}
+
+// TreeNodes mocks base method.
+func (m *MockClient) TreeNodes(ctx context.Context, req *proto2.TreeNodesRequest) (*proto2.TreeNodesResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TreeNodes", ctx, req)
+	ret0, _ := ret[0].(*proto2.TreeNodesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TreeNodes indicates an expected call of TreeNodes.
+func (mr *MockClientMockRecorder) TreeNodes(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TreeNodes", reflect.TypeOf((*MockClient)(nil).TreeNodes), ctx, req)
+}
@@ -10,6 +10,16 @@ import (
 	"github.com/maxpoletaev/kv/membership"
 )
 
+type registryOption func(*ConnRegistry)
+
+// WithStatsTracker makes the registry record the latency and the failures of the calls made
+// through the connections it returns.
+func WithStatsTracker(stats *membership.StatsTracker) registryOption {
+	return func(r *ConnRegistry) {
+		r.stats = stats
+	}
+}
+
 type ConnRegistry struct {
 	mut            sync.RWMutex
 	connections    map[membership.NodeID]Conn
@@ -17,15 +27,22 @@ type ConnRegistry struct {
 	members        MemberRegistry
 	connectTimeout time.Duration
 	dialer         Dialer
+	stats          *membership.StatsTracker
 }
 
-func NewConnRegistry(members MemberRegistry, dialer Dialer) *ConnRegistry {
-	return &ConnRegistry{
+func NewConnRegistry(members MemberRegistry, dialer Dialer, opts ...registryOption) *ConnRegistry {
+	r := &ConnRegistry{
 		connections:    make(map[membership.NodeID]Conn),
 		connectTimeout: 5 * time.Second,
 		members:        members,
 		dialer:         dialer,
 	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
 func (r *ConnRegistry) get(id membership.NodeID) (Conn, bool) {
@@ -143,6 +160,10 @@ func (r *ConnRegistry) CollectGarbage() {
 	for id, conn := range r.connections {
 		if !r.members.HasMember(id) {
 			_ = conn.Close()
+
+			if r.stats != nil {
+				r.stats.Remove(id)
+			}
 		}
 
 		// Remove all closed connections. They may have been closed manually.
@@ -155,11 +176,30 @@ func (r *ConnRegistry) CollectGarbage() {
 // Get returns a connection to the member with the given ID. If the connection
 // is not present, it attempts to dial the member and create a new connection.
 func (r *ConnRegistry) Get(id membership.NodeID) (Conn, error) {
-	if conn, ok := r.get(id); ok {
-		return conn, nil
+	conn, ok := r.get(id)
+
+	if !ok {
+		var err error
+		if conn, err = r.connect(id); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.stats != nil {
+		conn = &statsConn{Conn: conn, id: id, stats: r.stats}
+	}
+
+	return conn, nil
+}
+
+// MemberStats returns the statistics of the calls made to the member, or false if the
+// statistics are not collected or no calls have been made to the member yet.
+func (r *ConnRegistry) MemberStats(id membership.NodeID) (membership.Stats, bool) {
+	if r.stats == nil {
+		return membership.Stats{}, false
 	}
 
-	return r.connect(id)
+	return r.stats.Stats(id)
 }
 
 // Add adds a connection to the registry. If a connection to the member with
@@ -0,0 +1,57 @@
+package ring
+
+import (
+	"sync"
+
+	"github.com/maxpoletaev/kv/membership"
+)
+
+// DefaultReplicationFactor is the default number of nodes each key is replicated to.
+const DefaultReplicationFactor = 3
+
+// Placement decides which nodes each key is stored on: the key is replicated to the first
+// N distinct nodes found on the ring starting from the key, where N is the replication
+// factor. The replication, the rebalancing and the anti-entropy must share the same
+// placement, since they would disagree on the replicas of the keys otherwise. It is safe
+// for concurrent use.
+type Placement struct {
+	replicationFactor int
+	vnodes            int
+
+	mut  sync.Mutex
+	ring *Ring
+}
+
+// NewPlacement creates a placement that replicates each key to the given number of nodes,
+// each node placed at vnodes points of the ring. All nodes of the cluster must use the
+// same settings.
+func NewPlacement(replicationFactor, vnodes int) *Placement {
+	return &Placement{
+		replicationFactor: replicationFactor,
+		vnodes:            vnodes,
+	}
+}
+
+// DefaultPlacement creates a placement with the default replication factor and number
+// of virtual nodes.
+func DefaultPlacement() *Placement {
+	return NewPlacement(DefaultReplicationFactor, DefaultVirtualNodes)
+}
+
+// ReplicationFactor returns the number of nodes each key is replicated to.
+func (p *Placement) ReplicationFactor() int {
+	return p.replicationFactor
+}
+
+// Ring returns the ring of the given nodes. The last built ring is reused as long as
+// no nodes join or leave the cluster.
+func (p *Placement) Ring(nodes []membership.NodeID) *Ring {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.ring == nil || !p.ring.HasNodes(nodes) {
+		p.ring = New(nodes, p.vnodes)
+	}
+
+	return p.ring
+}
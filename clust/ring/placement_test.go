@@ -0,0 +1,19 @@
+package ring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxpoletaev/kv/membership"
+)
+
+func TestPlacement_Ring(t *testing.T) {
+	p := DefaultPlacement()
+	assert.Equal(t, DefaultReplicationFactor, p.ReplicationFactor())
+
+	// The ring is reused until the nodes change.
+	r := p.Ring([]membership.NodeID{1, 2, 3})
+	assert.Same(t, r, p.Ring([]membership.NodeID{3, 2, 1}))
+	assert.NotSame(t, r, p.Ring([]membership.NodeID{1, 2}))
+}
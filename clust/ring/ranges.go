@@ -0,0 +1,99 @@
+package ring
+
+import (
+	"sort"
+
+	"github.com/maxpoletaev/kv/membership"
+)
+
+// RangeSet is a set of the arcs of the ring. Each arc ends at a token of either of the two
+// rings it has been built from, and holds the keys with the hashes after the previous token
+// up to and including its own, the same way as the keys are assigned to the tokens.
+type RangeSet struct {
+	ends  []uint64
+	moved []bool
+	count int
+}
+
+// MovedRanges returns the ranges of the ring in which the replicas of the keys differ
+// between the two rings, and the given node is one of the replicas on either of them.
+// These are the only ranges whose keys the node has to hand off or receive when the
+// keys are redistributed from one ring to another.
+func MovedRanges(from, to *Ring, n int, node membership.NodeID) *RangeSet {
+	ends := make([]uint64, 0, len(from.tokens)+len(to.tokens))
+
+	for _, t := range from.tokens {
+		ends = append(ends, t.hash)
+	}
+
+	for _, t := range to.tokens {
+		ends = append(ends, t.hash)
+	}
+
+	sort.Slice(ends, func(i, j int) bool {
+		return ends[i] < ends[j]
+	})
+
+	s := &RangeSet{
+		ends:  ends[:0],
+		moved: make([]bool, 0, len(ends)),
+	}
+
+	for i, end := range ends {
+		if i > 0 && end == ends[i-1] {
+			continue
+		}
+
+		// All hashes of the arc are assigned to the same tokens on both rings,
+		// so the preference list at the end of the arc is the one of the whole arc.
+		before, after := from.walk(end, n), to.walk(end, n)
+		moved := !sameNodes(before, after) && (contains(before, node) || contains(after, node))
+
+		s.ends = append(s.ends, end)
+		s.moved = append(s.moved, moved)
+
+		if moved {
+			s.count++
+		}
+	}
+
+	return s
+}
+
+// Empty returns true if there are no ranges in the set.
+func (s *RangeSet) Empty() bool {
+	return s.count == 0
+}
+
+// Contains returns true if the hash is in one of the ranges of the set.
+func (s *RangeSet) Contains(hash uint64) bool {
+	if s.count == 0 {
+		return false
+	}
+
+	i := sort.Search(len(s.ends), func(i int) bool {
+		return s.ends[i] >= hash
+	})
+
+	// The hashes after the last token belong to the first one.
+	if i == len(s.ends) {
+		i = 0
+	}
+
+	return s.moved[i]
+}
+
+// sameNodes returns true if both lists have the same nodes, regardless of the order.
+func sameNodes(a, b []membership.NodeID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, id := range a {
+		if !contains(b, id) {
+			return false
+		}
+	}
+
+	return true
+}
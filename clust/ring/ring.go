@@ -0,0 +1,153 @@
+package ring
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/twmb/murmur3"
+
+	"github.com/maxpoletaev/kv/membership"
+)
+
+// DefaultVirtualNodes is the default number of virtual nodes per cluster node.
+const DefaultVirtualNodes = 64
+
+type token struct {
+	hash uint64
+	node membership.NodeID
+}
+
+// Ring is a consistent hash ring. Each node is placed on the ring at several points,
+// called virtual nodes, so that the keys are spread evenly between the nodes, and a node
+// joining or leaving the cluster takes over or hands off the keys of many other nodes
+// rather than of its single neighbour. The positions depend only on the node IDs, so all
+// nodes build the same ring from the same membership list. The ring is immutable and can
+// be used from multiple goroutines.
+type Ring struct {
+	nodes  []membership.NodeID
+	tokens []token
+}
+
+// New creates a ring of the given nodes, each placed at vnodes points of the ring.
+func New(nodes []membership.NodeID, vnodes int) *Ring {
+	if vnodes < 1 {
+		vnodes = 1
+	}
+
+	unique := make(map[membership.NodeID]bool, len(nodes))
+	for _, id := range nodes {
+		unique[id] = true
+	}
+
+	r := &Ring{
+		nodes:  make([]membership.NodeID, 0, len(unique)),
+		tokens: make([]token, 0, len(unique)*vnodes),
+	}
+
+	for id := range unique {
+		r.nodes = append(r.nodes, id)
+
+		for i := 0; i < vnodes; i++ {
+			r.tokens = append(r.tokens, token{
+				hash: tokenHash(id, i),
+				node: id,
+			})
+		}
+	}
+
+	sort.Slice(r.nodes, func(i, j int) bool {
+		return r.nodes[i] < r.nodes[j]
+	})
+
+	// Hash collisions are unlikely, but the order must be
+	// the same on all nodes even if they happen.
+	sort.Slice(r.tokens, func(i, j int) bool {
+		if r.tokens[i].hash != r.tokens[j].hash {
+			return r.tokens[i].hash < r.tokens[j].hash
+		}
+
+		return r.tokens[i].node < r.tokens[j].node
+	})
+
+	return r
+}
+
+func tokenHash(id membership.NodeID, vnode int) uint64 {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b[:4], uint32(id))
+	binary.BigEndian.PutUint32(b[4:], uint32(vnode))
+
+	return murmur3.Sum64(b)
+}
+
+// KeyHash returns the position of the key on the ring.
+func KeyHash(key string) uint64 {
+	return murmur3.StringSum64(key)
+}
+
+// Nodes returns the IDs of the nodes of the ring in ascending order.
+func (r *Ring) Nodes() []membership.NodeID {
+	return r.nodes
+}
+
+// HasNodes returns true if the ring consists of exactly the given nodes.
+func (r *Ring) HasNodes(nodes []membership.NodeID) bool {
+	unique := make(map[membership.NodeID]bool, len(nodes))
+	for _, id := range nodes {
+		unique[id] = true
+	}
+
+	if len(unique) != len(r.nodes) {
+		return false
+	}
+
+	for _, id := range r.nodes {
+		if !unique[id] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PreferenceList returns the nodes responsible for the key, in the order of preference.
+// These are the first n distinct nodes found walking the ring clockwise from the position
+// of the key. If there are less than n nodes in the ring, all of them are returned.
+func (r *Ring) PreferenceList(key string, n int) []membership.NodeID {
+	return r.walk(KeyHash(key), n)
+}
+
+func (r *Ring) walk(hash uint64, n int) []membership.NodeID {
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+
+	if n <= 0 {
+		return nil
+	}
+
+	start := sort.Search(len(r.tokens), func(i int) bool {
+		return r.tokens[i].hash >= hash
+	})
+
+	list := make([]membership.NodeID, 0, n)
+
+	for i := 0; i < len(r.tokens) && len(list) < n; i++ {
+		node := r.tokens[(start+i)%len(r.tokens)].node
+		if !contains(list, node) {
+			list = append(list, node)
+		}
+	}
+
+	return list
+}
+
+func contains(list []membership.NodeID, id membership.NodeID) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,120 @@
+package ring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/membership"
+)
+
+func TestPreferenceList(t *testing.T) {
+	r := New([]membership.NodeID{1, 2, 3, 4, 5}, DefaultVirtualNodes)
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		list := r.PreferenceList(key, 3)
+
+		require.Len(t, list, 3)
+		assert.NotEqual(t, list[0], list[1])
+		assert.NotEqual(t, list[1], list[2])
+		assert.NotEqual(t, list[0], list[2])
+
+		// The shorter lists are the prefixes of the longer ones.
+		assert.Equal(t, list[:1], r.PreferenceList(key, 1))
+	}
+}
+
+func TestPreferenceList_FewerNodes(t *testing.T) {
+	r := New([]membership.NodeID{1, 2}, DefaultVirtualNodes)
+	assert.ElementsMatch(t, []membership.NodeID{1, 2}, r.PreferenceList("key", 3))
+
+	r = New(nil, DefaultVirtualNodes)
+	assert.Empty(t, r.PreferenceList("key", 3))
+}
+
+func TestNew_SameRingOnAllNodes(t *testing.T) {
+	a := New([]membership.NodeID{1, 2, 3, 3}, DefaultVirtualNodes)
+	b := New([]membership.NodeID{3, 2, 1}, DefaultVirtualNodes)
+
+	assert.Equal(t, a, b)
+	assert.Equal(t, []membership.NodeID{1, 2, 3}, a.Nodes())
+}
+
+func TestHasNodes(t *testing.T) {
+	r := New([]membership.NodeID{1, 2, 3}, DefaultVirtualNodes)
+
+	assert.True(t, r.HasNodes([]membership.NodeID{3, 1, 2}))
+	assert.False(t, r.HasNodes([]membership.NodeID{1, 2}))
+	assert.False(t, r.HasNodes([]membership.NodeID{1, 2, 4}))
+	assert.False(t, r.HasNodes([]membership.NodeID{1, 2, 3, 4}))
+}
+
+func TestRing_Balance(t *testing.T) {
+	const (
+		numNodes = 5
+		numKeys  = 50000
+	)
+
+	nodes := make([]membership.NodeID, 0, numNodes)
+	for i := 1; i <= numNodes; i++ {
+		nodes = append(nodes, membership.NodeID(i))
+	}
+
+	r := New(nodes, DefaultVirtualNodes)
+	owned := make(map[membership.NodeID]int)
+
+	for i := 0; i < numKeys; i++ {
+		owned[r.PreferenceList(fmt.Sprintf("key-%d", i), 1)[0]]++
+	}
+
+	// Each node owns roughly its fair share of the keys.
+	for _, id := range nodes {
+		share := float64(owned[id]) / numKeys
+		assert.InDelta(t, 1.0/numNodes, share, 0.08, "node %d owns %.2f of keys", id, share)
+	}
+}
+
+func TestRing_MinimalMovement(t *testing.T) {
+	const numKeys = 10000
+
+	before := New([]membership.NodeID{1, 2, 3, 4}, DefaultVirtualNodes)
+	after := New([]membership.NodeID{1, 2, 3, 4, 5}, DefaultVirtualNodes)
+	moved := 0
+
+	for i := 0; i < numKeys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		owner := after.PreferenceList(key, 1)[0]
+
+		if owner != before.PreferenceList(key, 1)[0] {
+			// The keys only move to the new node, never between the old ones.
+			require.Equal(t, membership.NodeID(5), owner)
+			moved++
+		}
+	}
+
+	// The new node takes over about a fifth of the keys.
+	assert.InDelta(t, 0.2, float64(moved)/numKeys, 0.08)
+}
+
+func TestMovedRanges(t *testing.T) {
+	const n = 2
+
+	before := New([]membership.NodeID{1, 2, 3}, DefaultVirtualNodes)
+	after := New([]membership.NodeID{1, 2, 3, 4}, DefaultVirtualNodes)
+	ranges := MovedRanges(before, after, n, 1)
+	require.False(t, ranges.Empty())
+
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		from, to := before.PreferenceList(key, n), after.PreferenceList(key, n)
+		moved := !sameNodes(from, to) && (contains(from, 1) || contains(to, 1))
+
+		assert.Equal(t, moved, ranges.Contains(KeyHash(key)), key)
+	}
+
+	// Nothing moves between the same rings.
+	assert.True(t, MovedRanges(before, before, n, 1).Empty())
+}
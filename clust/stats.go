@@ -0,0 +1,108 @@
+package clust
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	antientropypb "github.com/maxpoletaev/kv/antientropy/proto"
+	faildetectorpb "github.com/maxpoletaev/kv/faildetector/proto"
+	"github.com/maxpoletaev/kv/internal/grpcutil"
+	"github.com/maxpoletaev/kv/membership"
+	membershippb "github.com/maxpoletaev/kv/membership/proto"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+// statsConn records the latency and the failures of the calls made through the connection.
+// The streaming calls are not recorded, since their duration depends on the amount of data.
+type statsConn struct {
+	Conn
+	id    membership.NodeID
+	stats *membership.StatsTracker
+}
+
+// isFailure returns true if the call has failed due to the member being unreachable or
+// slow. The errors returned by the member itself, including the rejected writes and the
+// storage errors, say nothing about its health and are not counted.
+func isFailure(err error) bool {
+	switch grpcutil.ErrorCode(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// observe records the call started at the given time, once the call has returned the error.
+func (c *statsConn) observe(start time.Time, errp *error) {
+	err := *errp
+
+	// The caller is no longer interested in the result, which says nothing about the member.
+	if grpcutil.IsCanceled(err) || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	c.stats.Observe(c.id, time.Since(start), isFailure(err))
+}
+
+func (c *statsConn) Join(ctx context.Context, req *membershippb.JoinRequest) (_ *membershippb.JoinResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.Join(ctx, req)
+}
+
+func (c *statsConn) Members(ctx context.Context) (_ *membershippb.MembersResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.Members(ctx)
+}
+
+func (c *statsConn) Get(ctx context.Context, req *storagepb.GetRequest) (_ *storagepb.GetResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.Get(ctx, req)
+}
+
+func (c *statsConn) Put(ctx context.Context, req *storagepb.PutRequest) (_ *storagepb.PutResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.Put(ctx, req)
+}
+
+func (c *statsConn) Merge(ctx context.Context, req *storagepb.MergeRequest) (_ *storagepb.MergeResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.Merge(ctx, req)
+}
+
+func (c *statsConn) MultiGet(ctx context.Context, req *storagepb.MultiGetRequest) (_ *storagepb.MultiGetResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.MultiGet(ctx, req)
+}
+
+func (c *statsConn) MultiPut(ctx context.Context, req *storagepb.MultiPutRequest) (_ *storagepb.MultiPutResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.MultiPut(ctx, req)
+}
+
+func (c *statsConn) Repair(ctx context.Context, req *storagepb.RepairRequest) (_ *storagepb.RepairResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.Repair(ctx, req)
+}
+
+func (c *statsConn) TreeNodes(ctx context.Context, req *antientropypb.TreeNodesRequest) (_ *antientropypb.TreeNodesResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.TreeNodes(ctx, req)
+}
+
+func (c *statsConn) LeafKeys(ctx context.Context, req *antientropypb.LeafKeysRequest) (_ *antientropypb.LeafKeysResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.LeafKeys(ctx, req)
+}
+
+func (c *statsConn) PingDirect(ctx context.Context) (_ *faildetectorpb.PingResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.PingDirect(ctx)
+}
+
+func (c *statsConn) PingIndirect(ctx context.Context, req *faildetectorpb.PingRequest) (_ *faildetectorpb.PingResponse, err error) {
+	defer c.observe(time.Now(), &err)
+	return c.Conn.PingIndirect(ctx, req)
+}
@@ -0,0 +1,52 @@
+package clust
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/membership"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+func TestRegistry_Stats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	conn := mock.NewMockClient(ctrl)
+	conn.EXPECT().IsClosed().Return(false).AnyTimes()
+
+	gomock.InOrder(
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&storagepb.GetResponse{}, nil),
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.NotFound, "not found")),
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Unavailable, "unavailable")),
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.ResourceExhausted, "too many versions")),
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Internal, "storage failed")),
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.DeadlineExceeded, "deadline exceeded")),
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Canceled, "canceled")),
+	)
+
+	registry := NewConnRegistry(NewMockMemberRegistry(ctrl), NewMockDialer(ctrl),
+		WithStatsTracker(membership.NewStatsTracker()))
+	registry.connections[1] = conn
+
+	_, ok := registry.MemberStats(1)
+	require.False(t, ok)
+
+	got, err := registry.Get(1)
+	require.NoError(t, err)
+
+	for i := 0; i < 7; i++ {
+		_, _ = got.Get(context.Background(), &storagepb.GetRequest{Key: "key"})
+	}
+
+	// The canceled call is not counted, and only the unreachable or slow member is a failure.
+	stats, ok := registry.MemberStats(1)
+	require.True(t, ok)
+	assert.Equal(t, int64(6), stats.Calls)
+	assert.Equal(t, int64(2), stats.Errors)
+}
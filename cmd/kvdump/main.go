@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/maxpoletaev/kv/internal/keyring"
+	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+)
+
+// kvdump is a debugging tool that prints the records of the LSM-tree files (WAL,
+// SSTable data and index files, STATE and bloom filters) as JSON, one per line.
+// Encrypted files can be read if the key file is provided.
+//
+//	kvdump [-key-file=keys.txt] [-type=wal|data|index|state|bloom] FILE...
+
+var newMessage = map[string]func() protobuf.Message{
+	"wal":   func() protobuf.Message { return &proto.DataEntry{} },
+	"data":  func() protobuf.Message { return &proto.DataEntry{} },
+	"index": func() protobuf.Message { return &proto.IndexEntry{} },
+	"state": func() protobuf.Message { return &proto.StateLogEntry{} },
+}
+
+func detectType(path string) (string, error) {
+	name := filepath.Base(path)
+
+	switch {
+	case name == "STATE":
+		return "state", nil
+	case strings.HasSuffix(name, ".wal"):
+		return "wal", nil
+	case strings.HasSuffix(name, ".data"):
+		return "data", nil
+	case strings.HasSuffix(name, ".index"):
+		return "index", nil
+	case strings.HasSuffix(name, ".bloom"):
+		return "bloom", nil
+	default:
+		return "", fmt.Errorf("unable to detect file type of %s, please specify -type", name)
+	}
+}
+
+func dumpBloom(path string, out io.Writer) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	bf := &proto.BloomFilter{}
+	if err := protobuf.Unmarshal(data, bf); err != nil {
+		return fmt.Errorf("failed to unmarshal bloom filter: %w", err)
+	}
+
+	// The filter itself is not very useful for debugging, so only print the header.
+	bf.Data = nil
+
+	line, err := protojson.Marshal(bf)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(line))
+
+	return err
+}
+
+func dumpRecords(path, fileType string, cipher protoio.Cipher, out io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := protoio.NewEncryptedReader(f, cipher)
+
+	for {
+		msg := newMessage[fileType]()
+		offset := reader.Offset()
+
+		if _, err := reader.ReadNext(msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return fmt.Errorf("failed to read record at offset %d: %w", offset, err)
+		}
+
+		line, err := protojson.Marshal(msg)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(out, string(line)); err != nil {
+			return err
+		}
+	}
+}
+
+func main() {
+	keyFile := flag.String("key-file", "", "path to the encryption key file")
+	fileType := flag.String("type", "", "file type: wal, data, index, state or bloom (detected by name if not set)")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: kvdump [-key-file=FILE] [-type=TYPE] FILE...")
+		os.Exit(2)
+	}
+
+	var cipher protoio.Cipher
+
+	if *keyFile != "" {
+		kr, err := keyring.Load(*keyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load keys: %s\n", err)
+			os.Exit(1)
+		}
+
+		cipher = kr
+	}
+
+	for _, path := range flag.Args() {
+		typ := *fileType
+
+		if typ == "" {
+			var err error
+
+			if typ, err = detectType(path); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+
+		var err error
+
+		switch {
+		case typ == "bloom":
+			err = dumpBloom(path, os.Stdout)
+		case newMessage[typ] != nil:
+			err = dumpRecords(path, typ, cipher, os.Stdout)
+		default:
+			err = fmt.Errorf("unknown file type: %s", typ)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/maxpoletaev/kv/internal/keyring"
+	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/storage/lsmtree"
+	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+)
+
+func TestDumpRecords_Encrypted(t *testing.T) {
+	kr, err := keyring.New(map[uint16][]byte{1: bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+
+	conf := lsmtree.DefaultConfig()
+	conf.DataRoot = t.TempDir()
+	conf.Cipher = kr
+	conf.MaxMemtableSize = 1
+
+	tree, err := lsmtree.Create(conf)
+	require.NoError(t, err)
+
+	// Every write flushes the previous memtable, so there is at least one table on disk.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, tree.Put(&proto.DataEntry{
+			Key:    fmt.Sprintf("key%d", i),
+			Values: []*proto.Value{{Data: []byte("secret")}},
+		}))
+	}
+
+	require.NoError(t, tree.Close())
+
+	files, err := filepath.Glob(filepath.Join(conf.DataRoot, "*.data"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	typ, err := detectType(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, "data", typ)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, dumpRecords(files[0], typ, kr, out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.NotEmpty(t, lines)
+
+	entry := &proto.DataEntry{}
+	require.NoError(t, protojson.Unmarshal([]byte(lines[0]), entry))
+	assert.Equal(t, "key0", entry.Key)
+	assert.Equal(t, []byte("secret"), entry.Values[0].Data)
+
+	// The records can not be read without the keys.
+	err = dumpRecords(files[0], typ, nil, &bytes.Buffer{})
+	assert.ErrorIs(t, err, protoio.ErrNoCipher)
+}
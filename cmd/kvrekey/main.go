@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/maxpoletaev/kv/internal/keyring"
+	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/storage/btree"
+	"github.com/maxpoletaev/kv/storage/lsmtree"
+	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+)
+
+// kvrekey is an offline tool that copies the data of a stopped node into a new directory,
+// encrypting it with the active key of the key file. The storage engines never rewrite the
+// existing data on their own (there is no compaction in the LSM-tree, and the B+tree only
+// rewrites the pages it modifies), so the data encrypted with a retired key stays on disk
+// until it is copied by this tool. Once the new directory replaces the old one, the retired
+// keys can be removed from the key file. The in-memory engine does not need it, since every
+// snapshot is written with the active key and replaces the older files.
+//
+//	kvrekey -key-file=keys.txt [-engine=lsmtree|btree] -src=DIR -dst=DIR
+
+const keysBatchSize = 1000
+
+var errNotEmpty = errors.New("destination directory is not empty")
+
+// prepareDst creates the destination directory, which must not contain any files yet,
+// so that the data of another node is never mixed with the copied one.
+func prepareDst(dst string) error {
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		return fmt.Errorf("failed to read destination directory: %w", err)
+	}
+
+	if len(entries) > 0 {
+		return errNotEmpty
+	}
+
+	return nil
+}
+
+// syncDir flushes the files of the directory and the directory itself to disk,
+// since the engines are opened without syncing every write to speed up the copy.
+func syncDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(entries)+1)
+	for _, entry := range entries {
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+
+	for _, path := range append(paths, dir) {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		err = f.Sync()
+		f.Close()
+
+		if err != nil {
+			return fmt.Errorf("failed to sync %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// rekeyLSM copies all entries of the LSM-tree, including the deleted values, which must
+// be kept so that the deletes are not undone by the replicas.
+func rekeyLSM(src, dst string, cipher protoio.Cipher) (int, error) {
+	srcConf := lsmtree.DefaultConfig()
+	srcConf.DataRoot = src
+	srcConf.Cipher = cipher
+
+	srcTree, err := lsmtree.Create(srcConf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open source: %w", err)
+	}
+	defer srcTree.Close()
+
+	dstConf := lsmtree.DefaultConfig()
+	dstConf.DataRoot = dst
+	dstConf.Cipher = cipher
+	dstConf.MaxMemtableSize = 4 * 1024 * 1024 // 4MB
+
+	dstTree, err := lsmtree.Create(dstConf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create destination: %w", err)
+	}
+
+	copied := 0
+
+	for from := ""; ; {
+		keys, err := srcTree.Keys(from, keysBatchSize)
+		if err != nil {
+			dstTree.Close()
+			return copied, fmt.Errorf("failed to list keys: %w", err)
+		}
+
+		if len(keys) == 0 {
+			break
+		}
+
+		for _, key := range keys {
+			entry, found, err := srcTree.Get(key)
+			if err != nil {
+				dstTree.Close()
+				return copied, fmt.Errorf("failed to read %s: %w", key, err)
+			}
+
+			if !found {
+				continue
+			}
+
+			if err := dstTree.Put(protobuf.Clone(entry).(*proto.DataEntry)); err != nil {
+				dstTree.Close()
+				return copied, fmt.Errorf("failed to write %s: %w", key, err)
+			}
+
+			copied++
+		}
+
+		// The smallest key that is greater than the last one.
+		from = keys[len(keys)-1] + "\x00"
+	}
+
+	if err := dstTree.Close(); err != nil {
+		return copied, fmt.Errorf("failed to close destination: %w", err)
+	}
+
+	return copied, nil
+}
+
+func rekeyBTree(src, dst string, cipher protoio.Cipher) (int, error) {
+	// The page size of the existing file takes precedence over the one from the config.
+	srcConf := btree.DefaultConfig()
+	srcConf.DataRoot = src
+	srcConf.Cipher = cipher
+
+	srcTree, err := btree.Open(srcConf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open source: %w", err)
+	}
+	defer srcTree.Close()
+
+	dstConf := btree.DefaultConfig()
+	dstConf.DataRoot = dst
+	dstConf.Cipher = cipher
+	dstConf.NoSync = true
+
+	dstTree, err := btree.Open(dstConf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create destination: %w", err)
+	}
+
+	copied := 0
+	it := srcTree.Scan()
+
+	for it.HasNext() {
+		key, value := it.Next()
+
+		if err := dstTree.Put(key, value); err != nil {
+			dstTree.Close()
+			return copied, fmt.Errorf("failed to write %s: %w", key, err)
+		}
+
+		copied++
+	}
+
+	if err := it.Err(); err != nil {
+		dstTree.Close()
+		return copied, fmt.Errorf("failed to scan source: %w", err)
+	}
+
+	if err := dstTree.Close(); err != nil {
+		return copied, fmt.Errorf("failed to close destination: %w", err)
+	}
+
+	return copied, nil
+}
+
+func rekey(engine, src, dst string, cipher protoio.Cipher) (int, error) {
+	if err := prepareDst(dst); err != nil {
+		return 0, err
+	}
+
+	var (
+		copied int
+		err    error
+	)
+
+	switch engine {
+	case "lsmtree":
+		copied, err = rekeyLSM(src, dst, cipher)
+	case "btree":
+		copied, err = rekeyBTree(src, dst, cipher)
+	default:
+		return 0, fmt.Errorf("unknown storage engine: %s", engine)
+	}
+
+	if err != nil {
+		return copied, err
+	}
+
+	return copied, syncDir(dst)
+}
+
+func main() {
+	keyFile := flag.String("key-file", "", "path to the encryption key file")
+	engine := flag.String("engine", "lsmtree", "storage engine of the data directory: lsmtree or btree")
+	src := flag.String("src", "", "data directory of the stopped node")
+	dst := flag.String("dst", "", "new empty directory to copy the data to")
+	flag.Parse()
+
+	if *keyFile == "" || *src == "" || *dst == "" {
+		fmt.Fprintln(os.Stderr, "usage: kvrekey -key-file=FILE [-engine=ENGINE] -src=DIR -dst=DIR")
+		os.Exit(2)
+	}
+
+	kr, err := keyring.Load(*keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load keys: %s\n", err)
+		os.Exit(1)
+	}
+
+	copied, err := rekey(*engine, *src, *dst, kr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to copy data: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("copied %d keys to %s\n", copied, *dst)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/keyring"
+	"github.com/maxpoletaev/kv/storage/btree"
+	"github.com/maxpoletaev/kv/storage/lsmtree"
+	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+)
+
+func testKeyring(t *testing.T, ids ...uint16) *keyring.Keyring {
+	keys := make(map[uint16][]byte)
+	for _, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(id)}, 32)
+	}
+
+	kr, err := keyring.New(keys)
+	require.NoError(t, err)
+
+	return kr
+}
+
+func TestRekey_LSMTree(t *testing.T) {
+	src, dst := t.TempDir(), filepath.Join(t.TempDir(), "new")
+
+	conf := lsmtree.DefaultConfig()
+	conf.DataRoot = src
+	conf.Cipher = testKeyring(t, 1)
+	conf.MaxMemtableSize = 100
+
+	tree, err := lsmtree.Create(conf)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, tree.Put(&proto.DataEntry{
+			Key:    fmt.Sprintf("key%03d", i),
+			Values: []*proto.Value{{Data: []byte("value")}},
+		}))
+	}
+
+	require.NoError(t, tree.Put(&proto.DataEntry{Key: "key000", Tombstone: true}))
+	require.NoError(t, tree.Close())
+
+	// The key 2 becomes active, and the key 1 is retired.
+	copied, err := rekey("lsmtree", src, dst, testKeyring(t, 1, 2))
+	require.NoError(t, err)
+	assert.Equal(t, 99, copied)
+
+	// The copy is readable without the retired key.
+	conf.DataRoot = dst
+	conf.Cipher = testKeyring(t, 2)
+
+	tree, err = lsmtree.Create(conf)
+	require.NoError(t, err)
+
+	defer tree.Close()
+
+	entry, found, err := tree.Get("key099")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, []byte("value"), entry.Values[0].Data)
+
+	_, found, err = tree.Get("key000")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestRekey_BTree(t *testing.T) {
+	src, dst := t.TempDir(), filepath.Join(t.TempDir(), "new")
+
+	conf := btree.DefaultConfig()
+	conf.DataRoot = src
+	conf.Cipher = testKeyring(t, 1)
+	conf.NoSync = true
+
+	tree, err := btree.Open(conf)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, tree.Put(fmt.Sprintf("key%03d", i), []byte("value")))
+	}
+
+	require.NoError(t, tree.Close())
+
+	copied, err := rekey("btree", src, dst, testKeyring(t, 1, 2))
+	require.NoError(t, err)
+	assert.Equal(t, 100, copied)
+
+	conf.DataRoot = dst
+	conf.Cipher = testKeyring(t, 2)
+
+	tree, err = btree.Open(conf)
+	require.NoError(t, err)
+
+	defer tree.Close()
+
+	value, found, err := tree.Get("key099")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, []byte("value"), value)
+}
+
+func TestRekey_DstNotEmpty(t *testing.T) {
+	dst := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "STATE"), nil, 0o644))
+
+	_, err := rekey("lsmtree", t.TempDir(), dst, testKeyring(t, 1))
+	assert.ErrorIs(t, err, errNotEmpty)
+}
@@ -1,26 +1,109 @@
 package main
 
-import "flag"
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/maxpoletaev/kv/clust/ring"
+	"github.com/maxpoletaev/kv/replication/consistency"
+	"github.com/maxpoletaev/kv/storage"
+)
+
+// keyspaceFlag collects the keyspaces from the repeated -keyspace flags.
+type keyspaceFlag storage.Keyspaces
+
+func (f *keyspaceFlag) String() string {
+	prefixes := make([]string, 0, len(*f))
+	for _, ks := range *f {
+		prefixes = append(prefixes, ks.Prefix)
+	}
+
+	return strings.Join(prefixes, ",")
+}
+
+func (f *keyspaceFlag) Set(value string) error {
+	ks, err := storage.ParseKeyspace(value)
+	if err != nil {
+		return err
+	}
+
+	*f = append(*f, ks)
+
+	return nil
+}
+
+// levelFlag is a consistency level set by its name.
+type levelFlag consistency.Level
+
+func (f *levelFlag) String() string {
+	return consistency.Level(*f).String()
+}
+
+func (f *levelFlag) Set(value string) error {
+	level, err := consistency.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	*f = levelFlag(level)
+
+	return nil
+}
 
 type cliArgs struct {
-	nodeID           uint
-	nodeName         string
-	grpcLocalAddr    string
-	grpcBindAddr     string
-	grpcPublicAddr   string
-	gossipBindAddr   string
-	gossipPublicAddr string
-	joinAddr         string
-	dataDirectory    string
-	inMemory         bool
-	verbose          bool
-	memtableSize     int64
+	nodeID              uint
+	nodeName            string
+	grpcLocalAddr       string
+	grpcBindAddr        string
+	grpcPublicAddr      string
+	gossipBindAddr      string
+	gossipPublicAddr    string
+	joinAddr            string
+	dataDirectory       string
+	inMemory            bool
+	engine              string
+	btreePageSize       int
+	snapshotInterval    time.Duration
+	verbose             bool
+	memtableSize        int64
+	ioRateLimit         int64
+	keyFile             string
+	maxSiblings         int
+	maxSiblingBytes     int
+	siblingAction       string
+	vclockMaxEntries    int
+	vclockMaxAge        time.Duration
+	keyspaces           keyspaceFlag
+	maxClockOffset      time.Duration
+	replicationFactor   int
+	vnodes              int
+	rebalanceInterval   time.Duration
+	rebalanceRate       int64
+	hintedHandoff       bool
+	hintWindow          time.Duration
+	hintReplayInterval  time.Duration
+	antiEntropyInterval time.Duration
+	merkleTreeDepth     int
+	readLevel           levelFlag
+	writeLevel          levelFlag
+	readRepairMode      string
+	readRepairChance    float64
+	readRepairWorkers   int
+	hedgedReads         bool
+	hedgePercentile     float64
+	hedgeMinDelay       time.Duration
+	dynamicSnitch       bool
+	snitchBadness       float64
 }
 
 func parseCliArgs() cliArgs {
-	args := cliArgs{}
+	args := cliArgs{
+		readLevel:  levelFlag(consistency.Quorum),
+		writeLevel: levelFlag(consistency.Quorum),
+	}
 
-	flag.UintVar(&args.nodeID, "node-id", 0, "unique node id")
+	flag.UintVar(&args.nodeID, "node-id", 0, "unique node id, must be greater than zero")
 	flag.StringVar(&args.nodeName, "node-name", "", "node name")
 
 	flag.StringVar(&args.grpcBindAddr, "grpc-bind-addr", "", "address to bind grpc server")
@@ -34,11 +117,46 @@ func parseCliArgs() cliArgs {
 
 	flag.BoolVar(&args.verbose, "verbose", false, "verbose mode")
 
-	flag.BoolVar(&args.inMemory, "in-memory", false, "use in-memory storage")
+	flag.StringVar(&args.engine, "engine", "lsmtree", "storage engine: lsmtree, btree or inmemory")
+	flag.BoolVar(&args.inMemory, "in-memory", false, "use in-memory storage, same as -engine=inmemory")
+	flag.IntVar(&args.btreePageSize, "btree-page-size", 4096, "page size of the btree engine")
+	flag.DurationVar(&args.snapshotInterval, "snapshot-interval", 5*time.Minute, "interval between snapshots of in-memory storage")
 	flag.Int64Var(&args.memtableSize, "memtable-size", 1000, "max memtable size")
 	flag.StringVar(&args.dataDirectory, "data-dir", "", "data directory")
+	flag.StringVar(&args.keyFile, "encryption-key-file", "", "file with encryption keys, enables encryption at rest")
+	flag.Int64Var(&args.ioRateLimit, "io-rate-limit", 0, "background disk writes limit in bytes per second (0 = unlimited)")
+	flag.IntVar(&args.maxSiblings, "max-siblings", 0, "max number of concurrent versions of a key (0 = unlimited)")
+	flag.IntVar(&args.maxSiblingBytes, "max-sibling-bytes", 0, "max total size of concurrent versions of a key (0 = unlimited)")
+	flag.StringVar(&args.siblingAction, "sibling-limit-action", "reject", "action on exceeding sibling limits: reject or fold")
+	flag.IntVar(&args.vclockMaxEntries, "vclock-max-entries", 0, "number of version vector entries above which the oldest are pruned, pruning may turn overwrites into siblings (0 = disabled)")
+	flag.DurationVar(&args.vclockMaxAge, "vclock-max-age", 0, "age of version vector entries above which they are pruned, pruning may turn overwrites into siblings (0 = disabled)")
+	flag.DurationVar(&args.maxClockOffset, "max-clock-offset", 0, "max offset of the clocks of other nodes, writes from nodes further ahead are rejected (0 = unlimited)")
+	flag.IntVar(&args.replicationFactor, "replication-factor", ring.DefaultReplicationFactor, "number of nodes each key is replicated to")
+	flag.IntVar(&args.vnodes, "vnodes", ring.DefaultVirtualNodes, "number of virtual nodes per node on the hash ring, must be the same on all nodes")
+	flag.DurationVar(&args.rebalanceInterval, "rebalance-interval", 10*time.Second, "interval between checks whether the keys have to be moved after membership changes")
+	flag.Int64Var(&args.rebalanceRate, "rebalance-rate-limit", 0, "rate of moving keys to other nodes in bytes per second (0 = unlimited)")
+	flag.BoolVar(&args.hintedHandoff, "hinted-handoff", true, "accept writes for unavailable replicas on other nodes and replay them later, requires -data-dir to keep the hints")
+	flag.DurationVar(&args.hintWindow, "hint-window", 3*time.Hour, "how long the hints for unavailable replicas are kept before they are dropped")
+	flag.DurationVar(&args.hintReplayInterval, "hint-replay-interval", 10*time.Second, "interval between attempts to replay the hints to recovered replicas")
+	flag.DurationVar(&args.antiEntropyInterval, "anti-entropy-interval", 10*time.Minute, "interval between comparisons of the merkle trees with other replicas")
+	flag.IntVar(&args.merkleTreeDepth, "merkle-tree-depth", 10, "depth of the merkle trees used by anti-entropy, must be the same on all nodes")
+	flag.Var(&args.readLevel, "read-consistency", "default consistency level of reads: one, two, quorum or all")
+	flag.Var(&args.writeLevel, "write-consistency", "default consistency level of writes: one, two, quorum or all")
+	flag.StringVar(&args.readRepairMode, "read-repair", "async", "read repair mode: sync waits for the repairs before responding, async repairs in the background")
+	flag.Float64Var(&args.readRepairChance, "read-repair-chance", 1, "probability of a read to repair the stale replicas, from 0 to 1")
+	flag.IntVar(&args.readRepairWorkers, "read-repair-workers", 8, "number of background workers running async read repairs")
+	flag.BoolVar(&args.hedgedReads, "hedged-reads", false, "read from the fastest replicas needed for the consistency level only, and from one more if a reply is late")
+	flag.Float64Var(&args.hedgePercentile, "hedge-percentile", 0.95, "percentile of the recent read latencies after which a hedged read is sent, from 0 to 1")
+	flag.DurationVar(&args.hedgeMinDelay, "hedge-min-delay", time.Millisecond, "minimum delay before a hedged read is sent")
+	flag.BoolVar(&args.dynamicSnitch, "dynamic-snitch", true, "skip the replicas that have become slow or erroneous on reads, while enough others are available")
+	flag.Float64Var(&args.snitchBadness, "dynamic-snitch-badness-threshold", 0.5, "how much worse than the best replica, as a fraction of its score, a replica must be to be skipped")
+	flag.Var(&args.keyspaces, "keyspace", "keyspace settings as prefix:options, options: dvv, lww; empty prefix applies to all keys (can be repeated)")
 
 	flag.Parse()
 
+	if args.inMemory {
+		args.engine = "inmemory"
+	}
+
 	return args
 }
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
@@ -14,19 +16,34 @@ import (
 	"github.com/go-kit/log/level"
 	"google.golang.org/grpc"
 
+	"github.com/maxpoletaev/kv/antientropy"
+	antientropypb "github.com/maxpoletaev/kv/antientropy/proto"
+	antientropysvc "github.com/maxpoletaev/kv/antientropy/service"
 	"github.com/maxpoletaev/kv/clust"
 	"github.com/maxpoletaev/kv/clust/grpcclient"
+	"github.com/maxpoletaev/kv/clust/ring"
 	"github.com/maxpoletaev/kv/faildetector"
 	faildetectorpb "github.com/maxpoletaev/kv/faildetector/proto"
 	faildetectorsvc "github.com/maxpoletaev/kv/faildetector/service"
 	"github.com/maxpoletaev/kv/gossip"
+	"github.com/maxpoletaev/kv/hints"
+	"github.com/maxpoletaev/kv/internal/keyring"
+	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/internal/vclock"
 	"github.com/maxpoletaev/kv/membership"
 	"github.com/maxpoletaev/kv/membership/broadcast"
 	membershippb "github.com/maxpoletaev/kv/membership/proto"
 	membershipsvc "github.com/maxpoletaev/kv/membership/service"
+	"github.com/maxpoletaev/kv/rebalance"
+	rebalancepb "github.com/maxpoletaev/kv/rebalance/proto"
+	rebalancesvc "github.com/maxpoletaev/kv/rebalance/service"
 	"github.com/maxpoletaev/kv/replication/consistency"
 	replicationpb "github.com/maxpoletaev/kv/replication/proto"
 	replicationsvc "github.com/maxpoletaev/kv/replication/service"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/btree"
+	btreeengine "github.com/maxpoletaev/kv/storage/btree/engine"
+	"github.com/maxpoletaev/kv/storage/inmemory"
 	"github.com/maxpoletaev/kv/storage/lsmtree"
 	"github.com/maxpoletaev/kv/storage/lsmtree/engine"
 	storagepb "github.com/maxpoletaev/kv/storage/proto"
@@ -67,6 +84,89 @@ func (a *App) Shutdown() {
 	a.wg.Wait()
 }
 
+// loadCipher loads the keys the data is encrypted with at rest, if the key file is given.
+func loadCipher(args cliArgs) (protoio.Cipher, error) {
+	if len(args.keyFile) == 0 {
+		return nil, nil
+	}
+
+	keys, err := keyring.Load(args.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// createStorage initializes the storage engine selected by the command line arguments.
+// It returns the engine and a function that must be called to close it on shutdown.
+func createStorage(args cliArgs, cipher protoio.Cipher, logger kitlog.Logger) (storage.Engine, func() error, error) {
+	limits := storage.SiblingLimits{
+		MaxSiblings: args.maxSiblings,
+		MaxBytes:    args.maxSiblingBytes,
+		Logger:      logger,
+	}
+
+	switch args.siblingAction {
+	case "reject":
+		limits.Action = storage.LimitReject
+	case "fold":
+		limits.Action = storage.LimitFold
+	default:
+		return nil, nil, fmt.Errorf("unknown sibling limit action: %s", args.siblingAction)
+	}
+
+	opts := []storage.Option{
+		storage.WithSiblingLimits(limits),
+		storage.WithKeyspaces(storage.Keyspaces(args.keyspaces)),
+	}
+
+	switch args.engine {
+	case "inmemory":
+		memConfig := inmemory.DefaultConfig()
+		memConfig.DataRoot = args.dataDirectory
+		memConfig.SnapshotInterval = args.snapshotInterval
+		memConfig.Logger = logger
+		memConfig.Cipher = cipher
+
+		mem, err := inmemory.Open(memConfig, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize in-memory storage: %w", err)
+		}
+
+		return mem, mem.Close, nil
+	case "btree":
+		btreeConfig := btree.DefaultConfig()
+		btreeConfig.DataRoot = args.dataDirectory
+		btreeConfig.PageSize = args.btreePageSize
+		btreeConfig.Cipher = cipher
+
+		tree, err := btree.Open(btreeConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize B-Tree storage: %w", err)
+		}
+
+		return btreeengine.New(tree, opts...), tree.Close, nil
+	case "lsmtree":
+		lsmConfig := lsmtree.DefaultConfig()
+		lsmConfig.MaxMemtableSize = args.memtableSize
+		lsmConfig.DataRoot = args.dataDirectory
+		lsmConfig.IORateLimit = args.ioRateLimit
+		lsmConfig.MmapDataFiles = true
+		lsmConfig.Logger = logger
+		lsmConfig.Cipher = cipher
+
+		lsmt, err := lsmtree.Create(lsmConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize LSM-Tree storage: %w", err)
+		}
+
+		return engine.New(lsmt, opts...), lsmt.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage engine: %s", args.engine)
+	}
+}
+
 func main() {
 	appctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	logger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
@@ -77,6 +177,13 @@ func main() {
 		logger = level.NewFilter(logger, level.AllowInfo())
 	}
 
+	// Node ID 0 means no node, for example in the writes that are not meant for another
+	// replica, so a node with that ID would silently lose the hints written for it.
+	if args.nodeID == 0 {
+		logger.Log("msg", "node id must be set to a non-zero value")
+		os.Exit(1)
+	}
+
 	gossipConf := gossip.DefaultConfig()
 	gossipConf.PeerID = gossip.PeerID(args.nodeID)
 	gossipConf.BindAddr = args.gossipBindAddr
@@ -106,33 +213,116 @@ func main() {
 	dialer := grpcclient.NewDialer()
 	eventSender := broadcast.NewSender(gossiper)
 	memberlist := membership.New(localMember, logger, eventSender)
-	connections := clust.NewConnRegistry(memberlist, dialer)
+	memberStats := membership.NewStatsTracker()
+	connections := clust.NewConnRegistry(memberlist, dialer, clust.WithStatsTracker(memberStats))
 	cluster := clust.New(localMember.ID, memberlist, connections)
 	memberlist.ConsumeEvents(eventReceiver.Chan())
 
-	lsmConfig := lsmtree.DefaultConfig()
-	lsmConfig.MaxMemtableSize = args.memtableSize
-	lsmConfig.DataRoot = args.dataDirectory
-	lsmConfig.MmapDataFiles = true
-	lsmConfig.Logger = logger
+	cipher, err := loadCipher(args)
+	if err != nil {
+		logger.Log("msg", "failed to initialize encryption", "err", err)
+		os.Exit(1)
+	}
 
-	lsmt, err := lsmtree.Create(lsmConfig)
+	storageEngine, closeStorage, err := createStorage(args, cipher, logger)
 	if err != nil {
-		logger.Log("msg", "failed to initialize LSM-Tree storage", "err", err)
+		logger.Log("msg", "failed to initialize storage", "err", err)
 		os.Exit(1)
 	}
 
-	storage := engine.New(lsmt)
 	grpcServer := grpc.NewServer()
-	storageService := storagesvc.New(storage, uint32(args.nodeID))
+	pruneConf := vclock.DefaultPruneConfig()
+	pruneConf.MaxEntries = args.vclockMaxEntries
+	pruneConf.MaxAge = args.vclockMaxAge
+
+	// The hints must survive restarts, so they are only kept when there is a data directory.
+	var hintLog *hints.Log
+	if args.hintedHandoff && len(args.dataDirectory) > 0 {
+		hintLog, err = hints.Open(filepath.Join(args.dataDirectory, "hints"), cipher)
+		if err != nil {
+			logger.Log("msg", "failed to open hint log", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	storageService := storagesvc.New(storageEngine, uint32(args.nodeID),
+		storagesvc.WithVersionPruning(pruneConf),
+		storagesvc.WithKeyspaces(storage.Keyspaces(args.keyspaces)),
+		storagesvc.WithMaxClockOffset(args.maxClockOffset),
+		storagesvc.WithHints(hintLog),
+	)
 	storagepb.RegisterStorageServiceServer(grpcServer, storageService)
-	membershipService := membershipsvc.NewMembershipService(memberlist)
+	membershipService := membershipsvc.NewMembershipService(memberlist, membershipsvc.WithStats(connections))
 	membershippb.RegisterMembershipServiceServer(grpcServer, membershipService)
-	replicationService := replicationsvc.New(cluster, logger, consistency.Quorum, consistency.Quorum)
+	readRepairConf := replicationsvc.DefaultReadRepairConfig()
+	readRepairConf.Chance = args.readRepairChance
+	readRepairConf.Workers = args.readRepairWorkers
+
+	readRepairConf.Mode, err = replicationsvc.ParseReadRepairMode(args.readRepairMode)
+	if err != nil {
+		logger.Log("msg", "invalid read repair mode", "err", err)
+		os.Exit(1)
+	}
+
+	hedgedReadConf := replicationsvc.DefaultHedgedReadConfig()
+	hedgedReadConf.Enabled = args.hedgedReads
+	hedgedReadConf.Percentile = args.hedgePercentile
+	hedgedReadConf.MinDelay = args.hedgeMinDelay
+
+	snitchConf := replicationsvc.DefaultSnitchConfig()
+	snitchConf.Enabled = args.dynamicSnitch
+	snitchConf.BadnessThreshold = args.snitchBadness
+
+	// The replication, the rebalancing and the anti-entropy must agree on the replicas of the keys.
+	placement := ring.NewPlacement(args.replicationFactor, args.vnodes)
+
+	replicationService := replicationsvc.New(cluster, logger,
+		consistency.Level(args.readLevel), consistency.Level(args.writeLevel),
+		replicationsvc.WithKeyspaces(storage.Keyspaces(args.keyspaces)),
+		replicationsvc.WithPlacement(placement),
+		replicationsvc.WithHintedHandoff(args.hintedHandoff),
+		replicationsvc.WithReadRepair(readRepairConf),
+		replicationsvc.WithHedgedReads(hedgedReadConf),
+		replicationsvc.WithDynamicSnitch(snitchConf),
+	)
 	replicationpb.RegisterCoordinatorServiceServer(grpcServer, replicationService)
 	faildetectorService := faildetectorsvc.New(cluster)
 	faildetectorpb.RegisterFailDetectorServiceServer(grpcServer, faildetectorService)
 
+	localStorage, ok := storageEngine.(rebalance.Storage)
+	if !ok {
+		logger.Log("msg", "storage engine does not support rebalancing", "engine", args.engine)
+		os.Exit(1)
+	}
+
+	var stateFile string
+	if len(args.dataDirectory) > 0 {
+		stateFile = filepath.Join(args.dataDirectory, "rebalance.state")
+	}
+
+	rebalancer := rebalance.New(cluster, localStorage, logger,
+		rebalance.WithPlacement(placement),
+		rebalance.WithCheckInterval(args.rebalanceInterval),
+		rebalance.WithRateLimit(args.rebalanceRate),
+		rebalance.WithStateFile(stateFile),
+	)
+	rebalanceService := rebalancesvc.New(rebalancer)
+	rebalancepb.RegisterRebalanceServiceServer(grpcServer, rebalanceService)
+
+	repairStorage, ok := storageEngine.(antientropy.Storage)
+	if !ok {
+		logger.Log("msg", "storage engine does not support anti-entropy", "engine", args.engine)
+		os.Exit(1)
+	}
+
+	repairer := antientropy.New(cluster, repairStorage, logger,
+		antientropy.WithPlacement(placement),
+		antientropy.WithInterval(args.antiEntropyInterval),
+		antientropy.WithTreeDepth(args.merkleTreeDepth),
+	)
+	antientropyService := antientropysvc.New(repairer)
+	antientropypb.RegisterAntiEntropyServiceServer(grpcServer, antientropyService)
+
 	wg := sync.WaitGroup{}
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -144,6 +334,31 @@ func main() {
 		detector.RunLoop(appctx)
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rebalancer.RunLoop(appctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		repairer.RunLoop(appctx)
+	}()
+
+	if hintLog != nil {
+		replayer := hints.NewReplayer(cluster, hintLog, logger,
+			hints.WithReplayInterval(args.hintReplayInterval),
+			hints.WithWindow(args.hintWindow),
+		)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replayer.RunLoop(appctx)
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -200,9 +415,19 @@ func main() {
 		}
 
 		grpcServer.GracefulStop()
+		replicationService.Close()
 		eventReceiver.Close()
 		gossiper.Shutdown()
-		lsmt.Close()
+
+		if err := closeStorage(); err != nil {
+			logger.Log("msg", "failed to close storage", "err", err)
+		}
+
+		if hintLog != nil {
+			if err := hintLog.Close(); err != nil {
+				logger.Log("msg", "failed to close hint log", "err", err)
+			}
+		}
 	}()
 
 	// Create a TCP listener for the GRPC server.
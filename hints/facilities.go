@@ -0,0 +1,13 @@
+package hints
+
+//go:generate mockgen -source=facilities.go -destination=facilities_mock_test.go -package=hints
+
+import (
+	"github.com/maxpoletaev/kv/clust"
+	"github.com/maxpoletaev/kv/membership"
+)
+
+type Cluster interface {
+	Members() []membership.Member
+	Conn(membership.NodeID) (clust.Conn, error)
+}
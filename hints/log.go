@@ -0,0 +1,460 @@
+package hints
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maxpoletaev/kv/hints/proto"
+	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/membership"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+	"github.com/maxpoletaev/kv/storage/vfs"
+)
+
+const segmentExt = ".hints"
+
+// Hint is a write stored on behalf of a node that was unavailable when the write happened.
+type Hint struct {
+	Key       string
+	Value     *storagepb.VersionedValue
+	CreatedAt time.Time
+}
+
+type segment struct {
+	seq    uint64
+	name   string
+	file   vfs.File
+	writer *protoio.Writer
+	// lastHint is the creation time of the latest hint in the segment. It is
+	// not known for the segments of the previous run until they are read.
+	lastHint time.Time
+}
+
+// Log is a durable log of the hints kept on this node. The hints meant for each node are
+// appended to their own segment files, so that they can be replayed and removed independently
+// of the hints of the other nodes. A segment is sealed once it is replayed, and the new hints
+// arriving in the meantime go to a new segment. Each hint is synced to disk before Append
+// returns, so that an acknowledged write is not lost if this node crashes. The hints hold
+// the client data, so they are encrypted the same way as the data files of the storage.
+type Log struct {
+	fs      vfs.FS
+	cipher  protoio.Cipher
+	mut     sync.Mutex
+	nextSeq uint64
+	active  map[membership.NodeID]*segment
+	sealed  map[membership.NodeID][]*segment
+}
+
+// Open opens the log in the given directory, creating the directory if it does not exist.
+// The hints are encrypted with the cipher, unless it is nil.
+func Open(dir string, cipher protoio.Cipher) (*Log, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create hints directory: %w", err)
+	}
+
+	return OpenFS(vfs.NewOS(dir), cipher)
+}
+
+// OpenFS opens the log stored in the given file system. The segments left from
+// the previous run are sealed and replayed as usual.
+func OpenFS(fs vfs.FS, cipher protoio.Cipher) (*Log, error) {
+	names, err := fs.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hints directory: %w", err)
+	}
+
+	l := &Log{
+		fs:      fs,
+		cipher:  cipher,
+		nextSeq: 1,
+		active:  make(map[membership.NodeID]*segment),
+		sealed:  make(map[membership.NodeID][]*segment),
+	}
+
+	for _, name := range names {
+		owner, seq, ok := parseSegmentName(name)
+		if !ok {
+			continue
+		}
+
+		l.sealed[owner] = append(l.sealed[owner], &segment{
+			seq:  seq,
+			name: name,
+		})
+
+		if seq >= l.nextSeq {
+			l.nextSeq = seq + 1
+		}
+	}
+
+	for _, segments := range l.sealed {
+		sort.Slice(segments, func(i, j int) bool {
+			return segments[i].seq < segments[j].seq
+		})
+	}
+
+	return l, nil
+}
+
+func segmentName(owner membership.NodeID, seq uint64) string {
+	return fmt.Sprintf("%d-%d%s", owner, seq, segmentExt)
+}
+
+func parseSegmentName(name string) (owner membership.NodeID, seq uint64, ok bool) {
+	if !strings.HasSuffix(name, segmentExt) {
+		return 0, 0, false
+	}
+
+	var id uint32
+	if _, err := fmt.Sscanf(strings.TrimSuffix(name, segmentExt), "%d-%d", &id, &seq); err != nil {
+		return 0, 0, false
+	}
+
+	return membership.NodeID(id), seq, true
+}
+
+// Append adds the hint to the log of the given node.
+func (l *Log) Append(owner membership.NodeID, hint Hint) error {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	seg := l.active[owner]
+
+	if seg == nil {
+		name := segmentName(owner, l.nextSeq)
+
+		file, err := l.fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to create hints segment: %w", err)
+		}
+
+		seg = &segment{
+			seq:    l.nextSeq,
+			name:   name,
+			file:   file,
+			writer: protoio.NewEncryptedWriter(file, l.cipher),
+		}
+
+		l.active[owner] = seg
+		l.nextSeq++
+	}
+
+	if _, err := seg.writer.Append(toProtoHint(hint)); err != nil {
+		return fmt.Errorf("failed to append hint: %w", err)
+	}
+
+	if err := seg.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync hints segment: %w", err)
+	}
+
+	if hint.CreatedAt.After(seg.lastHint) {
+		seg.lastHint = hint.CreatedAt
+	}
+
+	return nil
+}
+
+// Owners returns the nodes that have hints stored in the log, in ascending order.
+func (l *Log) Owners() []membership.NodeID {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	owners := make([]membership.NodeID, 0, len(l.active)+len(l.sealed))
+
+	for id := range l.active {
+		owners = append(owners, id)
+	}
+
+	for id := range l.sealed {
+		if l.active[id] == nil {
+			owners = append(owners, id)
+		}
+	}
+
+	sort.Slice(owners, func(i, j int) bool {
+		return owners[i] < owners[j]
+	})
+
+	return owners
+}
+
+// seal closes the segment of the node that is open for appends, if any,
+// and returns all segments of the node, oldest first.
+func (l *Log) seal(owner membership.NodeID) ([]*segment, error) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if err := l.sealActive(owner); err != nil {
+		return nil, err
+	}
+
+	segments := make([]*segment, len(l.sealed[owner]))
+	copy(segments, l.sealed[owner])
+
+	return segments, nil
+}
+
+// sealActive closes the segment of the node that is open for appends, if any.
+// Must be called with the lock held.
+func (l *Log) sealActive(owner membership.NodeID) error {
+	if seg := l.active[owner]; seg != nil {
+		delete(l.active, owner)
+
+		if err := seg.file.Close(); err != nil {
+			return fmt.Errorf("failed to close hints segment: %w", err)
+		}
+
+		seg.file, seg.writer = nil, nil
+		l.sealed[owner] = append(l.sealed[owner], seg)
+	}
+
+	return nil
+}
+
+// remove deletes the sealed segment of the node.
+func (l *Log) remove(owner membership.NodeID, seg *segment) error {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	return l.removeSealed(owner, seg)
+}
+
+// removeSealed is like remove, but must be called with the lock held.
+func (l *Log) removeSealed(owner membership.NodeID, seg *segment) error {
+	segments := l.sealed[owner]
+
+	for i := range segments {
+		if segments[i] == seg {
+			segments = append(segments[:i], segments[i+1:]...)
+			break
+		}
+	}
+
+	if len(segments) == 0 {
+		delete(l.sealed, owner)
+	} else {
+		l.sealed[owner] = segments
+	}
+
+	if err := l.fs.Remove(seg.name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove hints segment: %w", err)
+	}
+
+	return nil
+}
+
+// Replay passes the hints of the node to the deliver function, oldest first. Each segment
+// is removed once all of its hints are delivered. If the delivery fails, the replay stops,
+// and the hints of the segment are passed again on the next replay, including the ones that
+// have been delivered already. It returns the number of the delivered hints. Replay must not
+// be called concurrently for the same node.
+func (l *Log) Replay(owner membership.NodeID, deliver func(Hint) error) (int, error) {
+	segments, err := l.seal(owner)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+
+	for _, seg := range segments {
+		hints, err := l.readSegment(seg)
+		if err != nil {
+			return delivered, err
+		}
+
+		for _, hint := range hints {
+			if err := deliver(hint); err != nil {
+				return delivered, err
+			}
+
+			delivered++
+		}
+
+		if err := l.remove(owner, seg); err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+// Expire removes the segments that have no hints created since the given time, so that
+// the hints of the nodes that never come back do not pile up forever. It returns the
+// number of removed segments.
+func (l *Log) Expire(before time.Time) (int, error) {
+	l.mut.Lock()
+	segments := make(map[*segment]membership.NodeID)
+
+	for id, seg := range l.active {
+		segments[seg] = id
+	}
+
+	for id, sealed := range l.sealed {
+		for _, seg := range sealed {
+			segments[seg] = id
+		}
+	}
+	l.mut.Unlock()
+
+	expired := 0
+
+	for seg, id := range segments {
+		lastHint, err := l.lastHint(seg)
+		if err != nil {
+			return expired, err
+		}
+
+		if !lastHint.Before(before) {
+			continue
+		}
+
+		removed, err := l.expireSegment(id, seg, before)
+		if err != nil {
+			return expired, err
+		}
+
+		if removed {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// expireSegment removes the segment unless a hint has been appended to it since its last
+// hint was checked. The check, the sealing and the removal are done under a single lock
+// hold, so that no hint can be appended to the segment after the check and removed with it.
+func (l *Log) expireSegment(owner membership.NodeID, seg *segment, before time.Time) (bool, error) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if l.active[owner] == seg {
+		if !seg.lastHint.Before(before) {
+			return false, nil
+		}
+
+		if err := l.sealActive(owner); err != nil {
+			return false, err
+		}
+	}
+
+	if err := l.removeSealed(owner, seg); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// lastHint returns the creation time of the latest hint in the segment. The segments of the
+// previous run are read to find it out. The segments with no readable hints are considered
+// to be empty since forever.
+func (l *Log) lastHint(seg *segment) (time.Time, error) {
+	l.mut.Lock()
+	lastHint, active := seg.lastHint, seg.file != nil
+	l.mut.Unlock()
+
+	if active || !lastHint.IsZero() {
+		return lastHint, nil
+	}
+
+	hints, err := l.readSegment(seg)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, hint := range hints {
+		if hint.CreatedAt.After(lastHint) {
+			lastHint = hint.CreatedAt
+		}
+	}
+
+	l.mut.Lock()
+	seg.lastHint = lastHint
+	l.mut.Unlock()
+
+	return lastHint, nil
+}
+
+// Close closes the segments open for appends.
+func (l *Log) Close() error {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	for id, seg := range l.active {
+		if err := seg.file.Close(); err != nil {
+			return fmt.Errorf("failed to close hints segment: %w", err)
+		}
+
+		seg.file, seg.writer = nil, nil
+		l.sealed[id] = append(l.sealed[id], seg)
+		delete(l.active, id)
+	}
+
+	return nil
+}
+
+// readSegment reads all hints of the segment. A partially written hint at the
+// end of the segment, left after a crash, is ignored, since it was never acknowledged.
+func (l *Log) readSegment(seg *segment) ([]Hint, error) {
+	file, err := l.fs.OpenFile(seg.name, os.O_RDONLY, 0)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to open hints segment: %w", err)
+	}
+
+	defer file.Close()
+
+	reader := protoio.NewEncryptedReader(file, l.cipher)
+	hints := make([]Hint, 0)
+
+	for {
+		msg := &proto.Hint{}
+
+		if _, err := reader.ReadNext(msg); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+
+			return nil, fmt.Errorf("failed to read hint: %w", err)
+		}
+
+		hints = append(hints, fromProtoHint(msg))
+	}
+
+	return hints, nil
+}
+
+func toProtoHint(hint Hint) *proto.Hint {
+	return &proto.Hint{
+		Key:       hint.Key,
+		Version:   hint.Value.Version,
+		Data:      hint.Value.Data,
+		Timestamp: hint.Value.Timestamp,
+		Origin:    hint.Value.Origin,
+		Tombstone: hint.Value.Tombstone,
+		CreatedAt: hint.CreatedAt.UnixMilli(),
+	}
+}
+
+func fromProtoHint(msg *proto.Hint) Hint {
+	return Hint{
+		Key: msg.Key,
+		Value: &storagepb.VersionedValue{
+			Version:   msg.Version,
+			Data:      msg.Data,
+			Timestamp: msg.Timestamp,
+			Origin:    msg.Origin,
+			Tombstone: msg.Tombstone,
+		},
+		CreatedAt: time.UnixMilli(msg.CreatedAt),
+	}
+}
@@ -0,0 +1,200 @@
+package hints
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/keyring"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/membership"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+	"github.com/maxpoletaev/kv/storage/vfs"
+)
+
+func testHint(key string) Hint {
+	return Hint{
+		Key: key,
+		Value: &storagepb.VersionedValue{
+			Version:   vclock.NewEncoded(vclock.V{1: 1}),
+			Data:      []byte(key),
+			Timestamp: 10,
+			Origin:    1,
+		},
+		CreatedAt: time.UnixMilli(1000),
+	}
+}
+
+func replayAll(t *testing.T, l *Log, owner membership.NodeID) []Hint {
+	replayed := make([]Hint, 0)
+
+	_, err := l.Replay(owner, func(h Hint) error {
+		replayed = append(replayed, h)
+		return nil
+	})
+	require.NoError(t, err)
+
+	return replayed
+}
+
+func TestLog_Replay(t *testing.T) {
+	l, err := Open(t.TempDir(), nil)
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	require.NoError(t, l.Append(2, testHint("a")))
+	require.NoError(t, l.Append(3, testHint("b")))
+	require.NoError(t, l.Append(2, testHint("c")))
+	assert.Equal(t, []membership.NodeID{2, 3}, l.Owners())
+
+	// Only the hints of the given node are replayed, in the order they were added.
+	assert.Equal(t, []Hint{testHint("a"), testHint("c")}, replayAll(t, l, 2))
+	assert.Equal(t, []membership.NodeID{3}, l.Owners())
+	assert.Empty(t, replayAll(t, l, 2))
+
+	// The hints added after the replay go to a new segment.
+	require.NoError(t, l.Append(2, testHint("d")))
+	assert.Equal(t, []Hint{testHint("d")}, replayAll(t, l, 2))
+}
+
+func TestLog_ReplayFailed(t *testing.T) {
+	l, err := Open(t.TempDir(), nil)
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	require.NoError(t, l.Append(2, testHint("a")))
+	require.NoError(t, l.Append(2, testHint("b")))
+
+	delivered, err := l.Replay(2, func(h Hint) error {
+		if h.Key == "b" {
+			return assert.AnError
+		}
+
+		return nil
+	})
+
+	require.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 1, delivered)
+
+	// The segment is kept, and the new hints are replayed after the old ones.
+	require.NoError(t, l.Append(2, testHint("c")))
+	assert.Equal(t, []Hint{testHint("a"), testHint("b"), testHint("c")}, replayAll(t, l, 2))
+}
+
+func TestLog_Reopen(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, nil)
+	require.NoError(t, err)
+	require.NoError(t, l.Append(2, testHint("a")))
+	require.NoError(t, l.Append(3, testHint("b")))
+	require.NoError(t, l.Close())
+
+	l, err = Open(dir, nil)
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	assert.Equal(t, []membership.NodeID{2, 3}, l.Owners())
+
+	// The segments of the previous run are not appended to.
+	require.NoError(t, l.Append(2, testHint("c")))
+	assert.Equal(t, []Hint{testHint("a"), testHint("c")}, replayAll(t, l, 2))
+	assert.Equal(t, []Hint{testHint("b")}, replayAll(t, l, 3))
+}
+
+func TestLog_Expire(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, nil)
+	require.NoError(t, err)
+
+	// The hints keep their creation time with millisecond precision.
+	now := time.UnixMilli(time.Now().UnixMilli())
+	old, recent := testHint("old"), testHint("recent")
+	old.CreatedAt, recent.CreatedAt = now.Add(-time.Hour), now
+
+	// The segment of node 2 has not been written to for a long time.
+	require.NoError(t, l.Append(2, old))
+	require.NoError(t, l.Append(3, recent))
+	require.NoError(t, l.Append(4, old))
+	require.NoError(t, l.Close())
+
+	l, err = Open(dir, nil)
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	// The node 4 has a recent hint appended after the restart, but the old segment still expires.
+	require.NoError(t, l.Append(4, recent))
+
+	removed, err := l.Expire(now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	assert.Equal(t, []membership.NodeID{3, 4}, l.Owners())
+	assert.Empty(t, replayAll(t, l, 2))
+	assert.Equal(t, []Hint{recent}, replayAll(t, l, 3))
+	assert.Equal(t, []Hint{recent}, replayAll(t, l, 4))
+}
+
+func TestLog_ExpireAppended(t *testing.T) {
+	l, err := OpenFS(vfs.NewMem(), nil)
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	now := time.UnixMilli(time.Now().UnixMilli())
+	old, recent := testHint("old"), testHint("recent")
+	old.CreatedAt, recent.CreatedAt = now.Add(-time.Hour), now
+
+	// The segment is found to be expired, but a recent hint is appended to it
+	// before it is removed, so it must be kept.
+	require.NoError(t, l.Append(2, old))
+	seg := l.active[2]
+	require.NoError(t, l.Append(2, recent))
+
+	removed, err := l.expireSegment(2, seg, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	assert.Equal(t, []Hint{old, recent}, replayAll(t, l, 2))
+}
+
+func TestLog_Encrypted(t *testing.T) {
+	kr, err := keyring.New(map[uint16][]byte{1: bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+
+	fs := vfs.NewMem()
+
+	l, err := OpenFS(fs, kr)
+	require.NoError(t, err)
+	require.NoError(t, l.Append(2, testHint("secret")))
+	require.NoError(t, l.Close())
+
+	names, err := fs.List()
+	require.NoError(t, err)
+	require.Len(t, names, 1)
+
+	file, err := fs.OpenFile(names[0], os.O_RDONLY, 0)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	// The hints are only readable with the same keys.
+	l, err = OpenFS(fs, kr)
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	assert.Equal(t, []Hint{testHint("secret")}, replayAll(t, l, 2))
+}
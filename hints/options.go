@@ -0,0 +1,20 @@
+package hints
+
+import "time"
+
+type option func(*Replayer)
+
+// WithReplayInterval sets how often the hints are replayed to the nodes that are healthy.
+func WithReplayInterval(d time.Duration) option {
+	return func(r *Replayer) {
+		r.interval = d
+	}
+}
+
+// WithWindow sets how long the hints are kept. The hints older than that are dropped rather
+// than replayed, and the node they were meant for has to be repaired by other means.
+func WithWindow(d time.Duration) option {
+	return func(r *Replayer) {
+		r.window = d
+	}
+}
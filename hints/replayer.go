@@ -0,0 +1,143 @@
+package hints
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"google.golang.org/grpc/codes"
+
+	"github.com/maxpoletaev/kv/internal/grpcutil"
+	"github.com/maxpoletaev/kv/internal/multierror"
+	"github.com/maxpoletaev/kv/membership"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+const (
+	defaultReplayInterval = 10 * time.Second
+	defaultWindow         = 3 * time.Hour
+	putTimeout            = 5 * time.Second
+)
+
+// Replayer delivers the hints stored on this node to the nodes they were meant for. It polls
+// the membership list, and replays the hints of a node once the failure detector marks the
+// node healthy again. The hints are written to the node as regular replica writes, so that
+// the values the node has received from elsewhere in the meantime are not overwritten.
+type Replayer struct {
+	cluster  Cluster
+	log      *Log
+	logger   log.Logger
+	interval time.Duration
+	window   time.Duration
+	now      func() time.Time
+}
+
+func NewReplayer(cluster Cluster, hintLog *Log, logger log.Logger, opts ...option) *Replayer {
+	r := &Replayer{
+		cluster:  cluster,
+		log:      hintLog,
+		logger:   logger,
+		interval: defaultReplayInterval,
+		window:   defaultWindow,
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *Replayer) RunLoop(ctx context.Context) {
+	level.Info(r.logger).Log(
+		"msg", "hints replayer loop started",
+		"replay_interval", r.interval,
+		"window", r.window,
+	)
+
+	for {
+		select {
+		case <-time.After(r.interval):
+			// noop
+		case <-ctx.Done():
+			return
+		}
+
+		if err := r.Replay(ctx); err != nil {
+			level.Error(r.logger).Log("msg", "hints replay failed", "err", err)
+		}
+	}
+}
+
+// Replay drops the expired hints and replays the rest to the nodes that are healthy.
+// The hints of the nodes that are still unavailable are left for the next replay.
+// It must not be called concurrently with RunLoop.
+func (r *Replayer) Replay(ctx context.Context) error {
+	deadline := r.now().Add(-r.window)
+
+	removed, err := r.log.Expire(deadline)
+	if err != nil {
+		return fmt.Errorf("failed to expire hints: %w", err)
+	}
+
+	if removed > 0 {
+		level.Warn(r.logger).Log("msg", "expired hints dropped", "segments", removed)
+	}
+
+	members := make(map[membership.NodeID]membership.Member)
+	for _, m := range r.cluster.Members() {
+		members[m.ID] = m
+	}
+
+	errs := multierror.New[membership.NodeID]()
+
+	for _, owner := range r.log.Owners() {
+		member, ok := members[owner]
+		if !ok || !member.IsReacheable() {
+			continue
+		}
+
+		delivered, err := r.replayTo(ctx, member, deadline)
+		if err != nil {
+			errs.Add(owner, err)
+		}
+
+		if delivered > 0 {
+			level.Info(r.logger).Log("msg", "hints replayed", "node", member.Name, "hints", delivered)
+		}
+	}
+
+	return errs.Ret()
+}
+
+func (r *Replayer) replayTo(ctx context.Context, member membership.Member, deadline time.Time) (int, error) {
+	conn, err := r.cluster.Conn(member.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	return r.log.Replay(member.ID, func(hint Hint) error {
+		// The segment has been written to recently, but not all of its hints are.
+		if hint.CreatedAt.Before(deadline) {
+			return nil
+		}
+
+		putCtx, cancel := context.WithTimeout(ctx, putTimeout)
+		defer cancel()
+
+		_, err := conn.Put(putCtx, &storagepb.PutRequest{
+			Key:   hint.Key,
+			Value: hint.Value,
+		})
+
+		// The node already has a newer value, the hint is no longer needed.
+		if err != nil && grpcutil.ErrorCode(err) != codes.AlreadyExists {
+			return fmt.Errorf("failed to replay hint: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,92 @@
+package hints
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/membership"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+func TestReplayer_Replay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	l, err := Open(t.TempDir(), nil)
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	now := time.UnixMilli(1000)
+	expired := testHint("expired")
+	expired.CreatedAt = now.Add(-2 * time.Hour)
+
+	require.NoError(t, l.Append(2, expired))
+	require.NoError(t, l.Append(2, testHint("a")))
+	require.NoError(t, l.Append(2, testHint("b")))
+	require.NoError(t, l.Append(3, testHint("c")))
+
+	cluster := NewMockCluster(ctrl)
+	cluster.EXPECT().Members().Return([]membership.Member{
+		{ID: 1, Name: "node1", Status: membership.StatusHealthy},
+		{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+		{ID: 3, Name: "node3", Status: membership.StatusFaulty},
+	})
+
+	// The hints are written as regular replica writes, and the obsolete ones are skipped.
+	conn := mock.NewMockClient(ctrl)
+	conn.EXPECT().Put(gomock.Any(), &storagepb.PutRequest{
+		Key:   "a",
+		Value: testHint("a").Value,
+	}).Return(&storagepb.PutResponse{}, nil)
+	conn.EXPECT().Put(gomock.Any(), &storagepb.PutRequest{
+		Key:   "b",
+		Value: testHint("b").Value,
+	}).Return(nil, status.New(codes.AlreadyExists, "obsolete write").Err())
+
+	cluster.EXPECT().Conn(membership.NodeID(2)).Return(conn, nil)
+
+	r := NewReplayer(cluster, l, kitlog.NewNopLogger(), WithWindow(time.Hour))
+	r.now = func() time.Time { return now }
+
+	require.NoError(t, r.Replay(context.Background()))
+
+	// Node 3 is still faulty, so its hints are kept until it recovers.
+	assert.Equal(t, []membership.NodeID{3}, l.Owners())
+}
+
+func TestReplayer_ReplayFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	l, err := Open(t.TempDir(), nil)
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	require.NoError(t, l.Append(2, testHint("a")))
+
+	cluster := NewMockCluster(ctrl)
+	cluster.EXPECT().Members().Return([]membership.Member{
+		{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+	})
+
+	conn := mock.NewMockClient(ctrl)
+	conn.EXPECT().Put(gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
+	cluster.EXPECT().Conn(membership.NodeID(2)).Return(conn, nil)
+
+	r := NewReplayer(cluster, l, kitlog.NewNopLogger())
+	r.now = func() time.Time { return time.UnixMilli(1000) }
+
+	require.Error(t, r.Replay(context.Background()))
+	assert.Equal(t, []membership.NodeID{2}, l.Owners())
+}
@@ -42,6 +42,12 @@ func New(value []byte, k int) *Filter {
 // Same Performance: Building a Better Bloom Filter" by Adam Kirsch and
 // Michael Mitzenmacher.
 func NewWithProbability(n int, p float64) *Filter {
+	// An empty set still needs a filter of non-zero size, e.g. when an empty
+	// memtable left after a crash is flushed to disk.
+	if n < 1 {
+		n = 1
+	}
+
 	m := int(-float64(n) * math.Log(p) / (math.Log(2) * math.Log(2)))
 	k := int(float64(m) / float64(n) * math.Log(2))
 	return New(make([]byte, m/8), k)
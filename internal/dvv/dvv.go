@@ -0,0 +1,128 @@
+package dvv
+
+import (
+	"fmt"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+)
+
+// Dot identifies a single write: the node that has coordinated it, and the value of the
+// node's counter assigned to the write. Counters start from one, so the zero dot means
+// that the write is not known.
+type Dot struct {
+	Node    uint32
+	Counter uint32
+}
+
+// IsZero returns true if the dot is not set.
+func (d Dot) IsZero() bool {
+	return d.Counter == 0
+}
+
+// Version is a dotted version vector. Unlike a plain vector clock, where the coordinator
+// increments its entry of the version received from the client, the write is identified
+// by a separate dot, and the version received from the client is kept as the causal
+// context of the write. This allows to tell apart the concurrent writes coordinated by
+// the same node, so that the clients writing without reading first create siblings
+// rather than overwrite each other, and the siblings are not multiplied by the clients
+// writing with a stale version.
+//
+// A version without the dot is the same as a plain vector clock, which is how the values
+// written before the dotted versions were enabled are interpreted.
+type Version struct {
+	Dot     Dot
+	Context *vclock.Vector
+}
+
+// New returns a dotted version with the given dot and the causal context.
+// A nil context is the same as an empty one.
+func New(dot Dot, context *vclock.Vector) *Version {
+	if context == nil {
+		context = vclock.New()
+	}
+
+	return &Version{
+		Dot:     dot,
+		Context: context,
+	}
+}
+
+// Join returns a vector clock that covers both the context and the dot. This is the
+// version to be returned to the client, to be sent back as the context of the next write.
+func (v *Version) Join() *vclock.Vector {
+	if v.Dot.IsZero() {
+		return v.Context.Clone()
+	}
+
+	return vclock.Merge(v.Context, vclock.New(vclock.V{v.Dot.Node: v.Dot.Counter}))
+}
+
+// NextDot returns the dot for a new write coordinated by the given node. The counter is
+// incremented past all counters of the node seen in the given vectors, which must include
+// the context of the write and the versions of all values currently stored for the key.
+func NextDot(node uint32, seen ...*vclock.Vector) Dot {
+	var counter uint32
+
+	for _, v := range seen {
+		if c := v.Get(node); c > counter {
+			counter = c
+		}
+	}
+
+	return Dot{
+		Node:    node,
+		Counter: counter + 1,
+	}
+}
+
+// covers returns true if all writes known to a are also known to b. The writes known
+// to a version without the dot are not known exactly, so it is assumed that b knows
+// all writes of each node up to its counter, including the one of its own dot.
+func covers(b, a *Version) bool {
+	if !a.Dot.IsZero() {
+		return b.Dot == a.Dot || b.Context.Get(a.Dot.Node) >= a.Dot.Counter
+	}
+
+	switch vclock.Compare(a.Context, b.Join()) {
+	case vclock.Before, vclock.Equal:
+		return true
+	default:
+		return false
+	}
+}
+
+// Compare returns the causality relationship between two versions, in the same way as
+// vclock.Compare does. A write happened before the other if its dot is included in the
+// context of the other one. Versions without the dot are compared as vector clocks, so
+// the result for two such versions is the same as of vclock.Compare.
+func Compare(a, b *Version) vclock.Causality {
+	aBeforeB, bBeforeA := covers(b, a), covers(a, b)
+
+	switch {
+	case aBeforeB && bBeforeA:
+		return vclock.Equal
+	case aBeforeB:
+		return vclock.Before
+	case bBeforeA:
+		return vclock.After
+	default:
+		return vclock.Concurrent
+	}
+}
+
+// IsEqual returns true if the two versions are equal.
+func IsEqual(a, b *Version) bool {
+	return Compare(a, b) == vclock.Equal
+}
+
+// String returns a string representation of the version, which is
+// the context followed by the dot, if it is set: {1=1, 2=1}(2:3).
+func (v Version) String() string {
+	s := v.Context.String()
+
+	if !v.Dot.IsZero() {
+		s += fmt.Sprintf("(%d:%d)", v.Dot.Node, v.Dot.Counter)
+	}
+
+	return s
+}
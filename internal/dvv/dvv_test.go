@@ -0,0 +1,231 @@
+package dvv
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/internal/vclock/pb"
+)
+
+func TestCompare(t *testing.T) {
+	tests := map[string]struct {
+		a        *Version
+		b        *Version
+		expected vclock.Causality
+	}{
+		"SameDot": {
+			a:        New(Dot{1, 2}, vclock.New(vclock.V{1: 1})),
+			b:        New(Dot{1, 2}, vclock.New(vclock.V{1: 1})),
+			expected: vclock.Equal,
+		},
+		"DotInContext": {
+			a:        New(Dot{1, 1}, vclock.New()),
+			b:        New(Dot{2, 1}, vclock.New(vclock.V{1: 1})),
+			expected: vclock.Before,
+		},
+		"DotNotInContext": {
+			a:        New(Dot{1, 2}, vclock.New(vclock.V{2: 1})),
+			b:        New(Dot{2, 2}, vclock.New(vclock.V{1: 1})),
+			expected: vclock.Concurrent,
+		},
+		"BlindWritesThroughSameNode": {
+			a:        New(Dot{1, 1}, vclock.New()),
+			b:        New(Dot{1, 2}, vclock.New()),
+			expected: vclock.Concurrent,
+		},
+		"WriteAfterRead": {
+			a:        New(Dot{1, 3}, vclock.New(vclock.V{1: 2})),
+			b:        New(Dot{1, 2}, vclock.New()),
+			expected: vclock.After,
+		},
+		"VectorClocks": {
+			a:        New(Dot{}, vclock.New(vclock.V{1: 1, 2: 2})),
+			b:        New(Dot{}, vclock.New(vclock.V{1: 2, 2: 2})),
+			expected: vclock.Before,
+		},
+		"ConcurrentVectorClocks": {
+			a:        New(Dot{}, vclock.New(vclock.V{1: 1})),
+			b:        New(Dot{}, vclock.New(vclock.V{2: 1})),
+			expected: vclock.Concurrent,
+		},
+		"VectorClockBeforeDot": {
+			a:        New(Dot{}, vclock.New(vclock.V{1: 1})),
+			b:        New(Dot{1, 2}, vclock.New(vclock.V{2: 1})),
+			expected: vclock.Before,
+		},
+		"VectorClockCoversDot": {
+			a:        New(Dot{}, vclock.New(vclock.V{1: 2})),
+			b:        New(Dot{1, 2}, vclock.New(vclock.V{1: 1})),
+			expected: vclock.Equal,
+		},
+		"VectorClockConcurrentWithDot": {
+			a:        New(Dot{}, vclock.New(vclock.V{2: 2})),
+			b:        New(Dot{1, 2}, vclock.New(vclock.V{2: 1})),
+			expected: vclock.Concurrent,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Compare(tt.a, tt.b), "a=%s, b=%s", tt.a, tt.b)
+
+			// The relation must be symmetric.
+			switch tt.expected {
+			case vclock.Before:
+				assert.Equal(t, vclock.After, Compare(tt.b, tt.a))
+			case vclock.After:
+				assert.Equal(t, vclock.Before, Compare(tt.b, tt.a))
+			default:
+				assert.Equal(t, tt.expected, Compare(tt.b, tt.a))
+			}
+		})
+	}
+}
+
+func TestCompare_SameAsVectorClocks(t *testing.T) {
+	vectors := []*vclock.Vector{
+		vclock.New(),
+		vclock.New(vclock.V{1: 1}),
+		vclock.New(vclock.V{1: 2}),
+		vclock.New(vclock.V{1: 1, 2: 1}),
+		vclock.New(vclock.V{2: 1}),
+		vclock.New(vclock.V{1: 2, 2: 3}),
+	}
+
+	for _, a := range vectors {
+		for _, b := range vectors {
+			want := vclock.Compare(a, b)
+			got := Compare(New(Dot{}, a), New(Dot{}, b))
+			assert.Equal(t, want, got, "a=%s, b=%s", a, b)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	v := New(Dot{1, 3}, vclock.New(vclock.V{1: 1, 2: 2}))
+	assert.Equal(t, "{1=3, 2=2}", v.Join().String())
+	assert.Equal(t, "{1=1, 2=2}", v.Context.String())
+
+	v = New(Dot{}, vclock.New(vclock.V{1: 1}))
+	assert.Equal(t, "{1=1}", v.Join().String())
+}
+
+func TestNextDot(t *testing.T) {
+	dot := NextDot(1, vclock.New(vclock.V{1: 1}), vclock.New(vclock.V{1: 3, 2: 5}), vclock.New())
+	assert.Equal(t, Dot{Node: 1, Counter: 4}, dot)
+
+	dot = NextDot(2)
+	assert.Equal(t, Dot{Node: 2, Counter: 1}, dot)
+}
+
+func TestEncodeDecode(t *testing.T) {
+	tests := map[string]*Version{
+		"Empty":          New(Dot{}, nil),
+		"DotOnly":        New(Dot{1, 1}, nil),
+		"ContextOnly":    New(Dot{}, vclock.New(vclock.V{1: 10, 2: 5})),
+		"DotAndContext":  New(Dot{3, 7}, vclock.New(vclock.V{1: 10, 2: 5})),
+		"LargeDotValues": New(Dot{1 << 31, 1<<32 - 1}, vclock.New(vclock.V{1: 1})),
+	}
+
+	for name, v := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := Encode(v)
+			require.NoError(t, err)
+
+			got, err := Decode(s)
+			require.NoError(t, err)
+			assert.Equal(t, v, got, "want: %s, got: %s", v, got)
+		})
+	}
+}
+
+func TestEncode_CompatibleWithVectorClocks(t *testing.T) {
+	context := vclock.New(vclock.V{1: 10, 2: 5})
+
+	// Vector clocks are decoded as versions without the dot...
+	v, err := Decode(vclock.MustEncode(context))
+	require.NoError(t, err)
+	assert.True(t, v.Dot.IsZero())
+	assert.Equal(t, context, v.Context)
+
+	// ...and versions without the dot are encoded as vector clocks.
+	assert.Equal(t, vclock.MustEncode(context), MustEncode(New(Dot{}, context)))
+	assert.Equal(t, vclock.Marshal(context), Marshal(New(Dot{}, context)))
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	tests := map[string]*Version{
+		"Empty":          New(Dot{}, nil),
+		"DotOnly":        New(Dot{1, 1}, nil),
+		"ContextOnly":    New(Dot{}, vclock.New(vclock.V{1: 10, 2: 5})),
+		"DotAndContext":  New(Dot{3, 7}, vclock.New(vclock.V{1: 10, 2: 5})),
+		"LargeDotValues": New(Dot{1 << 31, 1<<32 - 1}, vclock.New(vclock.V{1: 1})),
+	}
+
+	for name, v := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Unmarshal(Marshal(v))
+			require.NoError(t, err)
+			assert.Equal(t, v, got, "want: %s, got: %s", v, got)
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	v := New(Dot{2, 300}, vclock.New(vclock.V{1: 1}))
+	want := []byte{BinaryFormat, 2, 0xac, 0x02, vclock.BinaryFormat, 1, 1, 1, 0}
+	assert.Equal(t, want, Marshal(v))
+}
+
+func TestUnmarshal_Legacy(t *testing.T) {
+	b, err := proto.Marshal(&pb.VectorClock{
+		Clocks: map[uint32]uint32{1: 10, 2: 5},
+	})
+	require.NoError(t, err)
+
+	// The dot used to be appended to the vector clock message as extra fields.
+	b = protowire.AppendTag(b, legacyDotNodeField, protowire.VarintType)
+	b = protowire.AppendVarint(b, 3)
+	b = protowire.AppendTag(b, legacyDotCounterField, protowire.VarintType)
+	b = protowire.AppendVarint(b, 7)
+
+	want := New(Dot{3, 7}, vclock.New(vclock.V{1: 10, 2: 5}))
+	s := base64.StdEncoding.EncodeToString(b)
+
+	got, err := Decode(s)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// The values stored in the engines before the binary encoding hold the base64 text.
+	got, err = Unmarshal([]byte(s))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	_, err := Decode("not base64!")
+	assert.Error(t, err)
+}
+
+func TestUnmarshal_Invalid(t *testing.T) {
+	tests := map[string][]byte{
+		"MissingContext":   {BinaryFormat, 1, 1},
+		"LegacyContext":    append([]byte{BinaryFormat, 1, 1}, "AQ=="...),
+		"TruncatedDot":     {BinaryFormat, 0x80},
+		"DotOverflow":      {BinaryFormat, 0x80, 0x80, 0x80, 0x80, 0x10, 1, vclock.BinaryFormat, 0},
+		"TruncatedContext": {BinaryFormat, 1, 1, vclock.BinaryFormat, 1},
+	}
+
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Unmarshal(b)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package dvv
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+)
+
+// BinaryFormat is the first byte of the binary encoding of a version with the dot. It is
+// followed by the dot and the binary encoding of the context. The versions without the
+// dot are encoded exactly as vector clocks, so the values written before the dotted
+// versions were enabled remain readable.
+const BinaryFormat byte = 0x02
+
+// In the legacy encoding, the dot was appended to the protobuf message of the context
+// as two extra fields, unknown to the vector clock message.
+const (
+	legacyDotNodeField    protowire.Number = 4
+	legacyDotCounterField protowire.Number = 5
+)
+
+// Marshal encodes the version into the canonical binary form.
+func Marshal(v *Version) []byte {
+	context := vclock.Marshal(v.Context)
+
+	if v.Dot.IsZero() {
+		return context
+	}
+
+	b := make([]byte, 1+2*binary.MaxVarintLen32, 1+2*binary.MaxVarintLen32+len(context))
+	b[0] = BinaryFormat
+
+	n := 1
+	n += binary.PutUvarint(b[n:], uint64(v.Dot.Node))
+	n += binary.PutUvarint(b[n:], uint64(v.Dot.Counter))
+
+	return append(b[:n], context...)
+}
+
+// Unmarshal decodes a version encoded with Marshal. Encoded vector clocks, including the
+// ones stored in the legacy format, are decoded as the versions without the dot.
+func Unmarshal(b []byte) (*Version, error) {
+	if len(b) == 0 || (b[0] != BinaryFormat && b[0] != vclock.BinaryFormat) {
+		// Records stored before the binary encoding hold the base64 text.
+		return Decode(string(b))
+	}
+
+	return unmarshalBinary(b)
+}
+
+// MustUnmarshal is like Unmarshal, but panics on error.
+func MustUnmarshal(b []byte) *Version {
+	v, err := Unmarshal(b)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// Encode encodes the version into a base64-encoded string of its binary form.
+// The encoding is the same for the same versions.
+func Encode(v *Version) (string, error) {
+	return base64.StdEncoding.EncodeToString(Marshal(v)), nil
+}
+
+// MustEncode is like Encode, but panics on error.
+func MustEncode(v *Version) string {
+	s, err := Encode(v)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// NewEncoded creates a base64-encoded string with the given dot and context
+// values. It is equivalent to MustEncode(New(dot, vclock.New(v...))).
+func NewEncoded(dot Dot, v ...vclock.V) string {
+	return MustEncode(New(dot, vclock.New(v...)))
+}
+
+// Decode decodes a base64-encoded string into a version. Encoded vector clocks
+// are decoded as the versions without the dot.
+func Decode(s string) (*Version, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64: %w", err)
+	}
+
+	if len(b) > 0 && (b[0] == BinaryFormat || b[0] == vclock.BinaryFormat) {
+		return unmarshalBinary(b)
+	}
+
+	return decodeLegacy(s, b)
+}
+
+// MustDecode is like Decode, but panics on error.
+func MustDecode(s string) *Version {
+	v, err := Decode(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+func unmarshalBinary(b []byte) (*Version, error) {
+	if b[0] == vclock.BinaryFormat {
+		context, err := vclock.Unmarshal(b)
+		if err != nil {
+			return nil, err
+		}
+
+		return New(Dot{}, context), nil
+	}
+
+	b = b[1:]
+
+	var dot Dot
+
+	for _, field := range []*uint32{&dot.Node, &dot.Counter} {
+		v, n := binary.Uvarint(b)
+		if n <= 0 || v > 1<<32-1 {
+			return nil, fmt.Errorf("failed to decode dot: %w", vclock.ErrInvalidEncoding)
+		}
+
+		*field = uint32(v)
+		b = b[n:]
+	}
+
+	// The context must be in the binary format as well, otherwise
+	// it would be mistaken for the legacy base64 text.
+	if len(b) == 0 || b[0] != vclock.BinaryFormat {
+		return nil, vclock.ErrInvalidEncoding
+	}
+
+	context, err := vclock.Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(dot, context), nil
+}
+
+// decodeLegacy decodes a version in the legacy protobuf encoding, where the dot is stored
+// in the extra fields of the vector clock message. The fields are skipped by vclock.Decode.
+func decodeLegacy(s string, b []byte) (*Version, error) {
+	context, err := vclock.Decode(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var dot Dot
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("failed to decode dot: %w", protowire.ParseError(n))
+		}
+
+		b = b[n:]
+
+		if typ == protowire.VarintType && (num == legacyDotNodeField || num == legacyDotCounterField) {
+			val, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, fmt.Errorf("failed to decode dot: %w", protowire.ParseError(n))
+			}
+
+			if num == legacyDotNodeField {
+				dot.Node = uint32(val)
+			} else {
+				dot.Counter = uint32(val)
+			}
+
+			b = b[n:]
+
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, fmt.Errorf("failed to decode dot: %w", protowire.ParseError(n))
+		}
+
+		b = b[n:]
+	}
+
+	return New(dot, context), nil
+}
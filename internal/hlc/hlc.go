@@ -0,0 +1,117 @@
+package hlc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const logicalBits = 16
+
+// ErrClockOffset is returned when a remote timestamp is too far ahead of the local clock.
+var ErrClockOffset = errors.New("remote clock is too far ahead")
+
+// Timestamp is a hybrid logical clock timestamp. The upper 48 bits hold the physical time
+// in milliseconds since the Unix epoch, and the lower 16 bits hold the logical counter that
+// orders the events happened within the same millisecond. The timestamps can be compared
+// as integers, and the zero timestamp means that the time is not known.
+type Timestamp uint64
+
+// NewTimestamp returns a timestamp with the given physical time and logical counter.
+func NewTimestamp(t time.Time, logical uint16) Timestamp {
+	return Timestamp(uint64(t.UnixMilli())<<logicalBits | uint64(logical))
+}
+
+// Physical returns the physical part of the timestamp, with a precision of one millisecond.
+func (t Timestamp) Physical() time.Time {
+	return time.UnixMilli(int64(t >> logicalBits))
+}
+
+// Logical returns the logical counter of the timestamp.
+func (t Timestamp) Logical() uint16 {
+	return uint16(t & (1<<logicalBits - 1))
+}
+
+// IsZero returns true if the timestamp is not set.
+func (t Timestamp) IsZero() bool {
+	return t == 0
+}
+
+// String returns a string representation of the timestamp, which is the physical
+// time in RFC3339 format followed by the logical counter: 2023-01-02T15:04:05.123Z+2.
+func (t Timestamp) String() string {
+	return fmt.Sprintf("%s+%d", t.Physical().UTC().Format("2006-01-02T15:04:05.000Z07:00"), t.Logical())
+}
+
+// Clock is a hybrid logical clock. It produces the timestamps that are close to the
+// physical time, yet always increase, even if the physical clock goes backwards, and
+// are always ahead of the timestamps received from the other nodes. This way, an event
+// caused by another event always has a greater timestamp, as with the logical clocks.
+type Clock struct {
+	mut       sync.Mutex
+	physical  func() time.Time
+	maxOffset time.Duration
+	last      Timestamp
+}
+
+// NewClock creates a new clock reading the physical time from the given function. The
+// timestamps received from the other nodes must not be ahead of the physical time by more
+// than maxOffset, so that a single node with a broken clock cannot push the clocks of the
+// whole cluster into the future. Zero maxOffset disables the check.
+func NewClock(physical func() time.Time, maxOffset time.Duration) *Clock {
+	return &Clock{
+		physical:  physical,
+		maxOffset: maxOffset,
+	}
+}
+
+// Now returns a new timestamp, which is greater than any timestamp returned
+// by the clock or passed to Update before.
+func (c *Clock) Now() Timestamp {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.last = c.next(NewTimestamp(c.physical(), 0))
+
+	return c.last
+}
+
+// Update advances the clock past the timestamp received from another node, so that
+// the timestamps returned by the clock afterwards are greater than the received one.
+func (c *Clock) Update(remote Timestamp) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.maxOffset > 0 {
+		if offset := remote.Physical().Sub(c.physical()); offset > c.maxOffset {
+			return fmt.Errorf("%w: %s ahead, max %s", ErrClockOffset, offset, c.maxOffset)
+		}
+	}
+
+	if remote > c.last {
+		c.last = remote
+	}
+
+	return nil
+}
+
+// Last returns the last timestamp returned by or passed to the clock.
+func (c *Clock) Last() Timestamp {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.last
+}
+
+// next returns a timestamp greater than the last one. When the physical time does not
+// move forward, the logical counter is incremented instead. The counter overflowing into
+// the physical part means that the clock runs slightly ahead of the physical time, until
+// the physical time catches up.
+func (c *Clock) next(wall Timestamp) Timestamp {
+	if wall > c.last {
+		return wall
+	}
+
+	return c.last + 1
+}
@@ -0,0 +1,123 @@
+package hlc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestTimestamp(t *testing.T) {
+	now := time.UnixMilli(1700000000123)
+	ts := NewTimestamp(now, 7)
+
+	assert.Equal(t, now, ts.Physical())
+	assert.Equal(t, uint16(7), ts.Logical())
+	assert.Equal(t, "2023-11-14T22:13:20.123Z+7", ts.String())
+	assert.False(t, ts.IsZero())
+	assert.True(t, Timestamp(0).IsZero())
+
+	// Timestamps are ordered by the physical time first.
+	assert.Less(t, uint64(NewTimestamp(now, 1<<16-1)), uint64(NewTimestamp(now.Add(time.Millisecond), 0)))
+}
+
+func TestClock_Now(t *testing.T) {
+	physical := &fakeClock{now: time.UnixMilli(1000)}
+	clock := NewClock(physical.Now, 0)
+
+	assert.Equal(t, NewTimestamp(physical.now, 0), clock.Now())
+
+	// The physical time has not changed, so the logical counter is incremented.
+	assert.Equal(t, NewTimestamp(physical.now, 1), clock.Now())
+
+	// The physical time goes backwards, but the timestamps keep increasing.
+	physical.now = time.UnixMilli(500)
+	assert.Equal(t, NewTimestamp(time.UnixMilli(1000), 2), clock.Now())
+
+	// The physical time moves forward, the logical counter is reset.
+	physical.now = time.UnixMilli(2000)
+	assert.Equal(t, NewTimestamp(physical.now, 0), clock.Now())
+}
+
+func TestClock_NowLogicalOverflow(t *testing.T) {
+	physical := &fakeClock{now: time.UnixMilli(1000)}
+	clock := NewClock(physical.Now, 0)
+	clock.last = NewTimestamp(physical.now, 1<<16-1)
+
+	assert.Equal(t, NewTimestamp(time.UnixMilli(1001), 0), clock.Now())
+}
+
+func TestClock_Update(t *testing.T) {
+	physical := &fakeClock{now: time.UnixMilli(1000)}
+	clock := NewClock(physical.Now, 0)
+
+	remote := NewTimestamp(time.UnixMilli(5000), 3)
+	require.NoError(t, clock.Update(remote))
+	assert.Equal(t, remote, clock.Last())
+
+	// The timestamps are ahead of the received one.
+	assert.Equal(t, remote+1, clock.Now())
+
+	// Older timestamps do not move the clock backwards.
+	require.NoError(t, clock.Update(NewTimestamp(time.UnixMilli(10), 0)))
+	assert.Equal(t, remote+1, clock.Last())
+}
+
+func TestClock_UpdateMaxOffset(t *testing.T) {
+	physical := &fakeClock{now: time.UnixMilli(1000)}
+	clock := NewClock(physical.Now, time.Second)
+
+	require.NoError(t, clock.Update(NewTimestamp(time.UnixMilli(2000), 0)))
+
+	err := clock.Update(NewTimestamp(time.UnixMilli(2001), 0))
+	assert.ErrorIs(t, err, ErrClockOffset)
+	assert.Equal(t, NewTimestamp(time.UnixMilli(2000), 0), clock.Last())
+}
+
+func TestClock_NowConcurrent(t *testing.T) {
+	clock := NewClock(time.Now, 0)
+
+	const (
+		workers   = 8
+		perWorker = 1000
+	)
+
+	results := make(chan Timestamp, workers*perWorker)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var prev Timestamp
+
+			for j := 0; j < perWorker; j++ {
+				ts := clock.Now()
+				assert.Greater(t, uint64(ts), uint64(prev))
+				prev = ts
+				results <- ts
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(results)
+
+	seen := make(map[Timestamp]bool, workers*perWorker)
+	for ts := range results {
+		assert.False(t, seen[ts], "duplicate timestamp %s", ts)
+		seen[ts] = true
+	}
+}
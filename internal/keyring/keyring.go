@@ -0,0 +1,165 @@
+package keyring
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnknownKey is returned when the data was encrypted with a key
+	// that is not present in the keyring.
+	ErrUnknownKey = errors.New("unknown encryption key")
+
+	// ErrEmptyKeyring is returned when the key file contains no keys.
+	ErrEmptyKeyring = errors.New("no keys in the keyring")
+)
+
+// Keyring holds a set of AES-GCM keys identified by a numeric ID. New data is always
+// encrypted with the active key, which is the one with the highest ID, while the older
+// keys are kept to decrypt the data written before the rotation.
+type Keyring struct {
+	keys     map[uint16]cipher.AEAD
+	activeID uint16
+}
+
+// New creates a keyring from the given set of keys. Each key must be 16, 24 or 32
+// bytes long to select AES-128, AES-192 or AES-256. Key ID 0 is reserved for the
+// unencrypted data and cannot be used.
+func New(keys map[uint16][]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, ErrEmptyKeyring
+	}
+
+	kr := &Keyring{
+		keys: make(map[uint16]cipher.AEAD, len(keys)),
+	}
+
+	for id, key := range keys {
+		if id == 0 {
+			return nil, fmt.Errorf("key id 0 is reserved")
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %d: %w", id, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %d: %w", id, err)
+		}
+
+		kr.keys[id] = aead
+
+		if id > kr.activeID {
+			kr.activeID = id
+		}
+	}
+
+	return kr, nil
+}
+
+// Load reads the keyring from a key file. Each non-empty line of the file defines one
+// key in the form of "<id>:<hex-encoded key>". Lines starting with # are ignored.
+// To rotate the key, append a new one with a higher ID and restart the server. The files
+// are not re-encrypted by the storage, so the retired key must be kept until the data is
+// copied with the active key by cmd/kvrekey.
+func Load(path string) (*Keyring, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open key file: %w", err)
+	}
+	defer f.Close()
+
+	return parse(f)
+}
+
+func parse(r io.Reader) (*Keyring, error) {
+	keys := make(map[uint16][]byte)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idStr, keyStr, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected <id>:<key>", lineNum)
+		}
+
+		id, err := strconv.ParseUint(strings.TrimSpace(idStr), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid key id: %w", lineNum, err)
+		}
+
+		key, err := hex.DecodeString(strings.TrimSpace(keyStr))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid key: %w", lineNum, err)
+		}
+
+		if _, ok := keys[uint16(id)]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key id %d", lineNum, id)
+		}
+
+		keys[uint16(id)] = key
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	return New(keys)
+}
+
+// ActiveKeyID returns the ID of the key used for encryption.
+func (kr *Keyring) ActiveKeyID() uint16 {
+	return kr.activeID
+}
+
+// Seal encrypts the data with the active key. The returned ciphertext is prefixed
+// with a random nonce and must be decrypted with the returned key ID.
+func (kr *Keyring) Seal(plaintext []byte) (uint16, []byte, error) {
+	aead := kr.keys[kr.activeID]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return kr.activeID, aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts the data previously encrypted with Seal using the key with the given ID.
+func (kr *Keyring) Open(keyID uint16, ciphertext []byte) ([]byte, error) {
+	aead, ok := kr.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKey, keyID)
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	nonce := ciphertext[:aead.NonceSize()]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return plaintext, nil
+}
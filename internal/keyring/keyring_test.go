@@ -0,0 +1,107 @@
+package keyring
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	kr, err := New(map[uint16][]byte{
+		1: bytes.Repeat([]byte{1}, 32),
+	})
+	require.NoError(t, err)
+
+	keyID, ciphertext, err := kr.Seal([]byte("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(1), keyID)
+	assert.NotContains(t, string(ciphertext), "secret")
+
+	plaintext, err := kr.Open(keyID, ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+}
+
+func TestRotation(t *testing.T) {
+	oldKr, err := New(map[uint16][]byte{
+		1: bytes.Repeat([]byte{1}, 32),
+	})
+	require.NoError(t, err)
+
+	oldKeyID, oldCiphertext, err := oldKr.Seal([]byte("old secret"))
+	require.NoError(t, err)
+
+	newKr, err := New(map[uint16][]byte{
+		1: bytes.Repeat([]byte{1}, 32),
+		2: bytes.Repeat([]byte{2}, 32),
+	})
+	require.NoError(t, err)
+
+	// New data is encrypted with the new key.
+	keyID, _, err := newKr.Seal([]byte("new secret"))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(2), keyID)
+
+	// Old data is still readable.
+	plaintext, err := newKr.Open(oldKeyID, oldCiphertext)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("old secret"), plaintext)
+}
+
+func TestOpen_UnknownKey(t *testing.T) {
+	kr, err := New(map[uint16][]byte{
+		1: bytes.Repeat([]byte{1}, 16),
+	})
+	require.NoError(t, err)
+
+	_, err = kr.Open(2, []byte("whatever"))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestOpen_Tampered(t *testing.T) {
+	kr, err := New(map[uint16][]byte{
+		1: bytes.Repeat([]byte{1}, 16),
+	})
+	require.NoError(t, err)
+
+	keyID, ciphertext, err := kr.Seal([]byte("secret"))
+	require.NoError(t, err)
+
+	ciphertext[len(ciphertext)-1] ^= 0xFF
+
+	_, err = kr.Open(keyID, ciphertext)
+	assert.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	kr, err := parse(strings.NewReader(`
+# old key
+1:0101010101010101010101010101010101010101010101010101010101010101
+2:0202020202020202020202020202020202020202020202020202020202020202
+`))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(2), kr.ActiveKeyID())
+	assert.Len(t, kr.keys, 2)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"Empty":        "# nothing here",
+		"NoSeparator":  "0101",
+		"InvalidID":    "x:0101010101010101010101010101010101",
+		"ReservedID":   "0:01010101010101010101010101010101",
+		"InvalidHex":   "1:zz",
+		"InvalidSize":  "1:0101",
+		"DuplicatedID": "1:01010101010101010101010101010101\n1:01010101010101010101010101010101",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parse(strings.NewReader(input))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -2,32 +2,56 @@ package lockmap
 
 import "sync"
 
+type keyLock struct {
+	mut  sync.Mutex
+	refs int
+}
+
+// Map is a set of mutexes identified by keys. The mutex of a key is allocated on
+// the first Lock and released once there are no goroutines holding or waiting for it.
 type Map[K comparable] struct {
 	mut   sync.Mutex
-	locks map[K]*sync.Mutex
+	locks map[K]*keyLock
 }
 
 func New[K comparable]() *Map[K] {
 	return &Map[K]{
-		locks: make(map[K]*sync.Mutex),
+		locks: make(map[K]*keyLock),
 	}
 }
 
 func (lm *Map[K]) Lock(key K) {
 	lm.mut.Lock()
-	defer lm.mut.Unlock()
 
-	if _, ok := lm.locks[key]; !ok {
-		lm.locks[key] = &sync.Mutex{}
+	lock, ok := lm.locks[key]
+	if !ok {
+		lock = &keyLock{}
+		lm.locks[key] = lock
 	}
 
-	lm.locks[key].Lock()
+	lock.refs++
+
+	// The map must not be locked while waiting for the key, otherwise
+	// the goroutine holding the key would not be able to release it.
+	lm.mut.Unlock()
+
+	lock.mut.Lock()
 }
 
-func (m *Map[K]) Unlock(key K) {
-	m.mut.Lock()
-	defer m.mut.Unlock()
+func (lm *Map[K]) Unlock(key K) {
+	lm.mut.Lock()
+	defer lm.mut.Unlock()
+
+	lock, ok := lm.locks[key]
+	if !ok {
+		panic("lockmap: unlock of unlocked key")
+	}
+
+	lock.refs--
+
+	if lock.refs == 0 {
+		delete(lm.locks, key)
+	}
 
-	m.locks[key].Unlock()
-	delete(m.locks, key)
+	lock.mut.Unlock()
 }
@@ -0,0 +1,42 @@
+package lockmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap_MutualExclusion(t *testing.T) {
+	lm := New[string]()
+	wg := sync.WaitGroup{}
+	counters := map[string]*int{"a": new(int), "b": new(int)}
+
+	for i := 0; i < 10; i++ {
+		for _, key := range []string{"a", "b"} {
+			wg.Add(1)
+
+			go func(key string) {
+				defer wg.Done()
+
+				for j := 0; j < 100; j++ {
+					lm.Lock(key)
+					*counters[key]++
+					lm.Unlock(key)
+				}
+			}(key)
+		}
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 1000, *counters["a"])
+	assert.Equal(t, 1000, *counters["b"])
+	assert.Empty(t, lm.locks)
+}
+
+func TestMap_UnlockUnlocked(t *testing.T) {
+	assert.Panics(t, func() {
+		New[string]().Unlock("key")
+	})
+}
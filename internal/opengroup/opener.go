@@ -4,21 +4,25 @@ import (
 	"os"
 
 	"github.com/maxpoletaev/kv/internal/multierror"
+	"github.com/maxpoletaev/kv/storage/vfs"
 )
 
 type Opener struct {
-	files  map[string]*os.File
-	errors multierror.Error[string]
+	fs     vfs.FS
+	files  map[string]vfs.File
+	errors *multierror.Error[string]
 }
 
-func New() *Opener {
+func New(fs vfs.FS) *Opener {
 	return &Opener{
-		files: make(map[string]*os.File),
+		fs:     fs,
+		files:  make(map[string]vfs.File),
+		errors: multierror.New[string](),
 	}
 }
 
-func (o *Opener) Open(name string, flag int, mode os.FileMode) *os.File {
-	f, err := os.OpenFile(name, flag, mode)
+func (o *Opener) Open(name string, flag int, mode os.FileMode) vfs.File {
+	f, err := o.fs.OpenFile(name, flag, mode)
 	if err != nil {
 		o.errors.Add(name, err)
 		return nil
@@ -44,6 +48,18 @@ func (o *Opener) Err() error {
 	return o.errors.Ret()
 }
 
+func (o *Opener) SyncAll() error {
+	errs := multierror.New[string]()
+
+	for name, f := range o.files {
+		if e := f.Sync(); e != nil {
+			errs.Add(name, e)
+		}
+	}
+
+	return errs.Ret()
+}
+
 func (o *Opener) CloseAll() error {
 	errs := multierror.New[string]()
 
@@ -60,7 +76,7 @@ func (o *Opener) RemoveAll() error {
 	errs := multierror.New[string]()
 
 	for name := range o.files {
-		if e := os.Remove(name); e != nil {
+		if e := o.fs.Remove(name); e != nil {
 			errs.Add(name, e)
 		}
 	}
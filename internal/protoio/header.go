@@ -15,9 +15,13 @@ var (
 	errInvalidHeaderSize = errors.New("invalid header size")
 )
 
+// entryHeader precedes every record in the file. The key ID is the ID of the key used
+// to encrypt the record, or zero if the record is not encrypted. Older files have the
+// last two bytes of the header zeroed, so they are read as unencrypted.
 type entryHeader struct {
 	separator uint16
 	dataSize  uint64
+	keyID     uint16
 }
 
 func encodeHeader(h *entryHeader, b []byte) error {
@@ -27,6 +31,7 @@ func encodeHeader(h *entryHeader, b []byte) error {
 
 	binary.LittleEndian.PutUint16(b[0:2], entrySeparator)
 	binary.LittleEndian.PutUint64(b[2:10], h.dataSize)
+	binary.LittleEndian.PutUint16(b[10:12], h.keyID)
 
 	return nil
 }
@@ -38,6 +43,7 @@ func decodeHeader(h *entryHeader, b []byte) error {
 
 	h.separator = binary.LittleEndian.Uint16(b[0:2])
 	h.dataSize = binary.LittleEndian.Uint64(b[2:10])
+	h.keyID = binary.LittleEndian.Uint16(b[10:12])
 
 	if h.separator != entrySeparator {
 		return errWrongSeparator
@@ -26,3 +26,11 @@ type SequentialWriter interface {
 	Append(entry proto.Message) (int, error)
 	Offset() int64
 }
+
+// Cipher is used to encrypt and decrypt the records. Each record is tagged with the
+// ID of the key it was encrypted with, so that the records written with different
+// keys can be read back after the key rotation. Key ID 0 means no encryption.
+type Cipher interface {
+	Seal(plaintext []byte) (keyID uint16, ciphertext []byte, err error)
+	Open(keyID uint16, ciphertext []byte) ([]byte, error)
+}
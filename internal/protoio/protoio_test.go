@@ -0,0 +1,80 @@
+package protoio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/maxpoletaev/kv/internal/keyring"
+)
+
+type buffer struct {
+	bytes.Buffer
+}
+
+func (b *buffer) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(b.Bytes()).ReadAt(p, off)
+}
+
+func readAll(t *testing.T, r *Reader) []string {
+	var values []string
+
+	for {
+		msg := &wrapperspb.StringValue{}
+
+		if _, err := r.ReadNext(msg); err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+
+		values = append(values, msg.Value)
+	}
+
+	return values
+}
+
+func TestWriteRead(t *testing.T) {
+	buf := &buffer{}
+	w := NewWriter(buf)
+
+	_, err := w.Append(wrapperspb.String("hello"))
+	require.NoError(t, err)
+	_, err = w.Append(wrapperspb.String("world"))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(buf.Len()), w.Offset())
+	assert.Equal(t, []string{"hello", "world"}, readAll(t, NewReader(buf)))
+}
+
+func TestWriteRead_Encrypted(t *testing.T) {
+	kr1, err := keyring.New(map[uint16][]byte{1: bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+
+	kr2, err := keyring.New(map[uint16][]byte{
+		1: bytes.Repeat([]byte{1}, 32),
+		2: bytes.Repeat([]byte{2}, 32),
+	})
+	require.NoError(t, err)
+
+	buf := &buffer{}
+
+	// Plain record written before the encryption was enabled.
+	_, err = NewWriter(buf).Append(wrapperspb.String("plain"))
+	require.NoError(t, err)
+
+	// Records written with the old and the new key.
+	_, err = NewEncryptedWriter(buf, kr1).Append(wrapperspb.String("secret1"))
+	require.NoError(t, err)
+	_, err = NewEncryptedWriter(buf, kr2).Append(wrapperspb.String("secret2"))
+	require.NoError(t, err)
+
+	assert.NotContains(t, buf.String(), "secret")
+	assert.Equal(t, []string{"plain", "secret1", "secret2"}, readAll(t, NewEncryptedReader(buf, kr2)))
+
+	_, err = NewReader(buf).ReadAt(&wrapperspb.StringValue{}, int64(headerSize+len("plain")+2))
+	assert.ErrorIs(t, err, ErrNoCipher)
+}
@@ -1,15 +1,20 @@
 package protoio
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
 	"google.golang.org/protobuf/proto"
 )
 
+// ErrNoCipher is returned when an encrypted record is read without a cipher.
+var ErrNoCipher = errors.New("record is encrypted, but no cipher is provided")
+
 type Reader struct {
 	headerBuf [headerSize]byte
 	file      io.ReaderAt
+	cipher    Cipher
 	offset    int64
 }
 
@@ -19,6 +24,16 @@ func NewReader(source io.ReaderAt) *Reader {
 	}
 }
 
+// NewEncryptedReader creates a reader that decrypts the records with the given cipher.
+// Unencrypted records are read as is, so it can be used on files written before the
+// encryption was enabled. If the cipher is nil, it is equivalent to NewReader.
+func NewEncryptedReader(source io.ReaderAt, cipher Cipher) *Reader {
+	return &Reader{
+		file:   source,
+		cipher: cipher,
+	}
+}
+
 func (r *Reader) readHeader(h *entryHeader) (int, error) {
 	buf := r.headerBuf[:]
 
@@ -52,6 +67,16 @@ func (r *Reader) readEntry(h entryHeader, entry proto.Message) (int, error) {
 		return 0, io.ErrUnexpectedEOF
 	}
 
+	if h.keyID != 0 {
+		if r.cipher == nil {
+			return 0, ErrNoCipher
+		}
+
+		if buf, err = r.cipher.Open(h.keyID, buf); err != nil {
+			return 0, err
+		}
+	}
+
 	if err := proto.Unmarshal(buf, entry); err != nil {
 		return 0, err
 	}
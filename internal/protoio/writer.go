@@ -10,6 +10,7 @@ import (
 
 type Writer struct {
 	file   io.Writer
+	cipher Cipher
 	offset int64
 }
 
@@ -25,15 +26,33 @@ func NewWriter(file io.Writer) *Writer {
 	}
 }
 
+// NewEncryptedWriter creates a writer that encrypts every record with the given cipher.
+// If the cipher is nil, it is equivalent to NewWriter.
+func NewEncryptedWriter(file io.Writer, cipher Cipher) *Writer {
+	w := NewWriter(file)
+	w.cipher = cipher
+
+	return w
+}
+
 func (w *Writer) writeEntry(entry proto.Message) (int, error) {
 	dataBuf, err := proto.Marshal(entry)
 	if err != nil {
 		return 0, fmt.Errorf("proto marshaling failed: %w", err)
 	}
 
+	var keyID uint16
+
+	if w.cipher != nil {
+		if keyID, dataBuf, err = w.cipher.Seal(dataBuf); err != nil {
+			return 0, fmt.Errorf("encryption failed: %w", err)
+		}
+	}
+
 	header := entryHeader{
 		dataSize:  uint64(len(dataBuf)),
 		separator: entrySeparator,
+		keyID:     keyID,
 	}
 
 	headerBuf := make([]byte, headerSize)
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the limiter counters.
+type Stats struct {
+	// Rate is the current rate limit in bytes per second.
+	Rate int64
+	// Bytes is the total number of bytes that passed through the limiter.
+	Bytes int64
+	// Throttled is the number of requests that had to wait for tokens.
+	Throttled int64
+	// ThrottledTime is the total time spent waiting for tokens.
+	ThrottledTime time.Duration
+}
+
+// Limiter is a token bucket rate limiter. The bucket is refilled at a constant
+// rate and holds at most one second worth of tokens. Requests larger than the
+// bucket are allowed to take the bucket into debt, so that the caller is delayed
+// proportionally to the size of the request instead of being blocked forever.
+// A nil limiter does not limit anything.
+type Limiter struct {
+	mut       sync.Mutex
+	rate      float64
+	tokens    float64
+	last      time.Time
+	now       func() time.Time
+	sleep     func(time.Duration)
+	bytes     int64
+	throttled int64
+	waitNanos int64
+}
+
+// New creates a new limiter allowing up to rate bytes per second.
+func New(rate int64) *Limiter {
+	l := &Limiter{
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+
+	l.last = l.now()
+	l.rate = float64(rate)
+	l.tokens = l.rate
+
+	return l
+}
+
+// SetRate changes the rate of the limiter. The tokens accumulated so far are
+// preserved, but capped by the new bucket size.
+func (l *Limiter) SetRate(rate int64) {
+	if l == nil {
+		return
+	}
+
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	l.refill()
+	l.rate = float64(rate)
+
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+}
+
+// Rate returns the current rate of the limiter in bytes per second.
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	return int64(l.rate)
+}
+
+func (l *Limiter) refill() {
+	now := l.now()
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+
+	l.tokens += elapsed * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+}
+
+// WaitN blocks until n bytes can be passed through the limiter.
+func (l *Limiter) WaitN(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+
+	atomic.AddInt64(&l.bytes, int64(n))
+
+	l.mut.Lock()
+
+	if l.rate <= 0 {
+		l.mut.Unlock()
+		return
+	}
+
+	l.refill()
+	l.tokens -= float64(n)
+
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+
+	l.mut.Unlock()
+
+	if wait > 0 {
+		atomic.AddInt64(&l.throttled, 1)
+		atomic.AddInt64(&l.waitNanos, int64(wait))
+		l.sleep(wait)
+	}
+}
+
+// Stats returns the current values of the limiter counters.
+func (l *Limiter) Stats() Stats {
+	if l == nil {
+		return Stats{}
+	}
+
+	return Stats{
+		Rate:          l.Rate(),
+		Bytes:         atomic.LoadInt64(&l.bytes),
+		Throttled:     atomic.LoadInt64(&l.throttled),
+		ThrottledTime: time.Duration(atomic.LoadInt64(&l.waitNanos)),
+	}
+}
+
+type writer struct {
+	w io.Writer
+	l *Limiter
+}
+
+// NewWriter wraps the writer so that every write is throttled by the limiter.
+func NewWriter(w io.Writer, l *Limiter) io.Writer {
+	if l == nil {
+		return w
+	}
+
+	return &writer{w: w, l: l}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.l.WaitN(len(p))
+
+	return w.w.Write(p)
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.slept += d
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(rate int64) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	l := New(rate)
+	l.now = clock.Now
+	l.sleep = clock.Sleep
+	l.last = clock.now
+
+	return l, clock
+}
+
+func TestLimiter_WithinBurst(t *testing.T) {
+	l, clock := newTestLimiter(1000)
+
+	l.WaitN(500)
+	l.WaitN(500)
+
+	assert.Equal(t, time.Duration(0), clock.slept)
+	assert.Equal(t, int64(0), l.Stats().Throttled)
+}
+
+func TestLimiter_Throttles(t *testing.T) {
+	l, clock := newTestLimiter(1000)
+
+	l.WaitN(1000)
+	l.WaitN(500)
+
+	assert.Equal(t, 500*time.Millisecond, clock.slept)
+
+	stats := l.Stats()
+	assert.Equal(t, int64(1500), stats.Bytes)
+	assert.Equal(t, int64(1), stats.Throttled)
+	assert.Equal(t, 500*time.Millisecond, stats.ThrottledTime)
+}
+
+func TestLimiter_LargerThanBurst(t *testing.T) {
+	l, clock := newTestLimiter(1000)
+
+	l.WaitN(3000)
+
+	assert.Equal(t, 2*time.Second, clock.slept)
+}
+
+func TestLimiter_SetRate(t *testing.T) {
+	l, clock := newTestLimiter(1000)
+
+	l.WaitN(1000)
+	l.SetRate(2000)
+	l.WaitN(1000)
+
+	assert.Equal(t, int64(2000), l.Rate())
+	assert.Equal(t, 500*time.Millisecond, clock.slept)
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	var l *Limiter
+
+	l.WaitN(1000)
+	l.SetRate(1000)
+
+	assert.Equal(t, int64(0), l.Rate())
+	assert.Equal(t, Stats{}, l.Stats())
+}
+
+func TestWriter(t *testing.T) {
+	l, clock := newTestLimiter(10)
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, l)
+
+	n, err := w.Write([]byte("hello world!"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 12, n)
+	assert.Equal(t, "hello world!", buf.String())
+	assert.Equal(t, 200*time.Millisecond, clock.slept)
+}
@@ -2,32 +2,100 @@ package vclock
 
 import (
 	"encoding/base64"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"sort"
 
-	"github.com/maxpoletaev/kv/internal/vclock/pb"
 	"google.golang.org/protobuf/proto"
+
+	"github.com/maxpoletaev/kv/internal/vclock/pb"
 )
 
-// Encode encodes the vector clock into a base64-encoded string.
-// The encoded string are always the same for the same vector clock,
-// so it can safely be used for comparasion.
-func Encode(v *Vector) (string, error) {
-	vc := &pb.VectorClock{
-		Clocks:    v.clocks,
-		Rollovers: v.rollovers,
+// BinaryFormat is the first byte of the binary encoding of a vector clock. It does not
+// clash with the first byte of the base64 text or of the protobuf message of the legacy
+// encoding, so that the encodings can be told apart when decoding.
+const BinaryFormat byte = 0x01
+
+// ErrInvalidEncoding is returned when the encoded vector clock is malformed.
+var ErrInvalidEncoding = errors.New("invalid vector clock encoding")
+
+// Marshal encodes the vector clock into the canonical binary form, which is the same for
+// the same vectors. The entries with zero clock values and no update time are omitted. The layout is:
+//
+//	format byte (BinaryFormat)
+//	number of entries (uvarint)
+//	node IDs in ascending order (uvarint each)
+//	clock values in the same order (uvarint each)
+//	rollover flags as a bitmap, one bit per entry, least significant bit first
+//	update times in unix seconds, zero if not known (varint each, only if any is known)
+func Marshal(v *Vector) []byte {
+	ids := make([]uint32, 0, len(v.clocks))
+	hasTime := false
+
+	for id, clock := range v.clocks {
+		if clock == 0 && !v.rollovers[id] && v.updated[id] == 0 {
+			continue
+		}
+
+		if v.updated[id] != 0 {
+			hasTime = true
+		}
+
+		ids = append(ids, id)
 	}
 
-	// TODO: Protobuf docs say that deterministic encoding is not guaranteed
-	//  to be stable across different versions of the library, so we should
-	//  probably use a different encoding.
-	opt := proto.MarshalOptions{Deterministic: true}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
 
-	b, err := opt.Marshal(vc)
-	if err != nil {
-		return "", fmt.Errorf("failed to encode to proto: %w", err)
+	b := make([]byte, 0, 2+len(ids)*4)
+	b = append(b, BinaryFormat)
+	b = appendUvarint(b, uint64(len(ids)))
+
+	for _, id := range ids {
+		b = appendUvarint(b, uint64(id))
 	}
 
-	return base64.StdEncoding.EncodeToString(b), nil
+	for _, id := range ids {
+		b = appendUvarint(b, uint64(v.clocks[id]))
+	}
+
+	bitmap := make([]byte, (len(ids)+7)/8)
+
+	for i, id := range ids {
+		if v.rollovers[id] {
+			bitmap[i/8] |= 1 << (i % 8)
+		}
+	}
+
+	b = append(b, bitmap...)
+
+	if hasTime {
+		for _, id := range ids {
+			b = appendVarint(b, v.updated[id])
+		}
+	}
+
+	return b
+}
+
+// Unmarshal decodes a vector clock encoded with Marshal. The vector clocks stored before
+// the binary encoding was introduced are the base64-encoded text of a protobuf message,
+// which is decoded as well, so the old records are migrated transparently when read.
+func Unmarshal(b []byte) (*Vector, error) {
+	if len(b) > 0 && b[0] == BinaryFormat {
+		return unmarshalBinary(b)
+	}
+
+	return Decode(string(b))
+}
+
+// Encode encodes the vector clock into a base64-encoded string of its binary form.
+// The encoded string are always the same for the same vector clock, so it can safely
+// be used for comparasion.
+func Encode(v *Vector) (string, error) {
+	return base64.StdEncoding.EncodeToString(Marshal(v)), nil
 }
 
 // MustEncode is like Encode, but panics on error.
@@ -46,13 +114,18 @@ func NewEncoded(v ...V) string {
 	return MustEncode(New(v...))
 }
 
-// Decode decodes a base64-encoded string into a vector clock.
+// Decode decodes a base64-encoded string into a vector clock. The strings produced by the
+// previous versions, which are base64-encoded protobuf messages, are also accepted.
 func Decode(s string) (*Vector, error) {
 	b, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode base64: %w", err)
 	}
 
+	if len(b) > 0 && b[0] == BinaryFormat {
+		return unmarshalBinary(b)
+	}
+
 	vc := &pb.VectorClock{}
 	if err := proto.Unmarshal(b, vc); err != nil {
 		return nil, fmt.Errorf("failed to decode proto: %w", err)
@@ -68,9 +141,15 @@ func Decode(s string) (*Vector, error) {
 		rollovers = make(map[uint32]bool)
 	}
 
+	updated := vc.Updated
+	if updated == nil {
+		updated = make(map[uint32]int64)
+	}
+
 	return &Vector{
 		clocks:    clocks,
 		rollovers: rollovers,
+		updated:   updated,
 	}, nil
 }
 
@@ -83,3 +162,123 @@ func MustDecode(s string) *Vector {
 
 	return v
 }
+
+func appendUvarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, v)
+
+	return append(b, buf[:n]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(buf, v)
+
+	return append(b, buf[:n]...)
+}
+
+// binaryReader reads the values of the binary encoding, and keeps the first error.
+type binaryReader struct {
+	b   []byte
+	err error
+}
+
+func (r *binaryReader) uvarint(max uint64) uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 || v > max {
+		r.err = ErrInvalidEncoding
+		return 0
+	}
+
+	r.b = r.b[n:]
+
+	return v
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.err = ErrInvalidEncoding
+		return 0
+	}
+
+	r.b = r.b[n:]
+
+	return v
+}
+
+func (r *binaryReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if len(r.b) < n {
+		r.err = ErrInvalidEncoding
+		return nil
+	}
+
+	b := r.b[:n]
+	r.b = r.b[n:]
+
+	return b
+}
+
+func unmarshalBinary(b []byte) (*Vector, error) {
+	r := &binaryReader{b: b[1:]}
+
+	// Each entry takes at least one byte, which limits the
+	// number of entries a malformed input can make us allocate.
+	n := int(r.uvarint(uint64(len(b))))
+	ids := make([]uint32, n)
+
+	for i := range ids {
+		ids[i] = uint32(r.uvarint(maxUint32))
+
+		// The IDs must be strictly ascending, for the encoding to be canonical.
+		if i > 0 && ids[i] <= ids[i-1] && r.err == nil {
+			r.err = ErrInvalidEncoding
+		}
+	}
+
+	v := New()
+
+	for _, id := range ids {
+		v.clocks[id] = uint32(r.uvarint(maxUint32))
+	}
+
+	bitmap := r.bytes((n + 7) / 8)
+
+	for i, id := range ids {
+		if r.err == nil && bitmap[i/8]&(1<<(i%8)) != 0 {
+			v.rollovers[id] = true
+		}
+	}
+
+	if r.err == nil && len(r.b) > 0 {
+		for _, id := range ids {
+			if ts := r.varint(); ts != 0 {
+				v.updated[id] = ts
+			}
+		}
+	}
+
+	if r.err == nil && len(r.b) > 0 {
+		r.err = ErrInvalidEncoding
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return v, nil
+}
+
+const maxUint32 = 1<<32 - 1
@@ -1,9 +1,15 @@
 package vclock
 
 import (
+	"encoding/base64"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/maxpoletaev/kv/internal/vclock/pb"
 )
 
 func TestEncodeDecode(t *testing.T) {
@@ -51,3 +57,98 @@ func TestEncodeConsistency(t *testing.T) {
 		}
 	}
 }
+
+func TestMarshal(t *testing.T) {
+	v := New(V{300: 2, 1: 1, 2: 0})
+	v.rollovers[300] = true
+
+	// Entries are sorted by ID, the zero entry is omitted, and the
+	// rollover of the second entry is set in the bitmap.
+	want := []byte{BinaryFormat, 2, 1, 0xac, 0x02, 1, 2, 0b10}
+	assert.Equal(t, want, Marshal(v))
+
+	// The update times are appended only when known.
+	v.updated[1] = 1
+	want = append(want, 2, 0)
+	assert.Equal(t, want, Marshal(v))
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	tests := map[string]func() *Vector{
+		"Empty": func() *Vector {
+			return New()
+		},
+		"Rollovers": func() *Vector {
+			v := New(V{1: 1, 2: 2, 3: 3, 4: 4, 5: 5, 6: 6, 7: 7, 8: 8, 9: 9})
+			v.rollovers[2] = true
+			v.rollovers[9] = true
+			return v
+		},
+		"UpdateTimes": func() *Vector {
+			v := New(V{1: 1, 2: 1})
+			v.UpdateAt(2, time.Unix(1700000000, 0))
+			v.UpdateAt(3, time.Unix(-100, 0))
+			return v
+		},
+		"MaxValues": func() *Vector {
+			return New(V{1<<32 - 1: 1<<32 - 1})
+		},
+	}
+
+	for name, makeVector := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := makeVector()
+
+			got, err := Unmarshal(Marshal(v))
+			require.NoError(t, err)
+			assert.Equal(t, v, got, "want: %s, got: %s", v, got)
+		})
+	}
+}
+
+func TestUnmarshal_Legacy(t *testing.T) {
+	b, err := proto.Marshal(&pb.VectorClock{
+		Clocks:    map[uint32]uint32{1: 10, 2: 5},
+		Rollovers: map[uint32]bool{2: true},
+		Updated:   map[uint32]int64{1: 1700000000},
+	})
+	require.NoError(t, err)
+
+	want := New(V{1: 10, 2: 5})
+	want.rollovers[2] = true
+	want.updated[1] = 1700000000
+
+	// The values stored before the binary encoding hold the base64 text.
+	s := base64.StdEncoding.EncodeToString(b)
+
+	got, err := Decode(s)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got, err = Unmarshal([]byte(s))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// Once re-encoded, the version is in the binary format.
+	assert.Equal(t, BinaryFormat, Marshal(got)[0])
+}
+
+func TestUnmarshal_Invalid(t *testing.T) {
+	tests := map[string][]byte{
+		"Truncated":       {BinaryFormat, 2, 1, 2, 1},
+		"MissingBitmap":   {BinaryFormat, 1, 1, 1},
+		"UnsortedIDs":     {BinaryFormat, 2, 2, 1, 1, 1, 0},
+		"DuplicateIDs":    {BinaryFormat, 2, 1, 1, 1, 1, 0},
+		"TooManyEntries":  {BinaryFormat, 0xff, 0x01},
+		"ClockOverflow":   {BinaryFormat, 1, 1, 0x80, 0x80, 0x80, 0x80, 0x10, 0},
+		"TrailingBytes":   {BinaryFormat, 1, 1, 1, 0, 2, 0},
+		"NotBase64Legacy": []byte("not base64!"),
+	}
+
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Unmarshal(b)
+			assert.Error(t, err)
+		})
+	}
+}
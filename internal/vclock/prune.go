@@ -0,0 +1,91 @@
+package vclock
+
+import (
+	"sort"
+	"time"
+)
+
+// PruneConfig defines when the entries of a vector clock are pruned. The rules follow
+// the ones used by Riak: the vector is never pruned while it is small, the entries that
+// have been updated recently are always kept, and the oldest entries are dropped as long
+// as the vector is too big or the entries are too old.
+type PruneConfig struct {
+	// MinEntries is the number of entries the vector may have before it is pruned.
+	MinEntries int
+	// MaxEntries is the number of entries above which the oldest entries are dropped,
+	// unless they are younger than MinAge. Zero means no limit.
+	MaxEntries int
+	// MinAge is the age of the entries that are never pruned.
+	MinAge time.Duration
+	// MaxAge is the age above which the entries are dropped, as long as the vector
+	// has more than MinEntries entries. Zero means no limit.
+	MaxAge time.Duration
+}
+
+// DefaultPruneConfig returns the default pruning configuration.
+func DefaultPruneConfig() PruneConfig {
+	return PruneConfig{
+		MinEntries: 10,
+		MaxEntries: 50,
+		MinAge:     20 * time.Second,
+		MaxAge:     24 * time.Hour,
+	}
+}
+
+// Prune drops the oldest entries of the vector according to the configuration, and returns
+// the number of dropped entries. The entries without the update time are considered older
+// than any other entry.
+//
+// Pruning loses the causal history, so the vector may no longer be recognized as a descendant
+// of the versions it has overtaken. A write with such a version becomes a sibling of the
+// value it was meant to replace, which the client then has to resolve. As long as the entry
+// of the node that has just updated the vector is younger than MinAge, a descendant can not
+// be mistaken for an obsolete version. However, a concurrent write may be rejected as obsolete
+// if the only entries that made it concurrent are pruned, which is why the pruned entries
+// must be old enough for no concurrent writes to be expected.
+func (v *Vector) Prune(conf PruneConfig, now time.Time) (pruned int) {
+	if len(v.clocks) <= conf.MinEntries {
+		return 0
+	}
+
+	ids := make([]uint32, 0, len(v.clocks))
+	for id := range v.clocks {
+		ids = append(ids, id)
+	}
+
+	// Oldest entries go first. The IDs are used to break ties, to make sure
+	// that all nodes prune the same vector in the same way.
+	sort.Slice(ids, func(i, j int) bool {
+		ti, tj := v.updated[ids[i]], v.updated[ids[j]]
+		if ti != tj {
+			return ti < tj
+		}
+
+		return ids[i] < ids[j]
+	})
+
+	for _, id := range ids {
+		if len(v.clocks) <= conf.MinEntries {
+			break
+		}
+
+		age := now.Sub(time.Unix(v.updated[id], 0))
+		if age < conf.MinAge {
+			break
+		}
+
+		tooBig := conf.MaxEntries > 0 && len(v.clocks) > conf.MaxEntries
+		tooOld := conf.MaxAge > 0 && age > conf.MaxAge
+
+		if !tooBig && !tooOld {
+			break
+		}
+
+		delete(v.clocks, id)
+		delete(v.rollovers, id)
+		delete(v.updated, id)
+		pruned++
+	}
+
+	return pruned
+}
@@ -0,0 +1,239 @@
+package vclock
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vectorAt returns a vector with the given clock values, each updated
+// the given number of seconds before now.
+func vectorAt(now time.Time, clocks V, ages map[uint32]int) *Vector {
+	v := New(clocks)
+
+	for id, age := range ages {
+		v.updated[id] = now.Add(-time.Duration(age) * time.Second).Unix()
+	}
+
+	return v
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+
+	conf := PruneConfig{
+		MinEntries: 2,
+		MaxEntries: 3,
+		MinAge:     20 * time.Second,
+		MaxAge:     time.Hour,
+	}
+
+	tests := map[string]struct {
+		vector     *Vector
+		wantClocks V
+		wantPruned int
+	}{
+		"SmallVectorIsNotPruned": {
+			vector:     vectorAt(now, V{1: 1, 2: 1}, map[uint32]int{1: 7200, 2: 7200}),
+			wantClocks: V{1: 1, 2: 1},
+		},
+		"BigVectorLosesOldestEntries": {
+			vector:     vectorAt(now, V{1: 1, 2: 1, 3: 1, 4: 1, 5: 1}, map[uint32]int{1: 50, 2: 40, 3: 30, 4: 60, 5: 0}),
+			wantClocks: V{2: 1, 3: 1, 5: 1},
+			wantPruned: 2,
+		},
+		"YoungEntriesAreKept": {
+			vector:     vectorAt(now, V{1: 1, 2: 1, 3: 1, 4: 1, 5: 1}, map[uint32]int{1: 50, 2: 10, 3: 10, 4: 10, 5: 0}),
+			wantClocks: V{2: 1, 3: 1, 4: 1, 5: 1},
+			wantPruned: 1,
+		},
+		"OldEntriesArePruned": {
+			vector:     vectorAt(now, V{1: 1, 2: 1, 3: 1}, map[uint32]int{1: 7200, 2: 3000, 3: 0}),
+			wantClocks: V{2: 1, 3: 1},
+			wantPruned: 1,
+		},
+		"OldEntriesAreKeptInSmallVector": {
+			vector:     vectorAt(now, V{1: 1, 2: 1, 3: 1}, map[uint32]int{1: 7200, 2: 7200, 3: 0}),
+			wantClocks: V{2: 1, 3: 1},
+			wantPruned: 1,
+		},
+		"EntriesWithoutTimeAreOldest": {
+			vector:     vectorAt(now, V{1: 1, 2: 1, 3: 1, 4: 1}, map[uint32]int{1: 100, 2: 100, 4: 100}),
+			wantClocks: V{1: 1, 2: 1, 4: 1},
+			wantPruned: 1,
+		},
+		"TiesAreBrokenByID": {
+			vector:     vectorAt(now, V{1: 1, 2: 1, 3: 1, 4: 1}, map[uint32]int{1: 100, 2: 100, 3: 100, 4: 100}),
+			wantClocks: V{2: 1, 3: 1, 4: 1},
+			wantPruned: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pruned := tt.vector.Prune(conf, now)
+			assert.Equal(t, tt.wantPruned, pruned)
+			assert.Equal(t, tt.wantClocks, tt.vector.clocks)
+
+			for id := range tt.vector.updated {
+				assert.Contains(t, tt.vector.clocks, id)
+			}
+		})
+	}
+}
+
+func TestPrune_EncodeDecode(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+
+	v := New()
+	v.UpdateAt(1, now.Add(-time.Hour))
+	v.UpdateAt(2, now)
+
+	got := MustDecode(MustEncode(v))
+	assert.Equal(t, v, got)
+	assert.Equal(t, now.Add(-time.Hour), got.UpdatedAt(1))
+	assert.Equal(t, time.Time{}, got.UpdatedAt(3))
+
+	got.Prune(PruneConfig{MaxAge: time.Minute}, now)
+	assert.Equal(t, "{2=1}", got.String())
+}
+
+func TestMerge_UpdateTime(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+
+	a := New()
+	a.UpdateAt(1, now.Add(-time.Hour))
+	a.UpdateAt(2, now)
+
+	b := New()
+	b.UpdateAt(1, now)
+	b.Update(3)
+
+	merged := Merge(a, b)
+	assert.Equal(t, now, merged.UpdatedAt(1))
+	assert.Equal(t, now, merged.UpdatedAt(2))
+	assert.Equal(t, time.Time{}, merged.UpdatedAt(3))
+}
+
+// The following tests show how pruning affects the causality. A coordinator increments its
+// own entry in the version it has received from the client, prunes it, and the result is
+// compared with the versions already stored. Only the comparisons that involve the pruned
+// version are affected, since the stored versions are not pruned retroactively.
+
+func TestPrune_DescendantMayBecomeConcurrent(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	conf := PruneConfig{MaxEntries: 2, MinAge: 20 * time.Second}
+
+	// The stored value has been written by node 1 long ago, and then by node 2.
+	stored := vectorAt(now, V{1: 1, 2: 1}, map[uint32]int{1: 3600, 2: 60})
+
+	// The client reads it and writes a new value through node 3.
+	incoming := stored.Clone()
+	incoming.UpdateAt(3, now)
+	require.Equal(t, After, Compare(incoming, stored))
+
+	// The entry of node 1 is pruned, so the new value no longer overtakes the
+	// stored one, and both are kept as siblings. This is a false conflict.
+	require.Equal(t, 1, incoming.Prune(conf, now))
+	require.Equal(t, Concurrent, Compare(incoming, stored))
+}
+
+func TestPrune_FreshEntryIsNeverPruned(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+
+	// Without the minimum age, the entry that has just been incremented may be pruned
+	// if it is the oldest one by the ID. This would make the new value look obsolete.
+	// The stored value has been written by node 2 in the same second as the new one.
+	stored := vectorAt(now, V{1: 1, 2: 1}, map[uint32]int{1: 0, 2: 0})
+
+	incoming := stored.Clone()
+	incoming.UpdateAt(1, now)
+	require.Equal(t, After, Compare(incoming, stored))
+
+	incoming.Prune(PruneConfig{MaxEntries: 1}, now)
+	require.Equal(t, Before, Compare(incoming, stored))
+
+	// With the minimum age set, both fresh entries are kept.
+	incoming = stored.Clone()
+	incoming.UpdateAt(1, now)
+
+	require.Equal(t, 0, incoming.Prune(PruneConfig{MaxEntries: 1, MinAge: time.Second}, now))
+	require.Equal(t, After, Compare(incoming, stored))
+}
+
+func TestPrune_ConcurrentMayBecomeObsolete(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	conf := PruneConfig{MaxAge: time.Hour, MinAge: 20 * time.Second}
+
+	// The stored value has been written through node 1 several times.
+	stored := vectorAt(now, V{1: 5}, map[uint32]int{1: 60})
+
+	// A client that has only seen an old value written by node 2 writes
+	// through node 1, concurrently with all the stored writes.
+	incoming := vectorAt(now, V{2: 1}, map[uint32]int{2: 7200})
+	incoming.UpdateAt(1, now)
+	require.Equal(t, Concurrent, Compare(incoming, stored))
+
+	// The entry of node 2 was the only one that made the write concurrent.
+	// Once it is pruned, the write is rejected as obsolete.
+	require.Equal(t, 1, incoming.Prune(conf, now))
+	require.Equal(t, Before, Compare(incoming, stored))
+}
+
+func TestPrune_CausalityProperties(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	rnd := rand.New(rand.NewSource(1))
+
+	conf := PruneConfig{
+		MinEntries: 1,
+		MaxEntries: 3,
+		MinAge:     20 * time.Second,
+		MaxAge:     time.Hour,
+	}
+
+	randomVector := func() *Vector {
+		v := New()
+
+		for i := 0; i < rnd.Intn(8); i++ {
+			id := uint32(rnd.Intn(8))
+			v.UpdateAt(id, now.Add(-time.Duration(rnd.Intn(7200))*time.Second))
+		}
+
+		return v
+	}
+
+	for i := 0; i < 10000; i++ {
+		stored := randomVector()
+		received := randomVector()
+		coordinator := uint32(rnd.Intn(8))
+
+		// The client has read the stored value, so the version it
+		// sends along with the write descends from the stored one.
+		readFirst := rnd.Intn(2) == 0
+		if readFirst {
+			received = Merge(received, stored)
+		}
+
+		incoming := received.Clone()
+		incoming.UpdateAt(coordinator, now)
+		before := Compare(incoming, stored)
+
+		incoming.Prune(conf, now)
+		after := Compare(incoming, stored)
+
+		if readFirst {
+			// A descendant may become concurrent, but never obsolete, since
+			// the entry of the coordinator is too young to be pruned.
+			require.Equal(t, After, before)
+			require.Contains(t, []Causality{After, Concurrent}, after, "stored=%s incoming=%s", stored, incoming)
+		}
+
+		if before == Before {
+			// An obsolete version stays obsolete, as pruning only makes it smaller.
+			require.Equal(t, Before, after, "stored=%s incoming=%s", stored, incoming)
+		}
+	}
+}
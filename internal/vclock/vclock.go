@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/maxpoletaev/kv/internal/generic"
 )
@@ -45,9 +46,12 @@ type V map[uint32]uint32
 // value of 2^32-1. The implementation keeps track of such rollover events, so that
 // the causality relationship between two events can be determined even if the clock
 // value has rolled over.
+// Each entry also keeps the time of its last update, which is used to prune the entries
+// of the nodes that no longer coordinate writes (see Prune).
 type Vector struct {
 	clocks    V
 	rollovers map[uint32]bool
+	updated   map[uint32]int64
 }
 
 // New returns a new vector clock. If the given values are not empty, the new vector
@@ -68,6 +72,7 @@ func New(values ...V) *Vector {
 	return &Vector{
 		clocks:    clocks,
 		rollovers: make(map[uint32]bool, len(clocks)),
+		updated:   make(map[uint32]int64),
 	}
 }
 
@@ -76,6 +81,17 @@ func (v *Vector) Get(id uint32) uint32 {
 	return v.clocks[id]
 }
 
+// UpdatedAt returns the time of the last update of the clock value for the given node ID,
+// as recorded by UpdateAt. It returns the zero time if the time is not known.
+func (v *Vector) UpdatedAt(id uint32) time.Time {
+	ts, ok := v.updated[id]
+	if !ok {
+		return time.Time{}
+	}
+
+	return time.Unix(ts, 0)
+}
+
 // Rollover inverts the rollover flag for the given node ID.
 func (v *Vector) Rollover(id uint32) {
 	v.rollovers[id] = !v.rollovers[id]
@@ -93,6 +109,13 @@ func (vc *Vector) Update(id uint32) {
 	}
 }
 
+// UpdateAt increments the clock value for the given node ID, same as Update, and records
+// the time of the update with a precision of one second.
+func (vc *Vector) UpdateAt(id uint32, t time.Time) {
+	vc.Update(id)
+	vc.updated[id] = t.Unix()
+}
+
 // Clone returns a copy of the vector clock. The copy is a deep copy,
 // so that the original vector clock can be modified without affecting
 // the copy.
@@ -100,10 +123,12 @@ func (v *Vector) Clone() *Vector {
 	newvec := &Vector{
 		clocks:    make(map[uint32]uint32, len(v.clocks)),
 		rollovers: make(map[uint32]bool, len(v.rollovers)),
+		updated:   make(map[uint32]int64, len(v.updated)),
 	}
 
 	generic.MapCopy(v.clocks, newvec.clocks)
 	generic.MapCopy(v.rollovers, newvec.rollovers)
+	generic.MapCopy(v.updated, newvec.updated)
 
 	return newvec
 }
@@ -195,16 +220,26 @@ func IsEqual(a, b *Vector) bool {
 
 // Merge returns a new vector that is the result of merging two vectors.
 // The merge operation is commutative and associative, so that
-// Merge(a, Merge(b, c)) == Merge(Merge(a, b), c).
+// Merge(a, Merge(b, c)) == Merge(Merge(a, b), c). The update time of
+// each entry is the latest of the two.
 func Merge(a, b *Vector) *Vector {
 	keys := generic.MapKeys(a.clocks, b.clocks)
 
 	clock := &Vector{
 		clocks:    make(map[uint32]uint32, len(keys)),
 		rollovers: make(map[uint32]bool, len(keys)),
+		updated:   make(map[uint32]int64),
 	}
 
 	for _, key := range keys {
+		if ts, ok := a.updated[key]; ok {
+			clock.updated[key] = ts
+		}
+
+		if ts, ok := b.updated[key]; ok && ts > clock.updated[key] {
+			clock.updated[key] = ts
+		}
+
 		// TODO: this is a bit ugly, but it works.
 		if a.rollovers[key] == b.rollovers[key] {
 			if a.clocks[key] > b.clocks[key] {
@@ -67,6 +67,103 @@ func (Status) EnumDescriptor() ([]byte, []int) {
 	return file_membership_proto_membership_proto_rawDescGZIP(), []int{0}
 }
 
+// MemberStats are the statistics of the calls made to a member by the node
+// that responds to the Members call. The latencies are in microseconds.
+type MemberStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Calls     int64   `protobuf:"varint,1,opt,name=calls,proto3" json:"calls,omitempty"`
+	Errors    int64   `protobuf:"varint,2,opt,name=errors,proto3" json:"errors,omitempty"`
+	ErrorRate float64 `protobuf:"fixed64,3,opt,name=error_rate,json=errorRate,proto3" json:"error_rate,omitempty"`
+	LatencyUs int64   `protobuf:"varint,4,opt,name=latency_us,json=latencyUs,proto3" json:"latency_us,omitempty"`
+	P50Us     int64   `protobuf:"varint,5,opt,name=p50_us,json=p50Us,proto3" json:"p50_us,omitempty"`
+	P95Us     int64   `protobuf:"varint,6,opt,name=p95_us,json=p95Us,proto3" json:"p95_us,omitempty"`
+	P99Us     int64   `protobuf:"varint,7,opt,name=p99_us,json=p99Us,proto3" json:"p99_us,omitempty"`
+}
+
+func (x *MemberStats) Reset() {
+	*x = MemberStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_membership_proto_membership_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MemberStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MemberStats) ProtoMessage() {}
+
+func (x *MemberStats) ProtoReflect() protoreflect.Message {
+	mi := &file_membership_proto_membership_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MemberStats.ProtoReflect.Descriptor instead.
+func (*MemberStats) Descriptor() ([]byte, []int) {
+	return file_membership_proto_membership_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MemberStats) GetCalls() int64 {
+	if x != nil {
+		return x.Calls
+	}
+	return 0
+}
+
+func (x *MemberStats) GetErrors() int64 {
+	if x != nil {
+		return x.Errors
+	}
+	return 0
+}
+
+func (x *MemberStats) GetErrorRate() float64 {
+	if x != nil {
+		return x.ErrorRate
+	}
+	return 0
+}
+
+func (x *MemberStats) GetLatencyUs() int64 {
+	if x != nil {
+		return x.LatencyUs
+	}
+	return 0
+}
+
+func (x *MemberStats) GetP50Us() int64 {
+	if x != nil {
+		return x.P50Us
+	}
+	return 0
+}
+
+func (x *MemberStats) GetP95Us() int64 {
+	if x != nil {
+		return x.P95Us
+	}
+	return 0
+}
+
+func (x *MemberStats) GetP99Us() int64 {
+	if x != nil {
+		return x.P99Us
+	}
+	return 0
+}
+
 type Member struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -78,12 +175,14 @@ type Member struct {
 	GossipAddr string `protobuf:"bytes,4,opt,name=gossip_addr,json=gossipAddr,proto3" json:"gossip_addr,omitempty"`
 	Status     Status `protobuf:"varint,5,opt,name=status,proto3,enum=membership.Status" json:"status,omitempty"`
 	Version    uint64 `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
+	// Only set in the Members response, if any calls have been made to the member.
+	Stats *MemberStats `protobuf:"bytes,7,opt,name=stats,proto3" json:"stats,omitempty"`
 }
 
 func (x *Member) Reset() {
 	*x = Member{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_membership_proto_membership_proto_msgTypes[0]
+		mi := &file_membership_proto_membership_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -96,7 +195,7 @@ func (x *Member) String() string {
 func (*Member) ProtoMessage() {}
 
 func (x *Member) ProtoReflect() protoreflect.Message {
-	mi := &file_membership_proto_membership_proto_msgTypes[0]
+	mi := &file_membership_proto_membership_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -109,7 +208,7 @@ func (x *Member) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Member.ProtoReflect.Descriptor instead.
 func (*Member) Descriptor() ([]byte, []int) {
-	return file_membership_proto_membership_proto_rawDescGZIP(), []int{0}
+	return file_membership_proto_membership_proto_rawDescGZIP(), []int{1}
 }
 
 func (x *Member) GetId() uint32 {
@@ -154,6 +253,13 @@ func (x *Member) GetVersion() uint64 {
 	return 0
 }
 
+func (x *Member) GetStats() *MemberStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
 type JoinRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -165,7 +271,7 @@ type JoinRequest struct {
 func (x *JoinRequest) Reset() {
 	*x = JoinRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_membership_proto_membership_proto_msgTypes[1]
+		mi := &file_membership_proto_membership_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -178,7 +284,7 @@ func (x *JoinRequest) String() string {
 func (*JoinRequest) ProtoMessage() {}
 
 func (x *JoinRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_membership_proto_membership_proto_msgTypes[1]
+	mi := &file_membership_proto_membership_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -191,7 +297,7 @@ func (x *JoinRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use JoinRequest.ProtoReflect.Descriptor instead.
 func (*JoinRequest) Descriptor() ([]byte, []int) {
-	return file_membership_proto_membership_proto_rawDescGZIP(), []int{1}
+	return file_membership_proto_membership_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *JoinRequest) GetMembersToAdd() []*Member {
@@ -212,7 +318,7 @@ type JoinResponse struct {
 func (x *JoinResponse) Reset() {
 	*x = JoinResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_membership_proto_membership_proto_msgTypes[2]
+		mi := &file_membership_proto_membership_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -225,7 +331,7 @@ func (x *JoinResponse) String() string {
 func (*JoinResponse) ProtoMessage() {}
 
 func (x *JoinResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_membership_proto_membership_proto_msgTypes[2]
+	mi := &file_membership_proto_membership_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -238,7 +344,7 @@ func (x *JoinResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use JoinResponse.ProtoReflect.Descriptor instead.
 func (*JoinResponse) Descriptor() ([]byte, []int) {
-	return file_membership_proto_membership_proto_rawDescGZIP(), []int{2}
+	return file_membership_proto_membership_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *JoinResponse) GetMembers() []*Member {
@@ -259,7 +365,7 @@ type MembersResponse struct {
 func (x *MembersResponse) Reset() {
 	*x = MembersResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_membership_proto_membership_proto_msgTypes[3]
+		mi := &file_membership_proto_membership_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -272,7 +378,7 @@ func (x *MembersResponse) String() string {
 func (*MembersResponse) ProtoMessage() {}
 
 func (x *MembersResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_membership_proto_membership_proto_msgTypes[3]
+	mi := &file_membership_proto_membership_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -285,7 +391,7 @@ func (x *MembersResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MembersResponse.ProtoReflect.Descriptor instead.
 func (*MembersResponse) Descriptor() ([]byte, []int) {
-	return file_membership_proto_membership_proto_rawDescGZIP(), []int{3}
+	return file_membership_proto_membership_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *MembersResponse) GetMembers() []*Member {
@@ -306,7 +412,7 @@ type ExpelRequest struct {
 func (x *ExpelRequest) Reset() {
 	*x = ExpelRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_membership_proto_membership_proto_msgTypes[4]
+		mi := &file_membership_proto_membership_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -319,7 +425,7 @@ func (x *ExpelRequest) String() string {
 func (*ExpelRequest) ProtoMessage() {}
 
 func (x *ExpelRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_membership_proto_membership_proto_msgTypes[4]
+	mi := &file_membership_proto_membership_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -332,7 +438,7 @@ func (x *ExpelRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExpelRequest.ProtoReflect.Descriptor instead.
 func (*ExpelRequest) Descriptor() ([]byte, []int) {
-	return file_membership_proto_membership_proto_rawDescGZIP(), []int{4}
+	return file_membership_proto_membership_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *ExpelRequest) GetMemberId() uint32 {
@@ -356,7 +462,7 @@ type MemberJoinedEvent struct {
 func (x *MemberJoinedEvent) Reset() {
 	*x = MemberJoinedEvent{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_membership_proto_membership_proto_msgTypes[5]
+		mi := &file_membership_proto_membership_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -369,7 +475,7 @@ func (x *MemberJoinedEvent) String() string {
 func (*MemberJoinedEvent) ProtoMessage() {}
 
 func (x *MemberJoinedEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_membership_proto_membership_proto_msgTypes[5]
+	mi := &file_membership_proto_membership_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -382,7 +488,7 @@ func (x *MemberJoinedEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MemberJoinedEvent.ProtoReflect.Descriptor instead.
 func (*MemberJoinedEvent) Descriptor() ([]byte, []int) {
-	return file_membership_proto_membership_proto_rawDescGZIP(), []int{5}
+	return file_membership_proto_membership_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *MemberJoinedEvent) GetMemberId() uint32 {
@@ -425,7 +531,7 @@ type MemberLeftEvent struct {
 func (x *MemberLeftEvent) Reset() {
 	*x = MemberLeftEvent{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_membership_proto_membership_proto_msgTypes[6]
+		mi := &file_membership_proto_membership_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -438,7 +544,7 @@ func (x *MemberLeftEvent) String() string {
 func (*MemberLeftEvent) ProtoMessage() {}
 
 func (x *MemberLeftEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_membership_proto_membership_proto_msgTypes[6]
+	mi := &file_membership_proto_membership_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -451,7 +557,7 @@ func (x *MemberLeftEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MemberLeftEvent.ProtoReflect.Descriptor instead.
 func (*MemberLeftEvent) Descriptor() ([]byte, []int) {
-	return file_membership_proto_membership_proto_rawDescGZIP(), []int{6}
+	return file_membership_proto_membership_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *MemberLeftEvent) GetMemberId() uint32 {
@@ -482,7 +588,7 @@ type MemberUpdatedEvent struct {
 func (x *MemberUpdatedEvent) Reset() {
 	*x = MemberUpdatedEvent{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_membership_proto_membership_proto_msgTypes[7]
+		mi := &file_membership_proto_membership_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -495,7 +601,7 @@ func (x *MemberUpdatedEvent) String() string {
 func (*MemberUpdatedEvent) ProtoMessage() {}
 
 func (x *MemberUpdatedEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_membership_proto_membership_proto_msgTypes[7]
+	mi := &file_membership_proto_membership_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -508,7 +614,7 @@ func (x *MemberUpdatedEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MemberUpdatedEvent.ProtoReflect.Descriptor instead.
 func (*MemberUpdatedEvent) Descriptor() ([]byte, []int) {
-	return file_membership_proto_membership_proto_rawDescGZIP(), []int{7}
+	return file_membership_proto_membership_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *MemberUpdatedEvent) GetMemberId() uint32 {
@@ -554,7 +660,7 @@ type ClusterEvent struct {
 func (x *ClusterEvent) Reset() {
 	*x = ClusterEvent{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_membership_proto_membership_proto_msgTypes[8]
+		mi := &file_membership_proto_membership_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -567,7 +673,7 @@ func (x *ClusterEvent) String() string {
 func (*ClusterEvent) ProtoMessage() {}
 
 func (x *ClusterEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_membership_proto_membership_proto_msgTypes[8]
+	mi := &file_membership_proto_membership_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -580,7 +686,7 @@ func (x *ClusterEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClusterEvent.ProtoReflect.Descriptor instead.
 func (*ClusterEvent) Descriptor() ([]byte, []int) {
-	return file_membership_proto_membership_proto_rawDescGZIP(), []int{8}
+	return file_membership_proto_membership_proto_rawDescGZIP(), []int{9}
 }
 
 func (m *ClusterEvent) GetEvent() isClusterEvent_Event {
@@ -640,19 +746,34 @@ var file_membership_proto_membership_proto_rawDesc = []byte{
 	0x74, 0x6f, 0x2f, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x68, 0x69, 0x70, 0x2e, 0x70, 0x72,
 	0x6f, 0x74, 0x6f, 0x12, 0x0a, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x68, 0x69, 0x70, 0x1a,
 	0x1b, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
-	0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xb4, 0x01, 0x0a,
-	0x06, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x73,
-	0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x41, 0x64, 0x64, 0x72, 0x12, 0x1f, 0x0a, 0x0b,
-	0x67, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x0a, 0x67, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x41, 0x64, 0x64, 0x72, 0x12, 0x2a, 0x0a,
-	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x12, 0x2e,
-	0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x68, 0x69, 0x70, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72,
-	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73,
-	0x69, 0x6f, 0x6e, 0x22, 0x47, 0x0a, 0x0b, 0x4a, 0x6f, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xbe, 0x01, 0x0a,
+	0x0b, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05,
+	0x63, 0x61, 0x6c, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x63, 0x61, 0x6c,
+	0x6c, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x61, 0x74, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x61, 0x74,
+	0x65, 0x6e, 0x63, 0x79, 0x5f, 0x75, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6c,
+	0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x55, 0x73, 0x12, 0x15, 0x0a, 0x06, 0x70, 0x35, 0x30, 0x5f,
+	0x75, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x70, 0x35, 0x30, 0x55, 0x73, 0x12,
+	0x15, 0x0a, 0x06, 0x70, 0x39, 0x35, 0x5f, 0x75, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x05, 0x70, 0x39, 0x35, 0x55, 0x73, 0x12, 0x15, 0x0a, 0x06, 0x70, 0x39, 0x39, 0x5f, 0x75, 0x73,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x70, 0x39, 0x39, 0x55, 0x73, 0x22, 0xe3, 0x01,
+	0x0a, 0x06, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x41, 0x64, 0x64, 0x72, 0x12, 0x1f, 0x0a,
+	0x0b, 0x67, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x67, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x41, 0x64, 0x64, 0x72, 0x12, 0x2a,
+	0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x12,
+	0x2e, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x68, 0x69, 0x70, 0x2e, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x2d, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x73, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x68, 0x69, 0x70,
+	0x2e, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x05, 0x73, 0x74,
+	0x61, 0x74, 0x73, 0x22, 0x47, 0x0a, 0x0b, 0x4a, 0x6f, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65,
 	0x73, 0x74, 0x12, 0x38, 0x0a, 0x0e, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x5f, 0x74, 0x6f,
 	0x5f, 0x61, 0x64, 0x64, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6d, 0x65, 0x6d,
 	0x62, 0x65, 0x72, 0x73, 0x68, 0x69, 0x70, 0x2e, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x52, 0x0c,
@@ -741,40 +862,42 @@ func file_membership_proto_membership_proto_rawDescGZIP() []byte {
 }
 
 var file_membership_proto_membership_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_membership_proto_membership_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_membership_proto_membership_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_membership_proto_membership_proto_goTypes = []interface{}{
 	(Status)(0),                // 0: membership.Status
-	(*Member)(nil),             // 1: membership.Member
-	(*JoinRequest)(nil),        // 2: membership.JoinRequest
-	(*JoinResponse)(nil),       // 3: membership.JoinResponse
-	(*MembersResponse)(nil),    // 4: membership.MembersResponse
-	(*ExpelRequest)(nil),       // 5: membership.ExpelRequest
-	(*MemberJoinedEvent)(nil),  // 6: membership.MemberJoinedEvent
-	(*MemberLeftEvent)(nil),    // 7: membership.MemberLeftEvent
-	(*MemberUpdatedEvent)(nil), // 8: membership.MemberUpdatedEvent
-	(*ClusterEvent)(nil),       // 9: membership.ClusterEvent
-	(*emptypb.Empty)(nil),      // 10: google.protobuf.Empty
+	(*MemberStats)(nil),        // 1: membership.MemberStats
+	(*Member)(nil),             // 2: membership.Member
+	(*JoinRequest)(nil),        // 3: membership.JoinRequest
+	(*JoinResponse)(nil),       // 4: membership.JoinResponse
+	(*MembersResponse)(nil),    // 5: membership.MembersResponse
+	(*ExpelRequest)(nil),       // 6: membership.ExpelRequest
+	(*MemberJoinedEvent)(nil),  // 7: membership.MemberJoinedEvent
+	(*MemberLeftEvent)(nil),    // 8: membership.MemberLeftEvent
+	(*MemberUpdatedEvent)(nil), // 9: membership.MemberUpdatedEvent
+	(*ClusterEvent)(nil),       // 10: membership.ClusterEvent
+	(*emptypb.Empty)(nil),      // 11: google.protobuf.Empty
 }
 var file_membership_proto_membership_proto_depIdxs = []int32{
 	0,  // 0: membership.Member.status:type_name -> membership.Status
-	1,  // 1: membership.JoinRequest.members_to_add:type_name -> membership.Member
-	1,  // 2: membership.JoinResponse.members:type_name -> membership.Member
-	1,  // 3: membership.MembersResponse.members:type_name -> membership.Member
-	0,  // 4: membership.MemberUpdatedEvent.status:type_name -> membership.Status
-	7,  // 5: membership.ClusterEvent.member_left:type_name -> membership.MemberLeftEvent
-	6,  // 6: membership.ClusterEvent.member_joined:type_name -> membership.MemberJoinedEvent
-	8,  // 7: membership.ClusterEvent.member_updated:type_name -> membership.MemberUpdatedEvent
-	2,  // 8: membership.MembershipService.Join:input_type -> membership.JoinRequest
-	5,  // 9: membership.MembershipService.Expel:input_type -> membership.ExpelRequest
-	10, // 10: membership.MembershipService.Members:input_type -> google.protobuf.Empty
-	3,  // 11: membership.MembershipService.Join:output_type -> membership.JoinResponse
-	10, // 12: membership.MembershipService.Expel:output_type -> google.protobuf.Empty
-	4,  // 13: membership.MembershipService.Members:output_type -> membership.MembersResponse
-	11, // [11:14] is the sub-list for method output_type
-	8,  // [8:11] is the sub-list for method input_type
-	8,  // [8:8] is the sub-list for extension type_name
-	8,  // [8:8] is the sub-list for extension extendee
-	0,  // [0:8] is the sub-list for field type_name
+	1,  // 1: membership.Member.stats:type_name -> membership.MemberStats
+	2,  // 2: membership.JoinRequest.members_to_add:type_name -> membership.Member
+	2,  // 3: membership.JoinResponse.members:type_name -> membership.Member
+	2,  // 4: membership.MembersResponse.members:type_name -> membership.Member
+	0,  // 5: membership.MemberUpdatedEvent.status:type_name -> membership.Status
+	8,  // 6: membership.ClusterEvent.member_left:type_name -> membership.MemberLeftEvent
+	7,  // 7: membership.ClusterEvent.member_joined:type_name -> membership.MemberJoinedEvent
+	9,  // 8: membership.ClusterEvent.member_updated:type_name -> membership.MemberUpdatedEvent
+	3,  // 9: membership.MembershipService.Join:input_type -> membership.JoinRequest
+	6,  // 10: membership.MembershipService.Expel:input_type -> membership.ExpelRequest
+	11, // 11: membership.MembershipService.Members:input_type -> google.protobuf.Empty
+	4,  // 12: membership.MembershipService.Join:output_type -> membership.JoinResponse
+	11, // 13: membership.MembershipService.Expel:output_type -> google.protobuf.Empty
+	5,  // 14: membership.MembershipService.Members:output_type -> membership.MembersResponse
+	12, // [12:15] is the sub-list for method output_type
+	9,  // [9:12] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_membership_proto_membership_proto_init() }
@@ -784,7 +907,7 @@ func file_membership_proto_membership_proto_init() {
 	}
 	if !protoimpl.UnsafeEnabled {
 		file_membership_proto_membership_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Member); i {
+			switch v := v.(*MemberStats); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -796,7 +919,7 @@ func file_membership_proto_membership_proto_init() {
 			}
 		}
 		file_membership_proto_membership_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*JoinRequest); i {
+			switch v := v.(*Member); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -808,7 +931,7 @@ func file_membership_proto_membership_proto_init() {
 			}
 		}
 		file_membership_proto_membership_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*JoinResponse); i {
+			switch v := v.(*JoinRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -820,7 +943,7 @@ func file_membership_proto_membership_proto_init() {
 			}
 		}
 		file_membership_proto_membership_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*MembersResponse); i {
+			switch v := v.(*JoinResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -832,7 +955,7 @@ func file_membership_proto_membership_proto_init() {
 			}
 		}
 		file_membership_proto_membership_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ExpelRequest); i {
+			switch v := v.(*MembersResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -844,7 +967,7 @@ func file_membership_proto_membership_proto_init() {
 			}
 		}
 		file_membership_proto_membership_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*MemberJoinedEvent); i {
+			switch v := v.(*ExpelRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -856,7 +979,7 @@ func file_membership_proto_membership_proto_init() {
 			}
 		}
 		file_membership_proto_membership_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*MemberLeftEvent); i {
+			switch v := v.(*MemberJoinedEvent); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -868,7 +991,7 @@ func file_membership_proto_membership_proto_init() {
 			}
 		}
 		file_membership_proto_membership_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*MemberUpdatedEvent); i {
+			switch v := v.(*MemberLeftEvent); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -880,6 +1003,18 @@ func file_membership_proto_membership_proto_init() {
 			}
 		}
 		file_membership_proto_membership_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MemberUpdatedEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_membership_proto_membership_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ClusterEvent); i {
 			case 0:
 				return &v.state
@@ -892,7 +1027,7 @@ func file_membership_proto_membership_proto_init() {
 			}
 		}
 	}
-	file_membership_proto_membership_proto_msgTypes[8].OneofWrappers = []interface{}{
+	file_membership_proto_membership_proto_msgTypes[9].OneofWrappers = []interface{}{
 		(*ClusterEvent_MemberLeft)(nil),
 		(*ClusterEvent_MemberJoined)(nil),
 		(*ClusterEvent_MemberUpdated)(nil),
@@ -903,7 +1038,7 @@ func file_membership_proto_membership_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_membership_proto_membership_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   9,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
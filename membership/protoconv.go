@@ -38,6 +38,19 @@ func ToProtoMembers(members []Member) []*proto.Member {
 	return result
 }
 
+// ToProtoStats converts Stats to a proto.MemberStats.
+func ToProtoStats(s Stats) *proto.MemberStats {
+	return &proto.MemberStats{
+		Calls:     s.Calls,
+		Errors:    s.Errors,
+		ErrorRate: s.ErrorRate,
+		LatencyUs: s.Latency.Microseconds(),
+		P50Us:     s.P50.Microseconds(),
+		P95Us:     s.P95.Microseconds(),
+		P99Us:     s.P99.Microseconds(),
+	}
+}
+
 // FromProtoStatus converts a proto.Status to a Status.
 func FromProtoStatus(s proto.Status) Status {
 	switch s {
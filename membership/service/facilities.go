@@ -9,3 +9,8 @@ type MemberRegistry interface {
 	Add(...membership.Member) error
 	Expel(membership.NodeID) error
 }
+
+// StatsProvider returns the statistics of the calls made to the members by this node.
+type StatsProvider interface {
+	MemberStats(membership.NodeID) (membership.Stats, bool)
+}
@@ -79,3 +79,41 @@ func (mr *MockMemberRegistryMockRecorder) Members() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Members", reflect.TypeOf((*MockMemberRegistry)(nil).Members))
 }
+
+// MockStatsProvider is a mock of StatsProvider interface.
+type MockStatsProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockStatsProviderMockRecorder
+}
+
+// MockStatsProviderMockRecorder is the mock recorder for MockStatsProvider.
+type MockStatsProviderMockRecorder struct {
+	mock *MockStatsProvider
+}
+
+// NewMockStatsProvider creates a new mock instance.
+func NewMockStatsProvider(ctrl *gomock.Controller) *MockStatsProvider {
+	mock := &MockStatsProvider{ctrl: ctrl}
+	mock.recorder = &MockStatsProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStatsProvider) EXPECT() *MockStatsProviderMockRecorder {
+	return m.recorder
+}
+
+// MemberStats mocks base method.
+func (m *MockStatsProvider) MemberStats(arg0 membership.NodeID) (membership.Stats, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MemberStats", arg0)
+	ret0, _ := ret[0].(membership.Stats)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// MemberStats indicates an expected call of MemberStats.
+func (mr *MockStatsProviderMockRecorder) MemberStats(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MemberStats", reflect.TypeOf((*MockStatsProvider)(nil).MemberStats), arg0)
+}
@@ -12,6 +12,14 @@ import (
 func (s *MembershipService) Members(ctx context.Context, _ *emptypb.Empty) (*proto.MembersResponse, error) {
 	members := membership.ToProtoMembers(s.memberlist.Members())
 
+	if s.stats != nil {
+		for _, member := range members {
+			if stats, ok := s.stats.MemberStats(membership.NodeID(member.Id)); ok {
+				member.Stats = membership.ToProtoStats(stats)
+			}
+		}
+	}
+
 	return &proto.MembersResponse{
 		Members: members,
 	}, nil
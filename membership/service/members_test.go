@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/maxpoletaev/kv/membership"
@@ -41,3 +42,43 @@ func TestMembers(t *testing.T) {
 	require.Equal(t, proto.Status_Healthy, resp.Members[0].Status)
 	require.Equal(t, uint64(1), resp.Members[0].Version)
 }
+
+func TestMembers_Stats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	memberlist := NewMockMemberRegistry(ctrl)
+
+	memberlist.EXPECT().Members().Return([]membership.Member{
+		{ID: 1, Name: "node1", Status: membership.StatusHealthy},
+		{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+	})
+
+	stats := NewMockStatsProvider(ctrl)
+	stats.EXPECT().MemberStats(membership.NodeID(1)).Return(membership.Stats{
+		Calls:     10,
+		Errors:    1,
+		ErrorRate: 0.1,
+		Latency:   2 * time.Millisecond,
+		P50:       time.Millisecond,
+		P95:       3 * time.Millisecond,
+		P99:       5 * time.Millisecond,
+	}, true)
+	stats.EXPECT().MemberStats(membership.NodeID(2)).Return(membership.Stats{}, false)
+
+	svc := NewMembershipService(memberlist, WithStats(stats))
+
+	resp, err := svc.Members(context.Background(), &emptypb.Empty{})
+	require.NoError(t, err)
+	require.Len(t, resp.Members, 2)
+
+	require.Equal(t, &proto.MemberStats{
+		Calls:     10,
+		Errors:    1,
+		ErrorRate: 0.1,
+		LatencyUs: 2000,
+		P50Us:     1000,
+		P95Us:     3000,
+		P99Us:     5000,
+	}, resp.Members[0].Stats)
+
+	require.Nil(t, resp.Members[1].Stats)
+}
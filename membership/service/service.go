@@ -4,13 +4,29 @@ import (
 	"github.com/maxpoletaev/kv/membership/proto"
 )
 
+type serviceOption func(*MembershipService)
+
+// WithStats makes the Members call include the statistics of the calls made to each member.
+func WithStats(stats StatsProvider) serviceOption {
+	return func(s *MembershipService) {
+		s.stats = stats
+	}
+}
+
 type MembershipService struct {
 	proto.UnimplementedMembershipServiceServer
 	memberlist MemberRegistry
+	stats      StatsProvider
 }
 
-func NewMembershipService(memberlist MemberRegistry) *MembershipService {
-	return &MembershipService{
+func NewMembershipService(memberlist MemberRegistry, opts ...serviceOption) *MembershipService {
+	s := &MembershipService{
 		memberlist: memberlist,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
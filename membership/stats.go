@@ -0,0 +1,140 @@
+package membership
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// statsAlpha is the weight of the latest call in the moving averages of a member.
+	statsAlpha = 0.1
+	// statsWindow is the number of the latest calls of a member the percentiles are computed over.
+	statsWindow = 128
+)
+
+// Stats are the statistics of the calls made to a member by this node.
+type Stats struct {
+	// Calls is the total number of calls made to the member.
+	Calls int64
+	// Errors is the total number of calls that failed due to the member being unavailable
+	// or too slow to respond in time. The errors returned by the member itself are not
+	// counted.
+	Errors int64
+	// Latency is the exponentially weighted moving average of the latency of the calls.
+	Latency time.Duration
+	// ErrorRate is the exponentially weighted moving average of the failed calls, from 0 to 1.
+	ErrorRate float64
+	// P50, P95 and P99 are the percentiles of the latency of the latest calls.
+	P50, P95, P99 time.Duration
+}
+
+type memberStats struct {
+	calls     int64
+	errors    int64
+	latency   float64
+	errorRate float64
+	window    []time.Duration
+	next      int
+}
+
+// StatsTracker collects the latency and the errors of the calls made to each member.
+type StatsTracker struct {
+	mut     sync.Mutex
+	members map[NodeID]*memberStats
+}
+
+func NewStatsTracker() *StatsTracker {
+	return &StatsTracker{
+		members: make(map[NodeID]*memberStats),
+	}
+}
+
+// Observe records a call made to the member.
+func (t *StatsTracker) Observe(id NodeID, latency time.Duration, failed bool) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	ms := t.members[id]
+
+	if ms == nil {
+		ms = &memberStats{
+			latency: float64(latency),
+			window:  make([]time.Duration, 0, statsWindow),
+		}
+
+		t.members[id] = ms
+	}
+
+	failure := 0.0
+	if failed {
+		failure = 1
+		ms.errors++
+	}
+
+	ms.calls++
+	ms.latency += statsAlpha * (float64(latency) - ms.latency)
+	ms.errorRate += statsAlpha * (failure - ms.errorRate)
+
+	if len(ms.window) < statsWindow {
+		ms.window = append(ms.window, latency)
+	} else {
+		ms.window[ms.next] = latency
+		ms.next = (ms.next + 1) % statsWindow
+	}
+}
+
+// Stats returns the statistics of the member, or false if no calls have been made to it.
+func (t *StatsTracker) Stats(id NodeID) (Stats, bool) {
+	t.mut.Lock()
+
+	ms := t.members[id]
+	if ms == nil {
+		t.mut.Unlock()
+		return Stats{}, false
+	}
+
+	stats := Stats{
+		Calls:     ms.calls,
+		Errors:    ms.errors,
+		Latency:   time.Duration(ms.latency),
+		ErrorRate: ms.errorRate,
+	}
+
+	samples := make([]time.Duration, len(ms.window))
+	copy(samples, ms.window)
+	t.mut.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+
+	stats.P50 = percentile(samples, 0.5)
+	stats.P95 = percentile(samples, 0.95)
+	stats.P99 = percentile(samples, 0.99)
+
+	return stats, true
+}
+
+// Remove drops the statistics of the member, once it has left the cluster.
+func (t *StatsTracker) Remove(id NodeID) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	delete(t.members, id)
+}
+
+// percentile returns the value the given fraction of the sorted samples fit into.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+
+	return sorted[idx]
+}
@@ -0,0 +1,55 @@
+package membership
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsTracker(t *testing.T) {
+	tracker := NewStatsTracker()
+
+	_, ok := tracker.Stats(1)
+	require.False(t, ok)
+
+	for i := 1; i <= 100; i++ {
+		tracker.Observe(1, time.Duration(i)*time.Millisecond, i%10 == 0)
+	}
+
+	stats, ok := tracker.Stats(1)
+	require.True(t, ok)
+
+	assert.Equal(t, int64(100), stats.Calls)
+	assert.Equal(t, int64(10), stats.Errors)
+	assert.Equal(t, 50*time.Millisecond, stats.P50)
+	assert.Equal(t, 95*time.Millisecond, stats.P95)
+	assert.Equal(t, 99*time.Millisecond, stats.P99)
+
+	// The moving averages favor the latest calls.
+	assert.Greater(t, stats.Latency, 80*time.Millisecond)
+	assert.Less(t, stats.Latency, 100*time.Millisecond)
+	assert.InDelta(t, 0.1, stats.ErrorRate, 0.1)
+
+	tracker.Remove(1)
+
+	_, ok = tracker.Stats(1)
+	require.False(t, ok)
+}
+
+func TestStatsTracker_Window(t *testing.T) {
+	tracker := NewStatsTracker()
+
+	for i := 0; i < statsWindow; i++ {
+		tracker.Observe(1, time.Second, false)
+	}
+
+	for i := 0; i < statsWindow; i++ {
+		tracker.Observe(1, time.Millisecond, false)
+	}
+
+	stats, _ := tracker.Stats(1)
+	assert.Equal(t, time.Millisecond, stats.P99)
+	assert.Equal(t, int64(2*statsWindow), stats.Calls)
+}
@@ -0,0 +1,22 @@
+package rebalance
+
+//go:generate mockgen -source=facilities.go -destination=facilities_mock_test.go -package=rebalance
+
+import (
+	"github.com/maxpoletaev/kv/clust"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/storage"
+)
+
+type Cluster interface {
+	Self() membership.Member
+	Members() []membership.Member
+	Conn(membership.NodeID) (clust.Conn, error)
+}
+
+// Storage is the local storage engine the keys are moved from. It must be
+// able to list its keys and to drop the keys once they are moved.
+type Storage interface {
+	storage.Scannable
+	storage.Discardable
+}
@@ -0,0 +1,49 @@
+package rebalance
+
+import (
+	"time"
+
+	"github.com/maxpoletaev/kv/clust/ring"
+)
+
+type option func(*Rebalancer)
+
+// WithPlacement sets the placement that decides which nodes each key is stored on.
+func WithPlacement(p *ring.Placement) option {
+	return func(r *Rebalancer) {
+		r.placement = p
+	}
+}
+
+// WithCheckInterval sets how often the membership is checked for changes.
+func WithCheckInterval(d time.Duration) option {
+	return func(r *Rebalancer) {
+		r.interval = d
+	}
+}
+
+// WithBatchSize sets the number of keys sent to the new owners at once.
+// The keys of a batch are dropped locally only when the whole batch is stored.
+func WithBatchSize(n int) option {
+	return func(r *Rebalancer) {
+		r.batchSize = n
+	}
+}
+
+// WithRateLimit limits the rate the keys are sent at, in bytes per second,
+// so that the rebalancing does not take over the bandwidth of the regular
+// requests. Zero rate disables the limit.
+func WithRateLimit(rate int64) option {
+	return func(r *Rebalancer) {
+		r.rateLimit = rate
+	}
+}
+
+// WithStateFile sets the file the progress of the rebalancer is saved to. Without it, the
+// rebalancer does not know how the keys were distributed before restart, and assumes that
+// they already match the ring built from the current members.
+func WithStateFile(path string) option {
+	return func(r *Rebalancer) {
+		r.stateFile = path
+	}
+}
@@ -0,0 +1,105 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.19.4
+// source: rebalance/proto/rebalance.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// RebalanceServiceClient is the client API for RebalanceService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RebalanceServiceClient interface {
+	Progress(ctx context.Context, in *ProgressRequest, opts ...grpc.CallOption) (*ProgressResponse, error)
+}
+
+type rebalanceServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRebalanceServiceClient(cc grpc.ClientConnInterface) RebalanceServiceClient {
+	return &rebalanceServiceClient{cc}
+}
+
+func (c *rebalanceServiceClient) Progress(ctx context.Context, in *ProgressRequest, opts ...grpc.CallOption) (*ProgressResponse, error) {
+	out := new(ProgressResponse)
+	err := c.cc.Invoke(ctx, "/rebalance.RebalanceService/Progress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RebalanceServiceServer is the server API for RebalanceService service.
+// All implementations must embed UnimplementedRebalanceServiceServer
+// for forward compatibility
+type RebalanceServiceServer interface {
+	Progress(context.Context, *ProgressRequest) (*ProgressResponse, error)
+	mustEmbedUnimplementedRebalanceServiceServer()
+}
+
+// UnimplementedRebalanceServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedRebalanceServiceServer struct {
+}
+
+func (UnimplementedRebalanceServiceServer) Progress(context.Context, *ProgressRequest) (*ProgressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Progress not implemented")
+}
+func (UnimplementedRebalanceServiceServer) mustEmbedUnimplementedRebalanceServiceServer() {}
+
+// UnsafeRebalanceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RebalanceServiceServer will
+// result in compilation errors.
+type UnsafeRebalanceServiceServer interface {
+	mustEmbedUnimplementedRebalanceServiceServer()
+}
+
+func RegisterRebalanceServiceServer(s grpc.ServiceRegistrar, srv RebalanceServiceServer) {
+	s.RegisterService(&RebalanceService_ServiceDesc, srv)
+}
+
+func _RebalanceService_Progress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProgressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RebalanceServiceServer).Progress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rebalance.RebalanceService/Progress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RebalanceServiceServer).Progress(ctx, req.(*ProgressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RebalanceService_ServiceDesc is the grpc.ServiceDesc for RebalanceService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RebalanceService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rebalance.RebalanceService",
+	HandlerType: (*RebalanceServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Progress",
+			Handler:    _RebalanceService_Progress_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "rebalance/proto/rebalance.proto",
+}
@@ -0,0 +1,516 @@
+package rebalance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/maxpoletaev/kv/clust/ring"
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/ratelimit"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/storage"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+const (
+	defaultCheckInterval = 10 * time.Second
+	defaultBatchSize     = 100
+)
+
+// Progress is a snapshot of the progress of the rebalancer.
+type Progress struct {
+	// Running is true while a pass is in progress, including a pass interrupted
+	// by an error, which is resumed on the next check.
+	Running bool
+	// From and To are the nodes of the rings the keys are moved between by the
+	// current pass, or by the last one if there is no pass in progress.
+	From []membership.NodeID
+	To   []membership.NodeID
+	// Checkpoint is the last key processed by the current pass.
+	Checkpoint string
+	// KeysScanned is the number of local keys read by the current pass. The keys outside
+	// of the moved ranges are read, but skipped without any further work.
+	KeysScanned int64
+	// KeysMoved is the number of keys sent to their new owners by the current pass.
+	KeysMoved int64
+	// KeysDiscarded is the number of keys dropped from this node by the current pass.
+	KeysDiscarded int64
+	// BytesSent is the number of bytes sent to the new owners by the current pass.
+	BytesSent int64
+	// StartedAt and FinishedAt are the times the last pass started and finished at.
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// PassesCompleted is the number of passes completed since the start.
+	PassesCompleted int64
+	// LastError is the error that interrupted the current pass, if any.
+	LastError string
+}
+
+// Rebalancer moves the keys stored on this node to their new owners when the members join or
+// leave the cluster. It compares the preference list of each key on the ring the keys are
+// distributed by with the one on the ring built from the current members. The keys are sent
+// to the nodes that have become their replicas, and if this node is no longer a replica, to
+// all of the new replicas, after which the local copy is dropped. The keys are dropped only
+// once all receivers have confirmed that they stored them, and the last key of every batch
+// is saved as a checkpoint, so that a pass interrupted by an error or a restart is resumed
+// from where it stopped. If the membership changes again during a pass, the pass starts over
+// towards the new ring, since the keys not processed yet are still distributed by the old one.
+//
+// Only the keys in the token ranges whose replicas have changed, with this node among them,
+// are moved. If there are no such ranges, the pass finishes without reading the keys at all.
+// Otherwise, the keys are still read in full, since the storage orders them by key rather
+// than by the position on the ring, but the keys of the other ranges are skipped right away.
+type Rebalancer struct {
+	cluster   Cluster
+	storage   Storage
+	logger    log.Logger
+	limiter   *ratelimit.Limiter
+	interval  time.Duration
+	batchSize int
+	rateLimit int64
+	placement *ring.Placement
+	stateFile string
+	state     *state
+	mut       sync.Mutex
+	progress  Progress
+}
+
+func New(cluster Cluster, storage Storage, logger log.Logger, opts ...option) *Rebalancer {
+	r := &Rebalancer{
+		cluster:   cluster,
+		storage:   storage,
+		logger:    logger,
+		interval:  defaultCheckInterval,
+		batchSize: defaultBatchSize,
+		placement: ring.DefaultPlacement(),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.rateLimit > 0 {
+		r.limiter = ratelimit.New(r.rateLimit)
+	}
+
+	return r
+}
+
+// Progress returns the progress of the rebalancer.
+func (r *Rebalancer) Progress() Progress {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	return r.progress
+}
+
+func (r *Rebalancer) updateProgress(fn func(p *Progress)) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	fn(&r.progress)
+}
+
+func (r *Rebalancer) RunLoop(ctx context.Context) {
+	level.Info(r.logger).Log(
+		"msg", "rebalancer loop started",
+		"check_interval", r.interval,
+	)
+
+	for {
+		select {
+		case <-time.After(r.interval):
+			// noop
+		case <-ctx.Done():
+			return
+		}
+
+		if err := r.Check(ctx); err != nil {
+			level.Error(r.logger).Log("msg", "rebalancing failed", "err", err)
+		}
+	}
+}
+
+// Check compares the ring the keys are distributed by with the ring of the current members,
+// and moves the keys if they differ, or if there is an unfinished pass. It must not be called
+// concurrently with RunLoop.
+func (r *Rebalancer) Check(ctx context.Context) error {
+	if r.state == nil && r.stateFile != "" {
+		st, err := loadState(r.stateFile)
+		if err != nil {
+			return err
+		}
+
+		r.state = st
+	}
+
+	current := r.memberNodes()
+
+	switch {
+	case r.state == nil:
+		// Nothing is known about how the keys were distributed before,
+		// so they are assumed to match the current members.
+		r.state = &state{from: current, to: current}
+		r.startProgress(false)
+
+		return r.saveState()
+	case r.state.active && sameNodes(r.state.to, current):
+		if !r.Progress().Running {
+			r.startProgress(true)
+		}
+
+		level.Info(r.logger).Log("msg", "resuming rebalancing", "checkpoint", r.state.checkpoint)
+	case sameNodes(r.state.from, current):
+		if r.state.active {
+			// The members that have joined during the pass have left again, so the keys
+			// not processed yet are in place, and the processed ones are still kept here.
+			r.state = &state{from: current, to: current}
+			r.startProgress(false)
+
+			return r.saveState()
+		}
+
+		return nil
+	default:
+		r.state.to, r.state.checkpoint, r.state.active = current, "", true
+		r.startProgress(true)
+
+		level.Info(r.logger).Log("msg", "rebalancing started", "from", fmt.Sprint(r.state.from), "to", fmt.Sprint(current))
+
+		if err := r.saveState(); err != nil {
+			return err
+		}
+	}
+
+	if err := r.runPass(ctx); err != nil {
+		r.updateProgress(func(p *Progress) {
+			p.LastError = err.Error()
+		})
+
+		return err
+	}
+
+	r.state = &state{from: r.state.to, to: r.state.to}
+
+	r.updateProgress(func(p *Progress) {
+		p.Running = false
+		p.FinishedAt = time.Now()
+		p.PassesCompleted++
+		p.LastError = ""
+	})
+
+	level.Info(r.logger).Log("msg", "rebalancing finished")
+
+	return r.saveState()
+}
+
+func (r *Rebalancer) startProgress(running bool) {
+	r.updateProgress(func(p *Progress) {
+		*p = Progress{
+			Running:         running,
+			From:            r.state.from,
+			To:              r.state.to,
+			PassesCompleted: p.PassesCompleted,
+		}
+
+		if running {
+			p.StartedAt = time.Now()
+		}
+	})
+}
+
+func (r *Rebalancer) saveState() error {
+	if r.stateFile == "" {
+		return nil
+	}
+
+	return saveState(r.stateFile, r.state)
+}
+
+// memberNodes returns the IDs of the current members in ascending order.
+func (r *Rebalancer) memberNodes() []membership.NodeID {
+	members := r.cluster.Members()
+
+	ids := make([]membership.NodeID, 0, len(members))
+	for i := range members {
+		ids = append(ids, members[i].ID)
+	}
+
+	return ring.New(ids, 1).Nodes()
+}
+
+func sameNodes(a, b []membership.NodeID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// batch holds the keys scanned since the last checkpoint.
+type batch struct {
+	requests map[membership.NodeID]*storagepb.HandoffRequest
+	discard  map[string][]storage.Value
+	lastKey  string
+	scanned  int
+	moved    int
+}
+
+func newBatch() *batch {
+	return &batch{
+		requests: make(map[membership.NodeID]*storagepb.HandoffRequest),
+		discard:  make(map[string][]storage.Value),
+	}
+}
+
+// pass moves the keys from one ring to another.
+type pass struct {
+	self    membership.NodeID
+	from    *ring.Ring
+	to      *ring.Ring
+	moved   *ring.RangeSet
+	streams map[membership.NodeID]storagepb.StorageService_HandoffClient
+}
+
+func (r *Rebalancer) runPass(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := &pass{
+		self:    r.cluster.Self().ID,
+		from:    r.placement.Ring(r.state.from),
+		to:      r.placement.Ring(r.state.to),
+		streams: make(map[membership.NodeID]storagepb.StorageService_HandoffClient),
+	}
+
+	p.moved = ring.MovedRanges(p.from, p.to, r.placement.ReplicationFactor(), p.self)
+	if p.moved.Empty() {
+		return nil
+	}
+
+	var start string
+	if r.state.checkpoint != "" {
+		// The smallest key that is greater than the checkpoint.
+		start = r.state.checkpoint + "\x00"
+	}
+
+	it := storage.NewKeyIterator(r.storage.ScanFrom(start))
+	b := newBatch()
+
+	for {
+		key, values, ok := it.Next()
+		if !ok {
+			break
+		}
+
+		r.addKey(p, b, key, values)
+
+		if b.scanned >= r.batchSize {
+			if err := r.flush(ctx, p, b); err != nil {
+				return err
+			}
+
+			b = newBatch()
+		}
+	}
+
+	if b.scanned > 0 {
+		if err := r.flush(ctx, p, b); err != nil {
+			return err
+		}
+	}
+
+	// A scan stopped by an error must not complete the pass, or the keys
+	// after the failed one would never be moved to their new owners.
+	if err := it.Err(); err != nil {
+		return fmt.Errorf("failed to scan keys: %w", err)
+	}
+
+	return p.close()
+}
+
+// addKey adds the key to the batch, if it has to be sent to other nodes.
+func (r *Rebalancer) addKey(p *pass, b *batch, key string, values []storage.Value) {
+	b.scanned++
+	b.lastKey = key
+
+	if !p.moved.Contains(ring.KeyHash(key)) {
+		return
+	}
+
+	before := p.from.PreferenceList(key, r.placement.ReplicationFactor())
+	after := p.to.PreferenceList(key, r.placement.ReplicationFactor())
+	owner := containsNode(after, p.self)
+	entry := &storagepb.HandoffEntry{Key: key, Values: toProtoValues(values)}
+	moved := false
+
+	for _, id := range after {
+		// The replicas that have been holding the key already have it, unless
+		// this node is about to drop its copy, which must exist elsewhere first.
+		if id == p.self || (owner && containsNode(before, id)) {
+			continue
+		}
+
+		req, ok := b.requests[id]
+		if !ok {
+			req = &storagepb.HandoffRequest{}
+			b.requests[id] = req
+		}
+
+		req.Entries = append(req.Entries, entry)
+		moved = true
+	}
+
+	if moved {
+		b.moved++
+	}
+
+	if !owner {
+		b.discard[key] = values
+	}
+}
+
+// flush sends the batch to the new owners, waits until all of them confirm that the keys
+// are stored, and then drops the keys this node no longer owns, and saves the checkpoint.
+func (r *Rebalancer) flush(ctx context.Context, p *pass, b *batch) error {
+	ids := make([]membership.NodeID, 0, len(b.requests))
+	for id := range b.requests {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	var sent int64
+
+	for _, id := range ids {
+		stream, err := r.stream(ctx, p, id)
+		if err != nil {
+			return err
+		}
+
+		req := b.requests[id]
+		size := protobuf.Size(req)
+		r.limiter.WaitN(size)
+
+		if err := stream.Send(req); err != nil {
+			return fmt.Errorf("failed to send keys to node %d: %w", id, err)
+		}
+
+		sent += int64(size)
+	}
+
+	for _, id := range ids {
+		resp, err := p.streams[id].Recv()
+		if err != nil {
+			return fmt.Errorf("failed to receive confirmation from node %d: %w", id, err)
+		}
+
+		entries := b.requests[id].Entries
+		if lastKey := entries[len(entries)-1].Key; resp.LastKey != lastKey {
+			return fmt.Errorf("node %d confirmed key %q instead of %q", id, resp.LastKey, lastKey)
+		}
+	}
+
+	// All receivers have stored the batch, so the local copies can be dropped.
+	// Only the versions that have been sent are dropped, the newer ones are kept.
+	for key, values := range b.discard {
+		if err := r.storage.Discard(key, values); err != nil {
+			return fmt.Errorf("failed to discard key %s: %w", key, err)
+		}
+	}
+
+	r.state.checkpoint = b.lastKey
+
+	if err := r.saveState(); err != nil {
+		return err
+	}
+
+	r.updateProgress(func(p *Progress) {
+		p.Checkpoint = b.lastKey
+		p.KeysScanned += int64(b.scanned)
+		p.KeysMoved += int64(b.moved)
+		p.KeysDiscarded += int64(len(b.discard))
+		p.BytesSent += sent
+	})
+
+	return nil
+}
+
+// stream returns the stream to the node, opening it on first use.
+func (r *Rebalancer) stream(ctx context.Context, p *pass, id membership.NodeID) (storagepb.StorageService_HandoffClient, error) {
+	if stream, ok := p.streams[id]; ok {
+		return stream, nil
+	}
+
+	conn, err := r.cluster.Conn(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection to node %d: %w", id, err)
+	}
+
+	stream, err := conn.Handoff(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open handoff stream to node %d: %w", id, err)
+	}
+
+	p.streams[id] = stream
+
+	return stream, nil
+}
+
+// close closes the streams of the pass, and waits for the receivers to finish.
+func (p *pass) close() error {
+	for id, stream := range p.streams {
+		if err := stream.CloseSend(); err != nil {
+			return fmt.Errorf("failed to close handoff stream to node %d: %w", id, err)
+		}
+
+		if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to close handoff stream to node %d: %v", id, err)
+		}
+	}
+
+	return nil
+}
+
+func containsNode(list []membership.NodeID, id membership.NodeID) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toProtoValues(values []storage.Value) []*storagepb.VersionedValue {
+	result := make([]*storagepb.VersionedValue, 0, len(values))
+
+	for _, value := range values {
+		result = append(result, &storagepb.VersionedValue{
+			Version:   dvv.MustEncode(value.DottedVersion()),
+			Data:      value.Data,
+			Timestamp: uint64(value.Timestamp),
+			Origin:    value.Origin,
+			Tombstone: value.Tombstone,
+		})
+	}
+
+	return result
+}
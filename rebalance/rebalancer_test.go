@@ -0,0 +1,458 @@
+package rebalance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/clust/ring"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/inmemory"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+// fakeNode stores the keys received through the handoff streams.
+type fakeNode struct {
+	keys []string
+	// failAfter is the number of batches the next stream acknowledges before
+	// it fails. Negative values mean that the stream never fails.
+	failAfter int
+}
+
+type fakeHandoffStream struct {
+	grpc.ClientStream
+	node      *fakeNode
+	pending   []*storagepb.HandoffRequest
+	failAfter int
+	closed    bool
+}
+
+func (s *fakeHandoffStream) Send(req *storagepb.HandoffRequest) error {
+	s.pending = append(s.pending, req)
+	return nil
+}
+
+func (s *fakeHandoffStream) Recv() (*storagepb.HandoffResponse, error) {
+	if len(s.pending) == 0 {
+		if s.closed {
+			return nil, io.EOF
+		}
+
+		return nil, errors.New("nothing to receive")
+	}
+
+	if s.failAfter == 0 {
+		return nil, errors.New("node failed")
+	}
+
+	s.failAfter--
+
+	req := s.pending[0]
+	s.pending = s.pending[1:]
+
+	for _, entry := range req.Entries {
+		s.node.keys = append(s.node.keys, entry.Key)
+	}
+
+	return &storagepb.HandoffResponse{
+		LastKey: req.Entries[len(req.Entries)-1].Key,
+	}, nil
+}
+
+func (s *fakeHandoffStream) CloseSend() error {
+	s.closed = true
+	return nil
+}
+
+type testCluster struct {
+	members []membership.Member
+	nodes   map[membership.NodeID]*fakeNode
+}
+
+func setupCluster(t *testing.T, ctrl *gomock.Controller, tc *testCluster) *MockCluster {
+	cluster := NewMockCluster(ctrl)
+
+	cluster.EXPECT().Self().Return(tc.members[0]).AnyTimes()
+
+	cluster.EXPECT().Members().DoAndReturn(func() []membership.Member {
+		return tc.members
+	}).AnyTimes()
+
+	cluster.EXPECT().Conn(gomock.Any()).DoAndReturn(func(id membership.NodeID) (*mock.MockClient, error) {
+		node, ok := tc.nodes[id]
+		require.True(t, ok, "unexpected connection to node %d", id)
+
+		conn := mock.NewMockClient(ctrl)
+		conn.EXPECT().Handoff(gomock.Any()).Return(&fakeHandoffStream{
+			node:      node,
+			failAfter: node.failAfter,
+		}, nil)
+
+		return conn, nil
+	}).AnyTimes()
+
+	return cluster
+}
+
+func member(id membership.NodeID) membership.Member {
+	return membership.Member{
+		ID:     id,
+		Name:   fmt.Sprintf("node%d", id),
+		Status: membership.StatusHealthy,
+	}
+}
+
+// fillStorage writes the keys the node owns on the ring of the given nodes.
+func fillStorage(t *testing.T, engine storage.Engine, self membership.NodeID, nodes []membership.NodeID, rf int) []string {
+	r := ring.New(nodes, ring.DefaultVirtualNodes)
+	keys := make([]string, 0)
+
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("key%03d", i)
+
+		if !containsNode(r.PreferenceList(key, rf), self) {
+			continue
+		}
+
+		require.NoError(t, engine.Put(key, storage.Value{
+			Version: vclock.New(vclock.V{1: 1}),
+			Data:    []byte(key),
+		}))
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func localKeys(engine storage.Scannable) []string {
+	keys := make([]string, 0)
+
+	for it := storage.NewKeyIterator(engine.Scan()); ; {
+		key, _, ok := it.Next()
+		if !ok {
+			break
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func TestCheck_NodeJoined(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tc := &testCluster{
+		members: []membership.Member{member(1), member(2)},
+		nodes:   map[membership.NodeID]*fakeNode{3: {failAfter: -1}},
+	}
+
+	engine := inmemory.New()
+	keys := fillStorage(t, engine, 1, []membership.NodeID{1, 2}, 1)
+	r := New(setupCluster(t, ctrl, tc), engine, kitlog.NewNopLogger(),
+		WithPlacement(ring.NewPlacement(1, ring.DefaultVirtualNodes)), WithBatchSize(10))
+
+	// The first check takes the current members as the starting point.
+	require.NoError(t, r.Check(context.Background()))
+	assert.Equal(t, []membership.NodeID{1, 2}, r.Progress().From)
+
+	tc.members = append(tc.members, member(3))
+	require.NoError(t, r.Check(context.Background()))
+
+	after := ring.New([]membership.NodeID{1, 2, 3}, ring.DefaultVirtualNodes)
+	moved, kept := make([]string, 0), make([]string, 0)
+
+	for _, key := range keys {
+		if after.PreferenceList(key, 1)[0] == 3 {
+			moved = append(moved, key)
+		} else {
+			kept = append(kept, key)
+		}
+	}
+
+	require.NotEmpty(t, moved)
+	require.NotEmpty(t, kept)
+
+	// The keys owned by the new node are moved there, the rest stay in place.
+	assert.Equal(t, moved, tc.nodes[3].keys)
+	assert.Equal(t, kept, localKeys(engine))
+
+	progress := r.Progress()
+	assert.False(t, progress.Running)
+	assert.Equal(t, []membership.NodeID{1, 2}, progress.From)
+	assert.Equal(t, []membership.NodeID{1, 2, 3}, progress.To)
+	assert.Equal(t, int64(len(keys)), progress.KeysScanned)
+	assert.Equal(t, int64(len(moved)), progress.KeysMoved)
+	assert.Equal(t, int64(len(moved)), progress.KeysDiscarded)
+	assert.Equal(t, int64(1), progress.PassesCompleted)
+	assert.Greater(t, progress.BytesSent, int64(0))
+	assert.Empty(t, progress.LastError)
+
+	// Nothing happens until the members change again.
+	require.NoError(t, r.Check(context.Background()))
+	assert.Equal(t, int64(1), r.Progress().PassesCompleted)
+}
+
+func TestCheck_ReplicationFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tc := &testCluster{
+		members: []membership.Member{member(1), member(2)},
+		nodes: map[membership.NodeID]*fakeNode{
+			2: {failAfter: -1},
+			3: {failAfter: -1},
+		},
+	}
+
+	engine := inmemory.New()
+	keys := fillStorage(t, engine, 1, []membership.NodeID{1, 2}, 2)
+	r := New(setupCluster(t, ctrl, tc), engine, kitlog.NewNopLogger(), WithPlacement(ring.NewPlacement(2, ring.DefaultVirtualNodes)))
+
+	require.NoError(t, r.Check(context.Background()))
+
+	tc.members = append(tc.members, member(3))
+	require.NoError(t, r.Check(context.Background()))
+
+	after := ring.New([]membership.NodeID{1, 2, 3}, ring.DefaultVirtualNodes)
+	local := localKeys(engine)
+
+	for _, key := range keys {
+		replicas := after.PreferenceList(key, 2)
+
+		switch {
+		case containsNode(replicas, 1):
+			// Still a replica, so the key is kept, and sent only to the new node.
+			assert.Contains(t, local, key)
+			assert.NotContains(t, tc.nodes[2].keys, key)
+			assert.Equal(t, containsNode(replicas, 3), contains(tc.nodes[3].keys, key), key)
+		default:
+			// No longer a replica, so the key is sent to all replicas before it is dropped.
+			assert.NotContains(t, local, key)
+			assert.Contains(t, tc.nodes[2].keys, key)
+			assert.Contains(t, tc.nodes[3].keys, key)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestCheck_ResumesAfterFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tc := &testCluster{
+		members: []membership.Member{member(1), member(2)},
+		nodes:   map[membership.NodeID]*fakeNode{3: {failAfter: 1}},
+	}
+
+	stateFile := filepath.Join(t.TempDir(), "rebalance.state")
+	engine := inmemory.New()
+	keys := fillStorage(t, engine, 1, []membership.NodeID{1, 2}, 1)
+	cluster := setupCluster(t, ctrl, tc)
+
+	opts := []option{WithPlacement(ring.NewPlacement(1, ring.DefaultVirtualNodes)), WithBatchSize(10), WithStateFile(stateFile)}
+	r := New(cluster, engine, kitlog.NewNopLogger(), opts...)
+
+	require.NoError(t, r.Check(context.Background()))
+
+	// The new node stores the first batch and fails on the second one.
+	tc.members = append(tc.members, member(3))
+	require.Error(t, r.Check(context.Background()))
+
+	progress := r.Progress()
+	assert.True(t, progress.Running)
+	assert.NotEmpty(t, progress.LastError)
+	assert.Equal(t, keys[9], progress.Checkpoint)
+
+	received := len(tc.nodes[3].keys)
+	require.Greater(t, received, 0)
+
+	// Only the confirmed keys are dropped, the keys after the checkpoint are still here.
+	local := localKeys(engine)
+	for _, key := range tc.nodes[3].keys {
+		assert.NotContains(t, local, key)
+	}
+
+	for _, key := range keys[10:] {
+		assert.Contains(t, local, key)
+	}
+
+	// A restarted node resumes the pass from the saved checkpoint.
+	tc.nodes[3].failAfter = -1
+	r = New(cluster, engine, kitlog.NewNopLogger(), opts...)
+	require.NoError(t, r.Check(context.Background()))
+
+	assert.Greater(t, tc.nodes[3].keys[received], keys[9])
+	assert.False(t, r.Progress().Running)
+	assert.Equal(t, []membership.NodeID{1, 2, 3}, r.Progress().To)
+
+	after := ring.New([]membership.NodeID{1, 2, 3}, ring.DefaultVirtualNodes)
+	for _, key := range localKeys(engine) {
+		assert.Equal(t, membership.NodeID(1), after.PreferenceList(key, 1)[0])
+	}
+}
+
+func TestCheck_MembersChangedDuringPass(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tc := &testCluster{
+		members: []membership.Member{member(1), member(2)},
+		nodes: map[membership.NodeID]*fakeNode{
+			3: {failAfter: 0},
+			4: {failAfter: -1},
+		},
+	}
+
+	engine := inmemory.New()
+	fillStorage(t, engine, 1, []membership.NodeID{1, 2}, 1)
+	r := New(setupCluster(t, ctrl, tc), engine, kitlog.NewNopLogger(), WithPlacement(ring.NewPlacement(1, ring.DefaultVirtualNodes)))
+
+	require.NoError(t, r.Check(context.Background()))
+
+	tc.members = append(tc.members, member(3))
+	require.Error(t, r.Check(context.Background()))
+
+	// Node 3 is replaced by node 4 before the pass has finished.
+	tc.members = []membership.Member{member(1), member(2), member(4)}
+	require.NoError(t, r.Check(context.Background()))
+
+	assert.Empty(t, tc.nodes[3].keys)
+	assert.NotEmpty(t, tc.nodes[4].keys)
+	assert.Equal(t, []membership.NodeID{1, 2}, r.Progress().From)
+	assert.Equal(t, []membership.NodeID{1, 2, 4}, r.Progress().To)
+}
+
+// failingStorage is a storage whose scans fail after the given number of keys.
+type failingStorage struct {
+	*inmemory.InMemoryEngine
+	failAfter int
+}
+
+func (s *failingStorage) ScanFrom(key string) storage.ScanIterator {
+	return &failingIterator{ScanIterator: s.InMemoryEngine.ScanFrom(key), left: s.failAfter}
+}
+
+type failingIterator struct {
+	storage.ScanIterator
+	left int
+}
+
+func (i *failingIterator) HasNext() bool {
+	return i.left > 0 && i.ScanIterator.HasNext()
+}
+
+func (i *failingIterator) Next() (string, storage.Value) {
+	i.left--
+	return i.ScanIterator.Next()
+}
+
+func (i *failingIterator) Err() error {
+	if i.left == 0 {
+		return assert.AnError
+	}
+
+	return nil
+}
+
+func TestCheck_ScanError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tc := &testCluster{
+		members: []membership.Member{member(1), member(2)},
+		nodes:   map[membership.NodeID]*fakeNode{3: {failAfter: -1}},
+	}
+
+	engine := &failingStorage{InMemoryEngine: inmemory.New(), failAfter: 15}
+	keys := fillStorage(t, engine, 1, []membership.NodeID{1, 2}, 1)
+	cluster := setupCluster(t, ctrl, tc)
+
+	opts := []option{
+		WithPlacement(ring.NewPlacement(1, ring.DefaultVirtualNodes)),
+		WithBatchSize(10),
+		WithStateFile(filepath.Join(t.TempDir(), "rebalance.state")),
+	}
+	r := New(cluster, engine, kitlog.NewNopLogger(), opts...)
+
+	require.NoError(t, r.Check(context.Background()))
+
+	// The pass stops at the failed key, and is resumed from there once the storage recovers.
+	tc.members = append(tc.members, member(3))
+	require.ErrorIs(t, r.Check(context.Background()), assert.AnError)
+
+	progress := r.Progress()
+	assert.True(t, progress.Running)
+	assert.NotEmpty(t, progress.LastError)
+	assert.Equal(t, keys[14], progress.Checkpoint)
+
+	engine.failAfter = len(keys)
+	require.NoError(t, r.Check(context.Background()))
+	assert.False(t, r.Progress().Running)
+
+	after := ring.New([]membership.NodeID{1, 2, 3}, ring.DefaultVirtualNodes)
+	for _, key := range localKeys(engine) {
+		assert.Equal(t, membership.NodeID(1), after.PreferenceList(key, 1)[0])
+	}
+}
+
+func TestCheck_NoMovedRanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	placement := ring.NewPlacement(1, 1)
+	before := []membership.NodeID{1, 2}
+
+	// Find a node that only takes over the keys of the other node when it joins.
+	joined := membership.NodeID(3)
+	for ; ; joined++ {
+		after := append([]membership.NodeID{joined}, before...)
+		if ring.MovedRanges(placement.Ring(before), ring.New(after, 1), 1, 1).Empty() {
+			break
+		}
+	}
+
+	tc := &testCluster{
+		members: []membership.Member{member(1), member(2)},
+		nodes:   map[membership.NodeID]*fakeNode{},
+	}
+
+	// The scan fails on the first key, so the pass only succeeds if it does not read the keys.
+	engine := &failingStorage{InMemoryEngine: inmemory.New()}
+	keys := fillStorage(t, engine, 1, before, 1)
+	require.NotEmpty(t, keys)
+
+	r := New(setupCluster(t, ctrl, tc), engine, kitlog.NewNopLogger(), WithPlacement(placement))
+	require.NoError(t, r.Check(context.Background()))
+
+	tc.members = append(tc.members, member(joined))
+	require.NoError(t, r.Check(context.Background()))
+
+	progress := r.Progress()
+	assert.Equal(t, int64(1), progress.PassesCompleted)
+	assert.Equal(t, int64(0), progress.KeysScanned)
+	assert.Equal(t, keys, localKeys(engine.InMemoryEngine))
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/rebalance"
+	"github.com/maxpoletaev/kv/rebalance/proto"
+)
+
+type Rebalancer interface {
+	Progress() rebalance.Progress
+}
+
+// RebalanceService exposes the progress of the rebalancer of this node.
+type RebalanceService struct {
+	proto.UnimplementedRebalanceServiceServer
+	rebalancer Rebalancer
+}
+
+func New(rebalancer Rebalancer) *RebalanceService {
+	return &RebalanceService{
+		rebalancer: rebalancer,
+	}
+}
+
+func (s *RebalanceService) Progress(ctx context.Context, req *proto.ProgressRequest) (*proto.ProgressResponse, error) {
+	progress := s.rebalancer.Progress()
+
+	resp := &proto.ProgressResponse{
+		Running:         progress.Running,
+		FromNodes:       toProtoNodes(progress.From),
+		ToNodes:         toProtoNodes(progress.To),
+		Checkpoint:      progress.Checkpoint,
+		KeysScanned:     progress.KeysScanned,
+		KeysMoved:       progress.KeysMoved,
+		KeysDiscarded:   progress.KeysDiscarded,
+		BytesSent:       progress.BytesSent,
+		PassesCompleted: progress.PassesCompleted,
+		LastError:       progress.LastError,
+	}
+
+	if !progress.StartedAt.IsZero() {
+		resp.StartedAt = progress.StartedAt.UnixMilli()
+	}
+
+	if !progress.FinishedAt.IsZero() {
+		resp.FinishedAt = progress.FinishedAt.UnixMilli()
+	}
+
+	return resp, nil
+}
+
+func toProtoNodes(ids []membership.NodeID) []uint32 {
+	nodes := make([]uint32, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, uint32(id))
+	}
+
+	return nodes
+}
@@ -0,0 +1,106 @@
+package rebalance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/rebalance/proto"
+)
+
+// state describes the distribution of the keys stored on this node. When the pass is active,
+// the keys are being moved from the ring of the "from" nodes to the ring of the "to" nodes,
+// and the keys up to and including the checkpoint have been moved already. Otherwise, the
+// keys are distributed by the ring of the "from" nodes.
+type state struct {
+	from       []membership.NodeID
+	to         []membership.NodeID
+	checkpoint string
+	active     bool
+}
+
+// loadState reads the state saved to the file. It returns nil if there is no file.
+func loadState(path string) (*state, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	msg := &proto.State{}
+	if err := protobuf.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	return &state{
+		from:       fromProtoNodes(msg.FromNodes),
+		to:         fromProtoNodes(msg.ToNodes),
+		checkpoint: msg.Checkpoint,
+		active:     msg.Active,
+	}, nil
+}
+
+// saveState writes the state into a temporary file, which then atomically replaces
+// the previous one, so that the state is never left partially written.
+func saveState(path string, st *state) error {
+	data, err := protobuf.Marshal(&proto.State{
+		FromNodes:  toProtoNodes(st.from),
+		ToNodes:    toProtoNodes(st.to),
+		Checkpoint: st.checkpoint,
+		Active:     st.active,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync state file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close state file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename state file: %w", err)
+	}
+
+	return nil
+}
+
+func toProtoNodes(ids []membership.NodeID) []uint32 {
+	nodes := make([]uint32, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, uint32(id))
+	}
+
+	return nodes
+}
+
+func fromProtoNodes(nodes []uint32) []membership.NodeID {
+	ids := make([]membership.NodeID, 0, len(nodes))
+	for _, id := range nodes {
+		ids = append(ids, membership.NodeID(id))
+	}
+
+	return ids
+}
@@ -54,3 +54,14 @@ func (l Level) String() string {
 		return ""
 	}
 }
+
+// Parse returns the consistency level by its string representation.
+func Parse(s string) (Level, error) {
+	for _, l := range []Level{One, Two, Quorum, All} {
+		if l.String() == s {
+			return l, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown consistency level: %s", s)
+}
@@ -12,3 +12,14 @@ func TestConsistencyLevel_Count(t *testing.T) {
 	assert.Equal(t, 5, Quorum.N(9))
 	assert.Equal(t, 9, All.N(9))
 }
+
+func TestParse(t *testing.T) {
+	for _, l := range []Level{One, Two, Quorum, All} {
+		parsed, err := Parse(l.String())
+		assert.NoError(t, err)
+		assert.Equal(t, l, parsed)
+	}
+
+	_, err := Parse("most")
+	assert.Error(t, err)
+}
@@ -24,6 +24,10 @@ const _ = grpc.SupportPackageIsVersion7
 type CoordinatorServiceClient interface {
 	ReplicatedGet(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
 	ReplicatedPut(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error)
+	ReplicatedMerge(ctx context.Context, in *MergeRequest, opts ...grpc.CallOption) (*MergeResponse, error)
+	ReplicatedMultiGet(ctx context.Context, in *MultiGetRequest, opts ...grpc.CallOption) (*MultiGetResponse, error)
+	ReplicatedMultiPut(ctx context.Context, in *MultiPutRequest, opts ...grpc.CallOption) (*MultiPutResponse, error)
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
 }
 
 type coordinatorServiceClient struct {
@@ -52,12 +56,52 @@ func (c *coordinatorServiceClient) ReplicatedPut(ctx context.Context, in *PutReq
 	return out, nil
 }
 
+func (c *coordinatorServiceClient) ReplicatedMerge(ctx context.Context, in *MergeRequest, opts ...grpc.CallOption) (*MergeResponse, error) {
+	out := new(MergeResponse)
+	err := c.cc.Invoke(ctx, "/replication.CoordinatorService/ReplicatedMerge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorServiceClient) ReplicatedMultiGet(ctx context.Context, in *MultiGetRequest, opts ...grpc.CallOption) (*MultiGetResponse, error) {
+	out := new(MultiGetResponse)
+	err := c.cc.Invoke(ctx, "/replication.CoordinatorService/ReplicatedMultiGet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorServiceClient) ReplicatedMultiPut(ctx context.Context, in *MultiPutRequest, opts ...grpc.CallOption) (*MultiPutResponse, error) {
+	out := new(MultiPutResponse)
+	err := c.cc.Invoke(ctx, "/replication.CoordinatorService/ReplicatedMultiPut", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorServiceClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, "/replication.CoordinatorService/Stats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CoordinatorServiceServer is the server API for CoordinatorService service.
 // All implementations must embed UnimplementedCoordinatorServiceServer
 // for forward compatibility
 type CoordinatorServiceServer interface {
 	ReplicatedGet(context.Context, *GetRequest) (*GetResponse, error)
 	ReplicatedPut(context.Context, *PutRequest) (*PutResponse, error)
+	ReplicatedMerge(context.Context, *MergeRequest) (*MergeResponse, error)
+	ReplicatedMultiGet(context.Context, *MultiGetRequest) (*MultiGetResponse, error)
+	ReplicatedMultiPut(context.Context, *MultiPutRequest) (*MultiPutResponse, error)
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
 	mustEmbedUnimplementedCoordinatorServiceServer()
 }
 
@@ -71,6 +115,18 @@ func (UnimplementedCoordinatorServiceServer) ReplicatedGet(context.Context, *Get
 func (UnimplementedCoordinatorServiceServer) ReplicatedPut(context.Context, *PutRequest) (*PutResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ReplicatedPut not implemented")
 }
+func (UnimplementedCoordinatorServiceServer) ReplicatedMerge(context.Context, *MergeRequest) (*MergeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplicatedMerge not implemented")
+}
+func (UnimplementedCoordinatorServiceServer) ReplicatedMultiGet(context.Context, *MultiGetRequest) (*MultiGetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplicatedMultiGet not implemented")
+}
+func (UnimplementedCoordinatorServiceServer) ReplicatedMultiPut(context.Context, *MultiPutRequest) (*MultiPutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplicatedMultiPut not implemented")
+}
+func (UnimplementedCoordinatorServiceServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
 func (UnimplementedCoordinatorServiceServer) mustEmbedUnimplementedCoordinatorServiceServer() {}
 
 // UnsafeCoordinatorServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -120,6 +176,78 @@ func _CoordinatorService_ReplicatedPut_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CoordinatorService_ReplicatedMerge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServiceServer).ReplicatedMerge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/replication.CoordinatorService/ReplicatedMerge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServiceServer).ReplicatedMerge(ctx, req.(*MergeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoordinatorService_ReplicatedMultiGet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MultiGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServiceServer).ReplicatedMultiGet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/replication.CoordinatorService/ReplicatedMultiGet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServiceServer).ReplicatedMultiGet(ctx, req.(*MultiGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoordinatorService_ReplicatedMultiPut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MultiPutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServiceServer).ReplicatedMultiPut(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/replication.CoordinatorService/ReplicatedMultiPut",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServiceServer).ReplicatedMultiPut(ctx, req.(*MultiPutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoordinatorService_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServiceServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/replication.CoordinatorService/Stats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServiceServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CoordinatorService_ServiceDesc is the grpc.ServiceDesc for CoordinatorService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -135,6 +263,22 @@ var CoordinatorService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReplicatedPut",
 			Handler:    _CoordinatorService_ReplicatedPut_Handler,
 		},
+		{
+			MethodName: "ReplicatedMerge",
+			Handler:    _CoordinatorService_ReplicatedMerge_Handler,
+		},
+		{
+			MethodName: "ReplicatedMultiGet",
+			Handler:    _CoordinatorService_ReplicatedMultiGet_Handler,
+		},
+		{
+			MethodName: "ReplicatedMultiPut",
+			Handler:    _CoordinatorService_ReplicatedMultiPut_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _CoordinatorService_Stats_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "replication/proto/replication.proto",
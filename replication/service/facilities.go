@@ -12,4 +12,5 @@ type Cluster interface {
 	Members() []membership.Member
 	SelfConn() clust.Conn
 	Conn(membership.NodeID) (clust.Conn, error)
+	MemberStats(membership.NodeID) (membership.Stats, bool)
 }
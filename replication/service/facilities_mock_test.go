@@ -8,7 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
-	"github.com/maxpoletaev/kv/clust"
+	clust "github.com/maxpoletaev/kv/clust"
 	membership "github.com/maxpoletaev/kv/membership"
 )
 
@@ -50,6 +50,21 @@ func (mr *MockClusterMockRecorder) Conn(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Conn", reflect.TypeOf((*MockCluster)(nil).Conn), arg0)
 }
 
+// MemberStats mocks base method.
+func (m *MockCluster) MemberStats(arg0 membership.NodeID) (membership.Stats, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MemberStats", arg0)
+	ret0, _ := ret[0].(membership.Stats)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// MemberStats indicates an expected call of MemberStats.
+func (mr *MockClusterMockRecorder) MemberStats(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MemberStats", reflect.TypeOf((*MockCluster)(nil).MemberStats), arg0)
+}
+
 // Members mocks base method.
 func (m *MockCluster) Members() []membership.Member {
 	m.ctrl.T.Helper()
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/log/level"
+
+	"github.com/maxpoletaev/kv/internal/grpcutil"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/replication/proto"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+// HedgedReadConfig defines how the reads are sent to the replicas.
+type HedgedReadConfig struct {
+	// Enabled makes the reads go to the minimum number of replicas needed for the consistency
+	// level, choosing the ones with the lowest average latency, rather than to all replicas.
+	// If a reply is late, one more request is sent to another replica.
+	Enabled bool
+	// Percentile of the latencies of the latest reads after which the extra request is sent,
+	// from 0 to 1. Until enough reads are measured, the extra request is not sent.
+	Percentile float64
+	// MinDelay is the lower bound of the delay before the extra request is sent, so that
+	// the hedges do not double the load when the replicas respond consistently fast.
+	MinDelay time.Duration
+}
+
+// DefaultHedgedReadConfig returns the default hedged read configuration.
+func DefaultHedgedReadConfig() HedgedReadConfig {
+	return HedgedReadConfig{
+		Enabled:    false,
+		Percentile: 0.95,
+		MinDelay:   time.Millisecond,
+	}
+}
+
+// hedgeCounters are the cumulative counters of the hedged reads, updated atomically.
+type hedgeCounters struct {
+	reads       int64
+	hedgesSent  int64
+	hedgesWon   int64
+	retriesSent int64
+}
+
+type hedgedReply struct {
+	result *nodeGetResult
+	hedge  bool
+	err    error
+}
+
+// hedgeDelay returns the time after which the extra read is sent, or false if the latencies
+// of not enough reads are known yet.
+func (s *ReplicationService) hedgeDelay() (time.Duration, bool) {
+	delay, ok := s.latencies.percentile(s.hedgedReads.Percentile)
+	if !ok {
+		return 0, false
+	}
+
+	if delay < s.hedgedReads.MinDelay {
+		delay = s.hedgedReads.MinDelay
+	}
+
+	return delay, true
+}
+
+// readOne reads the key from the replica, recording the latency of the successful reads.
+func (s *ReplicationService) readOne(ctx context.Context, replica *membership.Member, key string) (*nodeGetResult, error) {
+	conn, err := s.cluster.Conn(replica.ID)
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to get connection", "name", replica.Name, "err", err)
+		return nil, err
+	}
+
+	start := time.Now()
+
+	res, err := conn.Get(ctx, &storagepb.GetRequest{Key: key})
+	if err != nil {
+		if !grpcutil.IsCanceled(err) {
+			s.logger.Log("msg", "failed to read from replica", "name", replica.Name, "err", err)
+		}
+
+		return nil, err
+	}
+
+	s.latencies.observe(time.Since(start))
+
+	return &nodeGetResult{
+		NodeID: replica.ID,
+		Values: res.Value,
+	}, nil
+}
+
+// readHedged reads the key from the minAcks replicas with the best scores. If the
+// replies do not arrive within the hedge delay, one more replica is read, and whichever replies
+// come first are used. A failed read is replaced with a read from the next replica, so that the
+// reads still succeed as long as enough replicas are available.
+func (s *ReplicationService) readHedged(ctx context.Context, key string, replicas []membership.Member, minAcks int) ([]*nodeGetResult, error) {
+	atomic.AddInt64(&s.hedgeCounters.reads, 1)
+
+	// The replicas that have become slow are read last, if at all.
+	candidates, _ := s.rankReplicas(replicas)
+
+	readCtx, cancelRead := context.WithTimeout(ctx, s.readTimeout)
+	defer cancelRead()
+
+	// Buffered, so that the late replies do not block once the reads are done.
+	replies := make(chan hedgedReply, len(candidates))
+	sent, inflight := 0, 0
+
+	send := func(hedge bool) bool {
+		if sent == len(candidates) {
+			return false
+		}
+
+		go func(replica *membership.Member) {
+			res, err := s.readOne(readCtx, replica, key)
+			replies <- hedgedReply{result: res, hedge: hedge, err: err}
+		}(candidates[sent])
+
+		sent++
+		inflight++
+
+		return true
+	}
+
+	for i := 0; i < minAcks; i++ {
+		send(false)
+	}
+
+	var hedgeTimer <-chan time.Time
+
+	if delay, ok := s.hedgeDelay(); ok && sent < len(candidates) {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		hedgeTimer = timer.C
+	}
+
+	results := make([]*nodeGetResult, 0, minAcks)
+
+	for len(results) < minAcks {
+		if inflight == 0 {
+			return nil, errLevelNotSatisfied
+		}
+
+		select {
+		case r := <-replies:
+			inflight--
+
+			if r.err != nil {
+				if send(false) {
+					atomic.AddInt64(&s.hedgeCounters.retriesSent, 1)
+				}
+
+				continue
+			}
+
+			// The hedge won if it has replied while another read is still pending.
+			if r.hedge && inflight > 0 {
+				atomic.AddInt64(&s.hedgeCounters.hedgesWon, 1)
+			}
+
+			results = append(results, r.result)
+		case <-hedgeTimer:
+			hedgeTimer = nil
+
+			if send(true) {
+				atomic.AddInt64(&s.hedgeCounters.hedgesSent, 1)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return results, nil
+}
+
+// Stats returns the counters of the hedged reads.
+func (s *ReplicationService) Stats(ctx context.Context, req *proto.StatsRequest) (*proto.StatsResponse, error) {
+	delay, _ := s.hedgeDelay()
+
+	return &proto.StatsResponse{
+		HedgedModeReads: atomic.LoadInt64(&s.hedgeCounters.reads),
+		HedgesSent:      atomic.LoadInt64(&s.hedgeCounters.hedgesSent),
+		HedgesWon:       atomic.LoadInt64(&s.hedgeCounters.hedgesWon),
+		RetriesSent:     atomic.LoadInt64(&s.hedgeCounters.retriesSent),
+		HedgeDelayUs:    delay.Microseconds(),
+	}, nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clustmock "github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/replication/consistency"
+	"github.com/maxpoletaev/kv/replication/proto"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+func setupHedgedReads(ctrl *gomock.Controller) (*MockCluster, *ReplicationService) {
+	c := NewMockCluster(ctrl)
+	c.EXPECT().Members().Return([]membership.Member{
+		{ID: 1, Name: "node1", Status: membership.StatusHealthy},
+		{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+		{ID: 3, Name: "node3", Status: membership.StatusHealthy},
+	})
+
+	conf := DefaultHedgedReadConfig()
+	conf.Enabled = true
+
+	s := New(c, log.NewNopLogger(), consistency.Quorum, consistency.Quorum, WithHedgedReads(conf))
+
+	// Node 1 is the fastest one, and node 3 is the slowest one.
+	c.EXPECT().MemberStats(membership.NodeID(1)).Return(membership.Stats{Latency: time.Millisecond}, true).AnyTimes()
+	c.EXPECT().MemberStats(membership.NodeID(2)).Return(membership.Stats{Latency: 2 * time.Millisecond}, true).AnyTimes()
+	c.EXPECT().MemberStats(membership.NodeID(3)).Return(membership.Stats{Latency: time.Second}, true).AnyTimes()
+
+	for i := 0; i < latencyMinSamples/2; i++ {
+		s.latencies.observe(time.Millisecond)
+		s.latencies.observe(2 * time.Millisecond)
+	}
+
+	s.latencies.observe(time.Second)
+
+	return c, s
+}
+
+func replyWith(value *storagepb.VersionedValue) func(context.Context, *storagepb.GetRequest) (*storagepb.GetResponse, error) {
+	return func(ctx context.Context, req *storagepb.GetRequest) (*storagepb.GetResponse, error) {
+		return &storagepb.GetResponse{Value: []*storagepb.VersionedValue{value}}, nil
+	}
+}
+
+func hangUntilCanceled(ctx context.Context, req *storagepb.GetRequest) (*storagepb.GetResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestReplicatedGet_HedgedReadsFastestReplicas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c, s := setupHedgedReads(ctrl)
+	value := &storagepb.VersionedValue{Version: vclock.NewEncoded(vclock.V{1: 1}), Data: []byte("value")}
+
+	for _, id := range []membership.NodeID{1, 2} {
+		conn := clustmock.NewMockClient(ctrl)
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(replyWith(value))
+		c.EXPECT().Conn(id).Return(conn, nil)
+	}
+
+	got, err := s.ReplicatedGet(context.Background(), &proto.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), got.Values[0].Data)
+
+	stats, err := s.Stats(context.Background(), &proto.StatsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.HedgedModeReads)
+	assert.Equal(t, int64(0), stats.HedgesSent)
+	assert.Equal(t, int64(2000), stats.HedgeDelayUs)
+}
+
+func TestReplicatedGet_HedgeFires(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c, s := setupHedgedReads(ctrl)
+	value := &storagepb.VersionedValue{Version: vclock.NewEncoded(vclock.V{1: 1}), Data: []byte("value")}
+
+	// Node 1 has become slow, so the read waits for the hedge sent to node 3.
+	conn1 := clustmock.NewMockClient(ctrl)
+	conn1.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(hangUntilCanceled)
+	c.EXPECT().Conn(membership.NodeID(1)).Return(conn1, nil)
+
+	for _, id := range []membership.NodeID{2, 3} {
+		conn := clustmock.NewMockClient(ctrl)
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(replyWith(value))
+		c.EXPECT().Conn(id).Return(conn, nil)
+	}
+
+	got, err := s.ReplicatedGet(context.Background(), &proto.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), got.Values[0].Data)
+
+	stats, err := s.Stats(context.Background(), &proto.StatsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.HedgesSent)
+	assert.Equal(t, int64(1), stats.HedgesWon)
+	assert.Equal(t, int64(0), stats.RetriesSent)
+}
+
+func TestReplicatedGet_HedgedReadRetries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c, s := setupHedgedReads(ctrl)
+	value := &storagepb.VersionedValue{Version: vclock.NewEncoded(vclock.V{1: 1}), Data: []byte("value")}
+
+	// The failed read from node 1 is replaced with a read from node 3.
+	conn1 := clustmock.NewMockClient(ctrl)
+	conn1.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
+	c.EXPECT().Conn(membership.NodeID(1)).Return(conn1, nil)
+
+	for _, id := range []membership.NodeID{2, 3} {
+		conn := clustmock.NewMockClient(ctrl)
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(replyWith(value))
+		c.EXPECT().Conn(id).Return(conn, nil)
+	}
+
+	_, err := s.ReplicatedGet(context.Background(), &proto.GetRequest{Key: "key"})
+	require.NoError(t, err)
+
+	stats, err := s.Stats(context.Background(), &proto.StatsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.RetriesSent)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyWindow is the number of the latest reads the percentiles are computed over.
+	latencyWindow = 1000
+	// latencyMinSamples is the number of reads to be measured before the percentiles are known.
+	latencyMinSamples = 100
+	// latencyRefresh is the number of reads measured before the percentiles are recomputed.
+	latencyRefresh = 100
+)
+
+// latencyTracker keeps the latencies of the latest reads of all replicas to compute the
+// percentiles. Unlike the statistics of the cluster members, it only covers the reads.
+// The window is only sorted once every latencyRefresh reads, since the percentiles are
+// needed by every read, and barely move between the consecutive ones.
+type latencyTracker struct {
+	mut    sync.Mutex
+	window []time.Duration
+	next   int
+	sorted []time.Duration
+	stale  int
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{
+		window: make([]time.Duration, 0, latencyWindow),
+	}
+}
+
+// observe records the latency of a successful read.
+func (t *latencyTracker) observe(d time.Duration) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if len(t.window) < latencyWindow {
+		t.window = append(t.window, d)
+	} else {
+		t.window[t.next] = d
+		t.next = (t.next + 1) % latencyWindow
+	}
+
+	t.stale++
+}
+
+// percentile returns the latency the given fraction of the latest reads fit into, from 0 to 1.
+// It returns false until enough reads are measured for the result to make sense.
+func (t *latencyTracker) percentile(p float64) (time.Duration, bool) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if len(t.window) < latencyMinSamples {
+		return 0, false
+	}
+
+	if t.sorted == nil || t.stale >= latencyRefresh {
+		t.sorted = append(t.sorted[:0], t.window...)
+		t.stale = 0
+
+		sort.Slice(t.sorted, func(i, j int) bool {
+			return t.sorted[i] < t.sorted[j]
+		})
+	}
+
+	idx := int(math.Ceil(p*float64(len(t.sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(t.sorted) {
+		idx = len(t.sorted) - 1
+	}
+
+	return t.sorted[idx], true
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyTracker_Percentile(t *testing.T) {
+	tracker := newLatencyTracker()
+
+	for i := 1; i < latencyMinSamples; i++ {
+		tracker.observe(time.Duration(i) * time.Millisecond)
+	}
+
+	_, ok := tracker.percentile(0.5)
+	require.False(t, ok, "not enough samples")
+
+	tracker.observe(latencyMinSamples * time.Millisecond)
+
+	p50, ok := tracker.percentile(0.5)
+	require.True(t, ok)
+	assert.Equal(t, 50*time.Millisecond, p50)
+
+	p99, _ := tracker.percentile(0.99)
+	assert.Equal(t, 99*time.Millisecond, p99)
+
+	// The oldest samples are replaced once the window is full.
+	for i := 0; i < latencyWindow; i++ {
+		tracker.observe(time.Second)
+	}
+
+	p50, _ = tracker.percentile(0.5)
+	assert.Equal(t, time.Second, p50)
+
+	// The percentiles are only recomputed once enough new reads are measured.
+	for i := 1; i < latencyRefresh; i++ {
+		tracker.observe(time.Millisecond)
+	}
+
+	p0, _ := tracker.percentile(0)
+	assert.Equal(t, time.Second, p0)
+
+	tracker.observe(time.Millisecond)
+
+	p0, _ = tracker.percentile(0)
+	assert.Equal(t, time.Millisecond, p0)
+}
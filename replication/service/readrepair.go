@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-kit/log/level"
+
+	"github.com/maxpoletaev/kv/membership"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+// ReadRepairMode defines whether the reads wait for the stale replicas to be repaired.
+type ReadRepairMode int
+
+const (
+	// ReadRepairSync makes the read wait for the repairs until the number of replicas
+	// having the latest value satisfies the consistency level of the read.
+	ReadRepairSync ReadRepairMode = iota
+
+	// ReadRepairAsync makes the read respond as soon as the replies satisfy the consistency
+	// level. The repairs are queued to the background workers.
+	ReadRepairAsync
+)
+
+// String returns string representation of the read repair mode.
+func (m ReadRepairMode) String() string {
+	switch m {
+	case ReadRepairSync:
+		return "sync"
+	case ReadRepairAsync:
+		return "async"
+	default:
+		return ""
+	}
+}
+
+// ParseReadRepairMode returns the read repair mode by its string representation.
+func ParseReadRepairMode(s string) (ReadRepairMode, error) {
+	for _, m := range []ReadRepairMode{ReadRepairSync, ReadRepairAsync} {
+		if m.String() == s {
+			return m, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown read repair mode: %s", s)
+}
+
+// ReadRepairConfig defines how the stale replicas found by the reads are repaired.
+type ReadRepairConfig struct {
+	// Mode defines whether the reads wait for the repairs.
+	Mode ReadRepairMode
+	// Chance is the probability of a read to repair the stale replicas it has found, from 0
+	// to 1. Lower values reduce the number of writes caused by the reads of inconsistent keys.
+	Chance float64
+	// Workers is the number of background workers running the asynchronous repairs.
+	Workers int
+	// QueueSize is the number of asynchronous repairs waiting for a worker. The repairs
+	// found while the queue is full are dropped.
+	QueueSize int
+}
+
+// DefaultReadRepairConfig returns the default read repair configuration.
+func DefaultReadRepairConfig() ReadRepairConfig {
+	return ReadRepairConfig{
+		Mode:      ReadRepairSync,
+		Chance:    1,
+		Workers:   8,
+		QueueSize: 1024,
+	}
+}
+
+var (
+	errRepairPending   = errors.New("repair of the key is already pending")
+	errRepairQueueFull = errors.New("read repair queue is full")
+)
+
+type repairTask struct {
+	key      string
+	values   []*storagepb.VersionedValue
+	replicas []membership.Member
+}
+
+// repairPool runs the asynchronous repairs on a fixed number of workers. Only one repair of
+// a key can be pending at a time, the repairs of the key found meanwhile are dropped, since
+// the pending one brings the replicas up to date anyway, or the next read finds them again.
+type repairPool struct {
+	tasks   chan repairTask
+	repair  func(repairTask)
+	wg      sync.WaitGroup
+	mut     sync.Mutex
+	pending map[string]bool
+}
+
+func newRepairPool(workers, queueSize int, repair func(repairTask)) *repairPool {
+	p := &repairPool{
+		tasks:   make(chan repairTask, queueSize),
+		repair:  repair,
+		pending: make(map[string]bool),
+	}
+
+	p.wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+
+			for task := range p.tasks {
+				p.repair(task)
+
+				p.mut.Lock()
+				delete(p.pending, task.key)
+				p.mut.Unlock()
+			}
+		}()
+	}
+
+	return p
+}
+
+// submit queues the repair, unless the repair of the same key is pending or the queue is full.
+func (p *repairPool) submit(task repairTask) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.pending[task.key] {
+		return errRepairPending
+	}
+
+	select {
+	case p.tasks <- task:
+		p.pending[task.key] = true
+		return nil
+	default:
+		return errRepairQueueFull
+	}
+}
+
+// close waits for the queued repairs to finish and stops the workers.
+func (p *repairPool) close() {
+	close(p.tasks)
+	p.wg.Wait()
+}
+
+// repairSync repairs the replicas concurrently, and waits until the given number of them are
+// repaired. The rest are repaired in the background, until the write timeout fires.
+func (s *ReplicationService) repairSync(ctx context.Context, task repairTask, needed int) error {
+	repairCtx, cancelRepair := context.WithTimeout(context.Background(), s.writeTimeout)
+	repairResults := make(chan error, len(task.replicas))
+	wg := sync.WaitGroup{}
+
+	for i := range task.replicas {
+		wg.Add(1)
+
+		go func(replica *membership.Member) {
+			defer wg.Done()
+			repairResults <- s.repairReplica(repairCtx, replica, task.key, task.values)
+		}(&task.replicas[i])
+	}
+
+	go func() {
+		wg.Wait()
+		cancelRepair()
+		close(repairResults)
+	}()
+
+	for needed > 0 {
+		select {
+		case err, ok := <-repairResults:
+			if !ok {
+				return errLevelNotSatisfied
+			}
+
+			if err == nil {
+				needed--
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// repairAsync repairs the replicas one by one on a worker of the pool.
+func (s *ReplicationService) repairAsync(task repairTask) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.writeTimeout)
+	defer cancel()
+
+	for i := range task.replicas {
+		_ = s.repairReplica(ctx, &task.replicas[i], task.key, task.values)
+	}
+}
+
+// repairReplica sends all the latest values of the key to the stale replica. The replica keeps
+// the values it already has, unless they are covered by the ones being sent.
+func (s *ReplicationService) repairReplica(ctx context.Context, replica *membership.Member, key string, values []*storagepb.VersionedValue) error {
+	conn, err := s.cluster.Conn(replica.ID)
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to get connection", "name", replica.Name, "err", err)
+		return err
+	}
+
+	req := &storagepb.RepairRequest{
+		Key:    key,
+		Values: values,
+	}
+
+	if _, err := conn.Repair(ctx, req); err != nil {
+		s.logger.Log("msg", "failed to repair", "replica", replica.Name, "err", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clustmock "github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/replication/consistency"
+	"github.com/maxpoletaev/kv/replication/proto"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+func TestRepairPool_Submit(t *testing.T) {
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	repaired := make([]string, 0)
+
+	pool := newRepairPool(1, 1, func(task repairTask) {
+		started <- task.key
+		<-release
+		repaired = append(repaired, task.key)
+	})
+
+	require.NoError(t, pool.submit(repairTask{key: "a"}))
+	require.Equal(t, "a", <-started)
+
+	// The repair of the key is already running.
+	assert.ErrorIs(t, pool.submit(repairTask{key: "a"}), errRepairPending)
+
+	// The only worker is busy, so the second key fills the queue.
+	require.NoError(t, pool.submit(repairTask{key: "b"}))
+	assert.ErrorIs(t, pool.submit(repairTask{key: "c"}), errRepairQueueFull)
+
+	close(release)
+	pool.close()
+
+	assert.Equal(t, []string{"a", "b"}, repaired)
+}
+
+func TestParseReadRepairMode(t *testing.T) {
+	for _, m := range []ReadRepairMode{ReadRepairSync, ReadRepairAsync} {
+		parsed, err := ParseReadRepairMode(m.String())
+		require.NoError(t, err)
+		assert.Equal(t, m, parsed)
+	}
+
+	_, err := ParseReadRepairMode("never")
+	assert.Error(t, err)
+}
+
+// setupStaleReplica sets up three replicas, where node 2 has an outdated value.
+func setupStaleReplica(ctrl *gomock.Controller, c *MockCluster) *clustmock.MockClient {
+	c.EXPECT().Members().Return([]membership.Member{
+		{ID: 1, Name: "node1", Status: membership.StatusHealthy},
+		{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+		{ID: 3, Name: "node3", Status: membership.StatusHealthy},
+	})
+
+	newValue := &storagepb.VersionedValue{Version: vclock.NewEncoded(vclock.V{1: 2}), Data: []byte("new")}
+	oldValue := &storagepb.VersionedValue{Version: vclock.NewEncoded(vclock.V{1: 1}), Data: []byte("old")}
+
+	var staleConn *clustmock.MockClient
+
+	for id, value := range map[membership.NodeID]*storagepb.VersionedValue{1: newValue, 2: oldValue, 3: newValue} {
+		conn := clustmock.NewMockClient(ctrl)
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&storagepb.GetResponse{
+			Value: []*storagepb.VersionedValue{value},
+		}, nil)
+
+		c.EXPECT().Conn(id).Return(conn, nil).AnyTimes()
+
+		if id == 2 {
+			staleConn = conn
+		}
+	}
+
+	return staleConn
+}
+
+func TestReplicatedGet_AsyncRepair(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := NewMockCluster(ctrl)
+	staleConn := setupStaleReplica(ctrl, c)
+	release := make(chan struct{})
+
+	// The repair blocks, but the read does not wait for it.
+	staleConn.EXPECT().Repair(gomock.Any(), &storagepb.RepairRequest{
+		Key: "key",
+		Values: []*storagepb.VersionedValue{
+			{
+				Version: vclock.NewEncoded(vclock.V{1: 2}),
+				Data:    []byte("new"),
+			},
+		},
+	}).DoAndReturn(func(ctx context.Context, req *storagepb.RepairRequest) (*storagepb.RepairResponse, error) {
+		<-release
+		return &storagepb.RepairResponse{}, nil
+	})
+
+	conf := DefaultReadRepairConfig()
+	conf.Mode = ReadRepairAsync
+
+	s := New(c, log.NewNopLogger(), consistency.All, consistency.Quorum, WithReadRepair(conf))
+
+	got, err := s.ReplicatedGet(context.Background(), &proto.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new"), got.Values[0].Data)
+
+	close(release)
+	s.Close()
+}
+
+func TestReplicatedGet_RepairChance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := NewMockCluster(ctrl)
+	staleConn := setupStaleReplica(ctrl, c)
+	staleConn.EXPECT().Repair(gomock.Any(), gomock.Any()).Times(0)
+
+	conf := DefaultReadRepairConfig()
+	conf.Chance = 0.1
+
+	s := New(c, log.NewNopLogger(), consistency.All, consistency.Quorum, WithReadRepair(conf))
+	s.randFloat = func() float64 { return 0.5 }
+
+	got, err := s.ReplicatedGet(context.Background(), &proto.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new"), got.Values[0].Data)
+}
+
+func TestReplicatedGet_RepairSiblings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := NewMockCluster(ctrl)
+	c.EXPECT().Members().Return([]membership.Member{
+		{ID: 1, Name: "node1", Status: membership.StatusHealthy},
+		{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+		{ID: 3, Name: "node3", Status: membership.StatusHealthy},
+	})
+
+	first := &storagepb.VersionedValue{Version: vclock.NewEncoded(vclock.V{1: 1}), Data: []byte("first")}
+	second := &storagepb.VersionedValue{Version: vclock.NewEncoded(vclock.V{2: 1}), Data: []byte("second")}
+
+	// Node 1 has both siblings, node 2 is missing one of them, and node 3 has nothing.
+	replies := map[membership.NodeID][]*storagepb.VersionedValue{
+		1: {first, second},
+		2: {second},
+		3: nil,
+	}
+
+	for id, values := range replies {
+		conn := clustmock.NewMockClient(ctrl)
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&storagepb.GetResponse{Value: values}, nil)
+
+		if id != 1 {
+			// The siblings come in the order the replicas have replied.
+			conn.EXPECT().Repair(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *storagepb.RepairRequest) (*storagepb.RepairResponse, error) {
+					assert.Equal(t, "key", req.Key)
+					assert.ElementsMatch(t, []*storagepb.VersionedValue{first, second}, req.Values)
+					return &storagepb.RepairResponse{}, nil
+				},
+			)
+		}
+
+		c.EXPECT().Conn(id).Return(conn, nil).AnyTimes()
+	}
+
+	s := New(c, log.NewNopLogger(), consistency.All, consistency.Quorum)
+
+	got, err := s.ReplicatedGet(context.Background(), &proto.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	require.Len(t, got.Values, 2)
+}
@@ -6,12 +6,13 @@ import (
 	"sync"
 
 	"github.com/go-kit/log/level"
-	"github.com/maxpoletaev/kv/internal/generic"
-	"github.com/maxpoletaev/kv/internal/grpcutil"
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/hlc"
 	"github.com/maxpoletaev/kv/internal/set"
 	"github.com/maxpoletaev/kv/internal/vclock"
 	"github.com/maxpoletaev/kv/membership"
 	"github.com/maxpoletaev/kv/replication/proto"
+	"github.com/maxpoletaev/kv/storage"
 	storagepb "github.com/maxpoletaev/kv/storage/proto"
 )
 
@@ -28,64 +29,38 @@ func (s *ReplicationService) ReplicatedGet(ctx context.Context, req *proto.GetRe
 		return nil, err
 	}
 
-	replicas := s.cluster.Members()
+	readLevel, err := consistencyLevel(req.Consistency, s.readLevel)
+	if err != nil {
+		return nil, err
+	}
 
-	minAcks := s.readLevel.N(len(replicas))
+	replicas := s.replicas(req.Key)
+
+	minAcks := readLevel.N(len(replicas))
 
 	if countAlive(replicas) < minAcks {
 		return nil, errNotEnoughReplicas
 	}
 
-	readCtx, cacnelRead := context.WithTimeout(ctx, s.readTimeout)
-
-	readResults := make(chan *nodeGetResult)
-
-	wg := sync.WaitGroup{}
-
-	for i := range replicas {
-		replica := &replicas[i]
-		if !replica.IsReacheable() {
-			continue
-		}
-
-		wg.Add(1)
+	var results []*nodeGetResult
 
-		go func(replica *membership.Member) {
-			defer wg.Done()
-
-			select {
-			case <-readCtx.Done():
-				return
-			default:
-			}
-
-			conn, err := s.cluster.Conn(replica.ID)
-			if err != nil {
-				level.Warn(s.logger).Log("msg", "failed to get connection", "name", replica.Name, "err", err)
-				return
-			}
-
-			res, err := conn.Get(readCtx, &storagepb.GetRequest{Key: req.Key})
-			if err != nil {
-				if !grpcutil.IsCanceled(err) {
-					s.logger.Log("msg", "failed to read from replica", "name", replica.Name, "err", err)
-				}
-
-				return
-			}
+	if s.hedgedReads.Enabled {
+		results, err = s.readHedged(ctx, req.Key, replicas, minAcks)
+	} else {
+		results, err = s.readAll(ctx, req.Key, replicas, minAcks)
+	}
 
-			readResults <- &nodeGetResult{
-				NodeID: replica.ID,
-				Values: res.Value,
-			}
-		}(replica)
+	if err != nil {
+		return nil, err
 	}
 
-	go func() {
-		wg.Wait()
-		cacnelRead()
-		close(readResults)
-	}()
+	return s.resolveRead(ctx, req.Key, replicas, results, minAcks)
+}
+
+// resolveRead merges the values of the key received from the replicas into the response,
+// and repairs the replicas that have returned outdated or incomplete values.
+func (s *ReplicationService) resolveRead(ctx context.Context, key string, replicas []membership.Member,
+	results []*nodeGetResult, minAcks int) (*proto.GetResponse, error) {
 
 	// All values we received from the replicas.
 	receivedValues := make([]nodeValue, 0)
@@ -94,34 +69,18 @@ func (s *ReplicationService) ReplicatedGet(ctx context.Context, req *proto.GetRe
 	repliedReplicas := make(set.Set[membership.NodeID])
 	emptyReplicas := make(set.Set[membership.NodeID])
 
-readloop:
-	for {
-		select {
-		case r := <-readResults:
-			if r != nil {
-				repliedReplicas.Add(r.NodeID)
-
-				if len(r.Values) == 0 {
-					emptyReplicas.Add(r.NodeID)
-				}
-
-				for i := range r.Values {
-					receivedValues = append(receivedValues, nodeValue{
-						NodeID:         r.NodeID,
-						VersionedValue: r.Values[i],
-					})
-				}
-
-				// Got enough here, no need to wait for other reads.
-				if len(repliedReplicas) == minAcks {
-					cacnelRead()
-					break readloop
-				}
-			} else {
-				return nil, errLevelNotSatisfied
-			}
-		case <-ctx.Done():
-			return nil, ctx.Err()
+	for _, r := range results {
+		repliedReplicas.Add(r.NodeID)
+
+		if len(r.Values) == 0 {
+			emptyReplicas.Add(r.NodeID)
+		}
+
+		for i := range r.Values {
+			receivedValues = append(receivedValues, nodeValue{
+				NodeID:         r.NodeID,
+				VersionedValue: r.Values[i],
+			})
 		}
 	}
 
@@ -131,73 +90,59 @@ readloop:
 		return nil, err
 	}
 
-	// Replicas that did not retuned any value or returned an outdated value should be repaired.
+	// The conflicting values of the last-write-wins keys collapse into the winner,
+	// so that the replicas having any other value are repaired with the winner.
+	if s.keyspaces.Lookup(key).LastWriteWins {
+		if mergedValues, err = resolveLastWriteWins(mergedValues, receivedValues); err != nil {
+			return nil, err
+		}
+	}
+
+	// Replicas that did not retuned any value or are missing any of the latest values should be repaired.
 	repairSet := set.FromSlice(mergedValues.StaleReplicas).And(emptyReplicas)
 
-	// Read repair, only if there are no conflicts.
-	if len(mergedValues.Values) == 1 && len(repairSet) > 0 {
-		repairCtx, cancelRepair := context.WithTimeout(ctx, s.writeTimeout)
-		repairResults := make(chan *nodePutResult)
-		data := mergedValues.Values[0].Data
-		wg := sync.WaitGroup{}
+	// The replicas are repaired with all the latest values at once, keeping the original version
+	// of each, which may include the dot, rather than the merged version returned to the client.
+	// This way the replicas converge on the same set of siblings.
+	if len(mergedValues.Values) > 0 && len(repairSet) > 0 && s.randFloat() < s.readRepair.Chance {
+		stale := make([]membership.Member, 0, len(repairSet))
 
 		for i := range replicas {
-			replica := &replicas[i]
-			if !repairSet.Has(replica.ID) {
-				continue
+			if repairSet.Has(replicas[i].ID) {
+				stale = append(stale, replicas[i])
 			}
+		}
 
-			wg.Add(1)
-
-			go func(replica *membership.Member) {
-				defer wg.Done()
-
-				conn, err := s.cluster.Conn(replica.ID)
-				if err != nil {
-					level.Warn(s.logger).Log("msg", "failed to get connection", "name", replica.Name, "err", err)
-					return
-				}
-
-				version, err := put(repairCtx, conn, req.Key, data, mergedValues.Version, false)
-				if err != nil {
-					s.logger.Log("msg", "failed to repair", "replica", replica.Name, "err", err)
-					return
-				}
-
-				repairResults <- &nodePutResult{
-					NodeID:  replica.ID,
-					Version: version,
-				}
-			}(replica)
+		values := make([]*storagepb.VersionedValue, 0, len(mergedValues.Values))
+		for _, value := range mergedValues.Values {
+			values = append(values, value.VersionedValue)
 		}
 
-		go func() {
-			wg.Wait()
-			cancelRepair()
-			close(repairResults)
-		}()
+		task := repairTask{
+			key:      key,
+			values:   values,
+			replicas: stale,
+		}
 
-	repairloop:
-		for {
-			select {
-			case r := <-repairResults:
-				if r != nil {
-					repairSet.Remove(r.NodeID)
-					if len(repairSet) == 0 {
-						break repairloop
-					}
-				} else {
-					return nil, errLevelNotSatisfied
-				}
-			case <-ctx.Done():
-				return nil, ctx.Err()
+		if s.repairs != nil {
+			if err := s.repairs.submit(task); err != nil {
+				level.Debug(s.logger).Log("msg", "read repair skipped", "key", key, "err", err)
+			}
+		} else {
+			// Only the repairs needed to satisfy the consistency level are waited for.
+			needed := minAcks - (len(repliedReplicas) - len(repairSet))
+			if err := s.repairSync(ctx, task, needed); err != nil {
+				return nil, err
 			}
 		}
 	}
 
 	protoValues := make([]*proto.Value, 0, len(mergedValues.Values))
 	for _, value := range mergedValues.Values {
-		protoValues = append(protoValues, &proto.Value{Data: value.VersionedValue.Data})
+		protoValues = append(protoValues, &proto.Value{
+			Data:      value.VersionedValue.Data,
+			Timestamp: value.VersionedValue.Timestamp,
+		})
 	}
 
 	return &proto.GetResponse{
@@ -206,6 +151,82 @@ readloop:
 	}, nil
 }
 
+// readAll reads the key from all reachable replicas, and returns as soon as minAcks of them reply.
+// With the dynamic snitch, the replicas that have become slow are not read, unless the rest of
+// the replicas are not enough to satisfy the consistency level.
+func (s *ReplicationService) readAll(ctx context.Context, key string, replicas []membership.Member, minAcks int) ([]*nodeGetResult, error) {
+	targets := make([]*membership.Member, 0, len(replicas))
+
+	for i := range replicas {
+		if replicas[i].IsReacheable() {
+			targets = append(targets, &replicas[i])
+		}
+	}
+
+	if s.snitch.Enabled {
+		if ranked, good := s.rankReplicas(replicas); good >= minAcks {
+			targets = ranked[:good]
+		}
+	}
+
+	readCtx, cacnelRead := context.WithTimeout(ctx, s.readTimeout)
+
+	readResults := make(chan *nodeGetResult)
+
+	wg := sync.WaitGroup{}
+
+	for _, replica := range targets {
+		wg.Add(1)
+
+		go func(replica *membership.Member) {
+			defer wg.Done()
+
+			select {
+			case <-readCtx.Done():
+				return
+			default:
+			}
+
+			res, err := s.readOne(readCtx, replica, key)
+			if err != nil {
+				return
+			}
+
+			select {
+			case readResults <- res:
+			case <-readCtx.Done():
+			}
+		}(replica)
+	}
+
+	go func() {
+		wg.Wait()
+		cacnelRead()
+		close(readResults)
+	}()
+
+	results := make([]*nodeGetResult, 0, minAcks)
+
+	for {
+		select {
+		case r := <-readResults:
+			if r == nil {
+				return nil, errLevelNotSatisfied
+			}
+
+			results = append(results, r)
+
+			// Got enough here, no need to wait for other reads.
+			if len(results) == minAcks {
+				cacnelRead()
+				return results, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
 type mergeResult struct {
 	Version       string
 	Values        []nodeValue
@@ -213,14 +234,16 @@ type mergeResult struct {
 }
 
 func mergeVersions(values []nodeValue) (mergeResult, error) {
-	valueVersion := make([]*vclock.Vector, len(values))
+	valueVersion := make([]*dvv.Version, len(values))
 
-	// Keep decoded version for each value.
+	// Keep decoded version for each value. The versions are compared as dotted
+	// version vectors, which is the same as comparing plain vector clocks unless
+	// the dotted versions are enabled for the key.
 	for i, v := range values {
-		var ver *vclock.Vector
+		var ver *dvv.Version
 		var err error
 
-		if ver, err = vclock.Decode(v.Version); err != nil {
+		if ver, err = dvv.Decode(v.Version); err != nil {
 			return mergeResult{}, fmt.Errorf("invalid version: %w", err)
 		}
 
@@ -230,7 +253,7 @@ func mergeVersions(values []nodeValue) (mergeResult, error) {
 	// Merge all versions into one.
 	mergedVersion := vclock.New()
 	for i := 0; i < len(values); i++ {
-		mergedVersion = vclock.Merge(mergedVersion, valueVersion[i])
+		mergedVersion = vclock.Merge(mergedVersion, valueVersion[i].Join())
 	}
 
 	if len(values) < 2 {
@@ -240,37 +263,130 @@ func mergeVersions(values []nodeValue) (mergeResult, error) {
 		}, nil
 	}
 
-	var staleReplicas []membership.NodeID
-
-	uniqueValues := make(map[string]nodeValue)
+	uniqueValues := make([]nodeValue, 0, len(values))
+	uniqueVersions := make([]*dvv.Version, 0, len(values))
 
-	// Identify the highest version among all values.
-	highest := valueVersion[0]
-	for i := 1; i < len(values); i++ {
-		if vclock.Compare(highest, valueVersion[i]) == vclock.Before {
-			highest = valueVersion[i]
-		}
-	}
+	// The surviving versions each replica has returned, by their index in uniqueVersions.
+	replicaVersions := make(map[membership.NodeID]set.Set[int])
+	replicaOrder := make([]membership.NodeID, 0, len(values))
 
 	for i := 0; i < len(values); i++ {
 		value := values[i]
 
-		// Ignore the values that clearly precede the highest version.
-		// Keep track of the replicas that returned outdated values.
-		if vclock.Compare(valueVersion[i], highest) == vclock.Before {
-			staleReplicas = append(staleReplicas, value.NodeID)
+		if _, ok := replicaVersions[value.NodeID]; !ok {
+			replicaVersions[value.NodeID] = make(set.Set[int])
+			replicaOrder = append(replicaOrder, value.NodeID)
+		}
+
+		// Ignore the values that clearly precede any other value. Comparing with
+		// every value rather than the highest one matters when there are siblings,
+		// since a value may precede one of them while being concurrent to the other.
+		obsolete := false
+
+		for j := 0; j < len(values); j++ {
+			if dvv.Compare(valueVersion[i], valueVersion[j]) == vclock.Before {
+				obsolete = true
+				break
+			}
+		}
+
+		if obsolete {
 			continue
 		}
 
-		// Keep unique values only, based on the version.
-		if _, ok := uniqueValues[value.Version]; !ok {
-			uniqueValues[value.Version] = value
+		// Keep unique values only, based on the version. The same version may
+		// be encoded differently, if it has been written with and without the dot.
+		idx := -1
+
+		for j, ver := range uniqueVersions {
+			if dvv.IsEqual(ver, valueVersion[i]) {
+				idx = j
+				break
+			}
+		}
+
+		if idx == -1 {
+			idx = len(uniqueValues)
+			uniqueValues = append(uniqueValues, value)
+			uniqueVersions = append(uniqueVersions, valueVersion[i])
+		}
+
+		replicaVersions[value.NodeID].Add(idx)
+	}
+
+	// The replicas missing any of the surviving values are stale. These are the replicas
+	// that returned outdated values only, as well as the ones that have some of the
+	// concurrent values but not the others.
+	var staleReplicas []membership.NodeID
+
+	for _, nodeID := range replicaOrder {
+		if len(replicaVersions[nodeID]) < len(uniqueValues) {
+			staleReplicas = append(staleReplicas, nodeID)
 		}
 	}
 
 	return mergeResult{
 		Version:       vclock.MustEncode(mergedVersion),
-		Values:        generic.MapValues(uniqueValues),
+		Values:        uniqueValues,
+		StaleReplicas: staleReplicas,
+	}, nil
+}
+
+// resolveLastWriteWins collapses the conflicting values of the merge result into a single
+// winner, chosen the same way as the storage engines do in the last-write-wins keyspaces.
+// All replicas that have not returned the winner are considered stale.
+func resolveLastWriteWins(result mergeResult, values []nodeValue) (mergeResult, error) {
+	if len(result.Values) < 2 {
+		return result, nil
+	}
+
+	winner := result.Values[0]
+
+	for _, val := range result.Values[1:] {
+		if storage.Newer(toStorageValue(val), toStorageValue(winner)) {
+			winner = val
+		}
+	}
+
+	winnerVersion, err := dvv.Decode(winner.Version)
+	if err != nil {
+		return mergeResult{}, fmt.Errorf("invalid version: %w", err)
+	}
+
+	haveWinner := make(set.Set[membership.NodeID])
+
+	for _, val := range values {
+		ver, err := dvv.Decode(val.Version)
+		if err != nil {
+			return mergeResult{}, fmt.Errorf("invalid version: %w", err)
+		}
+
+		if dvv.IsEqual(ver, winnerVersion) {
+			haveWinner.Add(val.NodeID)
+		}
+	}
+
+	staleReplicas := make([]membership.NodeID, 0, len(values))
+	seen := make(set.Set[membership.NodeID])
+
+	for _, val := range values {
+		if !haveWinner.Has(val.NodeID) && !seen.Has(val.NodeID) {
+			staleReplicas = append(staleReplicas, val.NodeID)
+			seen.Add(val.NodeID)
+		}
+	}
+
+	return mergeResult{
+		Version:       result.Version,
+		Values:        []nodeValue{winner},
 		StaleReplicas: staleReplicas,
 	}, nil
 }
+
+func toStorageValue(v nodeValue) storage.Value {
+	return storage.Value{
+		Data:      v.Data,
+		Timestamp: hlc.Timestamp(v.Timestamp),
+		Origin:    v.Origin,
+	}
+}
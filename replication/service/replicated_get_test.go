@@ -1,12 +1,23 @@
 package service
 
 import (
+	"context"
 	"testing"
 
+	"github.com/go-kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	clustmock "github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/clust/ring"
+	"github.com/maxpoletaev/kv/internal/dvv"
 	"github.com/maxpoletaev/kv/internal/vclock"
 	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/replication/consistency"
+	"github.com/maxpoletaev/kv/replication/proto"
 	storagepb "github.com/maxpoletaev/kv/storage/proto"
 )
 
@@ -100,7 +111,163 @@ func TestMergeValues(t *testing.T) {
 					},
 				},
 				Version:       vclock.NewEncoded(vclock.V{1: 2, 2: 2}),
-				StaleReplicas: []membership.NodeID{1, 3},
+				StaleReplicas: []membership.NodeID{1, 2, 3, 4, 5},
+			},
+		},
+		"DottedVersions": {
+			values: []nodeValue{
+				{
+					NodeID: 1,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: dvv.NewEncoded(dvv.Dot{Node: 1, Counter: 2}),
+						Data:    []byte("blind write"),
+					},
+				},
+				{
+					NodeID: 2,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: dvv.NewEncoded(dvv.Dot{Node: 1, Counter: 3}, vclock.V{1: 1}),
+						Data:    []byte("newer value"),
+					},
+				},
+				{
+					NodeID: 3,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: vclock.NewEncoded(vclock.V{1: 1}),
+						Data:    []byte("older value"),
+					},
+				},
+				{
+					NodeID: 4,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: dvv.NewEncoded(dvv.Dot{Node: 1, Counter: 3}, vclock.V{1: 1}),
+						Data:    []byte("newer value"),
+					},
+				},
+			},
+			wantResult: mergeResult{
+				Values: []nodeValue{
+					{
+						NodeID: 1,
+						VersionedValue: &storagepb.VersionedValue{
+							Version: dvv.NewEncoded(dvv.Dot{Node: 1, Counter: 2}),
+							Data:    []byte("blind write"),
+						},
+					},
+					{
+						NodeID: 2,
+						VersionedValue: &storagepb.VersionedValue{
+							Version: dvv.NewEncoded(dvv.Dot{Node: 1, Counter: 3}, vclock.V{1: 1}),
+							Data:    []byte("newer value"),
+						},
+					},
+				},
+				Version:       vclock.NewEncoded(vclock.V{1: 3}),
+				StaleReplicas: []membership.NodeID{1, 2, 3, 4},
+			},
+		},
+		"MissingSibling": {
+			values: []nodeValue{
+				{
+					NodeID: 1,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: vclock.NewEncoded(vclock.V{1: 1}),
+						Data:    []byte("first sibling"),
+					},
+				},
+				{
+					NodeID: 1,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: vclock.NewEncoded(vclock.V{2: 1}),
+						Data:    []byte("second sibling"),
+					},
+				},
+				{
+					NodeID: 2,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: vclock.NewEncoded(vclock.V{2: 1}),
+						Data:    []byte("second sibling"),
+					},
+				},
+				{
+					NodeID: 3,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: vclock.NewEncoded(vclock.V{1: 1}),
+						Data:    []byte("first sibling"),
+					},
+				},
+				{
+					NodeID: 3,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: vclock.NewEncoded(vclock.V{2: 1}),
+						Data:    []byte("second sibling"),
+					},
+				},
+			},
+			wantResult: mergeResult{
+				Values: []nodeValue{
+					{
+						NodeID: 1,
+						VersionedValue: &storagepb.VersionedValue{
+							Version: vclock.NewEncoded(vclock.V{1: 1}),
+							Data:    []byte("first sibling"),
+						},
+					},
+					{
+						NodeID: 1,
+						VersionedValue: &storagepb.VersionedValue{
+							Version: vclock.NewEncoded(vclock.V{2: 1}),
+							Data:    []byte("second sibling"),
+						},
+					},
+				},
+				Version:       vclock.NewEncoded(vclock.V{1: 1, 2: 1}),
+				StaleReplicas: []membership.NodeID{2},
+			},
+		},
+		"ObsoleteSiblingOfConcurrent": {
+			values: []nodeValue{
+				{
+					NodeID: 1,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: vclock.NewEncoded(vclock.V{1: 2}),
+						Data:    []byte("first sibling"),
+					},
+				},
+				{
+					NodeID: 2,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: vclock.NewEncoded(vclock.V{2: 2}),
+						Data:    []byte("second sibling"),
+					},
+				},
+				{
+					NodeID: 3,
+					VersionedValue: &storagepb.VersionedValue{
+						Version: vclock.NewEncoded(vclock.V{2: 1}),
+						Data:    []byte("older second sibling"),
+					},
+				},
+			},
+			wantResult: mergeResult{
+				Values: []nodeValue{
+					{
+						NodeID: 1,
+						VersionedValue: &storagepb.VersionedValue{
+							Version: vclock.NewEncoded(vclock.V{1: 2}),
+							Data:    []byte("first sibling"),
+						},
+					},
+					{
+						NodeID: 2,
+						VersionedValue: &storagepb.VersionedValue{
+							Version: vclock.NewEncoded(vclock.V{2: 2}),
+							Data:    []byte("second sibling"),
+						},
+					},
+				},
+				Version:       vclock.NewEncoded(vclock.V{1: 2, 2: 2}),
+				StaleReplicas: []membership.NodeID{1, 2, 3},
 			},
 		},
 	}
@@ -113,3 +280,228 @@ func TestMergeValues(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveLastWriteWins(t *testing.T) {
+	older := &storagepb.VersionedValue{
+		Version: vclock.NewEncoded(vclock.V{1: 1}),
+		Data:    []byte("older"),
+	}
+
+	winner := &storagepb.VersionedValue{
+		Version:   vclock.NewEncoded(vclock.V{1: 1, 2: 1}),
+		Data:      []byte("winner"),
+		Timestamp: 20,
+		Origin:    2,
+	}
+
+	loser := &storagepb.VersionedValue{
+		Version:   vclock.NewEncoded(vclock.V{1: 1, 3: 1}),
+		Data:      []byte("loser"),
+		Timestamp: 20,
+		Origin:    1,
+	}
+
+	values := []nodeValue{
+		{NodeID: 1, VersionedValue: loser},
+		{NodeID: 2, VersionedValue: winner},
+		{NodeID: 3, VersionedValue: older},
+		{NodeID: 4, VersionedValue: winner},
+	}
+
+	merged, err := mergeVersions(values)
+	require.NoError(t, err)
+	require.Len(t, merged.Values, 2)
+
+	result, err := resolveLastWriteWins(merged, values)
+	require.NoError(t, err)
+
+	// The concurrent values collapse into the winner, and the replicas having
+	// either the losing or an older value are repaired with the winner.
+	require.Equal(t, mergeResult{
+		Version:       vclock.NewEncoded(vclock.V{1: 1, 2: 1, 3: 1}),
+		Values:        []nodeValue{{NodeID: 2, VersionedValue: winner}},
+		StaleReplicas: []membership.NodeID{1, 3},
+	}, result)
+}
+
+func TestReplicatedGet_PreferenceList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	members := make([]membership.Member, 0, 5)
+	ids := make([]membership.NodeID, 0, 5)
+
+	for i := 1; i <= 5; i++ {
+		id := membership.NodeID(i)
+		ids = append(ids, id)
+		members = append(members, membership.Member{ID: id, Status: membership.StatusHealthy})
+	}
+
+	replicas := ring.New(ids, ring.DefaultVirtualNodes).PreferenceList("key", 3)
+
+	// One of the replicas is down, the remaining two are still a quorum of three.
+	for i := range members {
+		if members[i].ID == replicas[2] {
+			members[i].Status = membership.StatusFaulty
+		}
+	}
+
+	c := NewMockCluster(ctrl)
+	c.EXPECT().Members().Return(members)
+
+	value := &storagepb.VersionedValue{
+		Version: vclock.NewEncoded(vclock.V{1: 1}),
+		Data:    []byte("value"),
+	}
+
+	for _, id := range replicas[:2] {
+		conn := clustmock.NewMockClient(ctrl)
+		conn.EXPECT().Get(gomock.Any(), &storagepb.GetRequest{Key: "key"}).
+			Return(&storagepb.GetResponse{Value: []*storagepb.VersionedValue{value}}, nil)
+
+		c.EXPECT().Conn(id).Return(conn, nil)
+	}
+
+	s := New(c, log.NewNopLogger(), consistency.Quorum, consistency.Quorum, WithPlacement(ring.NewPlacement(3, ring.DefaultVirtualNodes)))
+
+	got, err := s.ReplicatedGet(context.Background(), &proto.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	require.Len(t, got.Values, 1)
+	require.Equal(t, []byte("value"), got.Values[0].Data)
+}
+
+func TestReplicatedGet_ConsistencyLevel(t *testing.T) {
+	tests := map[string]struct {
+		level    proto.ConsistencyLevel
+		wantCode codes.Code
+	}{
+		"DefaultLevel": {
+			level:    proto.ConsistencyLevel_DEFAULT,
+			wantCode: codes.FailedPrecondition,
+		},
+		"RequestedLevel": {
+			level:    proto.ConsistencyLevel_ONE,
+			wantCode: codes.OK,
+		},
+		"UnknownLevel": {
+			level:    proto.ConsistencyLevel(100),
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Only one of three replicas is available, which is not enough for the default level.
+			c := NewMockCluster(ctrl)
+			c.EXPECT().Members().Return([]membership.Member{
+				{ID: 1, Name: "node1", Status: membership.StatusHealthy},
+				{ID: 2, Name: "node2", Status: membership.StatusFaulty},
+				{ID: 3, Name: "node3", Status: membership.StatusFaulty},
+			}).MaxTimes(1)
+
+			conn := clustmock.NewMockClient(ctrl)
+			conn.EXPECT().Get(gomock.Any(), &storagepb.GetRequest{Key: "key"}).Return(&storagepb.GetResponse{
+				Value: []*storagepb.VersionedValue{{
+					Version: vclock.NewEncoded(vclock.V{1: 1}),
+					Data:    []byte("value"),
+				}},
+			}, nil).MaxTimes(1)
+
+			c.EXPECT().Conn(membership.NodeID(1)).Return(conn, nil).MaxTimes(1)
+
+			s := New(c, log.NewNopLogger(), consistency.Quorum, consistency.Quorum)
+
+			got, err := s.ReplicatedGet(context.Background(), &proto.GetRequest{
+				Key:         "key",
+				Consistency: test.level,
+			})
+
+			require.Equal(t, test.wantCode, status.Code(err), err)
+
+			if test.wantCode == codes.OK {
+				require.Len(t, got.Values, 1)
+				assert.Equal(t, []byte("value"), got.Values[0].Data)
+			}
+		})
+	}
+}
+
+func TestReplicatedGet_RepairWaitsForLevel(t *testing.T) {
+	tests := map[string]struct {
+		repairErr error
+		wantCode  codes.Code
+	}{
+		"RepairsSucceed": {
+			wantCode: codes.OK,
+		},
+		"RepairFails": {
+			repairErr: assert.AnError,
+			wantCode:  codes.Unavailable,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			c := NewMockCluster(ctrl)
+			c.EXPECT().Members().Return([]membership.Member{
+				{ID: 1, Name: "node1", Status: membership.StatusHealthy},
+				{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+				{ID: 3, Name: "node3", Status: membership.StatusHealthy},
+			})
+
+			newValue := &storagepb.VersionedValue{
+				Version: vclock.NewEncoded(vclock.V{1: 2}),
+				Data:    []byte("new"),
+			}
+
+			oldValue := &storagepb.VersionedValue{
+				Version: vclock.NewEncoded(vclock.V{1: 1}),
+				Data:    []byte("old"),
+			}
+
+			conn1 := clustmock.NewMockClient(ctrl)
+			conn1.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&storagepb.GetResponse{
+				Value: []*storagepb.VersionedValue{newValue},
+			}, nil)
+
+			c.EXPECT().Conn(membership.NodeID(1)).Return(conn1, nil)
+
+			// Only node 1 has the latest value, so both stale replicas
+			// must be repaired to have it on all of them.
+			conn2 := clustmock.NewMockClient(ctrl)
+			conn2.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&storagepb.GetResponse{
+				Value: []*storagepb.VersionedValue{oldValue},
+			}, nil)
+			conn2.EXPECT().Repair(gomock.Any(), gomock.Any()).Return(&storagepb.RepairResponse{}, nil)
+
+			c.EXPECT().Conn(membership.NodeID(2)).Return(conn2, nil).Times(2)
+
+			conn3 := clustmock.NewMockClient(ctrl)
+			conn3.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&storagepb.GetResponse{
+				Value: []*storagepb.VersionedValue{oldValue},
+			}, nil)
+			conn3.EXPECT().Repair(gomock.Any(), gomock.Any()).Return(&storagepb.RepairResponse{}, test.repairErr)
+
+			c.EXPECT().Conn(membership.NodeID(3)).Return(conn3, nil).Times(2)
+
+			s := New(c, log.NewNopLogger(), consistency.Quorum, consistency.Quorum)
+
+			got, err := s.ReplicatedGet(context.Background(), &proto.GetRequest{
+				Key:         "key",
+				Consistency: proto.ConsistencyLevel_ALL,
+			})
+
+			require.Equal(t, test.wantCode, status.Code(err), err)
+
+			if test.wantCode == codes.OK {
+				assert.Equal(t, []byte("new"), got.Values[0].Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/internal/grpcutil"
+	"github.com/maxpoletaev/kv/replication/proto"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+func (s *ReplicationService) validateMergeRequest(req *proto.MergeRequest) error {
+	if len(req.Key) == 0 {
+		return errMissingKey
+	}
+
+	if len(req.Operator) == 0 {
+		return errMissingOperator
+	}
+
+	return nil
+}
+
+// ReplicatedMerge adds a merge operand to the key. Unlike the put, the merge does not require
+// the client to read the value and provide its version, as the operand is combined with the
+// value the primary node has. The resulting value and its version are then replicated the same
+// way as by ReplicatedPut, so that all replicas end up with the same value for the same version.
+// Note that the operand is applied again if the client retries a merge that has failed.
+func (s *ReplicationService) ReplicatedMerge(ctx context.Context, req *proto.MergeRequest) (*proto.MergeResponse, error) {
+	if err := s.validateMergeRequest(req); err != nil {
+		return nil, err
+	}
+
+	all := s.cluster.Members()
+	members := s.preferenceList(req.Key, all, s.placement.ReplicationFactor())
+	fallbacks := s.fallbacks(req.Key, all, members)
+	acksLeft := s.writeLevel.N(len(members))
+
+	if countAlive(members)+len(fallbacks) < acksLeft {
+		return nil, errNotEnoughReplicas
+	}
+
+	primary, primaryConn, err := s.primary(members)
+	if err != nil {
+		return nil, err
+	}
+
+	// The operand is applied on the primary only, which also rejects an invalid operand
+	// before anything is written to the other replicas.
+	resp, err := primaryConn.Merge(ctx, &storagepb.MergeRequest{
+		Key:      req.Key,
+		Operator: req.Operator,
+		Operand:  req.Operand,
+		Origin:   uint32(primary.ID),
+	})
+	if err != nil {
+		if grpcutil.ErrorCode(err) == codes.InvalidArgument {
+			return nil, err
+		}
+
+		s.logger.Log("msg", "primary merge failed", "err", err)
+
+		return nil, status.Errorf(codes.Internal, "failed to merge on primary: %s", err)
+	}
+
+	if err := s.replicate(ctx, req.Key, resp.Value, primary, members, fallbacks, acksLeft-1); err != nil {
+		return nil, err
+	}
+
+	return &proto.MergeResponse{
+		Version: resp.Value.Version,
+	}, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	clustmock "github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/replication/consistency"
+	"github.com/maxpoletaev/kv/replication/proto"
+	"github.com/maxpoletaev/kv/storage"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+func TestReplicatedMerge(t *testing.T) {
+	storageReq := &storagepb.MergeRequest{
+		Key:      "key",
+		Operator: "int64add",
+		Operand:  []byte{1},
+		Origin:   1,
+	}
+
+	merged := &storagepb.VersionedValue{
+		Version: vclock.NewEncoded(vclock.V{1: 2}),
+		Data:    storage.EncodeInt64(2),
+	}
+
+	// The replicas receive the value merged on the primary, rather than the operand.
+	replicaReq := &storagepb.PutRequest{Key: "key", Value: merged}
+
+	members := []membership.Member{
+		{ID: 1, Name: "node1", Status: membership.StatusHealthy},
+		{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+		{ID: 3, Name: "node3", Status: membership.StatusHealthy},
+	}
+
+	tests := map[string]struct {
+		setupCluster func(ctrl *gomock.Controller, c *MockCluster)
+		req          *proto.MergeRequest
+		want         *proto.MergeResponse
+		wantCode     codes.Code
+		wantErr      error
+	}{
+		"OneOfThreeNodesInQuorumFails": {
+			setupCluster: func(ctrl *gomock.Controller, c *MockCluster) {
+				conn1 := clustmock.NewMockClient(ctrl)
+				conn1.EXPECT().Merge(gomock.Any(), storageReq).Return(&storagepb.MergeResponse{Value: merged}, nil)
+
+				conn2 := clustmock.NewMockClient(ctrl)
+				conn2.EXPECT().Put(gomock.Any(), replicaReq).Return(&storagepb.PutResponse{}, nil).MaxTimes(1)
+
+				conn3 := clustmock.NewMockClient(ctrl)
+				conn3.EXPECT().Put(gomock.Any(), replicaReq).Return(nil, assert.AnError).MaxTimes(1)
+
+				c.EXPECT().Self().Return(members[0])
+				c.EXPECT().Members().Return(members)
+				c.EXPECT().SelfConn().Return(conn1)
+				c.EXPECT().Conn(membership.NodeID(2)).Return(conn2, nil).MaxTimes(1)
+				c.EXPECT().Conn(membership.NodeID(3)).Return(conn3, nil).MaxTimes(1)
+			},
+			req:  &proto.MergeRequest{Key: "key", Operator: "int64add", Operand: []byte{1}},
+			want: &proto.MergeResponse{Version: merged.Version},
+		},
+		"TwoOfThreeNodesInQuorumFail": {
+			setupCluster: func(ctrl *gomock.Controller, c *MockCluster) {
+				conn1 := clustmock.NewMockClient(ctrl)
+				conn1.EXPECT().Merge(gomock.Any(), storageReq).Return(&storagepb.MergeResponse{Value: merged}, nil)
+
+				conn2 := clustmock.NewMockClient(ctrl)
+				conn2.EXPECT().Put(gomock.Any(), replicaReq).Return(nil, assert.AnError)
+
+				conn3 := clustmock.NewMockClient(ctrl)
+				conn3.EXPECT().Put(gomock.Any(), replicaReq).Return(nil, assert.AnError)
+
+				c.EXPECT().Self().Return(members[0])
+				c.EXPECT().Members().Return(members)
+				c.EXPECT().SelfConn().Return(conn1)
+				c.EXPECT().Conn(membership.NodeID(2)).Return(conn2, nil)
+				c.EXPECT().Conn(membership.NodeID(3)).Return(conn3, nil)
+			},
+			req:      &proto.MergeRequest{Key: "key", Operator: "int64add", Operand: []byte{1}},
+			wantCode: codes.Unavailable,
+			wantErr:  errLevelNotSatisfied,
+		},
+		"InvalidOperandOnLocalNode": {
+			setupCluster: func(ctrl *gomock.Controller, c *MockCluster) {
+				conn1 := clustmock.NewMockClient(ctrl)
+				conn1.EXPECT().Merge(gomock.Any(), gomock.Any()).Return(
+					nil, status.Error(codes.InvalidArgument, "unknown operator"))
+
+				c.EXPECT().Self().Return(members[0])
+				c.EXPECT().Members().Return(members)
+				c.EXPECT().SelfConn().Return(conn1)
+			},
+			req:      &proto.MergeRequest{Key: "key", Operator: "unknown"},
+			wantCode: codes.InvalidArgument,
+		},
+		"MissingOperator": {
+			setupCluster: func(ctrl *gomock.Controller, c *MockCluster) {},
+			req:          &proto.MergeRequest{Key: "key"},
+			wantCode:     codes.InvalidArgument,
+			wantErr:      errMissingOperator,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			c := NewMockCluster(ctrl)
+			test.setupCluster(ctrl, c)
+
+			s := New(c, log.NewNopLogger(), consistency.One, consistency.Quorum)
+			got, err := s.ReplicatedMerge(context.Background(), test.req)
+			require.Equal(t, test.wantCode, status.Code(err), err)
+			require.Equal(t, test.want, got)
+
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	clustmock "github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/replication/consistency"
+	"github.com/maxpoletaev/kv/replication/proto"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+func TestReplicatedMultiGet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := NewMockCluster(ctrl)
+	c.EXPECT().Members().Return([]membership.Member{
+		{ID: 1, Name: "node1", Status: membership.StatusHealthy},
+		{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+		{ID: 3, Name: "node3", Status: membership.StatusHealthy},
+	})
+
+	value := &storagepb.VersionedValue{Version: vclock.NewEncoded(vclock.V{1: 1}), Data: []byte("value")}
+	keyErr := &storagepb.KeyError{Code: uint32(codes.Internal), Message: "storage get failed"}
+
+	// Each replica gets a single request with all of its keys, without the duplicates.
+	replies := map[membership.NodeID]*storagepb.MultiGetResponse{
+		1: {Results: []*storagepb.MultiGetResult{{Values: []*storagepb.VersionedValue{value}}, {Values: []*storagepb.VersionedValue{value}}}},
+		2: {Results: []*storagepb.MultiGetResult{{Values: []*storagepb.VersionedValue{value}}, {Error: keyErr}}},
+		3: nil,
+	}
+
+	for id, reply := range replies {
+		conn := clustmock.NewMockClient(ctrl)
+		call := conn.EXPECT().MultiGet(gomock.Any(), &storagepb.MultiGetRequest{Keys: []string{"a", "b"}})
+
+		if reply != nil {
+			call.Return(reply, nil)
+		} else {
+			call.Return(nil, assert.AnError)
+		}
+
+		c.EXPECT().Conn(id).Return(conn, nil)
+	}
+
+	s := New(c, log.NewNopLogger(), consistency.Quorum, consistency.Quorum)
+
+	got, err := s.ReplicatedMultiGet(context.Background(), &proto.MultiGetRequest{
+		Keys: []string{"a", "b", "", "a"},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, got.Results, 4)
+
+	for _, i := range []int{0, 3} {
+		assert.Equal(t, "a", got.Results[i].Key)
+		assert.Nil(t, got.Results[i].Error)
+		require.Len(t, got.Results[i].Values, 1)
+		assert.Equal(t, []byte("value"), got.Results[i].Values[0].Data)
+	}
+
+	// Only one replica has returned the value of b, which is not enough for the quorum.
+	assert.Equal(t, uint32(codes.Unavailable), got.Results[1].Error.Code)
+	assert.Equal(t, uint32(codes.InvalidArgument), got.Results[2].Error.Code)
+}
+
+func TestReplicatedMultiGet_InvalidBatch(t *testing.T) {
+	tests := map[string]struct {
+		req      *proto.MultiGetRequest
+		wantCode codes.Code
+	}{
+		"Empty": {
+			req:      &proto.MultiGetRequest{},
+			wantCode: codes.InvalidArgument,
+		},
+		"TooLarge": {
+			req:      &proto.MultiGetRequest{Keys: make([]string, maxBatchSize+1)},
+			wantCode: codes.InvalidArgument,
+		},
+		"InvalidLevel": {
+			req:      &proto.MultiGetRequest{Keys: []string{"key"}, Consistency: 100},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			s := New(NewMockCluster(ctrl), log.NewNopLogger(), consistency.Quorum, consistency.Quorum)
+
+			_, err := s.ReplicatedMultiGet(context.Background(), test.req)
+			assert.Equal(t, test.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestReplicatedMultiPut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	self := membership.Member{ID: 1, Name: "node1", Status: membership.StatusHealthy}
+
+	c := NewMockCluster(ctrl)
+	c.EXPECT().Self().Return(self).AnyTimes()
+	c.EXPECT().Members().Return([]membership.Member{
+		self,
+		{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+		{ID: 3, Name: "node3", Status: membership.StatusHealthy},
+	})
+
+	version1 := vclock.NewEncoded(vclock.V{1: 1})
+	version2 := vclock.NewEncoded(vclock.V{1: 2})
+
+	// The local node is the primary of both keys, and gets a single request for them.
+	selfConn := clustmock.NewMockClient(ctrl)
+	selfConn.EXPECT().MultiPut(gomock.Any(), &storagepb.MultiPutRequest{
+		Puts: []*storagepb.PutRequest{
+			{Key: "k1", Primary: true, Value: &storagepb.VersionedValue{Version: vclock.NewEncoded(), Data: []byte("v1")}},
+			{Key: "k2", Primary: true, Value: &storagepb.VersionedValue{Version: vclock.NewEncoded(), Data: []byte("v2")}},
+		},
+	}).Return(&storagepb.MultiPutResponse{
+		Results: []*storagepb.MultiPutResult{
+			{Version: version1, Timestamp: 10},
+			{Version: version2, Timestamp: 20},
+		},
+	}, nil)
+
+	c.EXPECT().SelfConn().Return(selfConn).AnyTimes()
+
+	replicaPuts := &storagepb.MultiPutRequest{
+		Puts: []*storagepb.PutRequest{
+			{Key: "k1", Value: &storagepb.VersionedValue{Version: version1, Data: []byte("v1"), Timestamp: 10, Origin: 1}},
+			{Key: "k2", Value: &storagepb.VersionedValue{Version: version2, Data: []byte("v2"), Timestamp: 20, Origin: 1}},
+		},
+	}
+
+	// The replies may arrive in any order, so the request may return before one of them.
+	conn2 := clustmock.NewMockClient(ctrl)
+	conn2.EXPECT().MultiPut(gomock.Any(), replicaPuts).Return(&storagepb.MultiPutResponse{
+		Results: []*storagepb.MultiPutResult{{}, {}},
+	}, nil).MaxTimes(1)
+
+	c.EXPECT().Conn(membership.NodeID(2)).Return(conn2, nil).MaxTimes(1)
+
+	// Node 3 already has a newer value of k2.
+	conn3 := clustmock.NewMockClient(ctrl)
+	conn3.EXPECT().MultiPut(gomock.Any(), replicaPuts).Return(&storagepb.MultiPutResponse{
+		Results: []*storagepb.MultiPutResult{
+			{},
+			{Error: &storagepb.KeyError{Code: uint32(codes.AlreadyExists), Message: "obsolete write"}},
+		},
+	}, nil).MaxTimes(1)
+
+	c.EXPECT().Conn(membership.NodeID(3)).Return(conn3, nil).MaxTimes(1)
+
+	s := New(c, log.NewNopLogger(), consistency.Quorum, consistency.Quorum)
+
+	got, err := s.ReplicatedMultiPut(context.Background(), &proto.MultiPutRequest{
+		Puts: []*proto.PutRequest{
+			{Key: "k1", Version: vclock.NewEncoded(), Value: &proto.Value{Data: []byte("v1")}},
+			{Key: "k2", Version: vclock.NewEncoded(), Value: &proto.Value{Data: []byte("v2")}, Consistency: proto.ConsistencyLevel_ALL},
+			{Key: "", Value: &proto.Value{Data: []byte("v3")}},
+			{Key: "k4"},
+		},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, got.Results, 4)
+
+	assert.Equal(t, "k1", got.Results[0].Key)
+	assert.Equal(t, version1, got.Results[0].Version)
+	assert.Nil(t, got.Results[0].Error)
+
+	assert.Equal(t, "k2", got.Results[1].Key)
+	assert.Equal(t, uint32(codes.AlreadyExists), got.Results[1].Error.Code)
+
+	assert.Equal(t, uint32(codes.InvalidArgument), got.Results[2].Error.Code)
+	assert.Equal(t, uint32(codes.InvalidArgument), got.Results[3].Error.Code)
+}
+
+func TestReplicatedMultiPut_PrimaryErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	self := membership.Member{ID: 1, Name: "node1", Status: membership.StatusHealthy}
+
+	c := NewMockCluster(ctrl)
+	c.EXPECT().Self().Return(self).AnyTimes()
+	c.EXPECT().Members().Return([]membership.Member{self})
+
+	// The primary rejects k1 and fails to write k2, the codes must reach the client as is.
+	selfConn := clustmock.NewMockClient(ctrl)
+	selfConn.EXPECT().MultiPut(gomock.Any(), gomock.Any()).Return(&storagepb.MultiPutResponse{
+		Results: []*storagepb.MultiPutResult{
+			{Error: &storagepb.KeyError{Code: uint32(codes.ResourceExhausted), Message: "too many concurrent versions"}},
+			{Error: &storagepb.KeyError{Code: uint32(codes.Internal), Message: "storage put failed"}},
+		},
+	}, nil)
+
+	c.EXPECT().SelfConn().Return(selfConn).AnyTimes()
+
+	s := New(c, log.NewNopLogger(), consistency.One, consistency.One)
+
+	got, err := s.ReplicatedMultiPut(context.Background(), &proto.MultiPutRequest{
+		Puts: []*proto.PutRequest{
+			{Key: "k1", Version: vclock.NewEncoded(), Value: &proto.Value{Data: []byte("v1")}},
+			{Key: "k2", Version: vclock.NewEncoded(), Value: &proto.Value{Data: []byte("v2")}},
+		},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, got.Results, 2)
+
+	assert.Equal(t, uint32(codes.ResourceExhausted), got.Results[0].Error.Code)
+	assert.Equal(t, uint32(codes.Internal), got.Results[1].Error.Code)
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-kit/log/level"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/replication/proto"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+// keyRead is the state of the read of a single key of a batch.
+type keyRead struct {
+	key      string
+	replicas []membership.Member
+	minAcks  int
+	results  []*nodeGetResult
+	resp     *proto.GetResponse
+	err      error
+}
+
+// replicaReads are the keys of a batch read from the same replica.
+type replicaReads struct {
+	replica *membership.Member
+	keys    []*keyRead
+}
+
+func toKeyError(err error) *proto.KeyError {
+	st := status.Convert(err)
+
+	return &proto.KeyError{
+		Code:    uint32(st.Code()),
+		Message: st.Message(),
+	}
+}
+
+// ReplicatedMultiGet reads several keys at once. The keys are grouped by the replicas, so that
+// each replica receives a single request with all of its keys. The consistency level applies
+// to each key separately, and the values are merged and repaired the same way as by
+// ReplicatedGet. The error of a key is returned in its result, so that it does not fail the
+// others. The batch reads go to all reachable replicas, they are neither hedged nor ranked.
+func (s *ReplicationService) ReplicatedMultiGet(ctx context.Context, req *proto.MultiGetRequest) (*proto.MultiGetResponse, error) {
+	if len(req.Keys) == 0 {
+		return nil, errEmptyBatch
+	}
+
+	if len(req.Keys) > maxBatchSize {
+		return nil, errBatchTooLarge
+	}
+
+	readLevel, err := consistencyLevel(req.Consistency, s.readLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	members := s.cluster.Members()
+	reads := make(map[string]*keyRead, len(req.Keys))
+	batches := make(map[membership.NodeID]*replicaReads)
+
+	// The same key requested twice is only read once.
+	for _, key := range req.Keys {
+		if _, ok := reads[key]; ok {
+			continue
+		}
+
+		read := &keyRead{key: key}
+		reads[key] = read
+
+		if len(key) == 0 {
+			read.err = errMissingKey
+			continue
+		}
+
+		read.replicas = s.preferenceList(key, members, s.placement.ReplicationFactor())
+		read.minAcks = readLevel.N(len(read.replicas))
+
+		if countAlive(read.replicas) < read.minAcks {
+			read.err = errNotEnoughReplicas
+			continue
+		}
+
+		for i := range read.replicas {
+			replica := &read.replicas[i]
+			if !replica.IsReacheable() {
+				continue
+			}
+
+			batch := batches[replica.ID]
+			if batch == nil {
+				batch = &replicaReads{replica: replica}
+				batches[replica.ID] = batch
+			}
+
+			batch.keys = append(batch.keys, read)
+		}
+	}
+
+	if err := s.readBatches(ctx, batches); err != nil {
+		return nil, err
+	}
+
+	for _, read := range reads {
+		if read.err != nil {
+			continue
+		}
+
+		if len(read.results) < read.minAcks {
+			read.err = errLevelNotSatisfied
+			continue
+		}
+
+		read.resp, read.err = s.resolveRead(ctx, read.key, read.replicas, read.results, read.minAcks)
+	}
+
+	results := make([]*proto.MultiGetResult, 0, len(req.Keys))
+
+	for _, key := range req.Keys {
+		read := reads[key]
+
+		if read.err != nil {
+			results = append(results, &proto.MultiGetResult{
+				Key:   key,
+				Error: toKeyError(read.err),
+			})
+
+			continue
+		}
+
+		results = append(results, &proto.MultiGetResult{
+			Key:     key,
+			Values:  read.resp.Values,
+			Version: read.resp.Version,
+		})
+	}
+
+	return &proto.MultiGetResponse{
+		Results: results,
+	}, nil
+}
+
+// readBatches sends the batches to the replicas concurrently, and collects the values of each
+// key until all keys have enough replies to satisfy the consistency level, or all replicas
+// have replied.
+func (s *ReplicationService) readBatches(ctx context.Context, batches map[membership.NodeID]*replicaReads) error {
+	type batchReply struct {
+		batch *replicaReads
+		resp  *storagepb.MultiGetResponse
+		err   error
+	}
+
+	readCtx, cancelRead := context.WithTimeout(ctx, s.readTimeout)
+	defer cancelRead()
+
+	// Buffered, so that the late replies do not block once the reads are done.
+	replies := make(chan batchReply, len(batches))
+	pending := make(map[*keyRead]bool)
+
+	for _, batch := range batches {
+		keys := make([]string, 0, len(batch.keys))
+
+		for _, read := range batch.keys {
+			keys = append(keys, read.key)
+			pending[read] = true
+		}
+
+		go func(batch *replicaReads) {
+			conn, err := s.cluster.Conn(batch.replica.ID)
+			if err != nil {
+				replies <- batchReply{batch: batch, err: err}
+				return
+			}
+
+			resp, err := conn.MultiGet(readCtx, &storagepb.MultiGetRequest{Keys: keys})
+			replies <- batchReply{batch: batch, resp: resp, err: err}
+		}(batch)
+	}
+
+	for received := 0; received < len(batches) && len(pending) > 0; received++ {
+		select {
+		case r := <-replies:
+			if r.err != nil {
+				level.Warn(s.logger).Log("msg", "failed to read batch from replica", "name", r.batch.replica.Name, "err", r.err)
+				continue
+			}
+
+			if len(r.resp.Results) != len(r.batch.keys) {
+				level.Warn(s.logger).Log("msg", "unexpected number of results in batch", "name", r.batch.replica.Name)
+				continue
+			}
+
+			for i, read := range r.batch.keys {
+				res := r.resp.Results[i]
+				if res.Error != nil {
+					continue
+				}
+
+				read.results = append(read.results, &nodeGetResult{
+					NodeID: r.batch.replica.ID,
+					Values: res.Values,
+				})
+
+				if len(read.results) >= read.minAcks {
+					delete(pending, read)
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
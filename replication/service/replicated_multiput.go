@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-kit/log/level"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/clust"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/replication/proto"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+// keyWrite is the state of the write of a single key of a batch.
+type keyWrite struct {
+	req          *proto.PutRequest
+	members      []membership.Member
+	fallbacks    map[membership.NodeID]membership.Member
+	primary      membership.Member
+	acksLeft     int
+	version      string
+	replicaValue *storagepb.VersionedValue
+	done         bool
+	err          error
+}
+
+// nodeWrites are the puts of a batch sent to the same node.
+type nodeWrites struct {
+	member *membership.Member
+	conn   clust.Conn
+	writes []*keyWrite
+	puts   []*storagepb.PutRequest
+}
+
+func (b *nodeWrites) add(w *keyWrite, put *storagepb.PutRequest) {
+	b.writes = append(b.writes, w)
+	b.puts = append(b.puts, put)
+}
+
+type nodeWritesReply struct {
+	batch *nodeWrites
+	resp  *storagepb.MultiPutResponse
+	err   error
+}
+
+// ReplicatedMultiPut writes several keys at once. The writes are grouped by the nodes, so that
+// each primary and then each replica receives a single request with all of its keys. The
+// consistency level applies to each key separately, and each key is written the same way as by
+// ReplicatedPut, including the hints for the unreachable replicas. The error of a key is
+// returned in its result, so that it does not fail the others.
+func (s *ReplicationService) ReplicatedMultiPut(ctx context.Context, req *proto.MultiPutRequest) (*proto.MultiPutResponse, error) {
+	if len(req.Puts) == 0 {
+		return nil, errEmptyBatch
+	}
+
+	if len(req.Puts) > maxBatchSize {
+		return nil, errBatchTooLarge
+	}
+
+	batchLevel, err := consistencyLevel(req.Consistency, s.writeLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	all := s.cluster.Members()
+	writes := make([]*keyWrite, 0, len(req.Puts))
+	primaries := make(map[membership.NodeID]*nodeWrites)
+
+	for _, put := range req.Puts {
+		w := &keyWrite{req: put}
+		writes = append(writes, w)
+
+		if w.err = s.validatePutRequest(put); w.err != nil {
+			continue
+		}
+
+		writeLevel, err := consistencyLevel(put.Consistency, batchLevel)
+		if err != nil {
+			w.err = err
+			continue
+		}
+
+		w.members = s.preferenceList(put.Key, all, s.placement.ReplicationFactor())
+		w.fallbacks = s.fallbacks(put.Key, all, w.members)
+		w.acksLeft = writeLevel.N(len(w.members))
+
+		if countAlive(w.members)+len(w.fallbacks) < w.acksLeft {
+			w.err = errNotEnoughReplicas
+			continue
+		}
+
+		primary, conn, err := s.primary(w.members)
+		if err != nil {
+			w.err = err
+			continue
+		}
+
+		w.primary = primary
+
+		batch := primaries[primary.ID]
+		if batch == nil {
+			batch = &nodeWrites{member: &primary, conn: conn}
+			primaries[primary.ID] = batch
+		}
+
+		batch.add(w, &storagepb.PutRequest{
+			Key:     put.Key,
+			Primary: true,
+			Value: &storagepb.VersionedValue{
+				Version: put.Version,
+				Data:    put.Value.Data,
+			},
+		})
+	}
+
+	if err := s.writePrimaries(ctx, primaries); err != nil {
+		return nil, err
+	}
+
+	if err := s.writeReplicas(ctx, writes); err != nil {
+		return nil, err
+	}
+
+	results := make([]*proto.MultiPutResult, 0, len(writes))
+
+	for _, w := range writes {
+		if w.err != nil {
+			results = append(results, &proto.MultiPutResult{
+				Key:   w.req.Key,
+				Error: toKeyError(w.err),
+			})
+
+			continue
+		}
+
+		results = append(results, &proto.MultiPutResult{
+			Key:     w.req.Key,
+			Version: w.version,
+		})
+	}
+
+	return &proto.MultiPutResponse{
+		Results: results,
+	}, nil
+}
+
+// sendWrites sends the batch to its node, and passes the reply to the channel.
+func sendWrites(ctx context.Context, batch *nodeWrites, replies chan<- nodeWritesReply) {
+	resp, err := batch.conn.MultiPut(ctx, &storagepb.MultiPutRequest{Puts: batch.puts})
+	if err == nil && len(resp.Results) != len(batch.puts) {
+		err = status.Error(codes.Internal, "unexpected number of results in batch")
+	}
+
+	replies <- nodeWritesReply{batch: batch, resp: resp, err: err}
+}
+
+// writePrimaries writes the keys to their primary nodes, which assign the versions and the
+// timestamps, and waits for all of them to reply.
+func (s *ReplicationService) writePrimaries(ctx context.Context, primaries map[membership.NodeID]*nodeWrites) error {
+	replies := make(chan nodeWritesReply, len(primaries))
+
+	for _, batch := range primaries {
+		go sendWrites(ctx, batch, replies)
+	}
+
+	for received := 0; received < len(primaries); received++ {
+		select {
+		case r := <-replies:
+			for i, w := range r.batch.writes {
+				if r.err != nil {
+					s.logger.Log("msg", "primary write failed", "err", r.err)
+					w.err = primaryError(r.err)
+
+					continue
+				}
+
+				res := r.resp.Results[i]
+				if res.Error != nil {
+					w.err = status.Errorf(codes.Code(res.Error.Code), "failed to write to primary: %s", res.Error.Message)
+					continue
+				}
+
+				w.version = res.Version
+				w.acksLeft--
+				w.done = w.acksLeft <= 0
+				w.replicaValue = &storagepb.VersionedValue{
+					Version:   res.Version,
+					Data:      w.req.Value.Data,
+					Timestamp: res.Timestamp,
+					Origin:    uint32(w.primary.ID),
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// writeReplicas replicates the keys written to the primaries across the other replicas,
+// or their fallback nodes if the replicas are unreachable, and waits until each key is
+// acknowledged by enough replicas to satisfy its consistency level. As with single writes,
+// the replication continues in the background until the write timeout fires.
+func (s *ReplicationService) writeReplicas(ctx context.Context, writes []*keyWrite) error {
+	batches := make(map[membership.NodeID]*nodeWrites)
+	pending := 0
+
+	for _, w := range writes {
+		if w.err != nil {
+			continue
+		}
+
+		if !w.done {
+			pending++
+		}
+
+		for i := range w.members {
+			replica := &w.members[i]
+			hintFor := membership.NodeID(0)
+
+			if replica.ID == w.primary.ID {
+				continue
+			}
+
+			if !replica.IsReacheable() {
+				fallback, ok := w.fallbacks[replica.ID]
+				if !ok {
+					continue
+				}
+
+				replica, hintFor = &fallback, replica.ID
+			}
+
+			batch := batches[replica.ID]
+			if batch == nil {
+				batch = &nodeWrites{member: replica}
+				batches[replica.ID] = batch
+			}
+
+			batch.add(w, &storagepb.PutRequest{
+				Key:     w.req.Key,
+				Value:   w.replicaValue,
+				HintFor: uint32(hintFor),
+			})
+		}
+	}
+
+	writeCtx, cancelWrite := context.WithTimeout(context.Background(), s.writeTimeout)
+	replies := make(chan nodeWritesReply, len(batches))
+	wg := sync.WaitGroup{}
+
+	for _, batch := range batches {
+		wg.Add(1)
+
+		go func(batch *nodeWrites) {
+			defer wg.Done()
+
+			conn, err := s.cluster.Conn(batch.member.ID)
+			if err != nil {
+				replies <- nodeWritesReply{batch: batch, err: err}
+				return
+			}
+
+			batch.conn = conn
+			sendWrites(writeCtx, batch, replies)
+		}(batch)
+	}
+
+	go func() {
+		wg.Wait()
+		cancelWrite()
+	}()
+
+	for received := 0; received < len(batches) && pending > 0; received++ {
+		select {
+		case r := <-replies:
+			if r.err != nil {
+				level.Warn(s.logger).Log("msg", "batch write to replica has failed", "replica", r.batch.member.Name, "err", r.err)
+				continue
+			}
+
+			for i, w := range r.batch.writes {
+				if w.done {
+					continue
+				}
+
+				res := r.resp.Results[i]
+
+				if res.Error != nil {
+					// Some replicas already have a newer value, there is no point to wait for the others.
+					if codes.Code(res.Error.Code) == codes.AlreadyExists {
+						w.err = status.Error(codes.AlreadyExists, res.Error.Message)
+						w.done = true
+						pending--
+					}
+
+					continue
+				}
+
+				w.acksLeft--
+
+				if w.acksLeft <= 0 {
+					w.done = true
+					pending--
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for _, w := range writes {
+		if w.err == nil && !w.done {
+			w.err = errLevelNotSatisfied
+		}
+	}
+
+	return nil
+}
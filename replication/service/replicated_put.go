@@ -11,7 +11,6 @@ import (
 	"github.com/maxpoletaev/kv/clust"
 	"github.com/maxpoletaev/kv/internal/grpcutil"
 	"github.com/maxpoletaev/kv/membership"
-	"github.com/maxpoletaev/kv/replication/consistency"
 	"github.com/maxpoletaev/kv/replication/proto"
 	storagepb "github.com/maxpoletaev/kv/storage/proto"
 )
@@ -21,6 +20,10 @@ func (s *ReplicationService) validatePutRequest(req *proto.PutRequest) error {
 		return errMissingKey
 	}
 
+	if req.Value == nil {
+		return errMissingValue
+	}
+
 	return nil
 }
 
@@ -29,40 +32,91 @@ func (s *ReplicationService) ReplicatedPut(ctx context.Context, req *proto.PutRe
 		return nil, err
 	}
 
-	members := s.cluster.Members()
-	acksLeft := s.writeLevel.N(len(members))
+	writeLevel, err := consistencyLevel(req.Consistency, s.writeLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	all := s.cluster.Members()
+	members := s.preferenceList(req.Key, all, s.placement.ReplicationFactor())
+	fallbacks := s.fallbacks(req.Key, all, members)
+	acksLeft := writeLevel.N(len(members))
 
-	if countAlive(members) < acksLeft {
+	if countAlive(members)+len(fallbacks) < acksLeft {
 		return nil, errNotEnoughReplicas
 	}
 
-	criterr := make(chan error, 1)
-	localMember := s.cluster.Self()
-	localConn := s.cluster.SelfConn()
-	putResults := make(chan *nodePutResult, len(members))
+	primary, primaryConn, err := s.primary(members)
+	if err != nil {
+		return nil, err
+	}
 
-	// Initial write goes to the local node which increments the verstion vector.
-	newVersion, err := put(ctx, localConn, req.Key, req.Value.Data, req.Version, true)
+	// Initial write goes to the primary node which increments the verstion vector.
+	// It also assigns the timestamp, which is then kept by all replicas along with the origin.
+	primaryResp, err := put(ctx, primaryConn, req.Key, &storagepb.VersionedValue{
+		Version: req.Version,
+		Data:    req.Value.Data,
+	}, true)
 	if err != nil {
 		s.logger.Log("msg", "primary write failed", "err", err)
-		return nil, status.Errorf(codes.Internal, "failed to write to primary: %s", err)
+		return nil, primaryError(err)
+	}
+
+	newVersion := primaryResp.Version
+
+	replicaValue := &storagepb.VersionedValue{
+		Version:   newVersion,
+		Data:      req.Value.Data,
+		Timestamp: primaryResp.Timestamp,
+		Origin:    uint32(primary.ID),
+	}
+
+	// The primary node has already acknowledged the write, which is enough
+	// to fulfill the consistency.One level.
+	if err := s.replicate(ctx, req.Key, replicaValue, primary, members, fallbacks, acksLeft-1); err != nil {
+		return nil, err
 	}
 
+	return &proto.PutResponse{
+		Version: newVersion,
+	}, nil
+}
+
+// replicate writes the value accepted by the primary node to the other replicas, and waits
+// until acksLeft of them acknowledge it. The writes meant for the unreachable replicas go to
+// their fallback nodes, which keep them as hints until the replicas recover.
+func (s *ReplicationService) replicate(
+	ctx context.Context, key string, value *storagepb.VersionedValue, primary membership.Member,
+	members []membership.Member, fallbacks map[membership.NodeID]membership.Member, acksLeft int,
+) error {
+	criterr := make(chan error, 1)
+	putResults := make(chan *nodePutResult, len(members))
+
 	writeCtx, cancelWrite := context.WithTimeout(context.Background(), s.writeTimeout)
 
 	wg := sync.WaitGroup{}
 	wg.Add(len(members))
 
-	// The write is then replicated across other nodes.
 	for i := range members {
 		replica := &members[i]
+		hintFor := membership.NodeID(0)
 
-		if !replica.IsReacheable() || replica.ID == localMember.ID {
+		if replica.ID == primary.ID {
 			wg.Done()
 			continue
 		}
 
-		go func(member *membership.Member) {
+		if !replica.IsReacheable() {
+			fallback, ok := fallbacks[replica.ID]
+			if !ok {
+				wg.Done()
+				continue
+			}
+
+			replica, hintFor = &fallback, replica.ID
+		}
+
+		go func(replica *membership.Member, hintFor membership.NodeID) {
 			defer wg.Done()
 
 			select {
@@ -77,7 +131,14 @@ func (s *ReplicationService) ReplicatedPut(ctx context.Context, req *proto.PutRe
 				return
 			}
 
-			version, err := put(writeCtx, conn, req.Key, req.Value.Data, newVersion, false)
+			var resp *storagepb.PutResponse
+
+			if hintFor != 0 {
+				resp, err = putHint(writeCtx, conn, key, value, hintFor)
+			} else {
+				resp, err = put(writeCtx, conn, key, value, false)
+			}
+
 			if err != nil {
 				if grpcutil.ErrorCode(err) == codes.AlreadyExists {
 					// Some replicas already have a newer value, there is no point
@@ -92,7 +153,7 @@ func (s *ReplicationService) ReplicatedPut(ctx context.Context, req *proto.PutRe
 				level.Warn(s.logger).Log(
 					"msg", "write to replica has failed",
 					"replica", replica.Name,
-					"key", req.Key,
+					"key", key,
 					"err", err,
 				)
 
@@ -101,9 +162,9 @@ func (s *ReplicationService) ReplicatedPut(ctx context.Context, req *proto.PutRe
 
 			putResults <- &nodePutResult{
 				NodeID:  replica.ID,
-				Version: version,
+				Version: resp.Version,
 			}
-		}(replica)
+		}(replica, hintFor)
 	}
 
 	// To provide best-effort, we continue writing to replicas in the background even after
@@ -117,14 +178,8 @@ func (s *ReplicationService) ReplicatedPut(ctx context.Context, req *proto.PutRe
 		close(putResults)
 	}()
 
-	// At this point we already have one ack from the local node...
-	acksLeft--
-
-	// ...which is enough to fulfill the consistency.One level.
-	if s.writeLevel == consistency.One {
-		return &proto.PutResponse{
-			Version: newVersion,
-		}, nil
+	if acksLeft <= 0 {
+		return nil
 	}
 
 	for {
@@ -135,44 +190,69 @@ func (s *ReplicationService) ReplicatedPut(ctx context.Context, req *proto.PutRe
 
 		select {
 		case r := <-putResults:
-			if r != nil {
-				acksLeft--
+			if r == nil {
+				return errLevelNotSatisfied
+			}
 
-				if acksLeft == 0 {
-					return &proto.PutResponse{
-						Version: newVersion,
-					}, nil
-				}
-			} else {
-				return nil, errLevelNotSatisfied
+			acksLeft--
+
+			if acksLeft == 0 {
+				return nil
 			}
 		case err := <-criterr:
 			if err != nil {
 				cancelWrite()
-				return nil, err
+				return err
 			}
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		}
 	}
 }
 
+// primaryError wraps the error of the write to the primary node. The code of the error is
+// kept, so that the client can tell the writes rejected by the primary, such as the obsolete
+// ones or the ones exceeding the sibling limits, from the writes that have failed.
+func primaryError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return status.Errorf(codes.Internal, "failed to write to primary: %s", err)
+	}
+
+	return status.Errorf(st.Code(), "failed to write to primary: %s", st.Message())
+}
+
 func put(ctx context.Context, conn clust.Conn, key string,
-	value []byte, version string, primary bool) (string, error) {
+	value *storagepb.VersionedValue, primary bool) (*storagepb.PutResponse, error) {
 
 	req := &storagepb.PutRequest{
 		Key:     key,
 		Primary: primary,
-		Value: &storagepb.VersionedValue{
-			Version: version,
-			Data:    value,
-		},
+		Value:   value,
 	}
 
 	resp, err := conn.Put(ctx, req)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// putHint sends the write meant for an unreachable replica to its fallback node.
+func putHint(ctx context.Context, conn clust.Conn, key string,
+	value *storagepb.VersionedValue, owner membership.NodeID) (*storagepb.PutResponse, error) {
+
+	req := &storagepb.PutRequest{
+		Key:     key,
+		Value:   value,
+		HintFor: uint32(owner),
+	}
+
+	resp, err := conn.Put(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
-	return resp.Version, nil
+	return resp, nil
 }
@@ -12,6 +12,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	clustmock "github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/clust/ring"
 	"github.com/maxpoletaev/kv/internal/vclock"
 	"github.com/maxpoletaev/kv/membership"
 	"github.com/maxpoletaev/kv/replication/consistency"
@@ -49,6 +50,7 @@ func TestReplicatedPut(t *testing.T) {
 					Value: &storagepb.VersionedValue{
 						Version: vclock.NewEncoded(vclock.V{1: 1}),
 						Data:    []byte("value"),
+						Origin:  1,
 					},
 				}).Return(&storagepb.PutResponse{
 					Version: vclock.NewEncoded(vclock.V{1: 1}),
@@ -60,6 +62,7 @@ func TestReplicatedPut(t *testing.T) {
 					Value: &storagepb.VersionedValue{
 						Version: vclock.NewEncoded(vclock.V{1: 1}),
 						Data:    []byte("value"),
+						Origin:  1,
 					},
 				}).Return(nil, assert.AnError).MaxTimes(1)
 
@@ -105,6 +108,7 @@ func TestReplicatedPut(t *testing.T) {
 					Value: &storagepb.VersionedValue{
 						Version: vclock.NewEncoded(vclock.V{1: 1}),
 						Data:    []byte("value"),
+						Origin:  1,
 					},
 				}).Return(nil, assert.AnError).MaxTimes(1)
 
@@ -114,6 +118,7 @@ func TestReplicatedPut(t *testing.T) {
 					Value: &storagepb.VersionedValue{
 						Version: vclock.NewEncoded(vclock.V{1: 1}),
 						Data:    []byte("value"),
+						Origin:  1,
 					},
 				}).Return(nil, assert.AnError).MaxTimes(1)
 
@@ -154,6 +159,34 @@ func TestReplicatedPut(t *testing.T) {
 			wantCode: codes.FailedPrecondition,
 			wantErr:  errNotEnoughReplicas,
 		},
+		"RequestedLevelOverridesDefault": {
+			writeLevel: consistency.Quorum,
+			setupCluster: func(ctrl *gomock.Controller, c *MockCluster) {
+				self := membership.Member{ID: 1, Name: "node1", Status: membership.StatusHealthy}
+
+				conn := clustmock.NewMockClient(ctrl)
+				conn.EXPECT().Put(gomock.Any(), gomock.Any()).Return(&storagepb.PutResponse{
+					Version: vclock.NewEncoded(vclock.V{1: 1}),
+				}, nil)
+
+				c.EXPECT().Self().Return(self)
+				c.EXPECT().SelfConn().Return(conn)
+				c.EXPECT().Members().Return([]membership.Member{
+					self,
+					{ID: 2, Name: "node2", Status: membership.StatusFaulty},
+					{ID: 3, Name: "node3", Status: membership.StatusFaulty},
+				})
+			},
+			req: &proto.PutRequest{
+				Key:         "key",
+				Version:     vclock.NewEncoded(),
+				Value:       &proto.Value{Data: []byte("value")},
+				Consistency: proto.ConsistencyLevel_ONE,
+			},
+			want: &proto.PutResponse{
+				Version: vclock.NewEncoded(vclock.V{1: 1}),
+			},
+		},
 		"WriteToLocalNodeFails": {
 			writeLevel: consistency.One,
 			setupCluster: func(ctrl *gomock.Controller, c *MockCluster) {
@@ -177,6 +210,40 @@ func TestReplicatedPut(t *testing.T) {
 			},
 			wantCode: codes.Internal,
 		},
+		"WriteRejectedByLocalNode": {
+			writeLevel: consistency.One,
+			setupCluster: func(ctrl *gomock.Controller, c *MockCluster) {
+				self := membership.Member{
+					ID:     1,
+					Name:   "node1",
+					Status: membership.StatusHealthy,
+				}
+
+				conn := clustmock.NewMockClient(ctrl)
+				conn.EXPECT().Put(gomock.Any(), gomock.Any()).Return(
+					nil, status.Error(codes.ResourceExhausted, "too many concurrent versions"))
+
+				c.EXPECT().Self().Return(self)
+				c.EXPECT().SelfConn().Return(conn)
+				c.EXPECT().Members().Return([]membership.Member{self})
+			},
+			req: &proto.PutRequest{
+				Key:     "key",
+				Version: vclock.NewEncoded(),
+				Value:   &proto.Value{Data: []byte("value")},
+			},
+			wantCode: codes.ResourceExhausted,
+		},
+		"MissingValue": {
+			writeLevel:   consistency.One,
+			setupCluster: func(ctrl *gomock.Controller, c *MockCluster) {},
+			req: &proto.PutRequest{
+				Key:     "key",
+				Version: vclock.NewEncoded(),
+			},
+			wantCode: codes.InvalidArgument,
+			wantErr:  errMissingValue,
+		},
 	}
 
 	for name, test := range tests {
@@ -201,3 +268,162 @@ func TestReplicatedPut(t *testing.T) {
 		})
 	}
 }
+
+func TestReplicatedPut_PreferenceList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	members := make([]membership.Member, 0, 5)
+	ids := make([]membership.NodeID, 0, 5)
+
+	for i := 1; i <= 5; i++ {
+		id := membership.NodeID(i)
+		ids = append(ids, id)
+		members = append(members, membership.Member{ID: id, Status: membership.StatusHealthy})
+	}
+
+	replicas := ring.New(ids, ring.DefaultVirtualNodes).PreferenceList("key", 3)
+
+	// The local node is not a replica of the key, so the
+	// write is coordinated by the first node of the list.
+	var self membership.Member
+
+	for _, m := range members {
+		if m.ID != replicas[0] && m.ID != replicas[1] && m.ID != replicas[2] {
+			self = m
+		}
+	}
+
+	c := NewMockCluster(ctrl)
+	c.EXPECT().Members().Return(members)
+	c.EXPECT().Self().Return(self)
+
+	primaryConn := clustmock.NewMockClient(ctrl)
+	primaryConn.EXPECT().Put(gomock.Any(), &storagepb.PutRequest{
+		Key:     "key",
+		Primary: true,
+		Value: &storagepb.VersionedValue{
+			Version: vclock.NewEncoded(),
+			Data:    []byte("value"),
+		},
+	}).Return(&storagepb.PutResponse{
+		Version:   vclock.NewEncoded(vclock.V{uint32(replicas[0]): 1}),
+		Timestamp: 10,
+	}, nil)
+
+	c.EXPECT().Conn(replicas[0]).Return(primaryConn, nil)
+
+	// Only the other two nodes of the list get the replicated write.
+	for _, id := range replicas[1:] {
+		conn := clustmock.NewMockClient(ctrl)
+		conn.EXPECT().Put(gomock.Any(), &storagepb.PutRequest{
+			Key: "key",
+			Value: &storagepb.VersionedValue{
+				Version:   vclock.NewEncoded(vclock.V{uint32(replicas[0]): 1}),
+				Data:      []byte("value"),
+				Timestamp: 10,
+				Origin:    uint32(replicas[0]),
+			},
+		}).Return(&storagepb.PutResponse{}, nil)
+
+		c.EXPECT().Conn(id).Return(conn, nil)
+	}
+
+	s := New(c, log.NewNopLogger(), consistency.One, consistency.All, WithPlacement(ring.NewPlacement(3, ring.DefaultVirtualNodes)))
+
+	got, err := s.ReplicatedPut(context.Background(), &proto.PutRequest{
+		Key:     "key",
+		Version: vclock.NewEncoded(),
+		Value:   &proto.Value{Data: []byte("value")},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, vclock.NewEncoded(vclock.V{uint32(replicas[0]): 1}), got.Version)
+}
+
+func TestReplicatedPut_HintedHandoff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	members := make([]membership.Member, 0, 5)
+	ids := make([]membership.NodeID, 0, 5)
+
+	for i := 1; i <= 5; i++ {
+		id := membership.NodeID(i)
+		ids = append(ids, id)
+		members = append(members, membership.Member{ID: id, Status: membership.StatusHealthy})
+	}
+
+	order := ring.New(ids, ring.DefaultVirtualNodes).PreferenceList("key", 5)
+	primary, replica, unreachable, fallback := order[0], order[1], order[2], order[3]
+
+	for i := range members {
+		if members[i].ID == unreachable {
+			members[i].Status = membership.StatusFaulty
+		}
+	}
+
+	var self membership.Member
+
+	for _, m := range members {
+		if m.ID == primary {
+			self = m
+		}
+	}
+
+	version := vclock.NewEncoded(vclock.V{uint32(primary): 1})
+	replicaValue := &storagepb.VersionedValue{
+		Version:   version,
+		Data:      []byte("value"),
+		Timestamp: 10,
+		Origin:    uint32(primary),
+	}
+
+	c := NewMockCluster(ctrl)
+	c.EXPECT().Members().Return(members).Times(2)
+	c.EXPECT().Self().Return(self)
+
+	primaryConn := clustmock.NewMockClient(ctrl)
+	primaryConn.EXPECT().Put(gomock.Any(), gomock.Any()).Return(&storagepb.PutResponse{
+		Version:   version,
+		Timestamp: 10,
+	}, nil)
+
+	c.EXPECT().SelfConn().Return(primaryConn)
+
+	replicaConn := clustmock.NewMockClient(ctrl)
+	replicaConn.EXPECT().Put(gomock.Any(), &storagepb.PutRequest{
+		Key:   "key",
+		Value: replicaValue,
+	}).Return(&storagepb.PutResponse{Version: version}, nil)
+
+	c.EXPECT().Conn(replica).Return(replicaConn, nil)
+
+	// The write meant for the unreachable replica goes to the next node on the ring.
+	fallbackConn := clustmock.NewMockClient(ctrl)
+	fallbackConn.EXPECT().Put(gomock.Any(), &storagepb.PutRequest{
+		Key:     "key",
+		Value:   replicaValue,
+		HintFor: uint32(unreachable),
+	}).Return(&storagepb.PutResponse{Version: version}, nil)
+
+	c.EXPECT().Conn(fallback).Return(fallbackConn, nil)
+
+	req := &proto.PutRequest{
+		Key:     "key",
+		Version: vclock.NewEncoded(),
+		Value:   &proto.Value{Data: []byte("value")},
+	}
+
+	// Without the hinted handoff, the consistency level cannot be satisfied.
+	s := New(c, log.NewNopLogger(), consistency.One, consistency.All, WithPlacement(ring.NewPlacement(3, ring.DefaultVirtualNodes)))
+	_, err := s.ReplicatedPut(context.Background(), req)
+	require.ErrorIs(t, err, errNotEnoughReplicas)
+
+	s = New(c, log.NewNopLogger(), consistency.One, consistency.All,
+		WithPlacement(ring.NewPlacement(3, ring.DefaultVirtualNodes)), WithHintedHandoff(true))
+
+	got, err := s.ReplicatedPut(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, version, got.Version)
+}
@@ -3,15 +3,21 @@ package service
 //go:generate moq -stub -out service_mock.go . cluster
 
 import (
+	"fmt"
+	"math/rand"
 	"time"
 
 	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/maxpoletaev/kv/clust"
+	"github.com/maxpoletaev/kv/clust/ring"
 	"github.com/maxpoletaev/kv/membership"
 	"github.com/maxpoletaev/kv/replication/consistency"
 	"github.com/maxpoletaev/kv/replication/proto"
+	"github.com/maxpoletaev/kv/storage"
 	storagepb "github.com/maxpoletaev/kv/storage/proto"
 )
 
@@ -19,6 +25,7 @@ const (
 	defaultReadTimeout      = time.Second * 5
 	defaultWriteTimeout     = time.Second * 5
 	defaultConsistencyLevel = consistency.Quorum
+	maxBatchSize            = 1000
 )
 
 var (
@@ -26,6 +33,11 @@ var (
 	errNotEnoughReplicas = status.Error(codes.FailedPrecondition, "not enough replicas available to satisfy the consistency level")
 	errMissingVersion    = status.Error(codes.InvalidArgument, "version is required")
 	errMissingKey        = status.Error(codes.InvalidArgument, "key is required")
+	errMissingOperator   = status.Error(codes.InvalidArgument, "operator is required")
+	errInvalidLevel      = status.Error(codes.InvalidArgument, "unknown consistency level")
+	errMissingValue      = status.Error(codes.InvalidArgument, "value is required")
+	errEmptyBatch        = status.Error(codes.InvalidArgument, "at least one key is required")
+	errBatchTooLarge     = status.Error(codes.InvalidArgument, fmt.Sprintf("batch size exceeds %d keys", maxBatchSize))
 )
 
 type nodePutResult struct {
@@ -45,6 +57,8 @@ type nodeValue struct {
 
 type serviceOption func(s *ReplicationService)
 
+// WithConsistencyLevel sets the default consistency levels of reads and writes,
+// used when the client does not request a level explicitly.
 func WithConsistencyLevel(read, write consistency.Level) serviceOption {
 	return func(s *ReplicationService) {
 		s.readLevel = read
@@ -52,25 +66,127 @@ func WithConsistencyLevel(read, write consistency.Level) serviceOption {
 	}
 }
 
+// WithKeyspaces sets the per-keyspace settings, such as the conflict resolution mode.
+func WithKeyspaces(keyspaces storage.Keyspaces) serviceOption {
+	return func(s *ReplicationService) {
+		s.keyspaces = keyspaces
+	}
+}
+
+// WithPlacement sets the placement that decides which nodes each key is stored on.
+func WithPlacement(p *ring.Placement) serviceOption {
+	return func(s *ReplicationService) {
+		s.placement = p
+	}
+}
+
+// WithHintedHandoff enables the sloppy quorum. The writes meant for the unreachable replicas
+// are sent to the next healthy nodes found on the ring past the replicas of the key, which
+// keep them as hints and pass them on once the replicas recover. The writes acknowledged by
+// such nodes count towards the consistency level.
+func WithHintedHandoff(enabled bool) serviceOption {
+	return func(s *ReplicationService) {
+		s.hintedHandoff = enabled
+	}
+}
+
+// WithReadRepair sets how the stale replicas found by the reads are repaired.
+func WithReadRepair(conf ReadRepairConfig) serviceOption {
+	return func(s *ReplicationService) {
+		s.readRepair = conf
+	}
+}
+
+// WithHedgedReads sets whether the reads go to all replicas or only to the fastest ones.
+func WithHedgedReads(conf HedgedReadConfig) serviceOption {
+	return func(s *ReplicationService) {
+		s.hedgedReads = conf
+	}
+}
+
+// WithDynamicSnitch sets whether the reads avoid the replicas that have become slow.
+func WithDynamicSnitch(conf SnitchConfig) serviceOption {
+	return func(s *ReplicationService) {
+		s.snitch = conf
+	}
+}
+
+// ReplicationService coordinates the reads and writes of the keys across the replicas.
+// The keys are partitioned with a consistent hash ring built from the cluster members,
+// and each key is stored on the first N nodes found on the ring starting from the key,
+// where N is the replication factor. The consistency levels are computed against N,
+// or against the cluster size if the cluster is smaller than that.
 type ReplicationService struct {
 	proto.UnimplementedCoordinatorServiceServer
 
-	cluster      Cluster
-	logger       kitlog.Logger
-	readTimeout  time.Duration
-	writeTimeout time.Duration
-	readLevel    consistency.Level
-	writeLevel   consistency.Level
+	cluster       Cluster
+	logger        kitlog.Logger
+	readTimeout   time.Duration
+	writeTimeout  time.Duration
+	readLevel     consistency.Level
+	writeLevel    consistency.Level
+	keyspaces     storage.Keyspaces
+	placement     *ring.Placement
+	hintedHandoff bool
+	readRepair    ReadRepairConfig
+	repairs       *repairPool
+	randFloat     func() float64
+	hedgedReads   HedgedReadConfig
+	hedgeCounters hedgeCounters
+	latencies     *latencyTracker
+	snitch        SnitchConfig
 }
 
-func New(clust Cluster, logger kitlog.Logger, readLevel, writeLevel consistency.Level) *ReplicationService {
-	return &ReplicationService{
+func New(clust Cluster, logger kitlog.Logger, readLevel, writeLevel consistency.Level, opts ...serviceOption) *ReplicationService {
+	svc := &ReplicationService{
 		cluster:      clust,
 		logger:       logger,
 		readTimeout:  defaultReadTimeout,
 		writeTimeout: defaultWriteTimeout,
 		readLevel:    readLevel,
 		writeLevel:   writeLevel,
+		placement:    ring.DefaultPlacement(),
+		readRepair:   DefaultReadRepairConfig(),
+		randFloat:    rand.Float64,
+		hedgedReads:  DefaultHedgedReadConfig(),
+		latencies:    newLatencyTracker(),
+		snitch:       DefaultSnitchConfig(),
+	}
+
+	for _, opt := range opts {
+		opt(svc)
+	}
+
+	if svc.readRepair.Mode == ReadRepairAsync {
+		svc.repairs = newRepairPool(svc.readRepair.Workers, svc.readRepair.QueueSize, svc.repairAsync)
+	}
+
+	return svc
+}
+
+// Close waits for the queued read repairs to finish.
+func (s *ReplicationService) Close() {
+	if s.repairs != nil {
+		s.repairs.close()
+	}
+}
+
+// consistencyLevel returns the consistency level requested by the client,
+// or the given default if the client has not requested any.
+func consistencyLevel(requested proto.ConsistencyLevel, fallback consistency.Level) (consistency.Level, error) {
+	switch requested {
+	case proto.ConsistencyLevel_DEFAULT:
+		return fallback, nil
+	case proto.ConsistencyLevel_ONE:
+		return consistency.One, nil
+	case proto.ConsistencyLevel_TWO:
+		return consistency.Two, nil
+	case proto.ConsistencyLevel_QUORUM:
+		return consistency.Quorum, nil
+	case proto.ConsistencyLevel_ALL:
+		return consistency.All, nil
+	default:
+		return 0, errInvalidLevel
 	}
 }
 
@@ -83,3 +199,99 @@ func countAlive(members []membership.Member) (alive int) {
 
 	return
 }
+
+// replicas returns the members the key is stored on, in the order of preference.
+func (s *ReplicationService) replicas(key string) []membership.Member {
+	return s.preferenceList(key, s.cluster.Members(), s.placement.ReplicationFactor())
+}
+
+// preferenceList returns the first n members found on the ring starting from the key.
+func (s *ReplicationService) preferenceList(key string, members []membership.Member, n int) []membership.Member {
+	byID := make(map[membership.NodeID]membership.Member, len(members))
+
+	for i := range members {
+		byID[members[i].ID] = members[i]
+	}
+
+	ids := s.hashRing(members).PreferenceList(key, n)
+	replicas := make([]membership.Member, 0, len(ids))
+
+	for _, id := range ids {
+		replicas = append(replicas, byID[id])
+	}
+
+	return replicas
+}
+
+// fallbacks assigns a reachable member found on the ring past the replicas of the key to
+// each unreachable replica, in the order of preference. The replicas left without one
+// are not in the result. It returns nil if the hinted handoff is disabled.
+func (s *ReplicationService) fallbacks(key string, members, replicas []membership.Member) map[membership.NodeID]membership.Member {
+	if !s.hintedHandoff || countAlive(replicas) == len(replicas) {
+		return nil
+	}
+
+	// The shorter preference lists are the prefixes of the longer ones,
+	// so the members past the replicas are the next in the order.
+	candidates := s.preferenceList(key, members, len(members))[len(replicas):]
+	fallbacks := make(map[membership.NodeID]membership.Member)
+
+	for i := range replicas {
+		if replicas[i].IsReacheable() {
+			continue
+		}
+
+		for len(candidates) > 0 && !candidates[0].IsReacheable() {
+			candidates = candidates[1:]
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		fallbacks[replicas[i].ID] = candidates[0]
+		candidates = candidates[1:]
+	}
+
+	return fallbacks
+}
+
+// hashRing returns the hash ring of the given members.
+func (s *ReplicationService) hashRing(members []membership.Member) *ring.Ring {
+	ids := make([]membership.NodeID, 0, len(members))
+	for i := range members {
+		ids = append(ids, members[i].ID)
+	}
+
+	return s.placement.Ring(ids)
+}
+
+// primary returns the replica that applies the write first and assigns its version. This
+// is the local node if it is one of the replicas, so that the write does not take an extra
+// hop, or the first reachable replica in the order of preference otherwise.
+func (s *ReplicationService) primary(replicas []membership.Member) (membership.Member, clust.Conn, error) {
+	self := s.cluster.Self()
+
+	for i := range replicas {
+		if replicas[i].ID == self.ID {
+			return self, s.cluster.SelfConn(), nil
+		}
+	}
+
+	for i := range replicas {
+		replica := &replicas[i]
+		if !replica.IsReacheable() {
+			continue
+		}
+
+		conn, err := s.cluster.Conn(replica.ID)
+		if err != nil {
+			level.Warn(s.logger).Log("msg", "failed to get connection", "name", replica.Name, "err", err)
+			continue
+		}
+
+		return *replica, conn, nil
+	}
+
+	return membership.Member{}, nil, errNotEnoughReplicas
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/maxpoletaev/kv/membership"
+)
+
+const (
+	// snitchErrorPenalty is how much the error rate of a replica adds to its score. A replica
+	// failing one call out of ten scores the same as a healthy one that is twice as slow.
+	snitchErrorPenalty = 10
+	// snitchMinScore is the score below which the replicas are considered equally fast, so
+	// that the noise in the sub-millisecond latencies does not make them avoided.
+	snitchMinScore = float64(time.Millisecond)
+)
+
+// SnitchConfig defines whether the reads avoid the replicas that have become slow.
+type SnitchConfig struct {
+	// Enabled makes the reads skip the replicas scoring worse than the badness threshold,
+	// as long as the rest of the replicas are enough to satisfy the consistency level. The
+	// score of a replica is the moving average of the latency of the calls made to it,
+	// increased by its recent error rate. This way a replica that has become slow is avoided
+	// before the failure detector marks it faulty.
+	Enabled bool
+	// BadnessThreshold is how much worse than the score of the best replica the score of
+	// a replica must be for it to be avoided, as a fraction of the best score.
+	BadnessThreshold float64
+}
+
+// DefaultSnitchConfig returns the default dynamic snitch configuration.
+func DefaultSnitchConfig() SnitchConfig {
+	return SnitchConfig{
+		Enabled:          false,
+		BadnessThreshold: 0.5,
+	}
+}
+
+// score returns the score of the replica, the lower the better, or false if no calls have
+// been made to the replica yet.
+func (s *ReplicationService) score(id membership.NodeID) (float64, bool) {
+	stats, ok := s.cluster.MemberStats(id)
+	if !ok {
+		return 0, false
+	}
+
+	return float64(stats.Latency) * (1 + snitchErrorPenalty*stats.ErrorRate), true
+}
+
+// rankReplicas returns the reachable replicas, ordered by their score, and the number of
+// the replicas at the beginning of the list that are not worse than the badness threshold.
+// The replicas that have not been called yet go first, so that they get a score. If the
+// snitch is disabled, none of the replicas are considered bad.
+func (s *ReplicationService) rankReplicas(replicas []membership.Member) ([]*membership.Member, int) {
+	ranked := make([]*membership.Member, 0, len(replicas))
+	scores := make(map[membership.NodeID]float64, len(replicas))
+	best, known := 0.0, false
+
+	for i := range replicas {
+		if !replicas[i].IsReacheable() {
+			continue
+		}
+
+		score, ok := s.score(replicas[i].ID)
+		if ok && (!known || score < best) {
+			best, known = score, true
+		}
+
+		ranked = append(ranked, &replicas[i])
+		scores[replicas[i].ID] = score
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].ID] < scores[ranked[j].ID]
+	})
+
+	if !s.snitch.Enabled {
+		return ranked, len(ranked)
+	}
+
+	if best < snitchMinScore {
+		best = snitchMinScore
+	}
+
+	limit := best * (1 + s.snitch.BadnessThreshold)
+	good := 0
+
+	for good < len(ranked) && scores[ranked[good].ID] <= limit {
+		good++
+	}
+
+	return ranked, good
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clustmock "github.com/maxpoletaev/kv/clust/mock"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/replication/consistency"
+	"github.com/maxpoletaev/kv/replication/proto"
+	storagepb "github.com/maxpoletaev/kv/storage/proto"
+)
+
+func TestRankReplicas(t *testing.T) {
+	tests := map[string]struct {
+		enabled   bool
+		stats     map[membership.NodeID]membership.Stats
+		faulty    membership.NodeID
+		wantOrder []membership.NodeID
+		wantGood  int
+	}{
+		"SlowReplicaAvoided": {
+			enabled: true,
+			stats: map[membership.NodeID]membership.Stats{
+				1: {Latency: 10 * time.Millisecond},
+				2: {Latency: 2 * time.Millisecond},
+				3: {Latency: 3 * time.Millisecond},
+			},
+			wantOrder: []membership.NodeID{2, 3, 1},
+			wantGood:  2,
+		},
+		"FailingReplicaAvoided": {
+			enabled: true,
+			stats: map[membership.NodeID]membership.Stats{
+				1: {Latency: 2 * time.Millisecond, ErrorRate: 0.2},
+				2: {Latency: 2 * time.Millisecond},
+				3: {Latency: 2 * time.Millisecond},
+			},
+			wantOrder: []membership.NodeID{2, 3, 1},
+			wantGood:  2,
+		},
+		"SubMillisecondNoiseIgnored": {
+			enabled: true,
+			stats: map[membership.NodeID]membership.Stats{
+				1: {Latency: 300 * time.Microsecond},
+				2: {Latency: 100 * time.Microsecond},
+				3: {Latency: 200 * time.Microsecond},
+			},
+			wantOrder: []membership.NodeID{2, 3, 1},
+			wantGood:  3,
+		},
+		"UnknownReplicaFirst": {
+			enabled: true,
+			stats: map[membership.NodeID]membership.Stats{
+				1: {Latency: 2 * time.Millisecond},
+				2: {Latency: 2 * time.Millisecond},
+			},
+			wantOrder: []membership.NodeID{3, 1, 2},
+			wantGood:  3,
+		},
+		"FaultyReplicaSkipped": {
+			enabled: true,
+			stats: map[membership.NodeID]membership.Stats{
+				1: {Latency: 2 * time.Millisecond},
+				3: {Latency: 2 * time.Millisecond},
+			},
+			faulty:    2,
+			wantOrder: []membership.NodeID{1, 3},
+			wantGood:  2,
+		},
+		"Disabled": {
+			enabled: false,
+			stats: map[membership.NodeID]membership.Stats{
+				1: {Latency: 10 * time.Millisecond},
+				2: {Latency: 2 * time.Millisecond},
+				3: {Latency: 3 * time.Millisecond},
+			},
+			wantOrder: []membership.NodeID{2, 3, 1},
+			wantGood:  3,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			c := NewMockCluster(ctrl)
+			c.EXPECT().MemberStats(gomock.Any()).DoAndReturn(func(id membership.NodeID) (membership.Stats, bool) {
+				stats, ok := test.stats[id]
+				return stats, ok
+			}).AnyTimes()
+
+			conf := DefaultSnitchConfig()
+			conf.Enabled = test.enabled
+
+			s := New(c, log.NewNopLogger(), consistency.Quorum, consistency.Quorum, WithDynamicSnitch(conf))
+
+			replicas := []membership.Member{
+				{ID: 1, Status: membership.StatusHealthy},
+				{ID: 2, Status: membership.StatusHealthy},
+				{ID: 3, Status: membership.StatusHealthy},
+			}
+
+			if test.faulty != 0 {
+				replicas[test.faulty-1].Status = membership.StatusFaulty
+			}
+
+			ranked, good := s.rankReplicas(replicas)
+
+			order := make([]membership.NodeID, 0, len(ranked))
+			for _, r := range ranked {
+				order = append(order, r.ID)
+			}
+
+			assert.Equal(t, test.wantOrder, order)
+			assert.Equal(t, test.wantGood, good)
+		})
+	}
+}
+
+func TestReplicatedGet_SnitchAvoidsSlowReplica(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := NewMockCluster(ctrl)
+	c.EXPECT().Members().Return([]membership.Member{
+		{ID: 1, Name: "node1", Status: membership.StatusHealthy},
+		{ID: 2, Name: "node2", Status: membership.StatusHealthy},
+		{ID: 3, Name: "node3", Status: membership.StatusHealthy},
+	})
+
+	c.EXPECT().MemberStats(membership.NodeID(1)).Return(membership.Stats{Latency: 2 * time.Millisecond}, true)
+	c.EXPECT().MemberStats(membership.NodeID(2)).Return(membership.Stats{Latency: 2 * time.Millisecond}, true)
+	c.EXPECT().MemberStats(membership.NodeID(3)).Return(membership.Stats{Latency: time.Second}, true)
+
+	value := &storagepb.VersionedValue{Version: vclock.NewEncoded(vclock.V{1: 1}), Data: []byte("value")}
+
+	// Node 3 is not read, even though the failure detector still considers it healthy.
+	for _, id := range []membership.NodeID{1, 2} {
+		conn := clustmock.NewMockClient(ctrl)
+		conn.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&storagepb.GetResponse{
+			Value: []*storagepb.VersionedValue{value},
+		}, nil)
+		c.EXPECT().Conn(id).Return(conn, nil)
+	}
+
+	conf := DefaultSnitchConfig()
+	conf.Enabled = true
+
+	s := New(c, log.NewNopLogger(), consistency.Quorum, consistency.Quorum, WithDynamicSnitch(conf))
+
+	got, err := s.ReplicatedGet(context.Background(), &proto.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), got.Values[0].Data)
+}
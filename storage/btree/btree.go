@@ -0,0 +1,380 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/maxpoletaev/kv/internal/protoio"
+)
+
+const dataFileName = "btree.db"
+
+var (
+	// ErrKeyTooLarge is returned when the key does not fit into a page.
+	ErrKeyTooLarge = errors.New("key is too large")
+	// ErrEmptyKey is returned when the key is empty.
+	ErrEmptyKey = errors.New("key is empty")
+	// ErrNotEncrypted is returned when a cipher is given for the data file created without encryption.
+	ErrNotEncrypted = errors.New("data file is not encrypted")
+)
+
+// BTree is a persistent key-value store based on the copy-on-write B+tree. It is
+// optimized for reads: a lookup reads a single page per level of the tree. Pages are
+// never modified in place. Instead, every write copies the path from the root to the
+// leaf into free pages, and then atomically switches to the new root by writing the
+// meta page. The pages of the old path are returned to the free-list and can be
+// reused by the next write. Writes are serialized, reads may run concurrently.
+//
+// When the tree is encrypted, every page except for the meta pages is stored as the
+// key ID followed by the sealed page body. The body is shorter than the page by the
+// size of the key ID and the cipher overhead, so the nodes are split by the body size.
+type BTree struct {
+	mut      sync.RWMutex
+	file     *os.File
+	meta     meta
+	free     []pgid // sorted in descending order
+	freePgs  []pgid // pages occupied by the free-list itself
+	cipher   protoio.Cipher
+	overhead int // bytes added to each page by the encryption
+	pageSize int // size of the page in the file
+	bodySize int // usable size of the page
+	noSync   bool
+}
+
+// Open opens the tree in the directory given in the config, or creates
+// a new one if the directory does not contain a tree yet.
+func Open(conf Config) (*BTree, error) {
+	if conf.PageSize < minPageSize || conf.PageSize > maxPageSize {
+		return nil, fmt.Errorf("page size must be between %d and %d", minPageSize, maxPageSize)
+	}
+
+	file, err := os.OpenFile(filepath.Join(conf.DataRoot, dataFileName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data file: %w", err)
+	}
+
+	t := &BTree{
+		file:     file,
+		cipher:   conf.Cipher,
+		pageSize: conf.PageSize,
+		noSync:   conf.NoSync,
+	}
+
+	if t.cipher != nil {
+		// The overhead does not depend on the size of the plaintext, so it is enough to seal
+		// an empty buffer to find it out.
+		_, probe, err := t.cipher.Seal(nil)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to probe cipher: %w", err)
+		}
+
+		t.overhead = 2 + len(probe)
+	}
+
+	t.bodySize = t.pageSize - t.overhead
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat data file: %w", err)
+	}
+
+	if info.Size() == 0 {
+		err = t.init()
+	} else {
+		err = t.load()
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// init writes the two meta pages and the empty root leaf into a new file.
+func (t *BTree) init() error {
+	buf := make([]byte, t.pageSize*3)
+	root := &node{leaf: true}
+	body := make([]byte, t.bodySize)
+	root.encode(body)
+
+	page, err := t.sealPage(body)
+	if err != nil {
+		return err
+	}
+
+	copy(buf[t.pageSize*2:], page)
+
+	t.meta = meta{
+		pageSize:  uint32(t.pageSize),
+		root:      2,
+		pageCount: 3,
+	}
+
+	if t.cipher != nil {
+		t.meta.flags |= metaFlagEncrypted
+	}
+
+	for i := 0; i < 2; i++ {
+		m := t.meta
+		m.txid = uint64(i)
+		m.encode(buf[t.pageSize*i:])
+	}
+
+	if _, err := t.file.WriteAt(buf, 0); err != nil {
+		return fmt.Errorf("failed to write initial pages: %w", err)
+	}
+
+	if err := t.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync data file: %w", err)
+	}
+
+	t.meta.txid = 1
+
+	return nil
+}
+
+// load reads the latest valid meta page and the free-list.
+func (t *BTree) load() error {
+	var metas [2]meta
+
+	buf := make([]byte, metaSize)
+	errs := make([]error, 2)
+
+	if _, err := t.file.ReadAt(buf, 0); err != nil {
+		return fmt.Errorf("failed to read meta page: %w", err)
+	}
+
+	// The page size of the existing file takes precedence over the one from the config.
+	// If the first meta is corrupted, we have to rely on the config to locate the second.
+	if errs[0] = metas[0].decode(buf); errs[0] == nil {
+		t.pageSize = int(metas[0].pageSize)
+	}
+
+	if _, err := t.file.ReadAt(buf, int64(t.pageSize)); err != nil {
+		return fmt.Errorf("failed to read meta page: %w", err)
+	}
+
+	errs[1] = metas[1].decode(buf)
+
+	switch {
+	case errs[0] != nil && errs[1] != nil:
+		return fmt.Errorf("both meta pages are invalid: %w", errs[0])
+	case errs[0] != nil:
+		t.meta = metas[1]
+	case errs[1] != nil:
+		t.meta = metas[0]
+	case metas[1].txid > metas[0].txid:
+		t.meta = metas[1]
+	default:
+		t.meta = metas[0]
+	}
+
+	t.pageSize = int(t.meta.pageSize)
+	t.bodySize = t.pageSize - t.overhead
+
+	encrypted := t.meta.flags&metaFlagEncrypted != 0
+
+	switch {
+	case encrypted && t.cipher == nil:
+		return protoio.ErrNoCipher
+	case !encrypted && t.cipher != nil:
+		return ErrNotEncrypted
+	}
+
+	for id := t.meta.freelist; id != 0; {
+		page, err := t.readPage(id)
+		if err != nil {
+			return fmt.Errorf("failed to read free-list: %w", err)
+		}
+
+		h := readPageHeader(page)
+		if h.typ != freelistPage {
+			return fmt.Errorf("unexpected page type %d in free-list", h.typ)
+		}
+
+		for i := 0; i < int(h.count); i++ {
+			t.free = append(t.free, pgid(byteOrder.Uint64(page[pageHeaderSize+i*8:])))
+		}
+
+		t.freePgs = append(t.freePgs, id)
+		id = h.next
+	}
+
+	sortDesc(t.free)
+
+	return nil
+}
+
+func sortDesc(ids []pgid) {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] > ids[j]
+	})
+}
+
+// readPage reads the page and returns its body, decrypted if the tree is encrypted.
+func (t *BTree) readPage(id pgid) ([]byte, error) {
+	buf := make([]byte, t.pageSize)
+
+	if _, err := t.file.ReadAt(buf, int64(id)*int64(t.pageSize)); err != nil {
+		return nil, fmt.Errorf("failed to read page %d: %w", id, err)
+	}
+
+	if t.cipher == nil {
+		return buf, nil
+	}
+
+	body, err := t.cipher.Open(byteOrder.Uint16(buf), buf[2:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt page %d: %w", id, err)
+	}
+
+	return body, nil
+}
+
+// sealPage turns the page body into the page as it is stored in the file.
+func (t *BTree) sealPage(body []byte) ([]byte, error) {
+	if t.cipher == nil {
+		return body, nil
+	}
+
+	keyID, sealed, err := t.cipher.Seal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt page: %w", err)
+	}
+
+	page := make([]byte, t.pageSize)
+	byteOrder.PutUint16(page, keyID)
+	copy(page[2:], sealed)
+
+	return page, nil
+}
+
+func (t *BTree) readNode(id pgid) (*node, error) {
+	page, err := t.readPage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &node{}
+	if err := n.decode(page); err != nil {
+		return nil, fmt.Errorf("failed to decode page %d: %w", id, err)
+	}
+
+	return n, nil
+}
+
+func (t *BTree) readValue(val leafValue) ([]byte, error) {
+	if val.overflow == 0 {
+		return val.data, nil
+	}
+
+	data := make([]byte, 0, val.size)
+
+	for id := val.overflow; id != 0; {
+		page, err := t.readPage(id)
+		if err != nil {
+			return nil, err
+		}
+
+		h := readPageHeader(page)
+		if h.typ != overflowPage {
+			return nil, fmt.Errorf("unexpected page type %d in overflow chain", h.typ)
+		}
+
+		data = append(data, page[pageHeaderSize:pageHeaderSize+int(h.count)]...)
+		id = h.next
+	}
+
+	return data, nil
+}
+
+// searchLeaf returns the position of the key in the leaf, and whether it is present.
+func searchLeaf(n *node, key string) (int, bool) {
+	i := sort.SearchStrings(n.keys, key)
+	return i, i < len(n.keys) && n.keys[i] == key
+}
+
+// searchBranch returns the position of the child whose subtree may contain the key.
+func searchBranch(n *node, key string) int {
+	i := sort.Search(len(n.keys), func(i int) bool {
+		return n.keys[i] > key
+	})
+
+	if i > 0 {
+		i--
+	}
+
+	return i
+}
+
+// Get returns the value of the key. The second return value is false if the key is not found.
+func (t *BTree) Get(key string) ([]byte, bool, error) {
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	n, err := t.readNode(t.meta.root)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for !n.leaf {
+		if n, err = t.readNode(n.children[searchBranch(n, key)]); err != nil {
+			return nil, false, err
+		}
+	}
+
+	i, found := searchLeaf(n, key)
+	if !found {
+		return nil, false, nil
+	}
+
+	value, err := t.readValue(n.values[i])
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+// Put inserts or replaces the value of the key. The change is durable once Put returns,
+// unless the tree is opened with NoSync. If Put fails, the tree remains unchanged.
+func (t *BTree) Put(key string, value []byte) error {
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+
+	if len(key) > t.bodySize/8 {
+		return ErrKeyTooLarge
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	tx := t.begin()
+
+	if err := tx.put(key, value); err != nil {
+		return err
+	}
+
+	return tx.commit()
+}
+
+// Close closes the data file. The tree must not be used after it is closed.
+func (t *BTree) Close() error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if err := t.file.Close(); err != nil {
+		return fmt.Errorf("failed to close data file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,282 @@
+package btree
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/keyring"
+	"github.com/maxpoletaev/kv/internal/protoio"
+)
+
+func openTestTree(t *testing.T, root string) *BTree {
+	conf := DefaultConfig()
+	conf.DataRoot = root
+	conf.PageSize = minPageSize
+	conf.NoSync = true
+
+	tree, err := Open(conf)
+	require.NoError(t, err)
+
+	return tree
+}
+
+func TestBTree_PutGet(t *testing.T) {
+	root := t.TempDir()
+	tree := openTestTree(t, root)
+
+	want := make(map[string][]byte)
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key%d", rnd.Intn(5000))
+		value := []byte(fmt.Sprintf("value%d", i))
+
+		require.NoError(t, tree.Put(key, value))
+		want[key] = value
+	}
+
+	check := func(tree *BTree) {
+		for key, value := range want {
+			got, found, err := tree.Get(key)
+			require.NoError(t, err)
+			require.True(t, found, key)
+			assert.Equal(t, value, got, key)
+		}
+
+		_, found, err := tree.Get("missing")
+		require.NoError(t, err)
+		assert.False(t, found)
+	}
+
+	check(tree)
+	require.NoError(t, tree.Close())
+
+	tree = openTestTree(t, root)
+	defer tree.Close()
+	check(tree)
+}
+
+func TestBTree_Overflow(t *testing.T) {
+	tree := openTestTree(t, t.TempDir())
+	defer tree.Close()
+
+	large := bytes.Repeat([]byte("x"), minPageSize*3)
+	require.NoError(t, tree.Put("large", large))
+	require.NoError(t, tree.Put("small", []byte("small")))
+
+	got, found, err := tree.Get("large")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, large, got)
+
+	// Replacing the value releases the overflow pages.
+	require.NoError(t, tree.Put("large", []byte("not so large")))
+	assert.GreaterOrEqual(t, len(tree.free), 3)
+
+	got, _, err = tree.Get("large")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("not so large"), got)
+}
+
+func TestBTree_PageReuse(t *testing.T) {
+	tree := openTestTree(t, t.TempDir())
+	defer tree.Close()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, tree.Put(fmt.Sprintf("key%d", i), []byte("value")))
+	}
+
+	pageCount := tree.meta.pageCount
+
+	// Overwriting the same keys should not grow the file, as the pages of
+	// the old versions are reused by the following transactions.
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, tree.Put(fmt.Sprintf("key%d", i%100), []byte("value")))
+	}
+
+	assert.LessOrEqual(t, tree.meta.pageCount, pageCount+10)
+}
+
+func TestBTree_KeyLimits(t *testing.T) {
+	tree := openTestTree(t, t.TempDir())
+	defer tree.Close()
+
+	assert.ErrorIs(t, tree.Put("", []byte("value")), ErrEmptyKey)
+	assert.ErrorIs(t, tree.Put(string(make([]byte, minPageSize)), []byte("value")), ErrKeyTooLarge)
+}
+
+func TestBTree_TornMeta(t *testing.T) {
+	root := t.TempDir()
+	tree := openTestTree(t, root)
+
+	require.NoError(t, tree.Put("key", []byte("old")))
+	require.NoError(t, tree.Put("key", []byte("new")))
+	txid := tree.meta.txid
+	require.NoError(t, tree.Close())
+
+	// Corrupt the meta page written by the last commit.
+	file, err := os.OpenFile(filepath.Join(root, dataFileName), os.O_RDWR, 0)
+	require.NoError(t, err)
+	_, err = file.WriteAt([]byte{0xFF, 0xFF}, int64(txid%2)*minPageSize+pageHeaderSize+20)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	// The tree falls back to the state of the previous commit.
+	tree = openTestTree(t, root)
+	defer tree.Close()
+
+	got, found, err := tree.Get("key")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, []byte("old"), got)
+}
+
+func TestBTree_Encrypted(t *testing.T) {
+	root := t.TempDir()
+	kr, err := keyring.New(map[uint16][]byte{1: bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+
+	conf := DefaultConfig()
+	conf.DataRoot = root
+	conf.PageSize = minPageSize
+	conf.NoSync = true
+	conf.Cipher = kr
+
+	tree, err := Open(conf)
+	require.NoError(t, err)
+
+	large := bytes.Repeat([]byte("secret"), minPageSize)
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, tree.Put(fmt.Sprintf("key%d", i), []byte("secret")))
+	}
+	require.NoError(t, tree.Put("large", large))
+	require.NoError(t, tree.Close())
+
+	data, err := os.ReadFile(filepath.Join(root, dataFileName))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	assert.NotContains(t, string(data), "key1")
+
+	tree, err = Open(conf)
+	require.NoError(t, err)
+
+	got, found, err := tree.Get("key999")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, []byte("secret"), got)
+
+	got, found, err = tree.Get("large")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, large, got)
+	require.NoError(t, tree.Close())
+
+	// The encrypted file cannot be opened without the cipher.
+	conf.Cipher = nil
+	_, err = Open(conf)
+	assert.ErrorIs(t, err, protoio.ErrNoCipher)
+
+	// The encryption cannot be enabled for an existing plaintext file.
+	plain := openTestTree(t, t.TempDir())
+	require.NoError(t, plain.Close())
+
+	conf.DataRoot = filepath.Dir(plain.file.Name())
+	conf.Cipher = kr
+	_, err = Open(conf)
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+}
+
+func TestBTree_Scan(t *testing.T) {
+	tree := openTestTree(t, t.TempDir())
+	defer tree.Close()
+
+	var keys []string
+
+	for i := 0; i < 500; i++ {
+		key := fmt.Sprintf("key%03d", i)
+		require.NoError(t, tree.Put(key, []byte(key)))
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	collect := func(it *Iterator) []string {
+		result := make([]string, 0)
+
+		for it.HasNext() {
+			key, value := it.Next()
+			assert.Equal(t, key, string(value))
+			result = append(result, key)
+		}
+
+		require.NoError(t, it.Err())
+
+		return result
+	}
+
+	assert.Equal(t, keys, collect(tree.Scan()))
+	assert.Equal(t, keys[100:], collect(tree.ScanFrom("key100")))
+	assert.Equal(t, keys[:101], collect(tree.ScanTo("key100")))
+	assert.Equal(t, keys[100:201], collect(tree.ScanRange("key100", "key200")))
+	assert.Equal(t, keys[100:], collect(tree.ScanRange("key099z", "z")))
+	assert.Equal(t, []string{}, collect(tree.ScanRange("x", "z")))
+}
+
+func TestBTree_ScanEmpty(t *testing.T) {
+	tree := openTestTree(t, t.TempDir())
+	defer tree.Close()
+
+	assert.False(t, tree.Scan().HasNext())
+}
+
+func TestBTree_Concurrent(t *testing.T) {
+	tree := openTestTree(t, t.TempDir())
+	defer tree.Close()
+
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < 200; i++ {
+				key := fmt.Sprintf("key%d-%d", w, i)
+				assert.NoError(t, tree.Put(key, []byte(key)))
+
+				got, found, err := tree.Get(key)
+				assert.NoError(t, err)
+				assert.True(t, found)
+				assert.Equal(t, []byte(key), got)
+			}
+		}(w)
+	}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 20; i++ {
+			prev := ""
+
+			for it := tree.Scan(); it.HasNext(); {
+				key, _ := it.Next()
+				assert.Greater(t, key, prev)
+				prev = key
+			}
+		}
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,28 @@
+package btree
+
+import "github.com/maxpoletaev/kv/internal/protoio"
+
+type Config struct {
+	// DataRoot is the directory where the data file is stored.
+	// Defaults to the current working directory.
+	DataRoot string
+	// PageSize is the size of the pages in bytes. It must be between 1KB and 64KB.
+	// Has no effect on an existing file, which keeps the page size it was created
+	// with. Defaults to 4KB.
+	PageSize int
+	// NoSync disables fsync on commit. It makes writes much faster, but the
+	// data may be lost or corrupted on power loss. Defaults to false.
+	NoSync bool
+	// Cipher is used to encrypt the pages of the data file. Every page carries the ID of
+	// the key it was encrypted with, so the pages written before the key rotation remain
+	// readable as long as the old keys are provided. The meta pages are not encrypted, as
+	// they only describe the layout of the file. The encryption can only be enabled when
+	// the file is created. Defaults to nil, which means no encryption.
+	Cipher protoio.Cipher
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PageSize: 4096,
+	}
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/maxpoletaev/kv/internal/lockmap"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/btree"
+	"github.com/maxpoletaev/kv/storage/btree/proto"
+)
+
+type BTreeEngine struct {
+	locks *lockmap.Map[string]
+	tree  *btree.BTree
+	opts  storage.Options
+}
+
+func New(tree *btree.BTree, opts ...storage.Option) *BTreeEngine {
+	return &BTreeEngine{
+		locks: lockmap.New[string](),
+		tree:  tree,
+		opts:  storage.NewOptions(opts...),
+	}
+}
+
+func (s *BTreeEngine) Get(key string) ([]storage.Value, error) {
+	data, found, err := s.tree.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, storage.ErrNotFound
+	}
+
+	values, err := decodeValues(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// The discarded keys are stored with no values.
+	if len(values) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return values, nil
+}
+
+func decodeValues(data []byte) ([]storage.Value, error) {
+	list := &proto.ValueList{}
+	if err := protobuf.Unmarshal(data, list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal values: %w", err)
+	}
+
+	return fromProtoValues(list.Values), nil
+}
+
+func (s *BTreeEngine) Put(key string, value storage.Value) error {
+	return s.update(key, func(values []storage.Value) ([]storage.Value, error) {
+		return s.opts.AppendVersion(key, values, value)
+	})
+}
+
+// Restore stores the version copied from another node, bypassing the sibling limits.
+func (s *BTreeEngine) Restore(key string, value storage.Value) error {
+	return s.update(key, func(values []storage.Value) ([]storage.Value, error) {
+		return s.opts.RestoreVersion(key, values, value)
+	})
+}
+
+// Merge applies the merge operand to the value of the key and stores the result, which
+// replaces all versions of the key.
+func (s *BTreeEngine) Merge(key string, operand storage.Operand) (storage.Value, error) {
+	var merged storage.Value
+
+	err := s.update(key, func(values []storage.Value) ([]storage.Value, error) {
+		var err error
+
+		merged, err = storage.ApplyOperands(values, []storage.Operand{operand})
+		if err != nil {
+			return nil, err
+		}
+
+		return []storage.Value{merged}, nil
+	})
+
+	return merged, err
+}
+
+// Discard drops the given versions of the key. The tree does not support removing keys,
+// so once there are no versions left, the key is stored with an empty list of values,
+// which reads as a missing key.
+func (s *BTreeEngine) Discard(key string, values []storage.Value) error {
+	s.locks.Lock(key)
+	defer s.locks.Unlock(key)
+
+	stored, err := s.Get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	kept := storage.DiscardVersions(stored, values)
+	if len(kept) == len(stored) {
+		return nil
+	}
+
+	return s.store(key, kept)
+}
+
+// update replaces the values of the key with the result of the given function.
+func (s *BTreeEngine) update(key string, fn func([]storage.Value) ([]storage.Value, error)) error {
+	s.locks.Lock(key)
+	defer s.locks.Unlock(key)
+
+	values, err := s.Get(key)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	values, err = fn(values)
+	if err != nil {
+		return err
+	}
+
+	return s.store(key, values)
+}
+
+// store replaces the values of the key. The key must be locked.
+func (s *BTreeEngine) store(key string, values []storage.Value) error {
+	data, err := protobuf.Marshal(&proto.ValueList{Values: toProtoValues(values)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal values: %w", err)
+	}
+
+	return s.tree.Put(key, data)
+}
+
+var _ storage.Discardable = &BTreeEngine{}
+var _ storage.Restorable = &BTreeEngine{}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/btree"
+	"github.com/maxpoletaev/kv/storage/enginetest"
+)
+
+func TestConformance(t *testing.T) {
+	enginetest.Run(t, func(t *testing.T, dir string) (storage.Engine, func() error) {
+		conf := btree.DefaultConfig()
+		conf.DataRoot = dir
+		conf.PageSize = 1024
+		conf.NoSync = true
+
+		tree, err := btree.Open(conf)
+		require.NoError(t, err)
+
+		return New(tree), tree.Close
+	}, enginetest.Options{Persistent: true})
+}
+
+func TestScan_DecodeError(t *testing.T) {
+	conf := btree.DefaultConfig()
+	conf.DataRoot = t.TempDir()
+	conf.NoSync = true
+
+	tree, err := btree.Open(conf)
+	require.NoError(t, err)
+
+	defer tree.Close()
+
+	engine := New(tree)
+	require.NoError(t, engine.Put("a", storage.Value{Version: vclock.New(vclock.V{1: 1}), Data: []byte("a")}))
+	require.NoError(t, tree.Put("b", []byte("not a value list")))
+	require.NoError(t, engine.Put("c", storage.Value{Version: vclock.New(vclock.V{1: 1}), Data: []byte("c")}))
+
+	// The scan stops at the value that cannot be decoded, and reports it.
+	it := engine.Scan()
+	keys := make([]string, 0)
+
+	for it.HasNext() {
+		key, _ := it.Next()
+		keys = append(keys, key)
+	}
+
+	assert.Equal(t, []string{"a"}, keys)
+	assert.Error(t, it.Err())
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/btree"
+)
+
+// scanIterator iterates over the versions of the keys in the tree. Concurrent versions
+// of the same key are returned one after another. The iteration stops early if a page
+// cannot be read or a value cannot be decoded, and the error is returned by Err.
+type scanIterator struct {
+	it     *btree.Iterator
+	key    string
+	values []storage.Value
+	err    error
+}
+
+func (i *scanIterator) fill() {
+	for len(i.values) == 0 && i.err == nil && i.it.HasNext() {
+		key, data := i.it.Next()
+
+		values, err := decodeValues(data)
+		if err != nil {
+			i.err = fmt.Errorf("failed to decode values of %s: %w", key, err)
+			return
+		}
+
+		i.key, i.values = key, values
+	}
+}
+
+// HasNext returns true if there are more items in the iterator.
+func (i *scanIterator) HasNext() bool {
+	i.fill()
+
+	return len(i.values) > 0
+}
+
+// Next returns the next key and one of its versions. It panics if there
+// are no more items, so HasNext should always be called before Next.
+func (i *scanIterator) Next() (string, storage.Value) {
+	i.fill()
+
+	if len(i.values) == 0 {
+		panic("no more items in the iterator")
+	}
+
+	value := i.values[0]
+	i.values = i.values[1:]
+
+	return i.key, value
+}
+
+// Err returns the error that stopped the iteration early, if any.
+func (i *scanIterator) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+
+	return i.it.Err()
+}
+
+// Scan returns an iterator over all keys of the engine.
+func (s *BTreeEngine) Scan() storage.ScanIterator {
+	return &scanIterator{it: s.tree.Scan()}
+}
+
+// ScanFrom returns an iterator over the keys greater than or equal to the given key.
+func (s *BTreeEngine) ScanFrom(key string) storage.ScanIterator {
+	return &scanIterator{it: s.tree.ScanFrom(key)}
+}
+
+// ScanTo returns an iterator over the keys less than or equal to the given key.
+func (s *BTreeEngine) ScanTo(key string) storage.ScanIterator {
+	return &scanIterator{it: s.tree.ScanTo(key)}
+}
+
+// ScanRange returns an iterator over the keys between from and to, inclusive.
+func (s *BTreeEngine) ScanRange(from, to string) storage.ScanIterator {
+	return &scanIterator{it: s.tree.ScanRange(from, to)}
+}
+
+var _ storage.Scannable = &BTreeEngine{}
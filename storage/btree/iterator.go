@@ -0,0 +1,166 @@
+package btree
+
+// Iterator iterates over the keys of the tree in ascending order. It reads one leaf at a
+// time under the read lock and does not keep any references to the pages between the
+// reads, so it never blocks the writers and never sees the pages that have been freed.
+// As a consequence, the iterator sees the changes made to the leaves it has not read yet.
+type Iterator struct {
+	tree   *BTree
+	from   string
+	to     *string
+	keys   []string
+	values [][]byte
+	done   bool
+	err    error
+}
+
+// Scan returns an iterator over all keys of the tree.
+func (t *BTree) Scan() *Iterator {
+	return &Iterator{tree: t}
+}
+
+// ScanFrom returns an iterator over the keys greater than or equal to the given key.
+func (t *BTree) ScanFrom(key string) *Iterator {
+	return &Iterator{tree: t, from: key}
+}
+
+// ScanTo returns an iterator over the keys less than or equal to the given key.
+func (t *BTree) ScanTo(key string) *Iterator {
+	return &Iterator{tree: t, to: &key}
+}
+
+// ScanRange returns an iterator over the keys between from and to, inclusive.
+func (t *BTree) ScanRange(from, to string) *Iterator {
+	return &Iterator{tree: t, from: from, to: &to}
+}
+
+func (it *Iterator) fill() {
+	if len(it.keys) > 0 || it.done {
+		return
+	}
+
+	keys, values, err := it.tree.readLeafFrom(it.from)
+	if err != nil {
+		it.err, it.done = err, true
+		return
+	}
+
+	if len(keys) == 0 {
+		it.done = true
+		return
+	}
+
+	// The smallest key that is greater than the last key of the leaf.
+	it.from = keys[len(keys)-1] + "\x00"
+
+	if it.to != nil {
+		for i, key := range keys {
+			if key > *it.to {
+				keys, values, it.done = keys[:i], values[:i], true
+				break
+			}
+		}
+	}
+
+	it.keys, it.values = keys, values
+}
+
+// HasNext returns true if there are more items in the iterator.
+func (it *Iterator) HasNext() bool {
+	it.fill()
+
+	return len(it.keys) > 0
+}
+
+// Next returns the next key-value pair. It panics if there are no more
+// items, so HasNext should always be called before calling Next.
+func (it *Iterator) Next() (string, []byte) {
+	it.fill()
+
+	if len(it.keys) == 0 {
+		panic("no more items in the iterator")
+	}
+
+	key, value := it.keys[0], it.values[0]
+	it.keys, it.values = it.keys[1:], it.values[1:]
+
+	return key, value
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// readLeafFrom returns the elements of the first leaf that contains keys greater
+// than or equal to the given key, starting from that key.
+func (t *BTree) readLeafFrom(key string) ([]string, [][]byte, error) {
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	type frame struct {
+		node *node
+		pos  int
+	}
+
+	var stack []frame
+
+	n, err := t.readNode(t.meta.root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for !n.leaf {
+		pos := searchBranch(n, key)
+		stack = append(stack, frame{n, pos})
+
+		if n, err = t.readNode(n.children[pos]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	pos, _ := searchLeaf(n, key)
+
+	// All keys of the leaf are less than the given key, so move to the next leaf
+	// by going up to the first ancestor that has a child to the right.
+	for pos == len(n.keys) {
+		for len(stack) > 0 && stack[len(stack)-1].pos+1 >= len(stack[len(stack)-1].node.children) {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			return nil, nil, nil
+		}
+
+		top := &stack[len(stack)-1]
+		top.pos++
+
+		if n, err = t.readNode(top.node.children[top.pos]); err != nil {
+			return nil, nil, err
+		}
+
+		for !n.leaf {
+			stack = append(stack, frame{n, 0})
+
+			if n, err = t.readNode(n.children[0]); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		pos = 0
+	}
+
+	keys := n.keys[pos:]
+	values := make([][]byte, 0, len(keys))
+
+	for _, val := range n.values[pos:] {
+		data, err := t.readValue(val)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		values = append(values, data)
+	}
+
+	return keys, values, nil
+}
@@ -0,0 +1,318 @@
+package btree
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+type pgid uint64
+
+var byteOrder = binary.LittleEndian
+
+const (
+	minPageSize = 1024
+	maxPageSize = 64 * 1024
+)
+
+// Every page starts with a header of the following format:
+//
+//	0       2       4               8                              16
+//	+-------+-------+---------------+-------------------------------+
+//	| type  | count |   reserved    |             next              |
+//	+-------+-------+---------------+-------------------------------+
+//
+// The meaning of count and next depends on the page type. For nodes, count is the
+// number of elements, and next is not used. For freelist pages, count is the number
+// of page ids stored in the page. For overflow pages, it is the number of data bytes.
+// Both freelist and overflow pages are chained through the next field. The reserved
+// field of the meta pages holds the flags of the file.
+const pageHeaderSize = 16
+
+const (
+	metaPage uint16 = iota + 1
+	leafPage
+	branchPage
+	freelistPage
+	overflowPage
+)
+
+type pageHeader struct {
+	typ   uint16
+	count uint16
+	next  pgid
+}
+
+func readPageHeader(buf []byte) pageHeader {
+	return pageHeader{
+		typ:   byteOrder.Uint16(buf[0:2]),
+		count: byteOrder.Uint16(buf[2:4]),
+		next:  pgid(byteOrder.Uint64(buf[8:16])),
+	}
+}
+
+func writePageHeader(buf []byte, h pageHeader) {
+	byteOrder.PutUint16(buf[0:2], h.typ)
+	byteOrder.PutUint16(buf[2:4], h.count)
+	byteOrder.PutUint64(buf[8:16], uint64(h.next))
+}
+
+const (
+	metaMagic   uint32 = 0xB7EEB7EE
+	metaVersion uint32 = 1
+	metaSize           = pageHeaderSize + 48
+)
+
+// metaFlagEncrypted is set when all pages of the file, except for the meta pages, are encrypted.
+const metaFlagEncrypted uint32 = 1
+
+var errInvalidMeta = errors.New("invalid meta page")
+
+// meta describes the committed state of the tree. There are two meta pages at the
+// beginning of the file, and commits alternate between them, so that if the write
+// of one is torn by a crash, the other one still points to a consistent tree.
+type meta struct {
+	flags     uint32
+	pageSize  uint32
+	root      pgid
+	freelist  pgid
+	pageCount uint64
+	txid      uint64
+}
+
+func (m *meta) encode(buf []byte) {
+	writePageHeader(buf, pageHeader{typ: metaPage})
+	byteOrder.PutUint32(buf[4:8], m.flags)
+
+	b := buf[pageHeaderSize:]
+	byteOrder.PutUint32(b[0:4], metaMagic)
+	byteOrder.PutUint32(b[4:8], metaVersion)
+	byteOrder.PutUint32(b[8:12], m.pageSize)
+	byteOrder.PutUint64(b[12:20], uint64(m.root))
+	byteOrder.PutUint64(b[20:28], uint64(m.freelist))
+	byteOrder.PutUint64(b[28:36], m.pageCount)
+	byteOrder.PutUint64(b[36:44], m.txid)
+	byteOrder.PutUint32(b[44:48], crc32.ChecksumIEEE(buf[:metaSize-4]))
+}
+
+func (m *meta) decode(buf []byte) error {
+	if readPageHeader(buf).typ != metaPage {
+		return errInvalidMeta
+	}
+
+	b := buf[pageHeaderSize:]
+	if byteOrder.Uint32(b[0:4]) != metaMagic {
+		return errInvalidMeta
+	}
+
+	if byteOrder.Uint32(b[44:48]) != crc32.ChecksumIEEE(buf[:metaSize-4]) {
+		return fmt.Errorf("%w: checksum mismatch", errInvalidMeta)
+	}
+
+	if v := byteOrder.Uint32(b[4:8]); v != metaVersion {
+		return fmt.Errorf("%w: unsupported version %d", errInvalidMeta, v)
+	}
+
+	m.flags = byteOrder.Uint32(buf[4:8])
+	m.pageSize = byteOrder.Uint32(b[8:12])
+	m.root = pgid(byteOrder.Uint64(b[12:20]))
+	m.freelist = pgid(byteOrder.Uint64(b[20:28]))
+	m.pageCount = byteOrder.Uint64(b[28:36])
+	m.txid = byteOrder.Uint64(b[36:44])
+
+	return nil
+}
+
+// leafValue is the value of a leaf element. Small values are stored inline in the
+// leaf page, while large ones are moved to a chain of overflow pages, so that every
+// leaf is guaranteed to hold at least a few elements.
+type leafValue struct {
+	data     []byte
+	overflow pgid
+	size     uint32
+}
+
+const (
+	leafElemHeaderSize   = 7 // flags(1) + key size(2) + value size(4)
+	branchElemHeaderSize = 10
+	flagOverflow         = 1
+)
+
+// node is a decoded leaf or branch page. The key of a branch element is the
+// smallest key of the subtree it points to.
+type node struct {
+	leaf     bool
+	keys     []string
+	values   []leafValue
+	children []pgid
+}
+
+func (n *node) elemSize(i int) int {
+	if !n.leaf {
+		return branchElemHeaderSize + len(n.keys[i])
+	}
+
+	if n.values[i].overflow != 0 {
+		return leafElemHeaderSize + len(n.keys[i]) + 8
+	}
+
+	return leafElemHeaderSize + len(n.keys[i]) + len(n.values[i].data)
+}
+
+func (n *node) size() int {
+	size := pageHeaderSize
+
+	for i := range n.keys {
+		size += n.elemSize(i)
+	}
+
+	return size
+}
+
+func (n *node) encode(buf []byte) {
+	typ := branchPage
+	if n.leaf {
+		typ = leafPage
+	}
+
+	writePageHeader(buf, pageHeader{typ: typ, count: uint16(len(n.keys))})
+	b := buf[pageHeaderSize:]
+
+	for i, key := range n.keys {
+		if !n.leaf {
+			byteOrder.PutUint16(b[0:2], uint16(len(key)))
+			byteOrder.PutUint64(b[2:10], uint64(n.children[i]))
+			b = b[branchElemHeaderSize+copy(b[branchElemHeaderSize:], key):]
+
+			continue
+		}
+
+		val := n.values[i]
+		b[0] = 0
+
+		if val.overflow != 0 {
+			b[0] = flagOverflow
+		}
+
+		byteOrder.PutUint16(b[1:3], uint16(len(key)))
+		byteOrder.PutUint32(b[3:7], val.size)
+		b = b[leafElemHeaderSize+copy(b[leafElemHeaderSize:], key):]
+
+		if val.overflow != 0 {
+			byteOrder.PutUint64(b[0:8], uint64(val.overflow))
+			b = b[8:]
+		} else {
+			b = b[copy(b, val.data):]
+		}
+	}
+}
+
+func (n *node) decode(buf []byte) error {
+	h := readPageHeader(buf)
+
+	switch h.typ {
+	case leafPage:
+		n.leaf = true
+	case branchPage:
+		n.leaf = false
+	default:
+		return fmt.Errorf("unexpected page type: %d", h.typ)
+	}
+
+	count := int(h.count)
+	n.keys = make([]string, 0, count)
+
+	if n.leaf {
+		n.values = make([]leafValue, 0, count)
+	} else {
+		n.children = make([]pgid, 0, count)
+	}
+
+	b := buf[pageHeaderSize:]
+
+	for i := 0; i < count; i++ {
+		if !n.leaf {
+			keySize := int(byteOrder.Uint16(b[0:2]))
+			n.children = append(n.children, pgid(byteOrder.Uint64(b[2:10])))
+			n.keys = append(n.keys, string(b[branchElemHeaderSize:branchElemHeaderSize+keySize]))
+			b = b[branchElemHeaderSize+keySize:]
+
+			continue
+		}
+
+		flags := b[0]
+		keySize := int(byteOrder.Uint16(b[1:3]))
+		val := leafValue{size: byteOrder.Uint32(b[3:7])}
+		n.keys = append(n.keys, string(b[leafElemHeaderSize:leafElemHeaderSize+keySize]))
+		b = b[leafElemHeaderSize+keySize:]
+
+		if flags&flagOverflow != 0 {
+			val.overflow = pgid(byteOrder.Uint64(b[0:8]))
+			b = b[8:]
+		} else {
+			val.data = make([]byte, val.size)
+			b = b[copy(val.data, b):]
+		}
+
+		n.values = append(n.values, val)
+	}
+
+	return nil
+}
+
+// split divides the node into several nodes that fit into a page each. The nodes
+// are filled up to a half of the page, so that there is space for the new elements.
+func (n *node) split(pageSize int) []*node {
+	if n.size() <= pageSize {
+		return []*node{n}
+	}
+
+	var (
+		nodes []*node
+		start int
+		size  = pageHeaderSize
+	)
+
+	for i := range n.keys {
+		size += n.elemSize(i)
+
+		if size >= pageSize/2 && i < len(n.keys)-1 {
+			nodes = append(nodes, n.slice(start, i+1))
+			start, size = i+1, pageHeaderSize
+		}
+	}
+
+	last := n.slice(start, len(n.keys))
+
+	// Merge the remainder into the previous node if it fits, to avoid tiny nodes.
+	if prev := nodes[len(nodes)-1]; prev.size()+last.size()-pageHeaderSize <= pageSize {
+		nodes[len(nodes)-1] = n.slice(start-len(prev.keys), len(n.keys))
+	} else {
+		nodes = append(nodes, last)
+	}
+
+	return nodes
+}
+
+func (n *node) slice(from, to int) *node {
+	s := &node{leaf: n.leaf, keys: n.keys[from:to:to]}
+
+	if n.leaf {
+		s.values = n.values[from:to:to]
+	} else {
+		s.children = n.children[from:to:to]
+	}
+
+	return s
+}
+
+// freelistCapacity returns the number of page ids that fit into a single freelist page.
+func freelistCapacity(pageSize int) int {
+	return (pageSize - pageHeaderSize) / 8
+}
+
+// overflowCapacity returns the number of data bytes that fit into a single overflow page.
+func overflowCapacity(pageSize int) int {
+	return pageSize - pageHeaderSize
+}
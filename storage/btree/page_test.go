@@ -0,0 +1,71 @@
+package btree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNode_EncodeDecode(t *testing.T) {
+	tests := map[string]*node{
+		"Leaf": {
+			leaf: true,
+			keys: []string{"a", "b"},
+			values: []leafValue{
+				{data: []byte("value"), size: 5},
+				{overflow: 42, size: 10000},
+			},
+		},
+		"Branch": {
+			keys:     []string{"a", "m"},
+			children: []pgid{3, 4},
+		},
+	}
+
+	for name, n := range tests {
+		t.Run(name, func(t *testing.T) {
+			buf := make([]byte, minPageSize)
+			n.encode(buf)
+
+			decoded := &node{}
+			require.NoError(t, decoded.decode(buf))
+			assert.Equal(t, n, decoded)
+		})
+	}
+}
+
+func TestNode_Split(t *testing.T) {
+	n := &node{leaf: true}
+
+	for i := 0; i < 100; i++ {
+		n.keys = append(n.keys, fmt.Sprintf("key%03d", i))
+		n.values = append(n.values, leafValue{data: make([]byte, 50), size: 50})
+	}
+
+	nodes := n.split(minPageSize)
+	require.Greater(t, len(nodes), 1)
+
+	var keys []string
+
+	for _, part := range nodes {
+		assert.LessOrEqual(t, part.size(), minPageSize)
+		keys = append(keys, part.keys...)
+	}
+
+	assert.Equal(t, n.keys, keys)
+}
+
+func TestMeta_EncodeDecode(t *testing.T) {
+	m := meta{pageSize: 4096, root: 10, freelist: 5, pageCount: 20, txid: 7}
+	buf := make([]byte, metaSize)
+	m.encode(buf)
+
+	decoded := meta{}
+	require.NoError(t, decoded.decode(buf))
+	assert.Equal(t, m, decoded)
+
+	buf[pageHeaderSize+12]++
+	assert.ErrorIs(t, decoded.decode(buf), errInvalidMeta)
+}
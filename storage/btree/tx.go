@@ -0,0 +1,331 @@
+package btree
+
+import (
+	"fmt"
+)
+
+// tx is a write transaction. The pages freed by the transaction are not reused until
+// it is committed, because the previous version of the tree must remain intact in case
+// the process crashes before the new meta page is written.
+type tx struct {
+	tree    *BTree
+	meta    meta
+	free    []pgid
+	pending []pgid
+	buf     []byte
+}
+
+// branchRef is a reference to a child node, as it is stored in the parent branch.
+type branchRef struct {
+	key   string
+	child pgid
+}
+
+func (t *BTree) begin() *tx {
+	return &tx{
+		tree: t,
+		meta: t.meta,
+		free: append([]pgid(nil), t.free...),
+		buf:  make([]byte, t.bodySize),
+	}
+}
+
+func (tx *tx) allocate() pgid {
+	if n := len(tx.free); n > 0 {
+		id := tx.free[n-1]
+		tx.free = tx.free[:n-1]
+
+		return id
+	}
+
+	id := pgid(tx.meta.pageCount)
+	tx.meta.pageCount++
+
+	return id
+}
+
+func (tx *tx) release(id pgid) {
+	tx.pending = append(tx.pending, id)
+}
+
+// writePage writes the page body, encrypted if the tree is encrypted.
+func (tx *tx) writePage(id pgid, body []byte) error {
+	page, err := tx.tree.sealPage(body)
+	if err != nil {
+		return err
+	}
+
+	return tx.writeRaw(id, page)
+}
+
+func (tx *tx) writeRaw(id pgid, buf []byte) error {
+	if _, err := tx.tree.file.WriteAt(buf, int64(id)*int64(tx.tree.pageSize)); err != nil {
+		return fmt.Errorf("failed to write page %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func (tx *tx) writeNodes(nodes []*node) ([]branchRef, error) {
+	refs := make([]branchRef, 0, len(nodes))
+
+	for _, n := range nodes {
+		for i := range tx.buf {
+			tx.buf[i] = 0
+		}
+
+		n.encode(tx.buf)
+		id := tx.allocate()
+
+		if err := tx.writePage(id, tx.buf); err != nil {
+			return nil, err
+		}
+
+		refs = append(refs, branchRef{key: n.keys[0], child: id})
+	}
+
+	return refs, nil
+}
+
+func (tx *tx) writeOverflow(data []byte) (pgid, error) {
+	capacity := overflowCapacity(tx.tree.bodySize)
+	ids := make([]pgid, (len(data)+capacity-1)/capacity)
+
+	for i := range ids {
+		ids[i] = tx.allocate()
+	}
+
+	for i, id := range ids {
+		chunk := data[i*capacity:]
+		if len(chunk) > capacity {
+			chunk = chunk[:capacity]
+		}
+
+		var next pgid
+		if i < len(ids)-1 {
+			next = ids[i+1]
+		}
+
+		writePageHeader(tx.buf, pageHeader{typ: overflowPage, count: uint16(len(chunk)), next: next})
+		n := copy(tx.buf[pageHeaderSize:], chunk)
+
+		for j := pageHeaderSize + n; j < len(tx.buf); j++ {
+			tx.buf[j] = 0
+		}
+
+		if err := tx.writePage(id, tx.buf); err != nil {
+			return 0, err
+		}
+	}
+
+	return ids[0], nil
+}
+
+func (tx *tx) releaseOverflow(id pgid) error {
+	for id != 0 {
+		page, err := tx.tree.readPage(id)
+		if err != nil {
+			return err
+		}
+
+		tx.release(id)
+		id = readPageHeader(page).next
+	}
+
+	return nil
+}
+
+func (tx *tx) put(key string, value []byte) error {
+	val := leafValue{data: value, size: uint32(len(value))}
+
+	// Large values are moved out of the leaf, so that a leaf always holds several elements.
+	if leafElemHeaderSize+len(key)+len(value) > tx.tree.bodySize/4 {
+		id, err := tx.writeOverflow(value)
+		if err != nil {
+			return err
+		}
+
+		val = leafValue{overflow: id, size: uint32(len(value))}
+	}
+
+	refs, err := tx.insert(tx.meta.root, key, val)
+	if err != nil {
+		return err
+	}
+
+	// The root has been split, so the tree grows by one level.
+	for len(refs) > 1 {
+		root := &node{
+			keys:     make([]string, 0, len(refs)),
+			children: make([]pgid, 0, len(refs)),
+		}
+
+		for _, ref := range refs {
+			root.keys = append(root.keys, ref.key)
+			root.children = append(root.children, ref.child)
+		}
+
+		if refs, err = tx.writeNodes(root.split(tx.tree.bodySize)); err != nil {
+			return err
+		}
+	}
+
+	tx.meta.root = refs[0].child
+
+	return nil
+}
+
+// insert copies the node with the given key inserted into its subtree. It returns
+// the references to the new copies, of which there may be several if the node
+// did not fit into a single page.
+func (tx *tx) insert(id pgid, key string, val leafValue) ([]branchRef, error) {
+	n, err := tx.tree.readNode(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.leaf {
+		i, found := searchLeaf(n, key)
+
+		if found {
+			if old := n.values[i]; old.overflow != 0 {
+				if err := tx.releaseOverflow(old.overflow); err != nil {
+					return nil, err
+				}
+			}
+
+			n.values[i] = val
+		} else {
+			n.keys = append(n.keys, "")
+			copy(n.keys[i+1:], n.keys[i:])
+			n.keys[i] = key
+
+			n.values = append(n.values, leafValue{})
+			copy(n.values[i+1:], n.values[i:])
+			n.values[i] = val
+		}
+	} else {
+		i := searchBranch(n, key)
+
+		refs, err := tx.insert(n.children[i], key, val)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := make([]string, 0, len(n.keys)+len(refs)-1)
+		keys = append(keys, n.keys[:i]...)
+		children := make([]pgid, 0, len(n.children)+len(refs)-1)
+		children = append(children, n.children[:i]...)
+
+		for _, ref := range refs {
+			keys = append(keys, ref.key)
+			children = append(children, ref.child)
+		}
+
+		n.keys = append(keys, n.keys[i+1:]...)
+		n.children = append(children, n.children[i+1:]...)
+	}
+
+	tx.release(id)
+
+	return tx.writeNodes(n.split(tx.tree.bodySize))
+}
+
+// writeFreelist writes the ids of the free pages, including the ones released by
+// this transaction, into a chain of pages. The pages for the free-list are taken
+// from the free-list itself, so it does not grow the file on every commit.
+func (tx *tx) writeFreelist() ([]pgid, error) {
+	tx.pending = append(tx.pending, tx.tree.freePgs...)
+	capacity := freelistCapacity(tx.tree.bodySize)
+
+	numPages := (len(tx.free) + len(tx.pending) + capacity - 1) / capacity
+	pages := make([]pgid, numPages)
+
+	for i := range pages {
+		pages[i] = tx.allocate()
+	}
+
+	ids := make([]pgid, 0, len(tx.free)+len(tx.pending))
+	ids = append(ids, tx.free...)
+	ids = append(ids, tx.pending...)
+
+	for i, id := range pages {
+		chunk := ids[i*capacity:]
+		if len(chunk) > capacity {
+			chunk = chunk[:capacity]
+		}
+
+		var next pgid
+		if i < len(pages)-1 {
+			next = pages[i+1]
+		}
+
+		for j := range tx.buf {
+			tx.buf[j] = 0
+		}
+
+		writePageHeader(tx.buf, pageHeader{typ: freelistPage, count: uint16(len(chunk)), next: next})
+
+		for j, freeID := range chunk {
+			byteOrder.PutUint64(tx.buf[pageHeaderSize+j*8:], uint64(freeID))
+		}
+
+		if err := tx.writePage(id, tx.buf); err != nil {
+			return nil, err
+		}
+	}
+
+	tx.meta.freelist = 0
+	if len(pages) > 0 {
+		tx.meta.freelist = pages[0]
+	}
+
+	sortDesc(ids)
+	tx.free = ids
+	tx.pending = nil
+
+	return pages, nil
+}
+
+// commit makes the changes of the transaction visible and durable. First, all new pages
+// are synced to disk, and only then the meta page pointing to the new root is written.
+// The meta pages alternate between commits, so a torn meta write is never fatal.
+func (tx *tx) commit() error {
+	t := tx.tree
+
+	freelistPages, err := tx.writeFreelist()
+	if err != nil {
+		return err
+	}
+
+	if !t.noSync {
+		if err := t.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync data file: %w", err)
+		}
+	}
+
+	tx.meta.txid++
+
+	for i := range tx.buf {
+		tx.buf[i] = 0
+	}
+
+	tx.meta.encode(tx.buf)
+
+	// The meta pages are never encrypted, since they are needed to locate the rest.
+	if err := tx.writeRaw(pgid(tx.meta.txid%2), tx.buf); err != nil {
+		return err
+	}
+
+	if !t.noSync {
+		if err := t.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync data file: %w", err)
+		}
+	}
+
+	t.meta = tx.meta
+	t.free = tx.free
+	t.freePgs = freelistPages
+
+	return nil
+}
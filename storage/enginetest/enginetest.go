@@ -0,0 +1,620 @@
+// Package enginetest provides a conformance test suite for the implementations of
+// storage.Engine. Every engine is expected to pass it, so that the engines can be
+// swapped without changing the behaviour of the upper layers.
+//
+//	func TestConformance(t *testing.T) {
+//		enginetest.Run(t, func(t *testing.T, dir string) (storage.Engine, func() error) {
+//			e := mustOpen(dir)
+//			return e, e.Close
+//		}, enginetest.Options{Persistent: true})
+//	}
+package enginetest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/storage"
+)
+
+// OpenFunc opens the engine in the given directory, and returns it along with a function
+// that closes it. Opening the engine in the same directory after it is closed (or after
+// it is abandoned, which simulates a crash) must restore the data, if it is persistent.
+// The engines running background work must not let the abandoned instance touch the files
+// of the reopened one, which can be done by opening them on a vfs.CrashFS that is crashed
+// when the engine is reopened.
+type OpenFunc func(t *testing.T, dir string) (storage.Engine, func() error)
+
+// Options describe the capabilities of the engine under test.
+type Options struct {
+	// Persistent enables the tests that reopen the engine and check that
+	// the data is restored after both clean shutdown and crash.
+	Persistent bool
+}
+
+// Run runs the conformance test suite against the engine. Scan, merge and discard tests are
+// only run if the engine implements storage.Scannable, storage.Mergeable and storage.Discardable.
+func Run(t *testing.T, open OpenFunc, opts Options) {
+	tests := map[string]func(t *testing.T, open OpenFunc){
+		"GetNotFound":        testGetNotFound,
+		"PutGet":             testPutGet,
+		"NewerVersion":       testNewerVersion,
+		"ConcurrentSiblings": testConcurrentSiblings,
+		"DottedVersions":     testDottedVersions,
+		"ObsoleteWrite":      testObsoleteWrite,
+		"Delete":             testDelete,
+		"Scan":               testScan,
+		"ConcurrentWriters":  testConcurrentWriters,
+		"Merge":              testMerge,
+		"MergeAfterPut":      testMergeAfterPut,
+		"ConcurrentMerges":   testConcurrentMerges,
+		"MergeInvalid":       testMergeInvalid,
+		"Discard":            testDiscard,
+	}
+
+	if opts.Persistent {
+		tests["Restart"] = testRestart
+		tests["CrashRestart"] = testCrashRestart
+		tests["MergeRestart"] = testMergeRestart
+		tests["DiscardRestart"] = testDiscardRestart
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			test(t, open)
+		})
+	}
+}
+
+func openEngine(t *testing.T, open OpenFunc, dir string) storage.Engine {
+	engine, closeFn := open(t, dir)
+
+	t.Cleanup(func() {
+		assert.NoError(t, closeFn())
+	})
+
+	return engine
+}
+
+func value(data string, v vclock.V) storage.Value {
+	return storage.Value{
+		Version: vclock.New(v),
+		Data:    []byte(data),
+	}
+}
+
+func dotted(data string, dot dvv.Dot, v vclock.V) storage.Value {
+	return storage.Value{
+		Version: vclock.New(v),
+		Dot:     dot,
+		Data:    []byte(data),
+	}
+}
+
+func tombstone(v vclock.V) storage.Value {
+	return storage.Value{
+		Version:   vclock.New(v),
+		Tombstone: true,
+	}
+}
+
+// requireValues checks that the engine returns exactly the expected values for
+// the key. The order of the concurrent versions is not part of the contract.
+func requireValues(t *testing.T, engine storage.Engine, key string, want ...storage.Value) {
+	t.Helper()
+
+	got, err := engine.Get(key)
+	require.NoError(t, err, key)
+	require.Len(t, got, len(want), key)
+
+	for _, w := range want {
+		found := false
+
+		for _, g := range got {
+			if w.Dot == g.Dot && vclock.IsEqual(w.Version, g.Version) {
+				assert.Equal(t, string(w.Data), string(g.Data), key)
+				assert.Equal(t, w.Tombstone, g.Tombstone, key)
+				found = true
+
+				break
+			}
+		}
+
+		assert.True(t, found, "version %s of %s not found", w.Version, key)
+	}
+}
+
+func put(t *testing.T, engine storage.Engine, key string, val storage.Value) {
+	t.Helper()
+
+	require.NoError(t, engine.Put(key, val))
+}
+
+func testGetNotFound(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+
+	_, err := engine.Get("key")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testPutGet(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+
+	put(t, engine, "key1", value("value1", vclock.V{1: 1}))
+	put(t, engine, "key2", value("value2", vclock.V{1: 1}))
+
+	requireValues(t, engine, "key1", value("value1", vclock.V{1: 1}))
+	requireValues(t, engine, "key2", value("value2", vclock.V{1: 1}))
+}
+
+func testNewerVersion(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+
+	put(t, engine, "key", value("v1", vclock.V{1: 1}))
+	put(t, engine, "key", value("v2", vclock.V{1: 2}))
+	put(t, engine, "key", value("v3", vclock.V{1: 2, 2: 1}))
+
+	requireValues(t, engine, "key", value("v3", vclock.V{1: 2, 2: 1}))
+}
+
+func testConcurrentSiblings(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+
+	put(t, engine, "key", value("a", vclock.V{1: 1}))
+	put(t, engine, "key", value("b", vclock.V{2: 1}))
+	put(t, engine, "key", value("c", vclock.V{3: 1}))
+
+	requireValues(t, engine, "key",
+		value("a", vclock.V{1: 1}),
+		value("b", vclock.V{2: 1}),
+		value("c", vclock.V{3: 1}),
+	)
+
+	// A write that descends from some of the siblings replaces only them.
+	put(t, engine, "key", value("ab", vclock.V{1: 1, 2: 1}))
+
+	requireValues(t, engine, "key",
+		value("c", vclock.V{3: 1}),
+		value("ab", vclock.V{1: 1, 2: 1}),
+	)
+
+	// A write that descends from all siblings resolves the conflict.
+	put(t, engine, "key", value("abc", vclock.V{1: 1, 2: 1, 3: 1}))
+
+	requireValues(t, engine, "key", value("abc", vclock.V{1: 1, 2: 1, 3: 1}))
+}
+
+func testDottedVersions(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+
+	// The writes coordinated by the same node without reading the key
+	// first are concurrent, as their dots are not in each other's context.
+	put(t, engine, "key", dotted("a", dvv.Dot{Node: 1, Counter: 1}, nil))
+	put(t, engine, "key", dotted("b", dvv.Dot{Node: 1, Counter: 2}, nil))
+
+	requireValues(t, engine, "key",
+		dotted("a", dvv.Dot{Node: 1, Counter: 1}, nil),
+		dotted("b", dvv.Dot{Node: 1, Counter: 2}, nil),
+	)
+
+	// A write with the context covering only the first dot replaces only the first value.
+	put(t, engine, "key", dotted("c", dvv.Dot{Node: 2, Counter: 1}, vclock.V{1: 1}))
+
+	requireValues(t, engine, "key",
+		dotted("b", dvv.Dot{Node: 1, Counter: 2}, nil),
+		dotted("c", dvv.Dot{Node: 2, Counter: 1}, vclock.V{1: 1}),
+	)
+
+	// Writing an already stored dot again is obsolete.
+	err := engine.Put("key", dotted("b", dvv.Dot{Node: 1, Counter: 2}, nil))
+	require.ErrorIs(t, err, storage.ErrObsoleteWrite)
+
+	// A write with the context covering all dots resolves the conflict. The
+	// values written before the dots were enabled are compared the same way.
+	put(t, engine, "key", value("d", vclock.V{1: 2, 2: 1}))
+	requireValues(t, engine, "key", value("d", vclock.V{1: 2, 2: 1}))
+}
+
+func testObsoleteWrite(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+
+	put(t, engine, "key", value("new", vclock.V{1: 2}))
+
+	err := engine.Put("key", value("old", vclock.V{1: 1}))
+	assert.ErrorIs(t, err, storage.ErrObsoleteWrite)
+
+	err = engine.Put("key", value("same", vclock.V{1: 2}))
+	assert.ErrorIs(t, err, storage.ErrObsoleteWrite)
+
+	requireValues(t, engine, "key", value("new", vclock.V{1: 2}))
+}
+
+func testDelete(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+
+	put(t, engine, "key", value("value", vclock.V{1: 1}))
+	put(t, engine, "key", tombstone(vclock.V{1: 2}))
+
+	requireValues(t, engine, "key", tombstone(vclock.V{1: 2}))
+
+	// The delete wins over the older writes...
+	err := engine.Put("key", value("old", vclock.V{1: 1}))
+	assert.ErrorIs(t, err, storage.ErrObsoleteWrite)
+
+	// ...but is kept as a sibling of the concurrent ones.
+	put(t, engine, "key", value("concurrent", vclock.V{2: 1}))
+
+	requireValues(t, engine, "key",
+		tombstone(vclock.V{1: 2}),
+		value("concurrent", vclock.V{2: 1}),
+	)
+
+	// A newer write brings the key back.
+	put(t, engine, "key", value("resurrected", vclock.V{1: 3, 2: 1}))
+
+	requireValues(t, engine, "key", value("resurrected", vclock.V{1: 3, 2: 1}))
+}
+
+func testScan(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+
+	scannable, ok := engine.(storage.Scannable)
+	if !ok {
+		t.Skip("engine does not implement storage.Scannable")
+	}
+
+	put(t, engine, "b", value("b", vclock.V{1: 1}))
+	put(t, engine, "d", value("d", vclock.V{1: 1}))
+	put(t, engine, "a", value("a", vclock.V{1: 1}))
+	put(t, engine, "c", value("c1", vclock.V{1: 1}))
+	put(t, engine, "c", value("c2", vclock.V{2: 1}))
+	put(t, engine, "e", tombstone(vclock.V{1: 1}))
+
+	collect := func(it storage.ScanIterator) []string {
+		items := make([]string, 0)
+
+		for it.HasNext() {
+			key, val := it.Next()
+
+			if val.Tombstone {
+				items = append(items, key+"=<deleted>")
+			} else {
+				items = append(items, key+"="+string(val.Data))
+			}
+		}
+
+		require.NoError(t, it.Err())
+
+		return items
+	}
+
+	// Siblings of the same key are returned one after another, in any order.
+	items := collect(scannable.Scan())
+	require.Len(t, items, 6)
+	assert.Equal(t, []string{"a=a", "b=b"}, items[:2])
+	assert.ElementsMatch(t, []string{"c=c1", "c=c2"}, items[2:4])
+	assert.Equal(t, []string{"d=d", "e=<deleted>"}, items[4:])
+
+	assert.Equal(t, []string{"d=d", "e=<deleted>"}, collect(scannable.ScanFrom("d")))
+	assert.Equal(t, []string{"d=d", "e=<deleted>"}, collect(scannable.ScanFrom("cz")))
+	assert.Equal(t, []string{"a=a", "b=b"}, collect(scannable.ScanTo("b")))
+	assert.Equal(t, []string{"a=a", "b=b"}, collect(scannable.ScanTo("bz")))
+	assert.Equal(t, []string{}, collect(scannable.ScanRange("x", "z")))
+
+	items = collect(scannable.ScanRange("b", "d"))
+	require.Len(t, items, 4)
+	assert.Equal(t, "b=b", items[0])
+	assert.Equal(t, "d=d", items[3])
+}
+
+// fillEngine writes a set of keys covering the overwritten values, siblings and
+// deletes, and returns a function that checks that all of them are in place.
+func fillEngine(t *testing.T, engine storage.Engine) func(engine storage.Engine) {
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%03d", i)
+		put(t, engine, key, value("old", vclock.V{1: 1}))
+		put(t, engine, key, value(key, vclock.V{1: 2}))
+	}
+
+	put(t, engine, "siblings", value("a", vclock.V{1: 1}))
+	put(t, engine, "siblings", value("b", vclock.V{2: 1}))
+	put(t, engine, "deleted", value("value", vclock.V{1: 1}))
+	put(t, engine, "deleted", tombstone(vclock.V{1: 2}))
+	put(t, engine, "dotted", dotted("a", dvv.Dot{Node: 1, Counter: 1}, nil))
+	put(t, engine, "dotted", dotted("b", dvv.Dot{Node: 1, Counter: 2}, nil))
+
+	return func(engine storage.Engine) {
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key%03d", i)
+			requireValues(t, engine, key, value(key, vclock.V{1: 2}))
+		}
+
+		requireValues(t, engine, "siblings", value("a", vclock.V{1: 1}), value("b", vclock.V{2: 1}))
+		requireValues(t, engine, "deleted", tombstone(vclock.V{1: 2}))
+		requireValues(t, engine, "dotted",
+			dotted("a", dvv.Dot{Node: 1, Counter: 1}, nil),
+			dotted("b", dvv.Dot{Node: 1, Counter: 2}, nil),
+		)
+	}
+}
+
+func testRestart(t *testing.T, open OpenFunc) {
+	dir := t.TempDir()
+
+	engine, closeFn := open(t, dir)
+	check := fillEngine(t, engine)
+	require.NoError(t, closeFn())
+
+	check(openEngine(t, open, dir))
+}
+
+func testCrashRestart(t *testing.T, open OpenFunc) {
+	dir := t.TempDir()
+
+	// The engine is abandoned without being closed, as if the process has crashed.
+	// It is only closed at the end of the test, to release the resources.
+	engine, closeFn := open(t, dir)
+	check := fillEngine(t, engine)
+
+	t.Cleanup(func() {
+		closeFn()
+	})
+
+	check(openEngine(t, open, dir))
+}
+
+func testConcurrentWriters(t *testing.T, open OpenFunc) {
+	const (
+		numWriters = 8
+		numWrites  = 50
+	)
+
+	engine := openEngine(t, open, t.TempDir())
+	wg := sync.WaitGroup{}
+
+	for w := 1; w <= numWriters; w++ {
+		wg.Add(1)
+
+		go func(node uint32) {
+			defer wg.Done()
+
+			// Every writer updates its own key, and all of them update the shared key with
+			// the versions that are concurrent to the other writers, so that no version
+			// should be lost regardless of the order the writes are applied in.
+			for i := 1; i <= numWrites; i++ {
+				version := vclock.V{node: uint32(i)}
+				assert.NoError(t, engine.Put(fmt.Sprintf("key%d", node), value(fmt.Sprint(i), version)))
+				assert.NoError(t, engine.Put("shared", value(fmt.Sprint(node), version)))
+			}
+		}(uint32(w))
+	}
+
+	wg.Wait()
+
+	shared := make([]storage.Value, 0, numWriters)
+
+	for w := uint32(1); w <= numWriters; w++ {
+		requireValues(t, engine, fmt.Sprintf("key%d", w), value(fmt.Sprint(numWrites), vclock.V{w: numWrites}))
+		shared = append(shared, value(fmt.Sprint(w), vclock.V{w: numWrites}))
+	}
+
+	requireValues(t, engine, "shared", shared...)
+}
+
+func mergeable(t *testing.T, engine storage.Engine) storage.Mergeable {
+	m, ok := engine.(storage.Mergeable)
+	if !ok {
+		t.Skip("engine does not implement storage.Mergeable")
+	}
+
+	return m
+}
+
+func add(t *testing.T, engine storage.Mergeable, key string, delta int64, origin uint32) {
+	t.Helper()
+
+	_, err := engine.Merge(key, storage.Operand{
+		Operator: storage.OperatorInt64Add,
+		Data:     storage.EncodeInt64(delta),
+		Origin:   origin,
+	})
+	require.NoError(t, err)
+}
+
+func testMerge(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+	merger := mergeable(t, engine)
+
+	// Enough merges to span several memtables, and to have the operands
+	// collapsed by the engines that store them separately.
+	for i := 1; i <= 100; i++ {
+		add(t, merger, "counter", int64(i), 1)
+		add(t, merger, fmt.Sprintf("key%d", i), 1, 1)
+	}
+
+	requireValues(t, engine, "counter", storage.Value{
+		Version: vclock.New(vclock.V{1: 100}),
+		Data:    storage.EncodeInt64(5050),
+	})
+
+	_, err := merger.Merge("counter", storage.Operand{Operator: "unknown", Origin: 1})
+	assert.ErrorIs(t, err, storage.ErrUnknownOperator)
+}
+
+func testMergeAfterPut(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+	merger := mergeable(t, engine)
+
+	put(t, engine, "list", value(string(storage.EncodeList([][]byte{[]byte("a")})), vclock.V{1: 1}))
+	put(t, engine, "list", value(string(storage.EncodeList([][]byte{[]byte("b")})), vclock.V{2: 1}))
+
+	// Both siblings are combined with the operand into a single value.
+	merged, err := merger.Merge("list", storage.Operand{
+		Operator: storage.OperatorSetUnion,
+		Data:     storage.EncodeList([][]byte{[]byte("c")}),
+		Origin:   3,
+	})
+	require.NoError(t, err)
+
+	want := value(string(storage.EncodeList([][]byte{[]byte("a"), []byte("b"), []byte("c")})), vclock.V{1: 1, 2: 1, 3: 1})
+	assert.Equal(t, want.Data, merged.Data)
+	assert.True(t, vclock.IsEqual(want.Version, merged.Version))
+	requireValues(t, engine, "list", want)
+
+	// The merge is a write, so the put based on the version before it is obsolete.
+	err = engine.Put("list", value("x", vclock.V{1: 1, 2: 1}))
+	assert.ErrorIs(t, err, storage.ErrObsoleteWrite)
+
+	put(t, engine, "list", value("x", vclock.V{1: 2, 2: 1, 3: 1}))
+	requireValues(t, engine, "list", value("x", vclock.V{1: 2, 2: 1, 3: 1}))
+}
+
+func testMergeInvalid(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+	merger := mergeable(t, engine)
+
+	put(t, engine, "text", value("not a number", vclock.V{1: 1}))
+	add(t, merger, "counter", 1, 1)
+
+	// Neither operand can be applied, so both must be rejected without breaking the keys.
+	_, err := merger.Merge("counter", storage.Operand{Operator: storage.OperatorInt64Add, Data: []byte("x"), Origin: 1})
+	assert.ErrorIs(t, err, storage.ErrInvalidOperand)
+
+	_, err = merger.Merge("text", storage.Operand{Operator: storage.OperatorInt64Add, Data: storage.EncodeInt64(1), Origin: 1})
+	assert.ErrorIs(t, err, storage.ErrInvalidOperand)
+
+	requireValues(t, engine, "counter", storage.Value{Version: vclock.New(vclock.V{1: 1}), Data: storage.EncodeInt64(1)})
+	requireValues(t, engine, "text", value("not a number", vclock.V{1: 1}))
+
+	put(t, engine, "text", value("still writable", vclock.V{1: 2}))
+}
+
+func testConcurrentMerges(t *testing.T, open OpenFunc) {
+	const (
+		numWriters = 8
+		numMerges  = 50
+	)
+
+	engine := openEngine(t, open, t.TempDir())
+	merger := mergeable(t, engine)
+	wg := sync.WaitGroup{}
+
+	for w := 1; w <= numWriters; w++ {
+		wg.Add(1)
+
+		go func(node uint32) {
+			defer wg.Done()
+
+			for i := 0; i < numMerges; i++ {
+				_, err := merger.Merge("counter", storage.Operand{
+					Operator: storage.OperatorInt64Add,
+					Data:     storage.EncodeInt64(1),
+					Origin:   node,
+				})
+				assert.NoError(t, err)
+			}
+		}(uint32(w))
+	}
+
+	wg.Wait()
+
+	version := vclock.V{}
+	for w := uint32(1); w <= numWriters; w++ {
+		version[w] = numMerges
+	}
+
+	requireValues(t, engine, "counter", storage.Value{
+		Version: vclock.New(version),
+		Data:    storage.EncodeInt64(numWriters * numMerges),
+	})
+}
+
+func testMergeRestart(t *testing.T, open OpenFunc) {
+	dir := t.TempDir()
+
+	engine, closeFn := open(t, dir)
+	merger := mergeable(t, engine)
+
+	for i := 0; i < 50; i++ {
+		add(t, merger, "counter", 2, 1)
+	}
+
+	require.NoError(t, closeFn())
+
+	requireValues(t, openEngine(t, open, dir), "counter", storage.Value{
+		Version: vclock.New(vclock.V{1: 50}),
+		Data:    storage.EncodeInt64(100),
+	})
+}
+
+func discardable(t *testing.T, engine storage.Engine) storage.Discardable {
+	d, ok := engine.(storage.Discardable)
+	if !ok {
+		t.Skip("engine does not implement storage.Discardable")
+	}
+
+	return d
+}
+
+func testDiscard(t *testing.T, open OpenFunc) {
+	engine := openEngine(t, open, t.TempDir())
+	discarder := discardable(t, engine)
+
+	put(t, engine, "key", value("a", vclock.V{1: 1}))
+	put(t, engine, "key", value("b", vclock.V{2: 1}))
+	put(t, engine, "other", value("other", vclock.V{1: 1}))
+
+	// The versions written after the discarded ones are kept.
+	require.NoError(t, discarder.Discard("key", []storage.Value{value("a", vclock.V{1: 1})}))
+	requireValues(t, engine, "key", value("b", vclock.V{2: 1}))
+
+	require.NoError(t, discarder.Discard("key", []storage.Value{value("b", vclock.V{2: 1})}))
+	_, err := engine.Get("key")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	// Discarding a missing key is not an error.
+	require.NoError(t, discarder.Discard("missing", []storage.Value{value("a", vclock.V{1: 1})}))
+
+	// Unlike a delete, a discard leaves no trace, so any version can be written again.
+	put(t, engine, "key", value("again", vclock.V{1: 1}))
+	requireValues(t, engine, "key", value("again", vclock.V{1: 1}))
+	requireValues(t, engine, "other", value("other", vclock.V{1: 1}))
+
+	if scannable, ok := engine.(storage.Scannable); ok {
+		require.NoError(t, discarder.Discard("other", []storage.Value{value("other", vclock.V{1: 1})}))
+
+		keys := make([]string, 0)
+		for it := scannable.Scan(); it.HasNext(); {
+			key, _ := it.Next()
+			keys = append(keys, key)
+		}
+
+		assert.Equal(t, []string{"key"}, keys)
+	}
+}
+
+func testDiscardRestart(t *testing.T, open OpenFunc) {
+	dir := t.TempDir()
+
+	engine, closeFn := open(t, dir)
+	discarder := discardable(t, engine)
+
+	put(t, engine, "discarded", value("value", vclock.V{1: 1}))
+	put(t, engine, "kept", value("value", vclock.V{1: 1}))
+	require.NoError(t, discarder.Discard("discarded", []storage.Value{value("value", vclock.V{1: 1})}))
+	require.NoError(t, closeFn())
+
+	engine = openEngine(t, open, dir)
+
+	_, err := engine.Get("discarded")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	requireValues(t, engine, "kept", value("value", vclock.V{1: 1}))
+}
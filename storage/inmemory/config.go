@@ -0,0 +1,37 @@
+package inmemory
+
+import (
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/maxpoletaev/kv/internal/protoio"
+)
+
+type Config struct {
+	// Logger is used to report the errors of the background snapshots.
+	// Defaults to a no-op logger.
+	Logger log.Logger
+	// DataRoot is the directory where the snapshot and the log files are stored.
+	// If empty, the data is kept in memory only and is lost on restart.
+	DataRoot string
+	// SnapshotInterval is the interval between the periodic snapshots. Every snapshot
+	// truncates the log, so the more often it is taken, the faster is the recovery.
+	// Zero disables periodic snapshots, but the log is still written. Defaults to 5m.
+	SnapshotInterval time.Duration
+	// SyncWrites makes every write to the log followed by fsync. Without it, the
+	// writes acknowledged shortly before a crash of the machine may be lost, but
+	// a crash of the process alone is still survived. Defaults to false.
+	SyncWrites bool
+	// Cipher is used to encrypt the records of the snapshot and the log files. The files
+	// written before the encryption was enabled remain readable. Defaults to nil, which
+	// means no encryption.
+	Cipher protoio.Cipher
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Logger:           log.NewNopLogger(),
+		SnapshotInterval: 5 * time.Minute,
+	}
+}
@@ -1,25 +1,152 @@
 package inmemory
 
 import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
 	"github.com/maxpoletaev/kv/internal/lockmap"
 	"github.com/maxpoletaev/kv/storage"
 	"github.com/maxpoletaev/kv/storage/skiplist"
 )
 
 type InMemoryEngine struct {
-	data  *skiplist.Skiplist[string, []storage.Value]
-	locks *lockmap.Map[string]
+	data   *skiplist.Skiplist[string, []storage.Value]
+	locks  *lockmap.Map[string]
+	logger log.Logger
+	opts   storage.Options
+
+	// The fields below are only used when the engine is persistent. logMut guards
+	// the log and makes sure that the entries are inserted into the skiplist in the
+	// same order as they are appended to the log. snapMut serializes the snapshots.
+	log     *writeLog
+	logMut  sync.Mutex
+	snapMut sync.Mutex
+	stop    chan struct{}
+	wg      sync.WaitGroup
 }
 
-func New() *InMemoryEngine {
-	return newWithData(skiplist.New[string, []storage.Value](skiplist.StringComparator))
+// New creates a new engine that keeps the data in memory only.
+func New(opts ...storage.Option) *InMemoryEngine {
+	return newWithData(skiplist.New[string, []storage.Value](skiplist.StringComparator), opts...)
 }
 
-func newWithData(data *skiplist.Skiplist[string, []storage.Value]) *InMemoryEngine {
+func newWithData(data *skiplist.Skiplist[string, []storage.Value], opts ...storage.Option) *InMemoryEngine {
 	return &InMemoryEngine{
-		locks: lockmap.New[string](),
-		data:  data,
+		locks:  lockmap.New[string](),
+		logger: log.NewNopLogger(),
+		opts:   storage.NewOptions(opts...),
+		data:   data,
+	}
+}
+
+// Open creates a new engine that persists the data in the conf.DataRoot directory, if
+// it is set. On start, the data is restored from the last snapshot and the log of the
+// writes made after it. Snapshots are taken periodically, according to the config.
+func Open(conf Config, opts ...storage.Option) (*InMemoryEngine, error) {
+	s := New(opts...)
+
+	if conf.Logger != nil {
+		s.logger = conf.Logger
 	}
+
+	if conf.DataRoot == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(conf.DataRoot, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	lastSeq, err := restore(conf.DataRoot, conf.Cipher, s.data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Always start a new log file, so that a partially written entry
+	// at the end of the previous one does not get in the way.
+	if s.log, err = createLog(conf.DataRoot, lastSeq+1, conf.SyncWrites, conf.Cipher); err != nil {
+		return nil, err
+	}
+
+	s.stop = make(chan struct{})
+
+	if conf.SnapshotInterval > 0 {
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+			s.snapshotLoop(conf.SnapshotInterval)
+		}()
+	}
+
+	return s, nil
+}
+
+func (s *InMemoryEngine) snapshotLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.Snapshot(); err != nil {
+				level.Error(s.logger).Log("msg", "failed to take a snapshot", "err", err)
+			}
+		}
+	}
+}
+
+// Snapshot writes the current state of the engine to disk and removes the log files
+// that are no longer needed. The writes are not blocked while the snapshot is taken.
+// Has no effect if the engine is not persistent.
+func (s *InMemoryEngine) Snapshot() error {
+	if s.log == nil {
+		return nil
+	}
+
+	s.snapMut.Lock()
+	defer s.snapMut.Unlock()
+
+	// All writes appended to the old log are already in the skiplist at this point, so
+	// they are guaranteed to be included into the snapshot. The writes that come after
+	// the rotation may also get into the snapshot, but since each log entry holds the
+	// full list of values of the key, replaying them again on restore is harmless.
+	s.logMut.Lock()
+	prevSeq, err := s.log.Rotate()
+	s.logMut.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("failed to rotate log: %w", err)
+	}
+
+	if err := writeSnapshot(s.log.root, s.log.cipher, s.data); err != nil {
+		return err
+	}
+
+	return s.log.RemoveUpTo(prevSeq)
+}
+
+// Close stops the background snapshots and closes the log. The engine
+// must not be used after it is closed.
+func (s *InMemoryEngine) Close() error {
+	if s.log == nil {
+		return nil
+	}
+
+	close(s.stop)
+	s.wg.Wait()
+
+	s.logMut.Lock()
+	defer s.logMut.Unlock()
+
+	return s.log.Close()
 }
 
 func (s *InMemoryEngine) Get(key string) ([]storage.Value, error) {
@@ -32,21 +159,100 @@ func (s *InMemoryEngine) Get(key string) ([]storage.Value, error) {
 }
 
 func (s *InMemoryEngine) Put(key string, value storage.Value) error {
+	return s.update(key, func(values []storage.Value) ([]storage.Value, error) {
+		return s.opts.AppendVersion(key, values, value)
+	})
+}
+
+// Restore stores the version copied from another node, bypassing the sibling limits.
+func (s *InMemoryEngine) Restore(key string, value storage.Value) error {
+	return s.update(key, func(values []storage.Value) ([]storage.Value, error) {
+		return s.opts.RestoreVersion(key, values, value)
+	})
+}
+
+// Merge applies the merge operand to the value of the key and stores the result, which
+// replaces all versions of the key.
+func (s *InMemoryEngine) Merge(key string, operand storage.Operand) (storage.Value, error) {
+	var merged storage.Value
+
+	err := s.update(key, func(values []storage.Value) ([]storage.Value, error) {
+		var err error
+
+		merged, err = storage.ApplyOperands(values, []storage.Operand{operand})
+		if err != nil {
+			return nil, err
+		}
+
+		return []storage.Value{merged}, nil
+	})
+
+	return merged, err
+}
+
+// Discard drops the given versions of the key. The key is removed
+// from the engine once there are no versions left.
+func (s *InMemoryEngine) Discard(key string, values []storage.Value) error {
+	s.locks.Lock(key)
+	defer s.locks.Unlock(key)
+
+	stored, _ := s.data.Get(key)
+
+	kept := storage.DiscardVersions(stored, values)
+	if len(kept) == len(stored) {
+		return nil
+	}
+
+	return s.store(key, kept)
+}
+
+// update replaces the values of the key with the result of the given function.
+func (s *InMemoryEngine) update(key string, fn func([]storage.Value) ([]storage.Value, error)) error {
 	// Since we read the value before updating it, we need to lock the key to avoid
 	// loosing versions during concurrent updates of the same key. The skiplist
 	// itself is thread-safe, that is why we do not lock it in Get.
 	s.locks.Lock(key)
+	defer s.locks.Unlock(key)
 
 	values, _ := s.data.Get(key)
 
-	values, err := storage.AppendVersion(values, value)
+	values, err := fn(values)
 	if err != nil {
-		s.locks.Unlock(key)
 		return err
 	}
 
-	s.data.Insert(key, values)
-	s.locks.Unlock(key)
+	return s.store(key, values)
+}
+
+// store writes the values of the key to the log, if the engine is persistent, and to the
+// skiplist. The key is removed if there are no values left. The key must be locked.
+func (s *InMemoryEngine) store(key string, values []storage.Value) error {
+	if s.log == nil {
+		storeValues(s.data, key, values)
+		return nil
+	}
+
+	s.logMut.Lock()
+	defer s.logMut.Unlock()
+
+	// An entry with no values is replayed as a removal.
+	if err := s.log.Append(toProtoEntry(key, values)); err != nil {
+		return err
+	}
+
+	storeValues(s.data, key, values)
 
 	return nil
 }
+
+func storeValues(data *skiplist.Skiplist[string, []storage.Value], key string, values []storage.Value) {
+	if len(values) == 0 {
+		data.Remove(key)
+		return
+	}
+
+	data.Insert(key, values)
+}
+
+var _ storage.Discardable = &InMemoryEngine{}
+var _ storage.Restorable = &InMemoryEngine{}
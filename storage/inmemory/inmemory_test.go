@@ -8,6 +8,7 @@ import (
 
 	"github.com/maxpoletaev/kv/internal/vclock"
 	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/enginetest"
 	"github.com/maxpoletaev/kv/storage/skiplist"
 )
 
@@ -175,3 +176,63 @@ func TestPutFails_SameVersion(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorIs(t, err, storage.ErrObsoleteWrite)
 }
+
+func TestPut_SiblingLimits(t *testing.T) {
+	lst := skiplist.New[string, []storage.Value](skiplist.StringComparator)
+	memstore := newWithData(lst, storage.WithSiblingLimits(storage.SiblingLimits{MaxSiblings: 1}))
+
+	err := memstore.Put("key", storage.Value{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1})})
+	require.NoError(t, err)
+
+	err = memstore.Put("key", storage.Value{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1})})
+	require.ErrorIs(t, err, storage.ErrSiblingLimit)
+
+	listValues, _ := lst.Get("key")
+	require.Len(t, listValues, 1)
+	require.Equal(t, []byte("a"), listValues[0].Data)
+}
+
+func TestPut_LastWriteWins(t *testing.T) {
+	lst := skiplist.New[string, []storage.Value](skiplist.StringComparator)
+	memstore := newWithData(lst,
+		storage.WithSiblingLimits(storage.SiblingLimits{MaxSiblings: 1}),
+		storage.WithKeyspaces(storage.Keyspaces{{Prefix: "sessions/", LastWriteWins: true}}),
+	)
+
+	err := memstore.Put("sessions/1", storage.Value{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 20})
+	require.NoError(t, err)
+
+	// The concurrent write collapses into the winner rather than exceeding the sibling limits.
+	err = memstore.Put("sessions/1", storage.Value{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 10})
+	require.NoError(t, err)
+
+	listValues, _ := lst.Get("sessions/1")
+	require.Len(t, listValues, 1)
+	require.Equal(t, []byte("a"), listValues[0].Data)
+
+	// The keys outside of the keyspace keep the siblings.
+	err = memstore.Put("users/1", storage.Value{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1})})
+	require.NoError(t, err)
+
+	err = memstore.Put("users/1", storage.Value{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1})})
+	require.ErrorIs(t, err, storage.ErrSiblingLimit)
+}
+
+func TestConformance(t *testing.T) {
+	enginetest.Run(t, func(t *testing.T, dir string) (storage.Engine, func() error) {
+		memstore := New()
+		return memstore, memstore.Close
+	}, enginetest.Options{})
+}
+
+func TestConformance_Persistent(t *testing.T) {
+	enginetest.Run(t, func(t *testing.T, dir string) (storage.Engine, func() error) {
+		conf := DefaultConfig()
+		conf.DataRoot = dir
+
+		memstore, err := Open(conf)
+		require.NoError(t, err)
+
+		return memstore, memstore.Close
+	}, enginetest.Options{Persistent: true})
+}
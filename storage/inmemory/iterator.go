@@ -0,0 +1,73 @@
+package inmemory
+
+import (
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/skiplist"
+)
+
+// scanIterator iterates over the versions of the keys in the skiplist. Concurrent
+// versions of the same key are returned one after another, in the order they are stored.
+type scanIterator struct {
+	it     *skiplist.Iterator[string, []storage.Value]
+	key    string
+	values []storage.Value
+}
+
+func newScanIterator(it *skiplist.Iterator[string, []storage.Value]) *scanIterator {
+	return &scanIterator{it: it}
+}
+
+func (i *scanIterator) fill() {
+	for len(i.values) == 0 && i.it.HasNext() {
+		i.key, i.values = i.it.Next()
+	}
+}
+
+// HasNext returns true if there are more items in the iterator.
+func (i *scanIterator) HasNext() bool {
+	i.fill()
+
+	return len(i.values) > 0
+}
+
+// Next returns the next key and one of its versions. It panics if there
+// are no more items, so HasNext should always be called before Next.
+func (i *scanIterator) Next() (string, storage.Value) {
+	i.fill()
+
+	if len(i.values) == 0 {
+		panic("no more items in the iterator")
+	}
+
+	value := i.values[0]
+	i.values = i.values[1:]
+
+	return i.key, value
+}
+
+// Err always returns nil, since the skiplist is in memory and cannot fail to be read.
+func (i *scanIterator) Err() error {
+	return nil
+}
+
+// Scan returns an iterator over all keys of the engine.
+func (s *InMemoryEngine) Scan() storage.ScanIterator {
+	return newScanIterator(s.data.Scan())
+}
+
+// ScanFrom returns an iterator over the keys greater than or equal to the given key.
+func (s *InMemoryEngine) ScanFrom(key string) storage.ScanIterator {
+	return newScanIterator(s.data.ScanFrom(key))
+}
+
+// ScanTo returns an iterator over the keys less than or equal to the given key.
+func (s *InMemoryEngine) ScanTo(key string) storage.ScanIterator {
+	return newScanIterator(s.data.ScanTo(key))
+}
+
+// ScanRange returns an iterator over the keys between from and to, inclusive.
+func (s *InMemoryEngine) ScanRange(from, to string) storage.ScanIterator {
+	return newScanIterator(s.data.ScanRange(from, to))
+}
+
+var _ storage.Scannable = &InMemoryEngine{}
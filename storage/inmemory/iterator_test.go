@@ -0,0 +1,40 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/skiplist"
+)
+
+func TestScan(t *testing.T) {
+	list := skiplist.New[string, []storage.Value](skiplist.StringComparator)
+	list.Insert("a", []storage.Value{{Version: vclock.New(), Data: []byte("a")}})
+	list.Insert("b", []storage.Value{
+		{Version: vclock.New(), Data: []byte("b1")},
+		{Version: vclock.New(), Data: []byte("b2")},
+	})
+	list.Insert("c", []storage.Value{{Version: vclock.New(), Data: []byte("c")}})
+
+	memstore := newWithData(list)
+
+	collect := func(it storage.ScanIterator) []string {
+		items := make([]string, 0)
+
+		for it.HasNext() {
+			key, value := it.Next()
+			items = append(items, key+"="+string(value.Data))
+		}
+
+		return items
+	}
+
+	assert.Equal(t, []string{"a=a", "b=b1", "b=b2", "c=c"}, collect(memstore.Scan()))
+	assert.Equal(t, []string{"b=b1", "b=b2", "c=c"}, collect(memstore.ScanFrom("b")))
+	assert.Equal(t, []string{"a=a", "b=b1", "b=b2"}, collect(memstore.ScanTo("b")))
+	assert.Equal(t, []string{"b=b1", "b=b2"}, collect(memstore.ScanRange("ab", "bz")))
+	assert.Equal(t, []string{}, collect(memstore.ScanRange("x", "z")))
+}
@@ -0,0 +1,256 @@
+package inmemory
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/inmemory/proto"
+	"github.com/maxpoletaev/kv/storage/skiplist"
+)
+
+const (
+	snapshotFile    = "snapshot.dat"
+	snapshotTmpFile = "snapshot.dat.tmp"
+	logFilePrefix   = "log-"
+	logFileSuffix   = ".log"
+)
+
+func logFileName(seq int64) string {
+	return fmt.Sprintf("%s%d%s", logFilePrefix, seq, logFileSuffix)
+}
+
+// listLogs returns the sequence numbers of the log files in the directory, in ascending order.
+func listLogs(root string) ([]int64, error) {
+	files, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	seqs := make([]int64, 0)
+
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasPrefix(name, logFilePrefix) || !strings.HasSuffix(name, logFileSuffix) {
+			continue
+		}
+
+		seq, err := strconv.ParseInt(name[len(logFilePrefix):len(name)-len(logFileSuffix)], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		seqs = append(seqs, seq)
+	}
+
+	sort.Slice(seqs, func(i, j int) bool {
+		return seqs[i] < seqs[j]
+	})
+
+	return seqs, nil
+}
+
+// readEntries reads the entries from the file into the skiplist. The entries that
+// appear later in the file overwrite the earlier ones. A partially written entry at
+// the end of the file, which is left after a crash in the middle of a write, is ignored.
+func readEntries(path string, cipher protoio.Cipher, data *skiplist.Skiplist[string, []storage.Value]) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	reader := protoio.NewEncryptedReader(file, cipher)
+
+	for {
+		entry := &proto.Entry{}
+
+		if _, err := reader.ReadNext(entry); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+
+			return fmt.Errorf("failed to read entry at offset %d: %w", reader.Offset(), err)
+		}
+
+		storeValues(data, entry.Key, fromProtoValues(entry.Values))
+	}
+}
+
+// restore loads the snapshot and replays the log files on top of it.
+// Returns the sequence number of the last log file, or zero if there are none.
+func restore(root string, cipher protoio.Cipher, data *skiplist.Skiplist[string, []storage.Value]) (int64, error) {
+	err := readEntries(filepath.Join(root, snapshotFile), cipher, data)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+
+	seqs, err := listLogs(root)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list log files: %w", err)
+	}
+
+	var lastSeq int64
+
+	for _, seq := range seqs {
+		if err := readEntries(filepath.Join(root, logFileName(seq)), cipher, data); err != nil {
+			return 0, fmt.Errorf("failed to replay log %d: %w", seq, err)
+		}
+
+		lastSeq = seq
+	}
+
+	return lastSeq, nil
+}
+
+// writeSnapshot writes all entries of the skiplist into a temporary file, which
+// then atomically replaces the previous snapshot.
+func writeSnapshot(root string, cipher protoio.Cipher, data *skiplist.Skiplist[string, []storage.Value]) error {
+	tmpPath := filepath.Join(root, snapshotTmpFile)
+
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+
+	writer := protoio.NewEncryptedWriter(file, cipher)
+
+	for it := data.Scan(); it.HasNext(); {
+		key, values := it.Next()
+
+		if _, err := writer.Append(toProtoEntry(key, values)); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write snapshot entry: %w", err)
+		}
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync snapshot file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(root, snapshotFile)); err != nil {
+		return fmt.Errorf("failed to rename snapshot file: %w", err)
+	}
+
+	return syncDir(root)
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer dir.Close()
+
+	return dir.Sync()
+}
+
+// writeLog is an append-only log of the writes made since the last snapshot.
+// It is not safe for concurrent use, the caller is responsible for locking.
+type writeLog struct {
+	root   string
+	seq    int64
+	file   *os.File
+	writer *protoio.Writer
+	cipher protoio.Cipher
+	sync   bool
+}
+
+func createLog(root string, seq int64, sync bool, cipher protoio.Cipher) (*writeLog, error) {
+	file, err := os.OpenFile(
+		filepath.Join(root, logFileName(seq)), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log file: %w", err)
+	}
+
+	return &writeLog{
+		root:   root,
+		seq:    seq,
+		file:   file,
+		writer: protoio.NewEncryptedWriter(file, cipher),
+		cipher: cipher,
+		sync:   sync,
+	}, nil
+}
+
+func (l *writeLog) Append(entry *proto.Entry) error {
+	if _, err := l.writer.Append(entry); err != nil {
+		return fmt.Errorf("failed to append to log: %w", err)
+	}
+
+	if l.sync {
+		if err := l.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync log: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Rotate closes the current log file and starts a new one. Returns the sequence
+// number of the closed file, so that it can be removed once the snapshot is taken.
+func (l *writeLog) Rotate() (int64, error) {
+	next, err := createLog(l.root, l.seq+1, l.sync, l.cipher)
+	if err != nil {
+		return 0, err
+	}
+
+	prevSeq := l.seq
+
+	if err := l.Close(); err != nil {
+		next.Close()
+		os.Remove(next.file.Name())
+
+		return 0, err
+	}
+
+	*l = *next
+
+	return prevSeq, nil
+}
+
+// RemoveUpTo removes the log files with sequence number less than or equal to seq.
+func (l *writeLog) RemoveUpTo(seq int64) error {
+	seqs, err := listLogs(l.root)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range seqs {
+		if s > seq {
+			break
+		}
+
+		if err := os.Remove(filepath.Join(l.root, logFileName(s))); err != nil {
+			return fmt.Errorf("failed to remove log file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (l *writeLog) Close() error {
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log: %w", err)
+	}
+
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package inmemory
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/keyring"
+	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/storage"
+)
+
+func putValue(t *testing.T, s *InMemoryEngine, key, data string, node uint32) {
+	version := vclock.New()
+	version.Update(node)
+
+	err := s.Put(key, storage.Value{
+		Version: version,
+		Data:    []byte(data),
+	})
+	require.NoError(t, err)
+}
+
+func openTestEngine(t *testing.T, root string) *InMemoryEngine {
+	conf := DefaultConfig()
+	conf.DataRoot = root
+	conf.SnapshotInterval = 0
+
+	s, err := Open(conf)
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestOpen_RestoreFromLog(t *testing.T) {
+	root := t.TempDir()
+
+	s := openTestEngine(t, root)
+	putValue(t, s, "key1", "value1", 1)
+	putValue(t, s, "key2", "value2", 1)
+	putValue(t, s, "key2", "concurrent", 2)
+	require.NoError(t, s.Close())
+
+	s = openTestEngine(t, root)
+	defer s.Close()
+
+	values, err := s.Get("key1")
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+	assert.Equal(t, []byte("value1"), values[0].Data)
+
+	values, err = s.Get("key2")
+	require.NoError(t, err)
+	require.Len(t, values, 2)
+	assert.Equal(t, []byte("value2"), values[0].Data)
+	assert.Equal(t, []byte("concurrent"), values[1].Data)
+}
+
+func TestOpen_RestoreFromSnapshot(t *testing.T) {
+	root := t.TempDir()
+
+	s := openTestEngine(t, root)
+	putValue(t, s, "key1", "value1", 1)
+	require.NoError(t, s.Snapshot())
+	putValue(t, s, "key2", "value2", 1)
+	require.NoError(t, s.Close())
+
+	// Only the log written after the snapshot is left.
+	seqs, err := listLogs(root)
+	require.NoError(t, err)
+	assert.Len(t, seqs, 1)
+
+	s = openTestEngine(t, root)
+	defer s.Close()
+
+	for i := 1; i <= 2; i++ {
+		values, err := s.Get(fmt.Sprintf("key%d", i))
+		require.NoError(t, err)
+		require.Len(t, values, 1)
+		assert.Equal(t, []byte(fmt.Sprintf("value%d", i)), values[0].Data)
+	}
+}
+
+func TestOpen_TornLogEntry(t *testing.T) {
+	root := t.TempDir()
+
+	s := openTestEngine(t, root)
+	putValue(t, s, "key1", "value1", 1)
+	putValue(t, s, "key2", "value2", 1)
+	require.NoError(t, s.Close())
+
+	// Simulate a crash in the middle of the last write.
+	path := filepath.Join(root, logFileName(1))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+
+	s = openTestEngine(t, root)
+	defer s.Close()
+
+	_, err = s.Get("key1")
+	assert.NoError(t, err)
+
+	_, err = s.Get("key2")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestOpen_InMemoryOnly(t *testing.T) {
+	s, err := Open(DefaultConfig())
+	require.NoError(t, err)
+
+	putValue(t, s, "key", "value", 1)
+	assert.Nil(t, s.log)
+	assert.NoError(t, s.Snapshot())
+	assert.NoError(t, s.Close())
+}
+
+func TestOpen_Encrypted(t *testing.T) {
+	root := t.TempDir()
+
+	kr, err := keyring.New(map[uint16][]byte{1: bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+
+	conf := DefaultConfig()
+	conf.DataRoot = root
+	conf.SnapshotInterval = 0
+	conf.Cipher = kr
+
+	s, err := Open(conf)
+	require.NoError(t, err)
+
+	putValue(t, s, "key1", "secret1", 1)
+	require.NoError(t, s.Snapshot())
+	putValue(t, s, "key2", "secret2", 1)
+	require.NoError(t, s.Close())
+
+	// Neither the snapshot nor the log contains the values in plaintext.
+	files, err := os.ReadDir(root)
+	require.NoError(t, err)
+
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(root, f.Name()))
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "secret", f.Name())
+	}
+
+	// The files can not be read without the key.
+	conf.Cipher = nil
+	_, err = Open(conf)
+	require.ErrorIs(t, err, protoio.ErrNoCipher)
+
+	conf.Cipher = kr
+	s, err = Open(conf)
+	require.NoError(t, err)
+
+	defer s.Close()
+
+	for key, data := range map[string]string{"key1": "secret1", "key2": "secret2"} {
+		values, err := s.Get(key)
+		require.NoError(t, err)
+		require.Len(t, values, 1)
+		assert.Equal(t, []byte(data), values[0].Data)
+	}
+}
@@ -0,0 +1,46 @@
+package inmemory
+
+import (
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/hlc"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/inmemory/proto"
+)
+
+func fromProtoValues(vs []*proto.Value) []storage.Value {
+	values := make([]storage.Value, 0, len(vs))
+
+	for _, v := range vs {
+		version := dvv.MustUnmarshal(v.Version)
+
+		values = append(values, storage.Value{
+			Version:   version.Context,
+			Dot:       version.Dot,
+			Data:      v.Data,
+			Tombstone: v.Tombstone,
+			Timestamp: hlc.Timestamp(v.Timestamp),
+			Origin:    v.Origin,
+		})
+	}
+
+	return values
+}
+
+func toProtoEntry(key string, vs []storage.Value) *proto.Entry {
+	values := make([]*proto.Value, 0, len(vs))
+
+	for _, v := range vs {
+		values = append(values, &proto.Value{
+			Version:   dvv.Marshal(v.DottedVersion()),
+			Data:      v.Data,
+			Tombstone: v.Tombstone,
+			Timestamp: uint64(v.Timestamp),
+			Origin:    v.Origin,
+		})
+	}
+
+	return &proto.Entry{
+		Key:    key,
+		Values: values,
+	}
+}
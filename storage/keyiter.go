@@ -0,0 +1,48 @@
+package storage
+
+// KeyIterator groups the versions returned by the scan iterator by key.
+type KeyIterator struct {
+	it    ScanIterator
+	key   string
+	value Value
+	ahead bool
+}
+
+// NewKeyIterator creates an iterator that returns all versions of each key at once.
+func NewKeyIterator(it ScanIterator) *KeyIterator {
+	return &KeyIterator{it: it}
+}
+
+// Next returns the next key along with its versions. It returns false
+// when there are no more keys.
+func (i *KeyIterator) Next() (string, []Value, bool) {
+	if !i.ahead {
+		if !i.it.HasNext() {
+			return "", nil, false
+		}
+
+		i.key, i.value = i.it.Next()
+	}
+
+	key := i.key
+	values := []Value{i.value}
+	i.ahead = false
+
+	for i.it.HasNext() {
+		k, v := i.it.Next()
+
+		if k != key {
+			i.key, i.value, i.ahead = k, v, true
+			break
+		}
+
+		values = append(values, v)
+	}
+
+	return key, values, true
+}
+
+// Err returns the error that stopped the iteration early, if any.
+func (i *KeyIterator) Err() error {
+	return i.it.Err()
+}
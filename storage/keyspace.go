@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keyspace holds the settings of the keys sharing the same prefix.
+type Keyspace struct {
+	// Prefix is the common prefix of the keys. The empty prefix matches all keys.
+	Prefix string
+	// DottedVersions enables the dotted version vectors for the keys. The writes are
+	// identified by a dot assigned by the coordinator, instead of incrementing the
+	// coordinator's entry of the vector clock. See dvv.Version for details.
+	DottedVersions bool
+	// LastWriteWins makes the concurrent versions of the keys collapse into a single
+	// winner instead of being kept as siblings. See AppendLastWriteWins for details.
+	LastWriteWins bool
+}
+
+// ParseKeyspace parses the keyspace definition in the form of "prefix:option,option".
+// The supported options are "dvv", which enables the dotted versions, and "lww", which
+// enables the last-write-wins resolution. The empty prefix applies to all keys.
+func ParseKeyspace(s string) (Keyspace, error) {
+	prefix, options, found := strings.Cut(s, ":")
+	if !found {
+		return Keyspace{}, fmt.Errorf("invalid keyspace %q: missing colon after the prefix", s)
+	}
+
+	ks := Keyspace{Prefix: prefix}
+
+	for _, opt := range strings.Split(options, ",") {
+		switch opt {
+		case "":
+		case "dvv":
+			ks.DottedVersions = true
+		case "lww":
+			ks.LastWriteWins = true
+		default:
+			return Keyspace{}, fmt.Errorf("invalid keyspace %q: unknown option %q", s, opt)
+		}
+	}
+
+	return ks, nil
+}
+
+// Keyspaces is a set of keyspaces, each key belongs to the one with the longest matching prefix.
+type Keyspaces []Keyspace
+
+// Lookup returns the keyspace of the key. If no keyspace matches,
+// the zero keyspace with the default settings is returned.
+func (k Keyspaces) Lookup(key string) Keyspace {
+	var (
+		match Keyspace
+		found bool
+	)
+
+	for _, ks := range k {
+		if strings.HasPrefix(key, ks.Prefix) && (!found || len(ks.Prefix) > len(match.Prefix)) {
+			match, found = ks, true
+		}
+	}
+
+	return match
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeyspace(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    Keyspace
+		wantErr bool
+	}{
+		"PrefixOnly": {
+			input: "users/:",
+			want:  Keyspace{Prefix: "users/"},
+		},
+		"DottedVersions": {
+			input: "carts/:dvv",
+			want:  Keyspace{Prefix: "carts/", DottedVersions: true},
+		},
+		"LastWriteWins": {
+			input: "sessions/:lww",
+			want:  Keyspace{Prefix: "sessions/", LastWriteWins: true},
+		},
+		"MultipleOptions": {
+			input: "carts/:dvv,lww",
+			want:  Keyspace{Prefix: "carts/", DottedVersions: true, LastWriteWins: true},
+		},
+		"EmptyPrefix": {
+			input: ":dvv",
+			want:  Keyspace{DottedVersions: true},
+		},
+		"MissingColon": {
+			input:   "users/",
+			wantErr: true,
+		},
+		"UnknownOption": {
+			input:   "users/:foo",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ks, err := ParseKeyspace(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ks)
+		})
+	}
+}
+
+func TestKeyspaces_Lookup(t *testing.T) {
+	keyspaces := Keyspaces{
+		{Prefix: "", DottedVersions: false},
+		{Prefix: "carts/", DottedVersions: true},
+		{Prefix: "carts/archived/", DottedVersions: false},
+	}
+
+	assert.Equal(t, "", keyspaces.Lookup("users/1").Prefix)
+	assert.Equal(t, "carts/", keyspaces.Lookup("carts/1").Prefix)
+	assert.Equal(t, "carts/archived/", keyspaces.Lookup("carts/archived/1").Prefix)
+	assert.Equal(t, Keyspace{}, Keyspaces{}.Lookup("key"))
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/vclock"
+)
+
+// ErrSiblingLimit is returned when a write would exceed the limits on the number or
+// the total size of the concurrent versions of a key, and the limits are configured
+// to reject such writes.
+var ErrSiblingLimit = errors.New("sibling limit exceeded")
+
+// LimitAction defines what happens to a write that exceeds the sibling limits.
+type LimitAction int
+
+const (
+	// LimitReject rejects the write with ErrSiblingLimit. The client has to resolve
+	// the conflict by writing a value with the version that overtakes the siblings.
+	LimitReject LimitAction = iota
+	// LimitFold folds the oldest siblings into a single value with the resolver,
+	// so that the write can be accepted.
+	LimitFold
+)
+
+// Resolver picks the value that replaces several concurrent versions of a key.
+// The values passed to the resolver are never empty.
+type Resolver func(values []Value) Value
+
+// LastWriteWins is a resolver that picks the value with the latest timestamp.
+// If the timestamps are equal, the value written later to this node wins.
+func LastWriteWins(values []Value) Value {
+	winner := values[0]
+
+	for _, val := range values[1:] {
+		if val.Timestamp >= winner.Timestamp {
+			winner = val
+		}
+	}
+
+	return winner
+}
+
+// SiblingLimits restrict the concurrent versions a key may have. Without the limits, a
+// key written by the clients that do not read it first collects a new sibling with each
+// write, and all of them are returned on every read. The zero value means no limits.
+type SiblingLimits struct {
+	// MaxSiblings is the maximum number of concurrent versions of a key.
+	// Zero means no limit.
+	MaxSiblings int
+	// MaxBytes is the maximum total size of the data of all concurrent versions
+	// of a key. Zero means no limit.
+	MaxBytes int
+	// Action defines what to do with a write exceeding the limits.
+	// Defaults to LimitReject.
+	Action LimitAction
+	// Resolver is used to fold the siblings when the action is LimitFold.
+	// Defaults to LastWriteWins.
+	Resolver Resolver
+	// Logger is used to report the keys exceeding the limits. Defaults to no logging.
+	Logger log.Logger
+}
+
+func (l *SiblingLimits) exceeded(values []Value) bool {
+	if l.MaxSiblings > 0 && len(values) > l.MaxSiblings {
+		return true
+	}
+
+	if l.MaxBytes > 0 && dataSize(values) > l.MaxBytes {
+		return true
+	}
+
+	return false
+}
+
+func dataSize(values []Value) (size int) {
+	for _, val := range values {
+		size += len(val.Data)
+	}
+
+	return size
+}
+
+// AppendVersion appends a new version to the list of versions, the same way as the
+// package-level AppendVersion does, and then enforces the limits on the result.
+func (l *SiblingLimits) AppendVersion(key string, values []Value, newValue Value) ([]Value, error) {
+	return l.appendVersion(key, values, newValue, false)
+}
+
+// RestoreVersion is like AppendVersion, but never rejects the new version. The siblings
+// exceeding the limits are folded regardless of the configured action.
+func (l *SiblingLimits) RestoreVersion(key string, values []Value, newValue Value) ([]Value, error) {
+	return l.appendVersion(key, values, newValue, true)
+}
+
+func (l *SiblingLimits) appendVersion(key string, values []Value, newValue Value, restore bool) ([]Value, error) {
+	values, err := AppendVersion(values, newValue)
+	if err != nil {
+		return nil, err
+	}
+
+	if !l.exceeded(values) {
+		return values, nil
+	}
+
+	logger := l.Logger
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	// A single value that is too large can not be helped by folding.
+	if !restore && (l.Action == LimitReject || (l.MaxBytes > 0 && len(newValue.Data) > l.MaxBytes)) {
+		level.Warn(logger).Log("msg", "write rejected due to sibling limits", "key", key,
+			"siblings", len(values), "bytes", dataSize(values))
+
+		return nil, fmt.Errorf("%w: %d siblings of %d bytes", ErrSiblingLimit, len(values), dataSize(values))
+	}
+
+	folded := l.fold(values)
+
+	level.Warn(logger).Log("msg", "siblings folded due to sibling limits", "key", key,
+		"siblings", len(values), "folded", len(values)-len(folded)+1)
+
+	return folded, nil
+}
+
+// fold replaces the oldest siblings with a single value, until the limits are satisfied.
+// The folded value has the version that overtakes the versions of all folded siblings.
+func (l *SiblingLimits) fold(values []Value) []Value {
+	resolve := l.Resolver
+	if resolve == nil {
+		resolve = LastWriteWins
+	}
+
+	// Order the siblings from the oldest to the newest. The values without a timestamp
+	// go first, and the values with the same timestamp keep the order they were written.
+	sorted := make([]Value, len(values))
+	copy(sorted, values)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp < sorted[j].Timestamp
+	})
+
+	for n := 2; n <= len(sorted); n++ {
+		toFold, rest := sorted[:n], sorted[n:]
+		version := vclock.New()
+
+		for _, val := range toFold {
+			version = vclock.Merge(version, val.Clock())
+		}
+
+		// The merged version may overtake some of the remaining siblings. Such siblings
+		// are folded as well, as they would be discarded by the next write anyway.
+		kept := make([]Value, 0, len(rest))
+
+		for _, val := range rest {
+			if vclock.Compare(val.Clock(), version) == vclock.Before {
+				toFold = append(toFold[:len(toFold):len(toFold)], val)
+				continue
+			}
+
+			kept = append(kept, val)
+		}
+
+		winner := resolve(toFold)
+		winner.Version = version
+		winner.Dot = dvv.Dot{}
+
+		result := append([]Value{winner}, kept...)
+		if !l.exceeded(result) {
+			return result
+		}
+	}
+
+	// Even a single value may exceed MaxBytes if it had been written before the limit
+	// was lowered. There is nothing else to fold then, so the values are kept as is.
+	return values
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+)
+
+func TestLastWriteWins(t *testing.T) {
+	values := []Value{
+		{Data: []byte("a"), Timestamp: 20},
+		{Data: []byte("b"), Timestamp: 30},
+		{Data: []byte("c"), Timestamp: 30},
+		{Data: []byte("d"), Timestamp: 10},
+	}
+
+	winner := LastWriteWins(values)
+	require.Equal(t, []byte("c"), winner.Data)
+}
+
+func TestSiblingLimits_AppendVersion(t *testing.T) {
+	type test struct {
+		limits        SiblingLimits
+		currentValues []Value
+		incomingValue Value
+		wantResult    []Value
+		wantErr       error
+	}
+
+	tests := map[string]test{
+		"NoLimits": {
+			limits: SiblingLimits{},
+			currentValues: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1})},
+			},
+			incomingValue: Value{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1})},
+			wantResult: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1})},
+				{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1})},
+			},
+		},
+		"WithinLimits": {
+			limits: SiblingLimits{MaxSiblings: 2, MaxBytes: 2},
+			currentValues: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1})},
+			},
+			incomingValue: Value{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1})},
+			wantResult: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1})},
+				{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1})},
+			},
+		},
+		"OvertakingWriteIsNotLimited": {
+			limits: SiblingLimits{MaxSiblings: 1},
+			currentValues: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1})},
+			},
+			incomingValue: Value{Data: []byte("b"), Version: vclock.New(vclock.V{1: 2})},
+			wantResult: []Value{
+				{Data: []byte("b"), Version: vclock.New(vclock.V{1: 2})},
+			},
+		},
+		"RejectsTooManySiblings": {
+			limits: SiblingLimits{MaxSiblings: 1},
+			currentValues: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1})},
+			},
+			incomingValue: Value{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1})},
+			wantErr:       ErrSiblingLimit,
+		},
+		"RejectsTooManyBytes": {
+			limits: SiblingLimits{MaxBytes: 5},
+			currentValues: []Value{
+				{Data: []byte("aaa"), Version: vclock.New(vclock.V{1: 1})},
+			},
+			incomingValue: Value{Data: []byte("bbb"), Version: vclock.New(vclock.V{2: 1})},
+			wantErr:       ErrSiblingLimit,
+		},
+		"FoldsOldestSiblingsByCount": {
+			limits: SiblingLimits{MaxSiblings: 2, Action: LimitFold},
+			currentValues: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 10},
+				{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 20},
+			},
+			incomingValue: Value{Data: []byte("c"), Version: vclock.New(vclock.V{3: 1}), Timestamp: 30},
+			wantResult: []Value{
+				{Data: []byte("b"), Version: vclock.New(vclock.V{1: 1, 2: 1}), Timestamp: 20},
+				{Data: []byte("c"), Version: vclock.New(vclock.V{3: 1}), Timestamp: 30},
+			},
+		},
+		"FoldsOldestSiblingsBySize": {
+			limits: SiblingLimits{MaxBytes: 10, Action: LimitFold},
+			currentValues: []Value{
+				{Data: []byte("aaaaa"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 10},
+				{Data: []byte("bbbbb"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 20},
+			},
+			incomingValue: Value{Data: []byte("cc"), Version: vclock.New(vclock.V{3: 1}), Timestamp: 30},
+			wantResult: []Value{
+				{Data: []byte("bbbbb"), Version: vclock.New(vclock.V{1: 1, 2: 1}), Timestamp: 20},
+				{Data: []byte("cc"), Version: vclock.New(vclock.V{3: 1}), Timestamp: 30},
+			},
+		},
+		"FoldsDominatedSiblings": {
+			limits: SiblingLimits{MaxSiblings: 3, Action: LimitFold},
+			currentValues: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 2}), Timestamp: 10},
+				{Data: []byte("b"), Version: vclock.New(vclock.V{2: 2}), Timestamp: 20},
+				{Data: []byte("c"), Version: vclock.New(vclock.V{1: 1, 2: 1}), Timestamp: 30},
+			},
+			incomingValue: Value{Data: []byte("d"), Version: vclock.New(vclock.V{3: 1}), Timestamp: 40},
+			wantResult: []Value{
+				{Data: []byte("c"), Version: vclock.New(vclock.V{1: 2, 2: 2}), Timestamp: 30},
+				{Data: []byte("d"), Version: vclock.New(vclock.V{3: 1}), Timestamp: 40},
+			},
+		},
+		"FoldsWithCustomResolver": {
+			limits: SiblingLimits{
+				MaxSiblings: 1,
+				Action:      LimitFold,
+				Resolver:    func(values []Value) Value { return values[0] },
+			},
+			currentValues: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 10},
+			},
+			incomingValue: Value{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 20},
+			wantResult: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1, 2: 1}), Timestamp: 10},
+			},
+		},
+		"RejectsTooLargeValueWhenFolding": {
+			limits:        SiblingLimits{MaxBytes: 4, Action: LimitFold},
+			incomingValue: Value{Data: []byte("hello"), Version: vclock.New(vclock.V{1: 1})},
+			wantErr:       ErrSiblingLimit,
+		},
+		"ObsoleteWrite": {
+			limits: SiblingLimits{MaxSiblings: 1},
+			currentValues: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 2})},
+			},
+			incomingValue: Value{Data: []byte("b"), Version: vclock.New(vclock.V{1: 1})},
+			wantErr:       ErrObsoleteWrite,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := tt.limits.AppendVersion("key", tt.currentValues, tt.incomingValue)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantResult, result)
+		})
+	}
+}
+
+func TestSiblingLimits_RestoreVersion(t *testing.T) {
+	limits := SiblingLimits{MaxSiblings: 1, MaxBytes: 4}
+
+	current := []Value{
+		{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 10},
+	}
+
+	// The limits configured to reject the writes fold the restored versions instead.
+	result, err := limits.RestoreVersion("key", current, Value{
+		Data: []byte("b"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 20,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Value{
+		{Data: []byte("b"), Version: vclock.New(vclock.V{1: 1, 2: 1}), Timestamp: 20},
+	}, result)
+
+	// A restored value is kept even if it is too large on its own.
+	result, err = limits.RestoreVersion("key", nil, Value{
+		Data: []byte("hello"), Version: vclock.New(vclock.V{1: 1}),
+	})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+}
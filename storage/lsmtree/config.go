@@ -2,6 +2,9 @@ package lsmtree
 
 import (
 	"github.com/go-kit/log"
+
+	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/storage/vfs"
 )
 
 type Config struct {
@@ -11,6 +14,13 @@ type Config struct {
 	// DataRoot is the directory where the lsm-tree will be stored. Has no effect
 	// if DataFS is specified. Defaults to the current working directory.
 	DataRoot string
+	// DataFS is the file system where the lsm-tree will be stored. Defaults to
+	// the OS file system rooted at DataRoot.
+	DataFS vfs.FS
+	// SyncWrites makes every write to the WAL followed by fsync, so that the
+	// acknowledged writes survive a power loss, at the cost of the write
+	// throughput. Defaults to false.
+	SyncWrites bool
 	// MaxMemtableSize is the maximum number of entries in the memtable before
 	// it is flushed to disk. Defaults to 1000.
 	MaxMemtableSize int64
@@ -28,6 +38,26 @@ type Config struct {
 	// use mmap in databases, so it is disabled by default. Please check out the following
 	// paper for more details: https://db.cs.cmu.edu/mmap-cidr2022/
 	MmapDataFiles bool
+	// IORateLimit is the maximum rate in bytes per second at which the background
+	// processes, such as flushes and compactions, write data to disk. Foreground writes
+	// to the WAL are never limited. Zero means no limit. Defaults to 0.
+	IORateLimit int64
+	// IORateLimitMax is the upper bound the rate limit is raised to when the flushes fall
+	// behind, so that the background processes can catch up with the incoming writes.
+	// Has no effect if IORateLimit is not set. Defaults to 4 times IORateLimit.
+	IORateLimitMax int64
+	// FlushBacklogBytes is the size of the memtables waiting to be flushed at which the
+	// rate limit reaches IORateLimitMax. Below this value, the limit grows linearly from
+	// IORateLimit towards IORateLimitMax. Defaults to 4 times MaxMemtableSize.
+	FlushBacklogBytes int64
+	// Cipher is used to encrypt the records of the WAL, SSTable and STATE files. Every
+	// record carries the ID of the key it was encrypted with, so the files written before
+	// the key rotation (or before the encryption was enabled) remain readable as long as
+	// the old keys are provided. The existing files are never re-encrypted, so the data
+	// has to be copied with cmd/kvrekey before an old key can be dropped. Bloom filters are
+	// not encrypted, as they only contain the hashes of the keys. Defaults to nil, which
+	// means no encryption.
+	Cipher protoio.Cipher
 }
 
 func DefaultConfig() Config {
@@ -0,0 +1,107 @@
+package lsmtree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+	"github.com/maxpoletaev/kv/storage/vfs"
+)
+
+// raceEnabled is set when the tests are built with the race detector, which slows
+// down the crash test by an order of magnitude.
+var raceEnabled = false
+
+// crashWorkload writes the given number of values to the tree, overwriting a small set of
+// keys over and over, so that the writes are spread across several memtables and sstables.
+// It stops at the first failed write and returns the values that have been acknowledged,
+// along with the key and the value of the write that has failed, if any.
+func crashWorkload(lsm *LSMTree, writes int) (acked map[string]string, inflightKey, inflightVal string) {
+	acked = make(map[string]string)
+
+	for i := 0; i < writes; i++ {
+		key := fmt.Sprintf("key%d", i%13)
+		val := fmt.Sprintf("value%d", i)
+
+		err := lsm.Put(&proto.DataEntry{
+			Key:    key,
+			Values: []*proto.Value{{Data: []byte(val)}},
+		})
+		if err != nil {
+			return acked, key, val
+		}
+
+		acked[key] = val
+	}
+
+	return acked, "", ""
+}
+
+func TestLSMTree_CrashRecovery(t *testing.T) {
+	writes := 1000
+	if testing.Short() || raceEnabled {
+		writes = 250
+	}
+
+	newConfig := func(fs vfs.FS) Config {
+		conf := DefaultConfig()
+		conf.Logger = log.NewNopLogger()
+		conf.DataFS = fs
+		conf.MaxMemtableSize = 256
+		conf.SyncWrites = true
+
+		return conf
+	}
+
+	// Crash the file system after every mutating operation, one by one, until
+	// the workload is able to complete without hitting the crash point.
+	for crashAt := 1; ; crashAt++ {
+		fs := vfs.NewCrashFS(crashAt)
+
+		lsm, err := Create(newConfig(fs))
+		if err != nil {
+			require.True(t, fs.Crashed(), "crash point %d: %v", crashAt, err)
+			continue
+		}
+
+		acked, inflightKey, inflightVal := crashWorkload(lsm, writes)
+		fs.Crash()
+
+		// The tree is not expected to close cleanly after the crash, we only
+		// need to wait for the background flushes to stop.
+		_ = lsm.Close()
+
+		if inflightKey == "" && fs.Ops() < crashAt {
+			t.Logf("checked %d crash points", crashAt-1)
+			break
+		}
+
+		recovered := fs.Recover(0)
+
+		restored, err := Create(newConfig(recovered))
+		require.NoError(t, err, "crash point %d", crashAt)
+
+		names, err := recovered.List()
+		require.NoError(t, err)
+		require.Empty(t, restored.state.Orphans(names), "crash point %d", crashAt)
+
+		for key, val := range acked {
+			entry, found, err := restored.Get(key)
+			require.NoError(t, err, "crash point %d", crashAt)
+			require.True(t, found, "crash point %d: key %s is lost", crashAt, key)
+			require.Len(t, entry.Values, 1)
+
+			got := string(entry.Values[0].Data)
+			if key == inflightKey && got == inflightVal {
+				continue
+			}
+
+			require.Equal(t, val, got, "crash point %d: key %s", crashAt, key)
+		}
+
+		require.NoError(t, restored.Close(), "crash point %d", crashAt)
+	}
+}
@@ -10,17 +10,19 @@ import (
 type LSMTEngine struct {
 	locks *lockmap.Map[string]
 	lsm   *lsmtree.LSMTree
+	opts  storage.Options
 }
 
-func New(lsm *lsmtree.LSMTree) *LSMTEngine {
+func New(lsm *lsmtree.LSMTree, opts ...storage.Option) *LSMTEngine {
 	return &LSMTEngine{
 		locks: lockmap.New[string](),
 		lsm:   lsm,
+		opts:  storage.NewOptions(opts...),
 	}
 }
 
 func (s *LSMTEngine) Get(key string) ([]storage.Value, error) {
-	entry, found, err := s.lsm.Get(key)
+	values, found, err := s.get(key)
 	if err != nil {
 		return nil, err
 	}
@@ -29,33 +31,132 @@ func (s *LSMTEngine) Get(key string) ([]storage.Value, error) {
 		return nil, storage.ErrNotFound
 	}
 
-	return fromProtoValues(entry.Values), nil
+	return values, nil
+}
+
+func (s *LSMTEngine) get(key string) ([]storage.Value, bool, error) {
+	entry, found, err := s.lsm.Get(key)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	return fromProtoValues(entry.Values), true, nil
 }
 
 func (s *LSMTEngine) Put(key string, value storage.Value) error {
+	return s.put(key, value, s.opts.AppendVersion)
+}
+
+// Restore stores the version copied from another node, bypassing the sibling limits.
+func (s *LSMTEngine) Restore(key string, value storage.Value) error {
+	return s.put(key, value, s.opts.RestoreVersion)
+}
+
+func (s *LSMTEngine) put(key string, value storage.Value, appendVersion func(string, []storage.Value, storage.Value) ([]storage.Value, error)) error {
 	s.locks.Lock(key)
 	defer s.locks.Unlock(key)
 
-	var values []storage.Value
-
-	entry, found, err := s.lsm.Get(key)
+	values, _, err := s.get(key)
 	if err != nil {
 		return err
-	} else if found {
-		values = fromProtoValues(entry.Values)
 	}
 
-	values, err = storage.AppendVersion(values, value)
+	values, err = appendVersion(key, values, value)
 	if err != nil {
 		return err
 	}
 
-	entry = &proto.DataEntry{
+	entry := &proto.DataEntry{
 		Key:    key,
 		Values: toProtoValues(values),
 	}
 
-	s.lsm.Put(entry)
+	return s.lsm.Put(entry)
+}
+
+// Merge applies the operand to the value of the key and stores the result, which
+// replaces all versions of the key.
+func (s *LSMTEngine) Merge(key string, operand storage.Operand) (storage.Value, error) {
+	s.locks.Lock(key)
+	defer s.locks.Unlock(key)
+
+	values, _, err := s.get(key)
+	if err != nil {
+		return storage.Value{}, err
+	}
+
+	merged, err := storage.ApplyOperands(values, []storage.Operand{operand})
+	if err != nil {
+		return storage.Value{}, err
+	}
 
-	return nil
+	err = s.lsm.Put(&proto.DataEntry{
+		Key:    key,
+		Values: toProtoValues([]storage.Value{merged}),
+	})
+	if err != nil {
+		return storage.Value{}, err
+	}
+
+	return merged, nil
 }
+
+// Discard drops the given versions of the key. The key is deleted with a tombstone once
+// there are no versions left, which hides the older entries of the key in the sstables.
+func (s *LSMTEngine) Discard(key string, values []storage.Value) error {
+	s.locks.Lock(key)
+	defer s.locks.Unlock(key)
+
+	stored, found, err := s.get(key)
+	if err != nil || !found {
+		return err
+	}
+
+	kept := storage.DiscardVersions(stored, values)
+
+	switch {
+	case len(kept) == len(stored):
+		return nil
+	case len(kept) == 0:
+		return s.lsm.Put(&proto.DataEntry{Key: key, Tombstone: true})
+	default:
+		return s.lsm.Put(&proto.DataEntry{Key: key, Values: toProtoValues(kept)})
+	}
+}
+
+// Stats returns the statistics of the underlying LSM-tree.
+func (s *LSMTEngine) Stats() storage.Stats {
+	stats := s.lsm.Stats()
+
+	levels := make([]storage.LevelStats, 0, len(stats.Levels))
+	for _, level := range stats.Levels {
+		levels = append(levels, storage.LevelStats{
+			Level:     level.Level,
+			NumTables: level.NumTables,
+			Bytes:     level.Bytes,
+		})
+	}
+
+	return storage.Stats{
+		Levels:                 levels,
+		MemtableBytes:          stats.MemtableBytes,
+		MemtableEntries:        stats.MemtableEntries,
+		FlushQueueLength:       stats.FlushQueueLength,
+		FlushQueueBytes:        stats.FlushQueueBytes,
+		Gets:                   stats.Gets,
+		TablesRead:             stats.TablesRead,
+		BloomFalsePositives:    stats.BloomFalsePositives,
+		PendingCompactionBytes: stats.PendingCompactionBytes,
+		UserBytesWritten:       stats.UserBytesWritten,
+		WALBytesWritten:        stats.WALBytesWritten,
+		FlushBytesWritten:      stats.FlushBytesWritten,
+		WriteAmplification:     stats.WriteAmplification,
+		IORateLimit:            stats.IOThrottle.Rate,
+		IOThrottled:            stats.IOThrottle.Throttled,
+		IOThrottledTime:        stats.IOThrottle.ThrottledTime,
+	}
+}
+
+var _ storage.Discardable = &LSMTEngine{}
+var _ storage.Restorable = &LSMTEngine{}
+var _ storage.StatsProvider = &LSMTEngine{}
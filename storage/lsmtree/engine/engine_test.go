@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/enginetest"
+	"github.com/maxpoletaev/kv/storage/lsmtree"
+	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+	"github.com/maxpoletaev/kv/storage/vfs"
+)
+
+// disk is the file system of a directory used by the conformance tests.
+type disk struct {
+	fs     *vfs.CrashFS
+	opened bool
+}
+
+func TestConformance(t *testing.T) {
+	disks := make(map[string]*disk)
+
+	enginetest.Run(t, func(t *testing.T, dir string) (storage.Engine, func() error) {
+		d, ok := disks[dir]
+		if !ok {
+			d = &disk{fs: vfs.NewCrashFS(0)}
+			disks[dir] = d
+		}
+
+		// The engine still open in the directory has been abandoned. It is crashed along with
+		// the flushes it may be running, and the new one starts from what has made it to disk.
+		if d.opened {
+			d.fs.Crash()
+			d.fs = d.fs.Recover(0)
+		}
+
+		conf := lsmtree.DefaultConfig()
+		conf.DataFS = d.fs
+		conf.MaxMemtableSize = 1024
+		conf.SyncWrites = true
+
+		lsm, err := lsmtree.Create(conf)
+		require.NoError(t, err)
+
+		fs := d.fs
+		d.opened = true
+
+		return New(lsm), func() error {
+			if d.fs == fs {
+				d.opened = false
+			}
+
+			return lsm.Close()
+		}
+	}, enginetest.Options{Persistent: true})
+}
+
+func TestGet_LegacyVersionEncoding(t *testing.T) {
+	conf := lsmtree.DefaultConfig()
+	conf.DataRoot = t.TempDir()
+
+	lsm, err := lsmtree.Create(conf)
+	require.NoError(t, err)
+
+	defer lsm.Close()
+
+	// The versions used to be stored as base64-encoded protobuf text. This one is {1=2, 2=1}.
+	err = lsm.Put(&proto.DataEntry{
+		Key: "key",
+		Values: []*proto.Value{
+			{Version: []byte("CgQIARACCgQIAhAB"), Data: []byte("old")},
+		},
+	})
+	require.NoError(t, err)
+
+	engine := New(lsm)
+
+	values, err := engine.Get("key")
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+	assert.Equal(t, "{1=2, 2=1}", values[0].Version.String())
+
+	// Writing a newer value replaces the old one, and stores the version in the binary format.
+	version := vclock.New(vclock.V{1: 3, 2: 1})
+	err = engine.Put("key", storage.Value{Version: version, Data: []byte("new")})
+	require.NoError(t, err)
+
+	entry, found, err := lsm.Get("key")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Len(t, entry.Values, 1)
+	assert.Equal(t, vclock.Marshal(version), entry.Values[0].Version)
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/maxpoletaev/kv/storage"
+)
+
+// scanBatchSize is the number of keys read from the tree at once during a scan.
+const scanBatchSize = 128
+
+// scanIterator iterates over the versions of the keys in the tree. The keys are read in
+// batches, and the values of each key are read when the iterator reaches it, so the scan
+// sees the writes made after it has started. Concurrent versions of the same key are
+// returned one after another. The iteration stops early if the tree cannot be read, and
+// the error is returned by Err.
+type scanIterator struct {
+	engine *LSMTEngine
+	from   string
+	to     *string
+	keys   []string
+	done   bool
+	key    string
+	values []storage.Value
+	err    error
+}
+
+func (i *scanIterator) readKeys() {
+	keys, err := i.engine.lsm.Keys(i.from, scanBatchSize)
+	if err != nil {
+		i.err, i.done = fmt.Errorf("failed to list keys: %w", err), true
+		return
+	}
+
+	if len(keys) == 0 {
+		i.done = true
+		return
+	}
+
+	// The smallest key that is greater than the last key of the batch.
+	i.from = keys[len(keys)-1] + "\x00"
+
+	if i.to != nil {
+		for j, key := range keys {
+			if key > *i.to {
+				keys, i.done = keys[:j], true
+				break
+			}
+		}
+	}
+
+	i.keys = keys
+}
+
+func (i *scanIterator) fill() {
+	for len(i.values) == 0 {
+		if len(i.keys) == 0 {
+			if i.done {
+				return
+			}
+
+			i.readKeys()
+
+			continue
+		}
+
+		key := i.keys[0]
+		i.keys = i.keys[1:]
+
+		// The deleted keys are listed by the tree, but have no values.
+		values, _, err := i.engine.get(key)
+		if err != nil {
+			i.err = fmt.Errorf("failed to read %s: %w", key, err)
+			i.keys, i.done = nil, true
+
+			return
+		}
+
+		i.key, i.values = key, values
+	}
+}
+
+// HasNext returns true if there are more items in the iterator.
+func (i *scanIterator) HasNext() bool {
+	i.fill()
+
+	return len(i.values) > 0
+}
+
+// Next returns the next key and one of its versions. It panics if there
+// are no more items, so HasNext should always be called before Next.
+func (i *scanIterator) Next() (string, storage.Value) {
+	i.fill()
+
+	if len(i.values) == 0 {
+		panic("no more items in the iterator")
+	}
+
+	value := i.values[0]
+	i.values = i.values[1:]
+
+	return i.key, value
+}
+
+// Err returns the error that stopped the iteration early, if any.
+func (i *scanIterator) Err() error {
+	return i.err
+}
+
+// Scan returns an iterator over all keys of the engine.
+func (s *LSMTEngine) Scan() storage.ScanIterator {
+	return &scanIterator{engine: s}
+}
+
+// ScanFrom returns an iterator over the keys greater than or equal to the given key.
+func (s *LSMTEngine) ScanFrom(key string) storage.ScanIterator {
+	return &scanIterator{engine: s, from: key}
+}
+
+// ScanTo returns an iterator over the keys less than or equal to the given key.
+func (s *LSMTEngine) ScanTo(key string) storage.ScanIterator {
+	return &scanIterator{engine: s, to: &key}
+}
+
+// ScanRange returns an iterator over the keys between from and to, inclusive.
+func (s *LSMTEngine) ScanRange(from, to string) storage.ScanIterator {
+	return &scanIterator{engine: s, from: from, to: &to}
+}
+
+var _ storage.Scannable = &LSMTEngine{}
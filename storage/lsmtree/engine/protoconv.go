@@ -1,15 +1,22 @@
 package engine
 
 import (
-	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/hlc"
 	"github.com/maxpoletaev/kv/storage"
 	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
 )
 
 func fromProtoValue(v *proto.Value) storage.Value {
+	version := dvv.MustUnmarshal(v.Version)
+
 	return storage.Value{
-		Version: vclock.MustDecode(v.Version),
-		Data:    v.Data,
+		Version:   version.Context,
+		Dot:       version.Dot,
+		Data:      v.Data,
+		Tombstone: v.Tombstone,
+		Timestamp: hlc.Timestamp(v.Timestamp),
+		Origin:    v.Origin,
 	}
 }
 
@@ -25,8 +32,11 @@ func fromProtoValues(vs []*proto.Value) []storage.Value {
 
 func toProtoValue(v storage.Value) *proto.Value {
 	return &proto.Value{
-		Version: vclock.MustEncode(v.Version),
-		Data:    v.Data,
+		Version:   dvv.Marshal(v.DottedVersion()),
+		Data:      v.Data,
+		Tombstone: v.Tombstone,
+		Timestamp: uint64(v.Timestamp),
+		Origin:    v.Origin,
 	}
 }
 
@@ -4,30 +4,36 @@ import (
 	"fmt"
 	"hash/crc32"
 	"os"
-	"path/filepath"
+	"sync/atomic"
 
 	protobuf "google.golang.org/protobuf/proto"
 
 	"github.com/maxpoletaev/kv/internal/bloom"
 	"github.com/maxpoletaev/kv/internal/opengroup"
 	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/internal/ratelimit"
 	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+	"github.com/maxpoletaev/kv/storage/vfs"
 )
 
 type flushOpts struct {
-	prefix    string
+	fs        vfs.FS
 	tableID   int64
 	indexGap  int64
 	useMmap   bool
 	bloomProb float64
+	limiter   *ratelimit.Limiter
+	cipher    protoio.Cipher
+	counters  *counters
 }
 
 // flushToDisk writes the contents of the memtable to disk and returns an SSTable
 // that can be used to read the data. The memtable must be closed before calling
 // this function to guarantee that it is not modified while the flush. The parameters
 // of the bloom filter are calculated based on the number of entries in the memtable.
+// All writes are throttled by the limiter, if one is given.
 func flushToDisk(mem *Memtable, opts flushOpts) (*SSTable, error) {
-	og := opengroup.New()
+	og := opengroup.New(opts.fs)
 	defer og.CloseAll()
 
 	info := &SSTableInfo{
@@ -38,16 +44,16 @@ func flushToDisk(mem *Memtable, opts flushOpts) (*SSTable, error) {
 		BloomFile:  fmt.Sprintf("sst-%d.bloom", opts.tableID),
 	}
 
-	indexFile := og.Open(filepath.Join(opts.prefix, info.IndexFile), os.O_CREATE|os.O_WRONLY, 0o644)
-	bloomFile := og.Open(filepath.Join(opts.prefix, info.BloomFile), os.O_CREATE|os.O_WRONLY, 0o644)
-	dataFile := og.Open(filepath.Join(opts.prefix, info.DataFile), os.O_CREATE|os.O_WRONLY, 0o644)
+	indexFile := og.Open(info.IndexFile, os.O_CREATE|os.O_WRONLY, 0o644)
+	bloomFile := og.Open(info.BloomFile, os.O_CREATE|os.O_WRONLY, 0o644)
+	dataFile := og.Open(info.DataFile, os.O_CREATE|os.O_WRONLY, 0o644)
 	if err := og.Err(); err != nil {
 		return nil, fmt.Errorf("failed to open files: %w", err)
 	}
 
 	bf := bloom.NewWithProbability(mem.Len(), opts.bloomProb)
-	indexWriter := protoio.NewWriter(indexFile)
-	dataWriter := protoio.NewWriter(dataFile)
+	indexWriter := protoio.NewEncryptedWriter(ratelimit.NewWriter(indexFile, opts.limiter), opts.cipher)
+	dataWriter := protoio.NewEncryptedWriter(ratelimit.NewWriter(dataFile, opts.limiter), opts.cipher)
 
 	var lastOffset int64
 
@@ -88,7 +94,7 @@ func flushToDisk(mem *Memtable, opts flushOpts) (*SSTable, error) {
 		return nil, fmt.Errorf("failed to marshal bloom filter: %w", err)
 	}
 
-	if _, err := bloomFile.Write(bloomData); err != nil {
+	if _, err := ratelimit.NewWriter(bloomFile, opts.limiter).Write(bloomData); err != nil {
 		return nil, fmt.Errorf("failed to write bloom filter: %w", err)
 	}
 
@@ -96,9 +102,21 @@ func flushToDisk(mem *Memtable, opts flushOpts) (*SSTable, error) {
 	// as it includes both the size of the keys and the values.
 	info.Size = dataWriter.Offset()
 
+	if opts.counters != nil {
+		written := info.Size + indexWriter.Offset() + int64(len(bloomData))
+		atomic.AddInt64(&opts.counters.flushBytes, written)
+	}
+
+	// The table must be durable before it is recorded in the state and the WAL
+	// is removed, otherwise the data would be lost if the machine crashes.
+	if err := og.SyncAll(); err != nil {
+		_ = og.RemoveAll()
+		return nil, fmt.Errorf("failed to sync table files: %w", err)
+	}
+
 	// Open the flushed table for reading. This should be done before discarding
 	// the memtable as we want to ensure that the table is readable.
-	sst, err := OpenTable(info, opts.prefix, opts.useMmap)
+	sst, err := OpenTable(info, opts.fs, opts.useMmap, opts.cipher)
 	if err != nil {
 		_ = og.RemoveAll() // Cleanup so that we don’t generate garbage in case of error.
 		return nil, fmt.Errorf("failed to open table: %w", err)
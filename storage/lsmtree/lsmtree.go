@@ -9,14 +9,17 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	protobuf "google.golang.org/protobuf/proto"
 
+	"github.com/maxpoletaev/kv/internal/ratelimit"
 	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+	"github.com/maxpoletaev/kv/storage/vfs"
 )
 
 // LSMTree is a persistent key-value store based on the LSM-Tree data structure. It is a
 // write-optimized, which means that it is optimized for writes, but reads may be slow.
 type LSMTree struct {
-	dataRoot   string
+	fs         vfs.FS
 	memtable   *Memtable
 	flushQueue *list.List // *Memtable
 	ssTables   *list.List // *SSTable
@@ -25,6 +28,8 @@ type LSMTree struct {
 	stop       chan struct{}
 	state      *loggedState
 	logger     log.Logger
+	limiter    *ratelimit.Limiter
+	counters   counters
 	conf       Config
 	inFlush    int32
 }
@@ -37,14 +42,34 @@ func Create(conf Config) (*LSMTree, error) {
 	flushQueue := list.New()
 	sstables := list.New()
 
-	state, err := newLoggedState(conf.DataRoot)
+	fs := conf.DataFS
+	if fs == nil {
+		fs = vfs.NewOS(conf.DataRoot)
+	}
+
+	state, err := newLoggedState(fs, conf.Cipher)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create state: %w", err)
 	}
 
+	// Remove the files left from an interrupted flush or memtable creation, they
+	// are not referenced by the state and will never be used again.
+	names, err := fs.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data files: %w", err)
+	}
+
+	for _, name := range state.Orphans(names) {
+		if err := fs.Remove(name); err != nil {
+			return nil, fmt.Errorf("failed to remove orphan file: %w", err)
+		}
+
+		level.Info(logger).Log("msg", "removed orphan file", "name", name)
+	}
+
 	// Restore the state of the tree from the previous run.
 	for _, info := range state.SSTables() {
-		sst, err := OpenTable(info, conf.DataRoot, conf.MmapDataFiles)
+		sst, err := OpenTable(info, fs, conf.MmapDataFiles, conf.Cipher)
 		if err != nil {
 			return nil, fmt.Errorf("failed to open sstable: %w", err)
 		}
@@ -56,7 +81,7 @@ func Create(conf Config) (*LSMTree, error) {
 	// the memtables and flush them to the disk. This may potetially create a lot
 	// of small sstables, but the compaction should take care of it eventually.
 	for _, info := range state.Memtables() {
-		memt, err := openMemtable(info, conf.DataRoot)
+		memt, err := openMemtable(info, fs, conf.Cipher)
 		if err != nil {
 			return nil, fmt.Errorf("failed to restore memtable: %w", err)
 		}
@@ -66,7 +91,7 @@ func Create(conf Config) (*LSMTree, error) {
 
 	lsm := &LSMTree{
 		stop:       make(chan struct{}),
-		dataRoot:   conf.DataRoot,
+		fs:         fs,
 		flushQueue: flushQueue,
 		ssTables:   sstables,
 		logger:     logger,
@@ -74,6 +99,12 @@ func Create(conf Config) (*LSMTree, error) {
 		conf:       conf,
 	}
 
+	// Background writes are throttled only if the limit is set. A nil limiter is a no-op.
+	if conf.IORateLimit > 0 {
+		lsm.limiter = ratelimit.New(conf.IORateLimit)
+		lsm.adjustIORate()
+	}
+
 	// Wait for the flush to finish before returning, so that we have no memtables
 	// in the queue when the tree is ready to use.
 	if flushQueue.Len() > 0 {
@@ -99,7 +130,7 @@ func (lsm *LSMTree) sheduleFlush() error {
 	lsm.mut.Lock()
 
 	// Check again, in case the memtable was flushed by another goroutine.
-	if lsm.memtable.Size() < lsm.conf.MaxMemtableSize {
+	if lsm.memtable == nil || lsm.memtable.Size() < lsm.conf.MaxMemtableSize {
 		lsm.mut.Unlock()
 		return nil
 	}
@@ -113,6 +144,7 @@ func (lsm *LSMTree) sheduleFlush() error {
 	// The active memtable is moved to the flush queue and will be flushed to disk in background.
 	lsm.flushQueue.PushBack(lsm.memtable)
 	lsm.memtable = nil
+	lsm.adjustIORate()
 	lsm.mut.Unlock()
 
 	// Start a background goroutine to flush the memtable to disk, only
@@ -155,7 +187,11 @@ func (lsm *LSMTree) flushWaiting() error {
 			bloomProb: lsm.conf.BloomFilterProbability,
 			indexGap:  lsm.conf.SparseIndexGapBytes,
 			tableID:   time.Now().UnixMicro(),
-			prefix:    lsm.dataRoot,
+			fs:        lsm.fs,
+			useMmap:   lsm.conf.MmapDataFiles,
+			limiter:   lsm.limiter,
+			cipher:    lsm.conf.Cipher,
+			counters:  &lsm.counters,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to flush: %w", err)
@@ -172,6 +208,7 @@ func (lsm *LSMTree) flushWaiting() error {
 
 			lsm.flushQueue.Remove(el)
 			lsm.ssTables.PushBack(sst)
+			lsm.adjustIORate()
 
 			return nil
 		}(); err != nil {
@@ -185,18 +222,100 @@ func (lsm *LSMTree) flushWaiting() error {
 	}
 }
 
+// pendingCompactionBytes returns the number of bytes that have to be rewritten by
+// the compaction. Since every flush produces a new level-0 table, all of them are
+// subject to be merged as soon as there is more than one. Must be called with the
+// lock held.
+func (lsm *LSMTree) pendingCompactionBytes() int64 {
+	var tables, size int64
+
+	for el := lsm.ssTables.Front(); el != nil; el = el.Next() {
+		sst := el.Value.(*SSTable)
+
+		if sst.Level == 0 {
+			size += sst.Size
+			tables++
+		}
+	}
+
+	if tables < 2 {
+		return 0
+	}
+
+	return size
+}
+
+// flushBacklogBytes returns the size of the memtables waiting to be flushed. Must be
+// called with the lock held.
+func (lsm *LSMTree) flushBacklogBytes() int64 {
+	var size int64
+
+	for el := lsm.flushQueue.Front(); el != nil; el = el.Next() {
+		size += el.Value.(*Memtable).Size()
+	}
+
+	return size
+}
+
+// adjustIORate raises the background I/O rate limit proportionally to the flush backlog,
+// so that the flushes do not fall behind the incoming writes indefinitely. The limit goes
+// back down as the backlog is flushed. Must be called with the lock held.
+func (lsm *LSMTree) adjustIORate() {
+	if lsm.limiter == nil {
+		return
+	}
+
+	minRate := lsm.conf.IORateLimit
+	maxRate := lsm.conf.IORateLimitMax
+
+	if maxRate < minRate {
+		maxRate = minRate * 4
+	}
+
+	backlogLimit := lsm.conf.FlushBacklogBytes
+	if backlogLimit <= 0 {
+		backlogLimit = lsm.conf.MaxMemtableSize * 4
+	}
+
+	backlog := lsm.flushBacklogBytes()
+	if backlog > backlogLimit {
+		backlog = backlogLimit
+	}
+
+	lsm.limiter.SetRate(minRate + (maxRate-minRate)*backlog/backlogLimit)
+}
+
 // Get returns the value for the given key, if it exists. It checks the active memtable first,
 // then the memtables that are waiting to be flushed, and finally the sstables on disk. Note that
 // the retuned entry is a pointer to the actual entry in the memtable or sstable, so it should not
 // be modified.
 func (lsm *LSMTree) Get(key string) (*proto.DataEntry, bool, error) {
+	var found *proto.DataEntry
+
+	err := lsm.find(key, func(entry *proto.DataEntry) bool {
+		found = entry
+		return false
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return found, found != nil, nil
+}
+
+// find passes the entries of the given key to the visit function, from the newest to the
+// oldest, until the function returns false or there are no more entries. The search stops
+// at the first tombstone, which is not passed to the function.
+func (lsm *LSMTree) find(key string, visit func(entry *proto.DataEntry) bool) error {
 	lsm.mut.RLock()
 	defer lsm.mut.RUnlock()
 
+	atomic.AddInt64(&lsm.counters.gets, 1)
+
 	// Check the active memtable first.
 	if lsm.memtable != nil {
-		if entry, found := lsm.memtable.Get(key); found {
-			return entry, true, nil
+		if entry, found := lsm.memtable.lookup(key); found && (entry.Tombstone || !visit(entry)) {
+			return nil
 		}
 	}
 
@@ -204,8 +323,8 @@ func (lsm *LSMTree) Get(key string) (*proto.DataEntry, bool, error) {
 	for el := lsm.flushQueue.Back(); el != nil; el = el.Prev() {
 		mt := el.Value.(*Memtable)
 
-		if entry, found := mt.Get(key); found {
-			return entry, true, nil
+		if entry, found := mt.lookup(key); found && (entry.Tombstone || !visit(entry)) {
+			return nil
 		}
 	}
 
@@ -213,14 +332,30 @@ func (lsm *LSMTree) Get(key string) (*proto.DataEntry, bool, error) {
 	for el := lsm.ssTables.Back(); el != nil; el = el.Prev() {
 		sst := el.Value.(*SSTable)
 
-		if entry, found, err := sst.Get(key); err != nil {
-			return nil, false, err
-		} else if found {
-			return entry, true, nil
+		// Check the bloom filter first, if it's not there, it's not in the SSTable.
+		if !sst.Contains(key) {
+			continue
+		}
+
+		atomic.AddInt64(&lsm.counters.tablesRead, 1)
+
+		entry, found, err := sst.find(key)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			atomic.AddInt64(&lsm.counters.bloomFalsePositives, 1)
+			continue
+		}
+
+		// The entry is nil if the key has been deleted.
+		if entry == nil || !visit(entry) {
+			return nil
 		}
 	}
 
-	return nil, false, nil
+	return nil
 }
 
 func (lsm *LSMTree) putToMem(entry *proto.DataEntry) error {
@@ -230,10 +365,14 @@ func (lsm *LSMTree) putToMem(entry *proto.DataEntry) error {
 		if lsm.memtable != nil {
 			defer lsm.mut.RUnlock()
 
-			if err := lsm.memtable.Put(entry); err != nil {
+			n, err := lsm.memtable.Put(entry)
+			if err != nil {
 				return fmt.Errorf("failed to put entry: %w", err)
 			}
 
+			atomic.AddInt64(&lsm.counters.userBytes, int64(protobuf.Size(entry)))
+			atomic.AddInt64(&lsm.counters.walBytes, int64(n))
+
 			return nil
 		}
 
@@ -243,7 +382,7 @@ func (lsm *LSMTree) putToMem(entry *proto.DataEntry) error {
 		// If there is no active memtable, create one. We postpone this operation until the first
 		// write, so that we don't create an empty wal file if there are no writes at all.
 		if lsm.memtable == nil {
-			memt, err := createMemtable(lsm.dataRoot)
+			memt, err := createMemtable(lsm.fs, lsm.conf.Cipher, lsm.conf.SyncWrites)
 			if err != nil {
 				lsm.mut.Unlock()
 				return fmt.Errorf("failed to create memtable: %w", err)
@@ -256,9 +395,9 @@ func (lsm *LSMTree) putToMem(entry *proto.DataEntry) error {
 			}
 
 			lsm.memtable = memt
-
-			lsm.mut.Unlock()
 		}
+
+		lsm.mut.Unlock()
 	}
 }
 
@@ -0,0 +1,327 @@
+package lsmtree
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/keyring"
+	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+	"github.com/maxpoletaev/kv/storage/vfs"
+)
+
+func TestLSMTree_IORateLimit(t *testing.T) {
+	conf := DefaultConfig()
+	conf.DataRoot = t.TempDir()
+	conf.MaxMemtableSize = 100
+	conf.IORateLimit = 1024 * 1024
+	conf.IORateLimitMax = 2 * 1024 * 1024
+
+	lsm, err := Create(conf)
+	require.NoError(t, err)
+
+	assert.Equal(t, conf.IORateLimit, lsm.limiter.Stats().Rate)
+
+	for i := 0; i < 100; i++ {
+		err := lsm.Put(&proto.DataEntry{
+			Key:    fmt.Sprintf("key%d", i),
+			Values: []*proto.Value{{Data: []byte("value")}},
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, lsm.Close())
+
+	// Once the backlog is flushed, the limit goes back to the minimum.
+	stats := lsm.limiter.Stats()
+	assert.Greater(t, stats.Bytes, int64(0))
+	assert.Equal(t, conf.IORateLimit, stats.Rate)
+}
+
+func TestLSMTree_AdjustIORate(t *testing.T) {
+	conf := DefaultConfig()
+	conf.DataRoot = t.TempDir()
+	conf.IORateLimit = 1000
+	conf.IORateLimitMax = 2000
+	conf.FlushBacklogBytes = 100
+
+	lsm, err := Create(conf)
+	require.NoError(t, err)
+
+	defer lsm.Close()
+
+	lsm.mut.Lock()
+	defer lsm.mut.Unlock()
+
+	// The limit grows with the size of the memtables waiting to be flushed.
+	memt := &Memtable{dataSize: 50}
+	el := lsm.flushQueue.PushBack(memt)
+	lsm.adjustIORate()
+	assert.Equal(t, int64(1500), lsm.limiter.Stats().Rate)
+
+	memt.dataSize = 500
+	lsm.adjustIORate()
+	assert.Equal(t, conf.IORateLimitMax, lsm.limiter.Stats().Rate)
+
+	lsm.flushQueue.Remove(el)
+	lsm.adjustIORate()
+	assert.Equal(t, conf.IORateLimit, lsm.limiter.Stats().Rate)
+}
+
+func TestLSMTree_NoIORateLimit(t *testing.T) {
+	conf := DefaultConfig()
+	conf.DataRoot = t.TempDir()
+
+	lsm, err := Create(conf)
+	require.NoError(t, err)
+	defer lsm.Close()
+
+	assert.Nil(t, lsm.limiter)
+	assert.Equal(t, int64(0), lsm.limiter.Stats().Rate)
+}
+
+func TestLSMTree_Encryption(t *testing.T) {
+	kr, err := keyring.New(map[uint16][]byte{1: bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+
+	conf := DefaultConfig()
+	conf.DataRoot = t.TempDir()
+	conf.MaxMemtableSize = 100
+	conf.Cipher = kr
+
+	lsm, err := Create(conf)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		err := lsm.Put(&proto.DataEntry{
+			Key:    fmt.Sprintf("key%d", i),
+			Values: []*proto.Value{{Data: []byte("plaintext")}},
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, lsm.Close())
+
+	// No data is stored in plain text.
+	files, err := os.ReadDir(conf.DataRoot)
+	require.NoError(t, err)
+
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(conf.DataRoot, f.Name()))
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "plaintext", f.Name())
+	}
+
+	// Rotate the key and make sure the old data is still readable.
+	kr, err = keyring.New(map[uint16][]byte{
+		1: bytes.Repeat([]byte{1}, 32),
+		2: bytes.Repeat([]byte{2}, 32),
+	})
+	require.NoError(t, err)
+
+	conf.Cipher = kr
+	lsm, err = Create(conf)
+	require.NoError(t, err)
+	defer lsm.Close()
+
+	for i := 0; i < 10; i++ {
+		entry, found, err := lsm.Get(fmt.Sprintf("key%d", i))
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, []byte("plaintext"), entry.Values[0].Data)
+	}
+
+	// Without the keys, the tree cannot be opened.
+	conf.Cipher = nil
+	_, err = Create(conf)
+	assert.ErrorIs(t, err, protoio.ErrNoCipher)
+}
+
+func TestLSMTree_Stats(t *testing.T) {
+	conf := DefaultConfig()
+	conf.DataRoot = t.TempDir()
+	conf.MaxMemtableSize = 100
+
+	lsm, err := Create(conf)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		err := lsm.Put(&proto.DataEntry{
+			Key:    fmt.Sprintf("key%d", i),
+			Values: []*proto.Value{{Data: []byte("value")}},
+		})
+		require.NoError(t, err)
+	}
+
+	// Wait for the background flushes to complete.
+	require.NoError(t, lsm.Close())
+
+	lsm, err = Create(conf)
+	require.NoError(t, err)
+	defer lsm.Close()
+
+	_, found, err := lsm.Get("key0")
+	require.NoError(t, err)
+	require.True(t, found)
+
+	stats := lsm.Stats()
+	require.Len(t, stats.Levels, 1)
+	assert.Equal(t, 0, stats.Levels[0].Level)
+	assert.Greater(t, stats.Levels[0].NumTables, 1)
+	assert.Greater(t, stats.Levels[0].Bytes, int64(0))
+	assert.Equal(t, stats.Levels[0].Bytes, stats.PendingCompactionBytes)
+	assert.Equal(t, int64(1), stats.Gets)
+	assert.GreaterOrEqual(t, stats.TablesRead, int64(1))
+	assert.Equal(t, stats.TablesRead-1, stats.BloomFalsePositives)
+	assert.Equal(t, 0, stats.FlushQueueLength)
+
+	// Counters are not persisted, so the write stats are reset after reopening.
+	assert.Equal(t, int64(0), stats.UserBytesWritten)
+	assert.Equal(t, float64(0), stats.WriteAmplification)
+}
+
+func TestLSMTree_WriteAmplification(t *testing.T) {
+	conf := DefaultConfig()
+	conf.DataRoot = t.TempDir()
+
+	lsm, err := Create(conf)
+	require.NoError(t, err)
+	defer lsm.Close()
+
+	err = lsm.Put(&proto.DataEntry{
+		Key:    "key",
+		Values: []*proto.Value{{Data: []byte("value")}},
+	})
+	require.NoError(t, err)
+
+	stats := lsm.Stats()
+	assert.Equal(t, 1, stats.MemtableEntries)
+	assert.Equal(t, stats.WALBytesWritten, stats.MemtableBytes)
+	assert.Greater(t, stats.WALBytesWritten, stats.UserBytesWritten)
+	assert.Greater(t, stats.WriteAmplification, 1.0)
+}
+
+func TestLSMTree_InMemoryFS(t *testing.T) {
+	fs := vfs.NewMem()
+
+	conf := DefaultConfig()
+	conf.DataFS = fs
+	conf.MaxMemtableSize = 100
+	conf.MmapDataFiles = true
+
+	lsm, err := Create(conf)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		err := lsm.Put(&proto.DataEntry{
+			Key:    fmt.Sprintf("key%d", i),
+			Values: []*proto.Value{{Data: []byte(fmt.Sprintf("value%d", i))}},
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, lsm.Close())
+
+	lsm, err = Create(conf)
+	require.NoError(t, err)
+
+	defer lsm.Close()
+
+	for i := 0; i < 100; i++ {
+		entry, found, err := lsm.Get(fmt.Sprintf("key%d", i))
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, fmt.Sprintf("value%d", i), string(entry.Values[0].Data))
+	}
+}
+
+func TestLSMTree_RemovesOrphans(t *testing.T) {
+	fs := vfs.NewMem()
+
+	for _, name := range []string{"mem-1.wal", "sst-1.data", "sst-1.index", "sst-1.bloom", "other"} {
+		f, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0o644)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	conf := DefaultConfig()
+	conf.DataFS = fs
+
+	lsm, err := Create(conf)
+	require.NoError(t, err)
+
+	defer lsm.Close()
+
+	names, err := fs.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"STATE", "other"}, names)
+}
+
+func TestLSMTree_Keys(t *testing.T) {
+	conf := DefaultConfig()
+	conf.DataFS = vfs.NewMem()
+	conf.MaxMemtableSize = 200
+
+	lsm, err := Create(conf)
+	require.NoError(t, err)
+
+	defer lsm.Close()
+
+	// The keys are spread over several tables, and some of them are rewritten.
+	for _, i := range []int{5, 1, 9, 3, 7, 0, 8, 2, 6, 4, 3, 5} {
+		err := lsm.Put(&proto.DataEntry{
+			Key:    fmt.Sprintf("key%d", i),
+			Values: []*proto.Value{{Data: []byte("value")}},
+		})
+		require.NoError(t, err)
+	}
+
+	lsm.wg.Wait()
+
+	keys, err := lsm.Keys("", 4)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key0", "key1", "key2", "key3"}, keys)
+
+	keys, err = lsm.Keys("key3\x00", 4)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key4", "key5", "key6", "key7"}, keys)
+
+	keys, err = lsm.Keys("key8", 4)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key8", "key9"}, keys)
+
+	keys, err = lsm.Keys("x", 4)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestLSMTree_TombstoneHidesOlderEntries(t *testing.T) {
+	conf := DefaultConfig()
+	conf.DataFS = vfs.NewMem()
+	conf.MaxMemtableSize = 1
+
+	lsm, err := Create(conf)
+	require.NoError(t, err)
+
+	defer lsm.Close()
+
+	require.NoError(t, lsm.Put(&proto.DataEntry{
+		Key:    "key",
+		Values: []*proto.Value{{Data: []byte("value")}},
+	}))
+
+	// Every write flushes the previous memtable, so the value is in an sstable,
+	// while the tombstone is in the memtable, and then in a newer sstable.
+	require.NoError(t, lsm.Put(&proto.DataEntry{Key: "key", Tombstone: true}))
+	lsm.wg.Wait()
+
+	_, found, err := lsm.Get("key")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
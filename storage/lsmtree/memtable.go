@@ -5,35 +5,40 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/maxpoletaev/kv/internal/protoio"
 	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
 	"github.com/maxpoletaev/kv/storage/skiplist"
+	"github.com/maxpoletaev/kv/storage/vfs"
 )
 
 type Memtable struct {
 	*MemtableInfo
 	entries   *skiplist.Skiplist[string, *proto.DataEntry]
 	walWriter protoio.SequentialWriter
-	walFile   *os.File
+	walFile   vfs.File
+	walMut    sync.Mutex
+	walSync   bool
+	fs        vfs.FS
 	dataSize  int64
 }
 
-func createMemtable(prefix string) (*Memtable, error) {
+// createMemtable creates a new memtable with an empty WAL file. If syncWrites is set,
+// every write to the WAL is followed by fsync, so that it survives a power loss.
+func createMemtable(fs vfs.FS, cipher protoio.Cipher, syncWrites bool) (*Memtable, error) {
 	id := time.Now().UnixMicro()
 	walFileName := fmt.Sprintf("mem-%d.wal", id)
 
-	walFile, err := os.OpenFile(
-		filepath.Join(prefix, walFileName), os.O_WRONLY|os.O_CREATE, 0o644)
+	walFile, err := fs.OpenFile(walFileName, os.O_WRONLY|os.O_CREATE, 0o644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create wal file: %w", err)
 	}
 
 	entries := skiplist.New[string, *proto.DataEntry](skiplist.StringComparator)
-	writer := protoio.NewWriter(walFile)
+	writer := protoio.NewEncryptedWriter(walFile, cipher)
 	info := &MemtableInfo{
 		WALFile: walFileName,
 		ID:      id,
@@ -44,24 +49,25 @@ func createMemtable(prefix string) (*Memtable, error) {
 		entries:      entries,
 		walWriter:    writer,
 		walFile:      walFile,
+		walSync:      syncWrites,
+		fs:           fs,
 	}, nil
 }
 
-func openMemtable(info *MemtableInfo, prefix string) (*Memtable, error) {
-	walFile, err := os.OpenFile(
-		filepath.Join(prefix, info.WALFile), os.O_RDWR, 0)
+func openMemtable(info *MemtableInfo, fs vfs.FS, cipher protoio.Cipher) (*Memtable, error) {
+	walFile, err := fs.OpenFile(info.WALFile, os.O_RDWR, 0)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open wal file: %w", err)
 	}
 
 	entries := skiplist.New[string, *proto.DataEntry](skiplist.StringComparator)
-	reader := protoio.NewReader(walFile)
+	reader := protoio.NewEncryptedReader(walFile, cipher)
 
 	for {
 		entry := &proto.DataEntry{}
 
 		if _, err := reader.ReadNext(entry); err != nil {
-			if errors.Is(err, io.EOF) {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 				break
 			}
 
@@ -75,8 +81,9 @@ func openMemtable(info *MemtableInfo, prefix string) (*Memtable, error) {
 		MemtableInfo: info,
 		entries:      entries,
 		walFile:      walFile,
+		fs:           fs,
 		dataSize:     reader.Offset(),
-		walWriter:    protoio.NewWriter(walFile),
+		walWriter:    protoio.NewEncryptedWriter(walFile, cipher),
 	}, nil
 }
 
@@ -92,21 +99,39 @@ func (mt *Memtable) Get(key string) (*proto.DataEntry, bool) {
 	return entry, true
 }
 
+// lookup is like Get, but also returns the tombstones, which must
+// shadow the entries of the same key in the older tables.
+func (mt *Memtable) lookup(key string) (*proto.DataEntry, bool) {
+	return mt.entries.Get(key)
+}
+
 // Put inserts a new entry into the memtable. The entry is first appended to the
 // WAL file and then inserted into the memtable. If the entry already exists in
 // the memtable, it is overwritten. Removing an entry is done by inserting a
-// entry with a tombstone flag set to true.
-func (mt *Memtable) Put(entry *proto.DataEntry) error {
+// entry with a tombstone flag set to true. Returns the number of bytes appended
+// to the WAL file.
+func (mt *Memtable) Put(entry *proto.DataEntry) (int, error) {
+	// Writes to the WAL must not interleave, and the entries must be inserted
+	// in the same order they are appended, so that the replay yields the same state.
+	mt.walMut.Lock()
+	defer mt.walMut.Unlock()
+
 	n, err := mt.walWriter.Append(entry)
 	if err != nil {
-		return fmt.Errorf("failed to append to WAL: %w", err)
+		return 0, fmt.Errorf("failed to append to WAL: %w", err)
+	}
+
+	if mt.walSync {
+		if err := mt.walFile.Sync(); err != nil {
+			return 0, fmt.Errorf("failed to sync WAL: %w", err)
+		}
 	}
 
 	mt.entries.Insert(entry.Key, entry)
 
 	atomic.AddInt64(&mt.dataSize, int64(n))
 
-	return nil
+	return n, nil
 }
 
 // Iter returns an iterator over the memtable.
@@ -142,9 +167,10 @@ func (mt *Memtable) Close() error {
 
 // Discard removes data files associated with the memtable. It is used when
 // the memtable is no longer needed, e.g. when it is merged into a SSTable.
-// Memtable should be closed before calling this method.
+// Memtable should be closed before calling this method. Discarding a memtable whose
+// WAL file is already gone is not an error.
 func (mt *Memtable) Discard() error {
-	if err := os.Remove(mt.walFile.Name()); err != nil {
+	if err := mt.fs.Remove(mt.WALFile); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("failed to remove wal file: %w", err)
 	}
 
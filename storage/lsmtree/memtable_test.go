@@ -7,6 +7,7 @@ import (
 
 	"github.com/maxpoletaev/kv/internal/protoio"
 	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+	"github.com/maxpoletaev/kv/storage/vfs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -14,7 +15,7 @@ import (
 func TestCreateMemtable(t *testing.T) {
 	tempDir := t.TempDir()
 
-	memt, err := createMemtable(tempDir)
+	memt, err := createMemtable(vfs.NewOS(tempDir), nil, false)
 	require.NoError(t, err)
 	defer memt.CloseAndDiscard()
 
@@ -35,7 +36,7 @@ func TestOpenMemtable(t *testing.T) {
 		Key: "key",
 		Values: []*proto.Value{
 			{
-				Version: "",
+				Version: nil,
 				Data:    []byte("value"),
 			},
 		},
@@ -44,7 +45,7 @@ func TestOpenMemtable(t *testing.T) {
 	memt, err := openMemtable(&MemtableInfo{
 		ID:      1,
 		WALFile: "1.wal",
-	}, tempDir)
+	}, vfs.NewOS(tempDir), nil)
 	require.NoError(t, err)
 	defer memt.CloseAndDiscard()
 
@@ -60,7 +61,7 @@ func TestOpenMemtable(t *testing.T) {
 func TestMemtable_GetAfterPut(t *testing.T) {
 	tempDir := t.TempDir()
 
-	memt, err := createMemtable(tempDir)
+	memt, err := createMemtable(vfs.NewOS(tempDir), nil, false)
 	require.NoError(t, err)
 	defer memt.CloseAndDiscard()
 
@@ -68,7 +69,7 @@ func TestMemtable_GetAfterPut(t *testing.T) {
 		Key: "key",
 		Values: []*proto.Value{
 			{
-				Version: "",
+				Version: nil,
 				Data:    []byte("value"),
 			},
 		},
@@ -86,7 +87,7 @@ func TestMemtable_GetAfterPut(t *testing.T) {
 func TestMemtable_PutRecordedInWAL(t *testing.T) {
 	tempDir := t.TempDir()
 
-	memt, err := createMemtable(tempDir)
+	memt, err := createMemtable(vfs.NewOS(tempDir), nil, false)
 	require.NoError(t, err)
 	defer memt.CloseAndDiscard()
 
@@ -94,7 +95,7 @@ func TestMemtable_PutRecordedInWAL(t *testing.T) {
 		Key: "key",
 		Values: []*proto.Value{
 			{
-				Version: "",
+				Version: nil,
 				Data:    []byte("value"),
 			},
 		},
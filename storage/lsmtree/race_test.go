@@ -0,0 +1,7 @@
+//go:build race
+
+package lsmtree
+
+func init() {
+	raceEnabled = true
+}
@@ -0,0 +1,100 @@
+package lsmtree
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/maxpoletaev/kv/internal/protoio"
+	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+)
+
+// Keys returns up to limit keys greater than or equal to the given one, in ascending order.
+// The keys are collected from all memtables and sstables, so the deleted keys are included
+// as well, and should be skipped by the caller when it finds no entry for them. The read
+// lock is held only while a single batch is collected, so scanning the whole tree batch by
+// batch does not block the flushes for long, and sees the writes made during the scan.
+func (lsm *LSMTree) Keys(from string, limit int) ([]string, error) {
+	lsm.mut.RLock()
+	defer lsm.mut.RUnlock()
+
+	// Each source contributes its first keys, the smallest of which form the result.
+	unique := make(map[string]struct{}, limit)
+
+	memtables := make([]*Memtable, 0, lsm.flushQueue.Len()+1)
+	if lsm.memtable != nil {
+		memtables = append(memtables, lsm.memtable)
+	}
+
+	for el := lsm.flushQueue.Front(); el != nil; el = el.Next() {
+		memtables = append(memtables, el.Value.(*Memtable))
+	}
+
+	for _, mt := range memtables {
+		it := mt.entries.ScanFrom(from)
+
+		for n := 0; n < limit && it.HasNext(); n++ {
+			key, _ := it.Next()
+			unique[key] = struct{}{}
+		}
+	}
+
+	for el := lsm.ssTables.Front(); el != nil; el = el.Next() {
+		keys, err := el.Value.(*SSTable).keysFrom(from, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			unique[key] = struct{}{}
+		}
+	}
+
+	keys := make([]string, 0, len(unique))
+	for key := range unique {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	return keys, nil
+}
+
+// keysFrom returns up to limit keys of the table greater than or equal to the given one.
+func (sst *SSTable) keysFrom(from string, limit int) ([]string, error) {
+	var offset int64
+
+	// Start from the closest offset in the sparse index, or from
+	// the beginning if the key is less than the first key of the table.
+	if _, off, found := sst.index.LessOrEqual(from); found {
+		offset = off
+	}
+
+	reader := protoio.NewEncryptedReader(sst.dataFile, sst.cipher)
+	keys := make([]string, 0, limit)
+
+	for len(keys) < limit {
+		entry := &proto.DataEntry{}
+
+		read, err := reader.ReadAt(entry, offset)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return nil, fmt.Errorf("failed to read data entry: %w", err)
+		}
+
+		offset += int64(read)
+
+		if entry.Key >= from {
+			keys = append(keys, entry.Key)
+		}
+	}
+
+	return keys, nil
+}
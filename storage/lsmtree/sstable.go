@@ -5,9 +5,7 @@ import (
 	"hash/crc32"
 	"io"
 	"os"
-	"path/filepath"
 
-	"golang.org/x/exp/mmap"
 	protobuf "google.golang.org/protobuf/proto"
 
 	"github.com/maxpoletaev/kv/internal/bloom"
@@ -15,31 +13,28 @@ import (
 	"github.com/maxpoletaev/kv/internal/protoio"
 	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
 	"github.com/maxpoletaev/kv/storage/skiplist"
+	"github.com/maxpoletaev/kv/storage/vfs"
 )
 
-type readerAtCloser interface {
-	io.ReaderAt
-	io.Closer
-}
-
 // SSTable is a sorted string table. It is a collection of key/value pairs
 // that are sorted by key. It is immutable, and is used to store data on disk.
 type SSTable struct {
 	*SSTableInfo
 	index       *skiplist.Skiplist[string, int64]
-	dataFile    readerAtCloser
+	dataFile    vfs.ReaderAtCloser
 	bloomfilter *bloom.Filter
+	cipher      protoio.Cipher
 }
 
 // OpenTable opens an SSTable from the given paths. All files must exist,
 // and the parameters of the bloom filter must match the parameters used
-// to create the SSTable.
-func OpenTable(info *SSTableInfo, prefix string, useMmap bool) (*SSTable, error) {
-	og := opengroup.New()
+// to create the SSTable. The cipher is required if the table is encrypted.
+func OpenTable(info *SSTableInfo, fs vfs.FS, useMmap bool, cipher protoio.Cipher) (*SSTable, error) {
+	og := opengroup.New(fs)
 	defer og.CloseAll()
 
-	indexFile := og.Open(filepath.Join(prefix, info.IndexFile), os.O_RDONLY, 0)
-	bloomFile := og.Open(filepath.Join(prefix, info.BloomFile), os.O_RDONLY, 0)
+	indexFile := og.Open(info.IndexFile, os.O_RDONLY, 0)
+	bloomFile := og.Open(info.BloomFile, os.O_RDONLY, 0)
 
 	if err := og.Err(); err != nil {
 		return nil, fmt.Errorf("failed to open files: %w", err)
@@ -47,7 +42,7 @@ func OpenTable(info *SSTableInfo, prefix string, useMmap bool) (*SSTable, error)
 
 	cmp := skiplist.StringComparator
 	index := skiplist.New[string, int64](cmp)
-	indexReader := protoio.NewReader(indexFile)
+	indexReader := protoio.NewEncryptedReader(indexFile, cipher)
 
 	for {
 		var entry proto.IndexEntry
@@ -82,7 +77,7 @@ func OpenTable(info *SSTableInfo, prefix string, useMmap bool) (*SSTable, error)
 	}
 
 	if useMmap {
-		dataFile, err := mmap.Open(filepath.Join(prefix, info.DataFile))
+		dataFile, err := fs.Mmap(info.DataFile)
 		if err != nil {
 			return nil, fmt.Errorf("failed to mmap data file: %w", err)
 		}
@@ -92,11 +87,11 @@ func OpenTable(info *SSTableInfo, prefix string, useMmap bool) (*SSTable, error)
 			index:       index,
 			dataFile:    dataFile,
 			bloomfilter: bloom.New(bf.Data, int(bf.NumHashes)),
+			cipher:      cipher,
 		}, nil
 	}
 
-	dataFile, err := os.OpenFile(
-		filepath.Join(prefix, info.DataFile), os.O_RDONLY, 0)
+	dataFile, err := fs.OpenFile(info.DataFile, os.O_RDONLY, 0)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open data file: %w", err)
 	}
@@ -106,6 +101,7 @@ func OpenTable(info *SSTableInfo, prefix string, useMmap bool) (*SSTable, error)
 		index:       index,
 		dataFile:    dataFile,
 		bloomfilter: bloom.New(bf.Data, int(bf.NumHashes)),
+		cipher:      cipher,
 	}, nil
 }
 
@@ -122,7 +118,7 @@ func (ss *SSTable) Close() error {
 
 // Iterator returns an iterator over the SSTable.
 func (sst *SSTable) Iterator() *Iterator {
-	reader := protoio.NewReader(sst.dataFile)
+	reader := protoio.NewEncryptedReader(sst.dataFile, sst.cipher)
 
 	next := &proto.DataEntry{}
 	if _, err := reader.ReadNext(next); err != nil {
@@ -130,7 +126,7 @@ func (sst *SSTable) Iterator() *Iterator {
 	}
 
 	return &Iterator{
-		reader: protoio.NewReader(sst.dataFile),
+		reader: protoio.NewEncryptedReader(sst.dataFile, sst.cipher),
 		next:   next,
 	}
 }
@@ -142,12 +138,19 @@ func (sst *SSTable) Contains(key string) bool {
 	return sst.bloomfilter.Check([]byte(key))
 }
 
+// Get returns the entry with the given key. The second return value is true if
+// the key was found, even if it has been deleted, in which case the entry is nil.
 func (sst *SSTable) Get(key string) (*proto.DataEntry, bool, error) {
 	// Check the bloom filter first, if it's not there, it's not in the SSTable.
 	if !sst.bloomfilter.Check([]byte(key)) {
 		return nil, false, nil
 	}
 
+	return sst.find(key)
+}
+
+// find looks up the key in the data file, bypassing the bloom filter.
+func (sst *SSTable) find(key string) (*proto.DataEntry, bool, error) {
 	// Find the closest offset in the sparse index.
 	_, offset, found := sst.index.LessOrEqual(key)
 	if !found {
@@ -156,7 +159,7 @@ func (sst *SSTable) Get(key string) (*proto.DataEntry, bool, error) {
 
 	// We create a new reader for each get. It is important for the reader to use pread
 	// instead of read, so that we do not need to synchronize with other readers.
-	reader := protoio.NewReader(sst.dataFile)
+	reader := protoio.NewEncryptedReader(sst.dataFile, sst.cipher)
 	entry := &proto.DataEntry{}
 
 	// Scan through the data file until we find the key we're looking for, or we bump
@@ -1,14 +1,16 @@
 package lsmtree
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/maxpoletaev/kv/internal/protoio"
 	"github.com/maxpoletaev/kv/storage/lsmtree/proto"
+	"github.com/maxpoletaev/kv/storage/vfs"
 )
 
 type MemtableInfo struct {
@@ -32,7 +34,8 @@ type SSTableInfo struct {
 // in use. The state itself is stored as a sequence of changes in a log file. It is not safe
 // to modify the state concurrently, so additional synchronization is required.
 type loggedState struct {
-	logFile   *os.File
+	cipher    protoio.Cipher
+	logFile   vfs.File
 	logWriter protoio.SequentialWriter
 	memtables []*MemtableInfo
 	sstables  []*SSTableInfo
@@ -41,9 +44,8 @@ type loggedState struct {
 // newLoggedState creates a new state manager. If the log file already exists, the state will be
 // restored from it, otherwise a new log file will be created. All chnages are immediately
 // flushed to the disk due to the file opened with O_SYNC flag.
-func newLoggedState(prefix string) (*loggedState, error) {
-	logFile, err := os.OpenFile(
-		filepath.Join(prefix, "STATE"), os.O_RDWR|os.O_CREATE|os.O_SYNC, 0o644)
+func newLoggedState(fs vfs.FS, cipher protoio.Cipher) (*loggedState, error) {
+	logFile, err := fs.OpenFile("STATE", os.O_RDWR|os.O_CREATE|os.O_SYNC, 0o644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
@@ -54,8 +56,9 @@ func newLoggedState(prefix string) (*loggedState, error) {
 	}
 
 	sm := &loggedState{
+		cipher:    cipher,
 		logFile:   logFile,
-		logWriter: protoio.NewWriter(logFile),
+		logWriter: protoio.NewEncryptedWriter(logFile, cipher),
 		memtables: make([]*MemtableInfo, 0),
 		sstables:  make([]*SSTableInfo, 0),
 	}
@@ -69,6 +72,36 @@ func newLoggedState(prefix string) (*loggedState, error) {
 	return sm, nil
 }
 
+// Orphans returns the names of the memtable and sstable files that are not referenced by
+// the state. Such files are left behind when the process crashes in the middle of creating
+// or flushing a memtable, or before the obsolete files are removed, and can be deleted.
+func (sm *loggedState) Orphans(names []string) []string {
+	inUse := make(map[string]bool)
+
+	for _, info := range sm.memtables {
+		inUse[info.WALFile] = true
+	}
+
+	for _, info := range sm.sstables {
+		inUse[info.IndexFile] = true
+		inUse[info.DataFile] = true
+		inUse[info.BloomFile] = true
+	}
+
+	orphans := make([]string, 0)
+
+	for _, name := range names {
+		isMemtable := strings.HasPrefix(name, "mem-") && strings.HasSuffix(name, ".wal")
+		isSSTable := strings.HasPrefix(name, "sst-")
+
+		if (isMemtable || isSSTable) && !inUse[name] {
+			orphans = append(orphans, name)
+		}
+	}
+
+	return orphans
+}
+
 // Memtables returns the list of currently active memtables.
 func (sm *loggedState) Memtables() []*MemtableInfo {
 	return sm.memtables
@@ -124,13 +157,17 @@ func (sm *loggedState) applyChange(change *proto.StateLogEntry) {
 	}
 }
 
+// restore replays the changes from the log file. A crash in the middle of an append may
+// leave a partially written record at the end of the log. Such a record is never applied,
+// as the change it describes has not been acknowledged, and it is truncated, so that the
+// following records are not appended after it.
 func (sm *loggedState) restore() error {
-	reader := protoio.NewReader(sm.logFile)
+	reader := protoio.NewEncryptedReader(sm.logFile, sm.cipher)
 	change := &proto.StateLogEntry{}
 
 	for {
 		if _, err := reader.ReadNext(change); err != nil {
-			if err == io.EOF {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 				break
 			}
 
@@ -142,6 +179,23 @@ func (sm *loggedState) restore() error {
 		change.Reset()
 	}
 
+	end, err := sm.logFile.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to seek to the end of log file: %w", err)
+	}
+
+	if end > reader.Offset() {
+		if err := sm.logFile.Truncate(reader.Offset()); err != nil {
+			return fmt.Errorf("failed to truncate log file: %w", err)
+		}
+
+		if _, err := sm.logFile.Seek(reader.Offset(), io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek log file: %w", err)
+		}
+
+		sm.logWriter = protoio.NewEncryptedWriter(sm.logFile, sm.cipher)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,124 @@
+package lsmtree
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/maxpoletaev/kv/internal/ratelimit"
+)
+
+// counters are the cumulative counters of the tree, updated atomically.
+type counters struct {
+	gets                int64
+	tablesRead          int64
+	bloomFalsePositives int64
+	userBytes           int64
+	walBytes            int64
+	flushBytes          int64
+}
+
+// LevelStats describes the sstables on a single level of the tree.
+type LevelStats struct {
+	Level     int
+	NumTables int
+	Bytes     int64
+}
+
+// Stats is a point-in-time snapshot of the state of the tree.
+type Stats struct {
+	// Levels contains the number and the size of the sstables on each level.
+	Levels []LevelStats
+	// MemtableBytes and MemtableEntries describe the active memtable.
+	MemtableBytes   int64
+	MemtableEntries int
+	// FlushQueueLength and FlushQueueBytes describe the memtables waiting to be flushed.
+	FlushQueueLength int
+	FlushQueueBytes  int64
+	// Gets is the number of lookups served by the tree.
+	Gets int64
+	// TablesRead is the number of sstables read from disk by all lookups, so
+	// that TablesRead/Gets is the average number of tables read per lookup.
+	TablesRead int64
+	// BloomFalsePositives is the number of times a bloom filter reported that a
+	// key may be in the table, but the table did not contain it.
+	BloomFalsePositives int64
+	// PendingCompactionBytes is the amount of data to be rewritten by the compaction.
+	PendingCompactionBytes int64
+	// UserBytesWritten is the size of the entries written to the tree.
+	UserBytesWritten int64
+	// WALBytesWritten is the number of bytes appended to the write-ahead logs.
+	WALBytesWritten int64
+	// FlushBytesWritten is the number of bytes written to disk by the flushes.
+	FlushBytesWritten int64
+	// WriteAmplification is the ratio of bytes written to disk to the bytes written
+	// by the user, including both the WAL and the sstables.
+	WriteAmplification float64
+	// IOThrottle contains the counters of the background I/O rate limiter.
+	IOThrottle ratelimit.Stats
+}
+
+// TablesPerGet returns the average number of sstables read per lookup.
+func (s Stats) TablesPerGet() float64 {
+	if s.Gets == 0 {
+		return 0
+	}
+
+	return float64(s.TablesRead) / float64(s.Gets)
+}
+
+// Stats returns the current statistics of the tree.
+func (lsm *LSMTree) Stats() Stats {
+	lsm.mut.RLock()
+	defer lsm.mut.RUnlock()
+
+	stats := Stats{
+		Gets:                   atomic.LoadInt64(&lsm.counters.gets),
+		TablesRead:             atomic.LoadInt64(&lsm.counters.tablesRead),
+		BloomFalsePositives:    atomic.LoadInt64(&lsm.counters.bloomFalsePositives),
+		UserBytesWritten:       atomic.LoadInt64(&lsm.counters.userBytes),
+		WALBytesWritten:        atomic.LoadInt64(&lsm.counters.walBytes),
+		FlushBytesWritten:      atomic.LoadInt64(&lsm.counters.flushBytes),
+		PendingCompactionBytes: lsm.pendingCompactionBytes(),
+		FlushQueueLength:       lsm.flushQueue.Len(),
+		IOThrottle:             lsm.limiter.Stats(),
+	}
+
+	if stats.UserBytesWritten > 0 {
+		diskBytes := stats.WALBytesWritten + stats.FlushBytesWritten
+		stats.WriteAmplification = float64(diskBytes) / float64(stats.UserBytesWritten)
+	}
+
+	if lsm.memtable != nil {
+		stats.MemtableBytes = lsm.memtable.Size()
+		stats.MemtableEntries = lsm.memtable.Len()
+	}
+
+	for el := lsm.flushQueue.Front(); el != nil; el = el.Next() {
+		stats.FlushQueueBytes += el.Value.(*Memtable).Size()
+	}
+
+	levels := make(map[int]*LevelStats)
+
+	for el := lsm.ssTables.Front(); el != nil; el = el.Next() {
+		sst := el.Value.(*SSTable)
+
+		level, ok := levels[sst.Level]
+		if !ok {
+			level = &LevelStats{Level: sst.Level}
+			levels[sst.Level] = level
+		}
+
+		level.NumTables++
+		level.Bytes += sst.Size
+	}
+
+	for _, level := range levels {
+		stats.Levels = append(stats.Levels, *level)
+	}
+
+	sort.Slice(stats.Levels, func(i, j int) bool {
+		return stats.Levels[i].Level < stats.Levels[j].Level
+	})
+
+	return stats
+}
@@ -0,0 +1,46 @@
+package storage
+
+import "bytes"
+
+// Newer returns true if the value a wins over the value b in the last-write-wins resolution.
+// The value with the greater timestamp wins, and the values with the same timestamp are
+// ordered by the ID of the node that coordinated the write. The values written before the
+// timestamps were stored are ordered by the data, so that all replicas pick the same winner.
+func Newer(a, b Value) bool {
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp > b.Timestamp
+	}
+
+	if a.Origin != b.Origin {
+		return a.Origin > b.Origin
+	}
+
+	if c := bytes.Compare(a.Data, b.Data); c != 0 {
+		return c > 0
+	}
+
+	return a.Tombstone && !b.Tombstone
+}
+
+// AppendLastWriteWins appends a new version to the list of versions, the same way as the
+// AppendVersion does, but then collapses the concurrent versions into a single winner
+// chosen with Newer. The winner keeps its own version, so that it is replicated as is,
+// and the replicas having any of the concurrent versions pick the same winner. A new
+// version concurrent to the existing one but losing to it is discarded without an error,
+// same as if it had been overwritten by the winner afterwards.
+func AppendLastWriteWins(values []Value, newValue Value) ([]Value, error) {
+	values, err := AppendVersion(values, newValue)
+	if err != nil {
+		return nil, err
+	}
+
+	winner := values[0]
+
+	for _, val := range values[1:] {
+		if Newer(val, winner) {
+			winner = val
+		}
+	}
+
+	return []Value{winner}, nil
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+)
+
+func TestNewer(t *testing.T) {
+	tests := map[string]struct {
+		a    Value
+		b    Value
+		want bool
+	}{
+		"GreaterTimestamp": {
+			a:    Value{Timestamp: 20, Origin: 1},
+			b:    Value{Timestamp: 10, Origin: 2},
+			want: true,
+		},
+		"LowerTimestamp": {
+			a:    Value{Timestamp: 10, Origin: 2},
+			b:    Value{Timestamp: 20, Origin: 1},
+			want: false,
+		},
+		"SameTimestampGreaterOrigin": {
+			a:    Value{Timestamp: 10, Origin: 2},
+			b:    Value{Timestamp: 10, Origin: 1},
+			want: true,
+		},
+		"NoTimestampGreaterData": {
+			a:    Value{Data: []byte("b")},
+			b:    Value{Data: []byte("a")},
+			want: true,
+		},
+		"TombstoneOverEmptyValue": {
+			a:    Value{Tombstone: true},
+			b:    Value{},
+			want: true,
+		},
+		"SameValue": {
+			a:    Value{Timestamp: 10, Origin: 1, Data: []byte("a")},
+			b:    Value{Timestamp: 10, Origin: 1, Data: []byte("a")},
+			want: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Newer(tt.a, tt.b))
+
+			if tt.want {
+				assert.False(t, Newer(tt.b, tt.a), "must be asymmetric")
+			}
+		})
+	}
+}
+
+func TestAppendLastWriteWins(t *testing.T) {
+	tests := map[string]struct {
+		values  []Value
+		value   Value
+		want    []Value
+		wantErr error
+	}{
+		"FirstValue": {
+			value: Value{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 10},
+			want: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 10},
+			},
+		},
+		"ConcurrentWinner": {
+			values: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 10},
+			},
+			value: Value{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 20},
+			want: []Value{
+				{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 20},
+			},
+		},
+		"ConcurrentLoser": {
+			values: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 20},
+			},
+			value: Value{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 10},
+			want: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 20},
+			},
+		},
+		"ConcurrentTieBrokenByOrigin": {
+			values: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 10, Origin: 2},
+			},
+			value: Value{Data: []byte("b"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 10, Origin: 1},
+			want: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 10, Origin: 2},
+			},
+		},
+		"DescendantWithOlderTimestamp": {
+			values: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 20},
+			},
+			value: Value{Data: []byte("b"), Version: vclock.New(vclock.V{1: 2}), Timestamp: 10},
+			want: []Value{
+				{Data: []byte("b"), Version: vclock.New(vclock.V{1: 2}), Timestamp: 10},
+			},
+		},
+		"ExistingSiblingsCollapse": {
+			values: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 30},
+				{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 10},
+			},
+			value: Value{Data: []byte("c"), Version: vclock.New(vclock.V{3: 1}), Timestamp: 20},
+			want: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 30},
+			},
+		},
+		"ObsoleteWrite": {
+			values: []Value{
+				{Data: []byte("a"), Version: vclock.New(vclock.V{1: 2}), Timestamp: 10},
+			},
+			value:   Value{Data: []byte("b"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 20},
+			wantErr: ErrObsoleteWrite,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := AppendLastWriteWins(tt.values, tt.value)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAppendLastWriteWins_OrderIndependent(t *testing.T) {
+	values := []Value{
+		{Data: []byte("a"), Version: vclock.New(vclock.V{1: 1}), Timestamp: 10, Origin: 1},
+		{Data: []byte("b"), Version: vclock.New(vclock.V{2: 1}), Timestamp: 30, Origin: 2},
+		{Data: []byte("c"), Version: vclock.New(vclock.V{3: 1}), Timestamp: 30, Origin: 3},
+		{Data: []byte("d"), Version: vclock.New(vclock.V{4: 1}), Timestamp: 20, Origin: 4},
+	}
+
+	// The replicas receiving the writes in any order must pick the same winner.
+	orders := [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {2, 0, 3, 1}, {1, 3, 0, 2}}
+
+	for _, order := range orders {
+		var (
+			stored []Value
+			err    error
+		)
+
+		for _, i := range order {
+			stored, err = AppendLastWriteWins(stored, values[i])
+			require.NoError(t, err)
+		}
+
+		require.Len(t, stored, 1)
+		assert.Equal(t, []byte("c"), stored[0].Data, "order %v", order)
+	}
+}
@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/maxpoletaev/kv/internal/hlc"
+	"github.com/maxpoletaev/kv/internal/vclock"
+)
+
+var (
+	// ErrUnknownOperator is returned when a merge operand refers to an operator
+	// that has not been registered.
+	ErrUnknownOperator = errors.New("unknown merge operator")
+
+	// ErrInvalidOperand is returned when a merge operand or an existing value
+	// cannot be decoded by the merge operator.
+	ErrInvalidOperand = errors.New("invalid merge operand")
+)
+
+// Names of the built-in merge operators.
+const (
+	OperatorInt64Add   = "int64add"
+	OperatorSetUnion   = "setunion"
+	OperatorListAppend = "listappend"
+)
+
+// Operand is a single merge operation on a key. Rather than replacing the value, the
+// operand is combined with it by the merge operator, which allows read-modify-write
+// updates, such as incrementing a counter, without reading the value first.
+type Operand struct {
+	// Operator is the name of the registered merge operator.
+	Operator string
+	// Data is the argument of the operation, its format depends on the operator.
+	Data []byte
+	// Origin is the ID of the node that has accepted the operation. The clock of this
+	// node is incremented in the version of the merged value.
+	Origin uint32
+	// Timestamp is the time the operation was accepted at, which is used to resolve the
+	// conflicts of the merged value in the last-write-wins keyspaces.
+	Timestamp hlc.Timestamp
+}
+
+// MergeOperator combines the existing value of a key with a sequence of operands. The
+// existing value may consist of several concurrent versions, or be empty if the key
+// does not exist. The operands are passed in the order they were written.
+type MergeOperator interface {
+	Merge(existing [][]byte, operands [][]byte) ([]byte, error)
+}
+
+// MergeOperatorFunc is an adapter to use an ordinary function as a merge operator.
+type MergeOperatorFunc func(existing [][]byte, operands [][]byte) ([]byte, error)
+
+func (f MergeOperatorFunc) Merge(existing [][]byte, operands [][]byte) ([]byte, error) {
+	return f(existing, operands)
+}
+
+// Mergeable is a storage that supports merge operands. The operand is applied to the
+// current value of the key, and the result replaces all versions of the key and is
+// returned. The operand itself is not stored.
+type Mergeable interface {
+	Merge(key string, operand Operand) (Value, error)
+}
+
+var (
+	operatorsMut sync.RWMutex
+	operators    = map[string]MergeOperator{
+		OperatorInt64Add:   Int64Add{},
+		OperatorSetUnion:   SetUnion{},
+		OperatorListAppend: ListAppend{},
+	}
+)
+
+// RegisterMergeOperator makes the merge operator available under the given name. Since
+// the operands refer to the operator by name, it must be registered on every node before
+// the node starts serving requests. It panics if the name is already taken.
+func RegisterMergeOperator(name string, op MergeOperator) {
+	operatorsMut.Lock()
+	defer operatorsMut.Unlock()
+
+	if _, ok := operators[name]; ok {
+		panic(fmt.Sprintf("merge operator %q is already registered", name))
+	}
+
+	operators[name] = op
+}
+
+// LookupMergeOperator returns the merge operator registered under the given name.
+func LookupMergeOperator(name string) (MergeOperator, bool) {
+	operatorsMut.RLock()
+	defer operatorsMut.RUnlock()
+
+	op, ok := operators[name]
+
+	return op, ok
+}
+
+// ApplyOperands combines the values of a key with the operands, and returns the single
+// resulting value. Concurrent versions are passed to the operator together and resolved
+// by it. The version of the result is the merge of all versions, with the clock of the
+// origin node of each operand incremented. The timestamp and the origin of the result are
+// taken from the last operand. Tombstones are treated as missing values.
+func ApplyOperands(values []Value, operands []Operand) (Value, error) {
+	version := vclock.New()
+	existing := make([][]byte, 0, len(values))
+
+	for _, val := range values {
+		version = vclock.Merge(version, val.Clock())
+
+		if !val.Tombstone {
+			existing = append(existing, val.Data)
+		}
+	}
+
+	// Consecutive operands of the same operator are combined in a single call. If the
+	// operator changes, the intermediate result becomes the existing value of the next.
+	for start := 0; start < len(operands); {
+		name := operands[start].Operator
+
+		op, ok := LookupMergeOperator(name)
+		if !ok {
+			return Value{}, fmt.Errorf("%w: %s", ErrUnknownOperator, name)
+		}
+
+		end := start
+		batch := make([][]byte, 0, len(operands)-start)
+
+		for ; end < len(operands) && operands[end].Operator == name; end++ {
+			batch = append(batch, operands[end].Data)
+			version.Update(operands[end].Origin)
+		}
+
+		data, err := op.Merge(existing, batch)
+		if err != nil {
+			return Value{}, fmt.Errorf("failed to apply %s operator: %w", name, err)
+		}
+
+		existing = [][]byte{data}
+		start = end
+	}
+
+	var data []byte
+	if len(existing) > 0 {
+		data = existing[0]
+	}
+
+	merged := Value{
+		Version: version,
+		Data:    data,
+	}
+
+	if n := len(operands); n > 0 {
+		merged.Timestamp = operands[n-1].Timestamp
+		merged.Origin = operands[n-1].Origin
+	}
+
+	return merged, nil
+}
+
+// EncodeInt64 encodes the value in the format of the Int64Add operator.
+func EncodeInt64(v int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+
+	return b
+}
+
+// DecodeInt64 decodes the value in the format of the Int64Add operator.
+func DecodeInt64(b []byte) (int64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("%w: expected 8 bytes, got %d", ErrInvalidOperand, len(b))
+	}
+
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// EncodeList encodes the elements in the format of the SetUnion and ListAppend
+// operators: each element is prefixed with its length as an unsigned varint.
+func EncodeList(elems [][]byte) []byte {
+	buf := make([]byte, 0)
+	size := make([]byte, binary.MaxVarintLen64)
+
+	for _, elem := range elems {
+		n := binary.PutUvarint(size, uint64(len(elem)))
+		buf = append(buf, size[:n]...)
+		buf = append(buf, elem...)
+	}
+
+	return buf
+}
+
+// DecodeList decodes the value in the format of the SetUnion and ListAppend operators.
+func DecodeList(b []byte) ([][]byte, error) {
+	elems := make([][]byte, 0)
+
+	for len(b) > 0 {
+		size, n := binary.Uvarint(b)
+		if n <= 0 || uint64(len(b)-n) < size {
+			return nil, fmt.Errorf("%w: malformed list", ErrInvalidOperand)
+		}
+
+		elems = append(elems, b[n:n+int(size)])
+		b = b[n+int(size):]
+	}
+
+	return elems, nil
+}
+
+// Int64Add adds the operands to the value, all of which are encoded with EncodeInt64.
+// A missing value is treated as zero. Concurrent versions are resolved by taking the
+// largest one, which never counts an increment twice, but may lose the increments
+// made concurrently on different replicas.
+type Int64Add struct{}
+
+func (Int64Add) Merge(existing [][]byte, operands [][]byte) ([]byte, error) {
+	var sum int64
+
+	for i, b := range existing {
+		v, err := DecodeInt64(b)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 || v > sum {
+			sum = v
+		}
+	}
+
+	for _, b := range operands {
+		v, err := DecodeInt64(b)
+		if err != nil {
+			return nil, err
+		}
+
+		sum += v
+	}
+
+	return EncodeInt64(sum), nil
+}
+
+// SetUnion adds the elements of the operands to the set. The value and the operands
+// are lists encoded with EncodeList. The resulting set is sorted and has no duplicates.
+// Concurrent versions are resolved by the union of all of them.
+type SetUnion struct{}
+
+func (SetUnion) Merge(existing [][]byte, operands [][]byte) ([]byte, error) {
+	all := make([][]byte, 0)
+
+	for _, lists := range [][][]byte{existing, operands} {
+		for _, b := range lists {
+			elems, err := DecodeList(b)
+			if err != nil {
+				return nil, err
+			}
+
+			all = append(all, elems...)
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return bytes.Compare(all[i], all[j]) < 0
+	})
+
+	unique := make([][]byte, 0, len(all))
+
+	for i := range all {
+		if i == 0 || !bytes.Equal(all[i], all[i-1]) {
+			unique = append(unique, all[i])
+		}
+	}
+
+	return EncodeList(unique), nil
+}
+
+// ListAppend appends the elements of the operands to the list. The value and the
+// operands are lists encoded with EncodeList. Concurrent versions are concatenated
+// in the order they are given.
+type ListAppend struct{}
+
+func (ListAppend) Merge(existing [][]byte, operands [][]byte) ([]byte, error) {
+	all := make([][]byte, 0)
+
+	for _, lists := range [][][]byte{existing, operands} {
+		for _, b := range lists {
+			elems, err := DecodeList(b)
+			if err != nil {
+				return nil, err
+			}
+
+			all = append(all, elems...)
+		}
+	}
+
+	return EncodeList(all), nil
+}
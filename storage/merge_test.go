@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+)
+
+func list(elems ...string) []byte {
+	b := make([][]byte, 0, len(elems))
+	for _, e := range elems {
+		b = append(b, []byte(e))
+	}
+
+	return EncodeList(b)
+}
+
+func TestApplyOperands(t *testing.T) {
+	type test struct {
+		values      []Value
+		operands    []Operand
+		wantData    []byte
+		wantVersion *vclock.Vector
+		wantErr     error
+	}
+
+	tests := map[string]test{
+		"Int64AddNoValue": {
+			operands: []Operand{
+				{Operator: OperatorInt64Add, Data: EncodeInt64(2), Origin: 1},
+				{Operator: OperatorInt64Add, Data: EncodeInt64(3), Origin: 1},
+			},
+			wantData:    EncodeInt64(5),
+			wantVersion: vclock.New(vclock.V{1: 2}),
+		},
+		"Int64AddSiblings": {
+			values: []Value{
+				{Data: EncodeInt64(10), Version: vclock.New(vclock.V{1: 1})},
+				{Data: EncodeInt64(12), Version: vclock.New(vclock.V{2: 1})},
+			},
+			operands: []Operand{
+				{Operator: OperatorInt64Add, Data: EncodeInt64(-2), Origin: 2},
+			},
+			wantData:    EncodeInt64(10),
+			wantVersion: vclock.New(vclock.V{1: 1, 2: 2}),
+		},
+		"Int64AddTombstone": {
+			values: []Value{
+				{Tombstone: true, Version: vclock.New(vclock.V{1: 1})},
+			},
+			operands: []Operand{
+				{Operator: OperatorInt64Add, Data: EncodeInt64(1), Origin: 1},
+			},
+			wantData:    EncodeInt64(1),
+			wantVersion: vclock.New(vclock.V{1: 2}),
+		},
+		"SetUnion": {
+			values: []Value{
+				{Data: list("b", "d"), Version: vclock.New(vclock.V{1: 1})},
+				{Data: list("a"), Version: vclock.New(vclock.V{2: 1})},
+			},
+			operands: []Operand{
+				{Operator: OperatorSetUnion, Data: list("c", "b"), Origin: 1},
+			},
+			wantData:    list("a", "b", "c", "d"),
+			wantVersion: vclock.New(vclock.V{1: 2, 2: 1}),
+		},
+		"ListAppend": {
+			values: []Value{
+				{Data: list("a"), Version: vclock.New(vclock.V{1: 1})},
+			},
+			operands: []Operand{
+				{Operator: OperatorListAppend, Data: list("b"), Origin: 1},
+				{Operator: OperatorListAppend, Data: list("a", "c"), Origin: 2},
+			},
+			wantData:    list("a", "b", "a", "c"),
+			wantVersion: vclock.New(vclock.V{1: 2, 2: 1}),
+		},
+		"MixedOperators": {
+			operands: []Operand{
+				{Operator: OperatorListAppend, Data: list("b"), Origin: 1},
+				{Operator: OperatorSetUnion, Data: list("a"), Origin: 1},
+			},
+			wantData:    list("a", "b"),
+			wantVersion: vclock.New(vclock.V{1: 2}),
+		},
+		"UnknownOperator": {
+			operands: []Operand{
+				{Operator: "unknown", Data: []byte("x"), Origin: 1},
+			},
+			wantErr: ErrUnknownOperator,
+		},
+		"InvalidOperand": {
+			operands: []Operand{
+				{Operator: OperatorInt64Add, Data: []byte("x"), Origin: 1},
+			},
+			wantErr: ErrInvalidOperand,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ApplyOperands(tt.values, tt.operands)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.wantData, got.Data)
+			require.True(t, vclock.IsEqual(tt.wantVersion, got.Version), "got version %s", got.Version)
+		})
+	}
+}
+
+func TestDecodeList(t *testing.T) {
+	elems, err := DecodeList(list("a", "", "bc"))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("a"), []byte(""), []byte("bc")}, elems)
+
+	_, err = DecodeList([]byte{5, 'a'})
+	require.ErrorIs(t, err, ErrInvalidOperand)
+}
+
+func TestRegisterMergeOperator(t *testing.T) {
+	require.Panics(t, func() {
+		RegisterMergeOperator(OperatorInt64Add, Int64Add{})
+	})
+
+	RegisterMergeOperator("test-max", MergeOperatorFunc(func(existing, operands [][]byte) ([]byte, error) {
+		return []byte("max"), nil
+	}))
+
+	op, ok := LookupMergeOperator("test-max")
+	require.True(t, ok)
+
+	data, err := op.Merge(nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("max"), data)
+}
@@ -86,6 +86,20 @@ func (m *MockScanIterator) EXPECT() *MockScanIteratorMockRecorder {
 	return m.recorder
 }
 
+// Err mocks base method.
+func (m *MockScanIterator) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Err indicates an expected call of Err.
+func (mr *MockScanIteratorMockRecorder) Err() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockScanIterator)(nil).Err))
+}
+
 // HasNext mocks base method.
 func (m *MockScanIterator) HasNext() bool {
 	m.ctrl.T.Helper()
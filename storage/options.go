@@ -0,0 +1,58 @@
+package storage
+
+// Options are the settings shared by all storage engines.
+type Options struct {
+	// SiblingLimits restrict the concurrent versions of a key.
+	SiblingLimits SiblingLimits
+	// Keyspaces hold the per-keyspace settings, such as the conflict resolution mode.
+	Keyspaces Keyspaces
+}
+
+// Option modifies the engine options.
+type Option func(o *Options)
+
+// WithSiblingLimits sets the limits on the concurrent versions of a key.
+func WithSiblingLimits(limits SiblingLimits) Option {
+	return func(o *Options) {
+		o.SiblingLimits = limits
+	}
+}
+
+// WithKeyspaces sets the per-keyspace settings.
+func WithKeyspaces(keyspaces Keyspaces) Option {
+	return func(o *Options) {
+		o.Keyspaces = keyspaces
+	}
+}
+
+// NewOptions returns the default options with the given modifications applied.
+func NewOptions(opts ...Option) Options {
+	o := Options{}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// AppendVersion appends a new version of the key to the list of its versions, resolving
+// the concurrent versions according to the keyspace of the key. Either all concurrent
+// versions collapse into a single winner, or they are kept as siblings within the limits.
+func (o *Options) AppendVersion(key string, values []Value, newValue Value) ([]Value, error) {
+	if o.Keyspaces.Lookup(key).LastWriteWins {
+		return AppendLastWriteWins(values, newValue)
+	}
+
+	return o.SiblingLimits.AppendVersion(key, values, newValue)
+}
+
+// RestoreVersion is like AppendVersion, but never rejects the version due to the sibling
+// limits. It is used for the versions copied from other nodes.
+func (o *Options) RestoreVersion(key string, values []Value, newValue Value) ([]Value, error) {
+	if o.Keyspaces.Lookup(key).LastWriteWins {
+		return AppendLastWriteWins(values, newValue)
+	}
+
+	return o.SiblingLimits.RestoreVersion(key, values, newValue)
+}
@@ -8,7 +8,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
-	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/internal/dvv"
 	"github.com/maxpoletaev/kv/storage"
 	"github.com/maxpoletaev/kv/storage/proto"
 )
@@ -39,8 +39,11 @@ func toResponseValues(values []storage.Value) []*proto.VersionedValue {
 
 	for _, value := range values {
 		versionedValues = append(versionedValues, &proto.VersionedValue{
-			Version: vclock.MustEncode(value.Version),
-			Data:    value.Data,
+			Version:   dvv.MustEncode(value.DottedVersion()),
+			Data:      value.Data,
+			Timestamp: uint64(value.Timestamp),
+			Origin:    value.Origin,
+			Tombstone: value.Tombstone,
 		})
 	}
 
@@ -28,8 +28,9 @@ func TestGet(t *testing.T) {
 			setupBackend: func(b *storagemock.MockBackend) {
 				b.EXPECT().Get("key").Return([]storage.Value{
 					{
-						Version: vclock.New(vclock.V{1: 1}),
-						Data:    []byte("value"),
+						Version:   vclock.New(vclock.V{1: 1}),
+						Data:      []byte("value"),
+						Timestamp: 42,
 					},
 				}, nil)
 			},
@@ -39,6 +40,7 @@ func TestGet(t *testing.T) {
 				assert.Equal(t, 1, len(res.Value))
 				assert.Equal(t, []byte("value"), res.Value[0].Data)
 				assert.Equal(t, vclock.New(vclock.V{1: 1}), vclock.MustDecode(res.Value[0].Version))
+				assert.Equal(t, uint64(42), res.Value[0].Timestamp)
 			},
 		},
 		"FoundMultipleValues": {
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/internal/hlc"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+// Handoff receives the keys moved to this node from the nodes that held them before. The
+// keys arrive in batches, and each batch is acknowledged with its last key once all of its
+// values are stored, so that the sender knows it can drop its copy. The versions that are
+// already covered by the stored ones are skipped, so a batch can be safely sent again.
+func (s *StorageService) Handoff(stream proto.StorageService_HandoffServer) error {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		if len(req.Entries) == 0 {
+			continue
+		}
+
+		for _, entry := range req.Entries {
+			if err := s.storeVersions(entry.Key, entry.Values); err != nil {
+				return err
+			}
+		}
+
+		resp := &proto.HandoffResponse{
+			LastKey: req.Entries[len(req.Entries)-1].Key,
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// storeVersions stores the versions of the key received from another node, skipping
+// the versions that are already covered by the stored ones. The versions are never
+// rejected due to the sibling limits, if the engine supports it, since the sender
+// would retry them forever.
+func (s *StorageService) storeVersions(key string, values []*proto.VersionedValue) error {
+	for _, v := range values {
+		value, err := fromRequestValue(v)
+		if err != nil {
+			return status.New(
+				codes.InvalidArgument, fmt.Sprintf("invalid version of %s: %s", key, err),
+			).Err()
+		}
+
+		err = s.restoreVersion(key, value)
+		if err == nil || errors.Is(err, storage.ErrObsoleteWrite) {
+			continue
+		}
+
+		if errors.Is(err, hlc.ErrClockOffset) {
+			return status.New(
+				codes.FailedPrecondition, fmt.Sprintf("timestamp of %s rejected: %s", key, err),
+			).Err()
+		}
+
+		return status.New(
+			codes.Internal, fmt.Sprintf("failed to store %s: %s", key, err),
+		).Err()
+	}
+
+	return nil
+}
+
+func (s *StorageService) restoreVersion(key string, value storage.Value) error {
+	restorer, ok := s.storage.(storage.Restorable)
+	if !ok {
+		return s.putReplica(key, value)
+	}
+
+	if err := s.observe(value); err != nil {
+		return err
+	}
+
+	return restorer.Restore(key, value)
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/grpcutil"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/inmemory"
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+type fakeHandoffStream struct {
+	grpc.ServerStream
+	requests  []*proto.HandoffRequest
+	responses []*proto.HandoffResponse
+}
+
+func (s *fakeHandoffStream) Recv() (*proto.HandoffRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+
+	return req, nil
+}
+
+func (s *fakeHandoffStream) Send(resp *proto.HandoffResponse) error {
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+func TestHandoff(t *testing.T) {
+	engine := inmemory.New()
+	svc := New(engine, 1)
+
+	require.NoError(t, engine.Put("a", storage.Value{
+		Version: vclock.New(vclock.V{2: 2}),
+		Data:    []byte("newer"),
+	}))
+
+	stream := &fakeHandoffStream{
+		requests: []*proto.HandoffRequest{
+			{Entries: []*proto.HandoffEntry{
+				{Key: "a", Values: []*proto.VersionedValue{
+					{Version: vclock.NewEncoded(vclock.V{2: 1}), Data: []byte("older")},
+				}},
+				{Key: "b", Values: []*proto.VersionedValue{
+					{Version: vclock.NewEncoded(vclock.V{2: 1}), Data: []byte("b1")},
+					{Version: vclock.NewEncoded(vclock.V{3: 1}), Data: []byte("b2")},
+				}},
+			}},
+			{Entries: []*proto.HandoffEntry{
+				{Key: "c", Values: []*proto.VersionedValue{
+					{Version: vclock.NewEncoded(vclock.V{2: 1}), Tombstone: true},
+				}},
+			}},
+		},
+	}
+
+	require.NoError(t, svc.Handoff(stream))
+
+	// Each batch is acknowledged with its last key.
+	require.Len(t, stream.responses, 2)
+	assert.Equal(t, "b", stream.responses[0].LastKey)
+	assert.Equal(t, "c", stream.responses[1].LastKey)
+
+	// The older version does not overwrite the newer one.
+	values, err := engine.Get("a")
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+	assert.Equal(t, "newer", string(values[0].Data))
+
+	values, err = engine.Get("b")
+	require.NoError(t, err)
+	assert.Len(t, values, 2)
+
+	values, err = engine.Get("c")
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+	assert.True(t, values[0].Tombstone)
+}
+
+func TestHandoff_InvalidVersion(t *testing.T) {
+	svc := New(inmemory.New(), 1)
+
+	stream := &fakeHandoffStream{
+		requests: []*proto.HandoffRequest{
+			{Entries: []*proto.HandoffEntry{
+				{Key: "a", Values: []*proto.VersionedValue{{Version: "invalid"}}},
+			}},
+		},
+	}
+
+	err := svc.Handoff(stream)
+	assert.Equal(t, codes.InvalidArgument, grpcutil.ErrorCode(err))
+	assert.Empty(t, stream.responses)
+}
+
+func TestHandoff_SiblingLimits(t *testing.T) {
+	engine := inmemory.New(storage.WithSiblingLimits(storage.SiblingLimits{MaxSiblings: 1}))
+	svc := New(engine, 1)
+
+	require.NoError(t, engine.Put("a", storage.Value{
+		Version:   vclock.New(vclock.V{2: 1}),
+		Data:      []byte("local"),
+		Timestamp: 10,
+	}))
+
+	// The concurrent version would be rejected by the limits if it were a client write.
+	stream := &fakeHandoffStream{
+		requests: []*proto.HandoffRequest{
+			{Entries: []*proto.HandoffEntry{
+				{Key: "a", Values: []*proto.VersionedValue{
+					{Version: vclock.NewEncoded(vclock.V{3: 1}), Data: []byte("remote"), Timestamp: 20},
+				}},
+			}},
+		},
+	}
+
+	require.NoError(t, svc.Handoff(stream))
+	require.Len(t, stream.responses, 1)
+
+	values, err := engine.Get("a")
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+	assert.Equal(t, []byte("remote"), values[0].Data)
+	assert.Equal(t, vclock.New(vclock.V{2: 1, 3: 1}), values[0].Version)
+}
+
+func TestHandoff_DottedVersions(t *testing.T) {
+	engine := inmemory.New()
+	svc := New(engine, 1)
+
+	version := dvv.New(dvv.Dot{Node: 2, Counter: 1}, vclock.New())
+
+	stream := &fakeHandoffStream{
+		requests: []*proto.HandoffRequest{
+			{Entries: []*proto.HandoffEntry{
+				{Key: "a", Values: []*proto.VersionedValue{
+					{Version: dvv.MustEncode(version), Data: []byte("value"), Timestamp: 10, Origin: 2},
+				}},
+			}},
+		},
+	}
+
+	require.NoError(t, svc.Handoff(stream))
+
+	// The values are stored exactly as they were on the previous holder.
+	values, err := engine.Get("a")
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+	assert.Equal(t, version.Dot, values[0].Dot)
+	assert.Equal(t, uint32(2), values[0].Origin)
+	assert.Equal(t, uint64(10), uint64(values[0].Timestamp))
+}
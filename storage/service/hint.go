@@ -0,0 +1,43 @@
+package service
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/hints"
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+// putHint keeps the write meant for another replica in the hint log. The value already has
+// the version and the timestamp assigned by the primary, so it is stored as is.
+func (s *StorageService) putHint(req *proto.PutRequest) (*proto.PutResponse, error) {
+	if s.hints == nil {
+		return nil, status.New(codes.FailedPrecondition, "hinted handoff is disabled").Err()
+	}
+
+	if _, err := dvv.Decode(req.Value.Version); err != nil {
+		return nil, status.New(
+			codes.InvalidArgument, fmt.Sprintf("invalid version: %s", err),
+		).Err()
+	}
+
+	err := s.hints.Append(membership.NodeID(req.HintFor), hints.Hint{
+		Key:       req.Key,
+		Value:     req.Value,
+		CreatedAt: s.now(),
+	})
+	if err != nil {
+		return nil, status.New(
+			codes.Internal, fmt.Sprintf("failed to store hint: %s", err),
+		).Err()
+	}
+
+	return &proto.PutResponse{
+		Version:   req.Value.Version,
+		Timestamp: req.Value.Timestamp,
+	}, nil
+}
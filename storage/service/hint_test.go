@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/maxpoletaev/kv/hints"
+	"github.com/maxpoletaev/kv/internal/grpcutil"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/membership"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/inmemory"
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+func TestPut_Hint(t *testing.T) {
+	hintLog, err := hints.Open(t.TempDir(), nil)
+	require.NoError(t, err)
+
+	defer hintLog.Close()
+
+	engine := inmemory.New()
+	svc := New(engine, 1, WithHints(hintLog))
+
+	value := &proto.VersionedValue{
+		Version:   vclock.NewEncoded(vclock.V{2: 1}),
+		Data:      []byte("value"),
+		Timestamp: 10,
+		Origin:    2,
+	}
+
+	res, err := svc.Put(context.Background(), &proto.PutRequest{
+		Key:     "key",
+		Value:   value,
+		HintFor: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, value.Version, res.Version)
+	assert.Equal(t, value.Timestamp, res.Timestamp)
+
+	// The value is kept for node 3 and does not get into the local storage.
+	_, err = engine.Get("key")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	hinted := make([]hints.Hint, 0)
+	_, err = hintLog.Replay(3, func(h hints.Hint) error {
+		hinted = append(hinted, h)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, hinted, 1)
+	assert.Equal(t, "key", hinted[0].Key)
+	assert.Equal(t, value.Data, hinted[0].Value.Data)
+	assert.Equal(t, []membership.NodeID{}, hintLog.Owners())
+}
+
+func TestPut_HintDisabled(t *testing.T) {
+	svc := New(inmemory.New(), 1)
+
+	_, err := svc.Put(context.Background(), &proto.PutRequest{
+		Key: "key",
+		Value: &proto.VersionedValue{
+			Version: vclock.NewEncoded(vclock.V{2: 1}),
+			Data:    []byte("value"),
+		},
+		HintFor: 3,
+	})
+
+	assert.Equal(t, codes.FailedPrecondition, grpcutil.ErrorCode(err))
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+// Merge applies the merge operand to the key, and returns the resulting value, so
+// that the coordinator can replicate it instead of the operand.
+func (s *StorageService) Merge(ctx context.Context, req *proto.MergeRequest) (*proto.MergeResponse, error) {
+	merger, ok := s.storage.(storage.Mergeable)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "storage engine does not support merge")
+	}
+
+	// The origin is the node that has accepted the merge from the client. When it is
+	// not set, the merge is considered to be accepted by this node.
+	origin := req.Origin
+	if origin == 0 {
+		origin = s.nodeID
+	}
+
+	// The merged value is stamped like any other write, so that it can be
+	// ordered against the concurrent writes in the last-write-wins keyspaces.
+	merged, err := merger.Merge(req.Key, storage.Operand{
+		Operator:  req.Operator,
+		Data:      req.Operand,
+		Origin:    origin,
+		Timestamp: s.clock.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUnknownOperator) {
+			return nil, status.New(
+				codes.InvalidArgument, fmt.Sprintf("unknown operator: %s", req.Operator),
+			).Err()
+		}
+
+		if errors.Is(err, storage.ErrInvalidOperand) {
+			return nil, status.New(
+				codes.InvalidArgument, fmt.Sprintf("invalid operand: %s", err),
+			).Err()
+		}
+
+		return nil, status.New(
+			codes.Internal, fmt.Sprintf("storage merge failed: %s", err),
+		).Err()
+	}
+
+	return &proto.MergeResponse{
+		Value: toResponseValues([]storage.Value{merged})[0],
+	}, nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/grpcutil"
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/inmemory"
+	"github.com/maxpoletaev/kv/storage/mock"
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+func TestMerge(t *testing.T) {
+	backend := inmemory.New()
+	service := New(backend, 100)
+
+	requests := []*proto.MergeRequest{
+		{Key: "key", Operator: storage.OperatorInt64Add, Operand: storage.EncodeInt64(2)},
+		{Key: "key", Operator: storage.OperatorInt64Add, Operand: storage.EncodeInt64(3), Origin: 200},
+	}
+
+	var resp *proto.MergeResponse
+
+	for _, req := range requests {
+		var err error
+
+		resp, err = service.Merge(context.Background(), req)
+		require.NoError(t, err)
+	}
+
+	values, err := backend.Get("key")
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+
+	assert.Equal(t, storage.EncodeInt64(5), values[0].Data)
+	assert.Equal(t, vclock.New(vclock.V{100: 1, 200: 1}), values[0].Version)
+
+	// The value is stamped as any other write, so it takes part in the last-write-wins.
+	assert.False(t, values[0].Timestamp.IsZero())
+	assert.Equal(t, uint32(200), values[0].Origin)
+
+	// The response has the resulting value, to be replicated as is.
+	assert.Equal(t, storage.EncodeInt64(5), resp.Value.Data)
+	assert.Equal(t, dvv.MustEncode(values[0].DottedVersion()), resp.Value.Version)
+	assert.Equal(t, uint64(values[0].Timestamp), resp.Value.Timestamp)
+	assert.Equal(t, values[0].Origin, resp.Value.Origin)
+}
+
+func TestMerge_UnknownOperator(t *testing.T) {
+	service := New(inmemory.New(), 100)
+
+	_, err := service.Merge(context.Background(), &proto.MergeRequest{
+		Key:      "key",
+		Operator: "unknown",
+	})
+
+	assert.Equal(t, codes.InvalidArgument, grpcutil.ErrorCode(err))
+}
+
+func TestMerge_InvalidOperand(t *testing.T) {
+	service := New(inmemory.New(), 100)
+
+	_, err := service.Merge(context.Background(), &proto.MergeRequest{
+		Key:      "key",
+		Operator: storage.OperatorInt64Add,
+		Operand:  []byte("x"),
+	})
+
+	assert.Equal(t, codes.InvalidArgument, grpcutil.ErrorCode(err))
+}
+
+func TestMerge_Unimplemented(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	service := New(mock.NewMockBackend(ctrl), 100)
+
+	_, err := service.Merge(context.Background(), &proto.MergeRequest{
+		Key:      "key",
+		Operator: storage.OperatorInt64Add,
+	})
+
+	assert.Equal(t, codes.Unimplemented, grpcutil.ErrorCode(err))
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+// MultiGet reads several keys at once. The keys are read the same way as by Get, and
+// the error of a key is returned in its result, so that it does not fail the others.
+func (s *StorageService) MultiGet(ctx context.Context, req *proto.MultiGetRequest) (*proto.MultiGetResponse, error) {
+	results := make([]*proto.MultiGetResult, 0, len(req.Keys))
+
+	for _, key := range req.Keys {
+		resp, err := s.Get(ctx, &proto.GetRequest{Key: key})
+		if err != nil {
+			results = append(results, &proto.MultiGetResult{Error: toKeyError(err)})
+			continue
+		}
+
+		results = append(results, &proto.MultiGetResult{Values: resp.Value})
+	}
+
+	return &proto.MultiGetResponse{
+		Results: results,
+	}, nil
+}
+
+// MultiPut writes several keys at once. Each put is applied the same way as by Put,
+// including the primary writes and the hints, and the error of a put is returned
+// in its result, so that it does not fail the others.
+func (s *StorageService) MultiPut(ctx context.Context, req *proto.MultiPutRequest) (*proto.MultiPutResponse, error) {
+	results := make([]*proto.MultiPutResult, 0, len(req.Puts))
+
+	for _, put := range req.Puts {
+		if put.GetValue() == nil {
+			results = append(results, &proto.MultiPutResult{
+				Error: &proto.KeyError{Code: uint32(codes.InvalidArgument), Message: "value is required"},
+			})
+
+			continue
+		}
+
+		resp, err := s.Put(ctx, put)
+		if err != nil {
+			results = append(results, &proto.MultiPutResult{Error: toKeyError(err)})
+			continue
+		}
+
+		results = append(results, &proto.MultiPutResult{
+			Version:   resp.Version,
+			Timestamp: resp.Timestamp,
+		})
+	}
+
+	return &proto.MultiPutResponse{
+		Results: results,
+	}, nil
+}
+
+func toKeyError(err error) *proto.KeyError {
+	st := status.Convert(err)
+
+	return &proto.KeyError{
+		Code:    uint32(st.Code()),
+		Message: st.Message(),
+	}
+}
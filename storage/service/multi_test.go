@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/storage/inmemory"
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+func TestMultiPutAndGet(t *testing.T) {
+	svc := New(inmemory.New(), 1)
+	ctx := context.Background()
+
+	putResp, err := svc.MultiPut(ctx, &proto.MultiPutRequest{
+		Puts: []*proto.PutRequest{
+			{
+				Key:     "key1",
+				Primary: true,
+				Value:   &proto.VersionedValue{Version: vclock.NewEncoded(), Data: []byte("value1")},
+			},
+			{
+				Key:   "key2",
+				Value: &proto.VersionedValue{Version: "invalid", Data: []byte("value2")},
+			},
+			{
+				Key: "key3",
+			},
+		},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, putResp.Results, 3)
+
+	assert.Nil(t, putResp.Results[0].Error)
+	assert.NotEmpty(t, putResp.Results[0].Version)
+	assert.NotZero(t, putResp.Results[0].Timestamp)
+	assert.Equal(t, uint32(codes.InvalidArgument), putResp.Results[1].Error.Code)
+	assert.Equal(t, uint32(codes.InvalidArgument), putResp.Results[2].Error.Code)
+
+	getResp, err := svc.MultiGet(ctx, &proto.MultiGetRequest{
+		Keys: []string{"key1", "key2"},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, getResp.Results, 2)
+
+	require.Len(t, getResp.Results[0].Values, 1)
+	assert.Equal(t, []byte("value1"), getResp.Results[0].Values[0].Data)
+	assert.Nil(t, getResp.Results[0].Error)
+	assert.Empty(t, getResp.Results[1].Values)
+	assert.Nil(t, getResp.Results[1].Error)
+}
@@ -8,40 +8,139 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/hlc"
 	"github.com/maxpoletaev/kv/internal/vclock"
 	"github.com/maxpoletaev/kv/storage"
 	"github.com/maxpoletaev/kv/storage/proto"
 )
 
 func (s *StorageService) Put(ctx context.Context, req *proto.PutRequest) (*proto.PutResponse, error) {
-	version, err := vclock.Decode(req.Value.Version)
+	if req.HintFor != 0 {
+		return s.putHint(req)
+	}
+
+	value, err := fromRequestValue(req.Value)
 	if err != nil {
 		return nil, status.New(
 			codes.InvalidArgument, fmt.Sprintf("invalid version: %s", err),
 		).Err()
 	}
 
+	// The primary node assigns the version and the timestamp, which
+	// are then passed to the replicas along with the value.
 	if req.Primary {
-		version.Update(s.nodeID)
+		err = s.putPrimary(req.Key, &value)
+	} else {
+		err = s.putReplica(req.Key, value)
 	}
 
-	value := storage.Value{
-		Data:    req.Value.Data,
-		Version: version,
-	}
-
-	err = s.storage.Put(req.Key, value)
 	if err != nil {
 		if errors.Is(err, storage.ErrObsoleteWrite) {
 			return nil, status.New(codes.AlreadyExists, "obsolete write").Err()
 		}
 
+		if errors.Is(err, hlc.ErrClockOffset) {
+			return nil, status.New(
+				codes.FailedPrecondition, fmt.Sprintf("timestamp rejected: %s", err),
+			).Err()
+		}
+
+		if errors.Is(err, storage.ErrSiblingLimit) {
+			return nil, status.New(
+				codes.ResourceExhausted, fmt.Sprintf("too many concurrent versions, resolve the conflict first: %s", err),
+			).Err()
+		}
+
 		return nil, status.New(
 			codes.Internal, fmt.Sprintf("storage put failed: %s", err),
 		).Err()
 	}
 
 	return &proto.PutResponse{
-		Version: vclock.MustEncode(version),
+		Version:   dvv.MustEncode(value.DottedVersion()),
+		Timestamp: uint64(value.Timestamp),
 	}, nil
 }
+
+func fromRequestValue(v *proto.VersionedValue) (storage.Value, error) {
+	version, err := dvv.Decode(v.Version)
+	if err != nil {
+		return storage.Value{}, err
+	}
+
+	return storage.Value{
+		Data:      v.Data,
+		Version:   version.Context,
+		Dot:       version.Dot,
+		Tombstone: v.Tombstone,
+		Timestamp: hlc.Timestamp(v.Timestamp),
+		Origin:    v.Origin,
+	}, nil
+}
+
+// putPrimary assigns the version, the timestamp and the origin to the value written through
+// this node, and writes it to the storage. The version received from the client is either
+// incremented, or used as the causal context of the new dot, if the dotted versions are enabled.
+func (s *StorageService) putPrimary(key string, value *storage.Value) error {
+	now := s.now()
+	value.Timestamp = s.clock.Now()
+	value.Origin = s.nodeID
+	clock := value.Clock()
+
+	if !s.keyspaces.Lookup(key).DottedVersions {
+		clock.UpdateAt(s.nodeID, now)
+
+		// Only the primary prunes the version, the replicas
+		// must store exactly the same version as the primary.
+		if s.pruning != nil {
+			clock.Prune(*s.pruning, now)
+		}
+
+		value.Version, value.Dot = clock, dvv.Dot{}
+
+		return s.storage.Put(key, *value)
+	}
+
+	// The dot must not be assigned to two different writes, so its counter is derived from
+	// the versions already stored, and the writes of the same key through this node are
+	// serialized until the new version is stored.
+	s.locks.Lock(key)
+	defer s.locks.Unlock(key)
+
+	values, err := s.storage.Get(key)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("failed to read stored versions: %w", err)
+	}
+
+	seen := make([]*vclock.Vector, 0, len(values)+1)
+	seen = append(seen, clock)
+
+	for _, val := range values {
+		seen = append(seen, val.Clock())
+	}
+
+	value.Version, value.Dot = clock, dvv.NextDot(s.nodeID, seen...)
+
+	return s.storage.Put(key, *value)
+}
+
+// putReplica writes the value received from the primary node. The clock of this node is
+// advanced past the timestamp of the value, so that the writes coordinated by this node
+// later get greater timestamps than the writes it has already seen.
+func (s *StorageService) putReplica(key string, value storage.Value) error {
+	if err := s.observe(value); err != nil {
+		return err
+	}
+
+	return s.storage.Put(key, value)
+}
+
+// observe advances the clock of this node past the timestamp of the value written elsewhere.
+func (s *StorageService) observe(value storage.Value) error {
+	if value.Timestamp.IsZero() {
+		return nil
+	}
+
+	return s.clock.Update(value.Timestamp)
+}
@@ -3,13 +3,16 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 
+	"github.com/maxpoletaev/kv/internal/dvv"
 	"github.com/maxpoletaev/kv/internal/grpcutil"
+	"github.com/maxpoletaev/kv/internal/hlc"
 	"github.com/maxpoletaev/kv/internal/vclock"
 	"github.com/maxpoletaev/kv/storage"
 	"github.com/maxpoletaev/kv/storage/mock"
@@ -17,6 +20,12 @@ import (
 )
 
 func TestPut(t *testing.T) {
+	now := time.Unix(1, 0)
+	timestamp := hlc.NewTimestamp(now, 0)
+
+	primaryVersion := vclock.New(vclock.V{100: 1, 200: 1})
+	primaryVersion.UpdateAt(100, now)
+
 	type test struct {
 		setupBackend   func(backend *mock.MockBackend)
 		request        *proto.PutRequest
@@ -27,8 +36,10 @@ func TestPut(t *testing.T) {
 		"OkPrimary": {
 			setupBackend: func(b *mock.MockBackend) {
 				b.EXPECT().Put("key", storage.Value{
-					Version: vclock.New(vclock.V{100: 2, 200: 1}),
-					Data:    []byte("value"),
+					Version:   primaryVersion,
+					Data:      []byte("value"),
+					Timestamp: timestamp,
+					Origin:    100,
 				}).Return(nil)
 			},
 			request: &proto.PutRequest{
@@ -42,23 +53,26 @@ func TestPut(t *testing.T) {
 			assertResponse: func(t *testing.T, res *proto.PutResponse, err error) {
 				require.NoError(t, err)
 
-				version := vclock.New(vclock.V{100: 2, 200: 1})
-				assert.Equal(t, version, vclock.MustDecode(res.Version))
+				assert.Equal(t, primaryVersion, vclock.MustDecode(res.Version))
+				assert.Equal(t, "{100=2, 200=1}", primaryVersion.String())
+				assert.Equal(t, uint64(timestamp), res.Timestamp)
 			},
 		},
 		"OkNonPrimary": {
 			setupBackend: func(b *mock.MockBackend) {
 				b.EXPECT().Put("key", storage.Value{
-					Version: vclock.New(vclock.V{100: 1, 200: 1}),
-					Data:    []byte("value"),
+					Version:   vclock.New(vclock.V{100: 1, 200: 1}),
+					Data:      []byte("value"),
+					Timestamp: 500,
 				}).Return(nil)
 			},
 			request: &proto.PutRequest{
 				Key:     "key",
 				Primary: false,
 				Value: &proto.VersionedValue{
-					Version: vclock.NewEncoded(vclock.V{100: 1, 200: 1}),
-					Data:    []byte("value"),
+					Version:   vclock.NewEncoded(vclock.V{100: 1, 200: 1}),
+					Data:      []byte("value"),
+					Timestamp: 500,
 				},
 			},
 			assertResponse: func(t *testing.T, res *proto.PutResponse, err error) {
@@ -88,6 +102,23 @@ func TestPut(t *testing.T) {
 				assert.Equal(t, codes.AlreadyExists, code)
 			},
 		},
+		"FailsSiblingLimit": {
+			setupBackend: func(b *mock.MockBackend) {
+				b.EXPECT().Put("key", gomock.Any()).Return(storage.ErrSiblingLimit)
+			},
+			request: &proto.PutRequest{
+				Key: "key",
+				Value: &proto.VersionedValue{
+					Version: vclock.NewEncoded(),
+					Data:    []byte{},
+				},
+			},
+			assertResponse: func(t *testing.T, res *proto.PutResponse, err error) {
+				require.Error(t, err)
+				code := grpcutil.ErrorCode(err)
+				assert.Equal(t, codes.ResourceExhausted, code)
+			},
+		},
 		"FailsRandomError": {
 			setupBackend: func(b *mock.MockBackend) {
 				b.EXPECT().Put("key", storage.Value{
@@ -115,6 +146,7 @@ func TestPut(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			backend := mock.NewMockBackend(ctrl)
 			service := New(backend, 100)
+			service.now = func() time.Time { return now }
 			ctx := context.Background()
 
 			tt.setupBackend(backend)
@@ -125,3 +157,161 @@ func TestPut(t *testing.T) {
 		})
 	}
 }
+
+func TestPut_PrunesVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(100000, 0)
+
+	version := vclock.New()
+	version.UpdateAt(1, now.Add(-48*time.Hour))
+	version.UpdateAt(2, now.Add(-time.Minute))
+
+	backend := mock.NewMockBackend(ctrl)
+	backend.EXPECT().Put("key", gomock.Any()).DoAndReturn(func(key string, value storage.Value) error {
+		assert.Equal(t, "{2=1, 100=1}", value.Version.String())
+		return nil
+	})
+
+	conf := vclock.DefaultPruneConfig()
+	conf.MinEntries = 1
+
+	service := New(backend, 100, WithVersionPruning(conf))
+	service.now = func() time.Time { return now }
+
+	res, err := service.Put(context.Background(), &proto.PutRequest{
+		Key:     "key",
+		Primary: true,
+		Value: &proto.VersionedValue{
+			Version: vclock.MustEncode(version),
+			Data:    []byte("value"),
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "{2=1, 100=1}", vclock.MustDecode(res.Version).String())
+}
+
+func TestPut_DottedVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.UnixMilli(1000)
+	backend := mock.NewMockBackend(ctrl)
+
+	// The counter of the new dot is past the counters of this node in the stored values.
+	backend.EXPECT().Get("carts/1").Return([]storage.Value{
+		{Version: vclock.New(), Dot: dvv.Dot{Node: 100, Counter: 1}},
+		{Version: vclock.New(vclock.V{100: 2}), Dot: dvv.Dot{Node: 200, Counter: 1}},
+	}, nil)
+
+	// The version sent by the client becomes the context of the write, unchanged.
+	backend.EXPECT().Put("carts/1", storage.Value{
+		Version:   vclock.New(vclock.V{200: 1}),
+		Dot:       dvv.Dot{Node: 100, Counter: 3},
+		Data:      []byte("value"),
+		Timestamp: hlc.NewTimestamp(now, 0),
+		Origin:    100,
+	}).Return(nil)
+
+	service := New(backend, 100, WithKeyspaces(storage.Keyspaces{{Prefix: "carts/", DottedVersions: true}}))
+	service.now = func() time.Time { return now }
+
+	res, err := service.Put(context.Background(), &proto.PutRequest{
+		Key:     "carts/1",
+		Primary: true,
+		Value: &proto.VersionedValue{
+			Version: vclock.NewEncoded(vclock.V{200: 1}),
+			Data:    []byte("value"),
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, dvv.NewEncoded(dvv.Dot{Node: 100, Counter: 3}, vclock.V{200: 1}), res.Version)
+}
+
+func TestPut_DottedVersionReplica(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	backend := mock.NewMockBackend(ctrl)
+
+	// The replicas store the dot assigned by the primary as is.
+	backend.EXPECT().Put("carts/1", storage.Value{
+		Version:   vclock.New(vclock.V{200: 1}),
+		Dot:       dvv.Dot{Node: 100, Counter: 3},
+		Data:      []byte("value"),
+		Timestamp: 1000,
+	}).Return(nil)
+
+	service := New(backend, 200)
+
+	_, err := service.Put(context.Background(), &proto.PutRequest{
+		Key: "carts/1",
+		Value: &proto.VersionedValue{
+			Version:   dvv.NewEncoded(dvv.Dot{Node: 100, Counter: 3}, vclock.V{200: 1}),
+			Data:      []byte("value"),
+			Timestamp: 1000,
+		},
+	})
+
+	require.NoError(t, err)
+}
+
+func TestPut_ReplicaAdvancesClock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.UnixMilli(1000)
+	remote := hlc.NewTimestamp(now.Add(time.Second), 5)
+
+	backend := mock.NewMockBackend(ctrl)
+	backend.EXPECT().Put("key", gomock.Any()).Return(nil).Times(2)
+
+	service := New(backend, 100)
+	service.now = func() time.Time { return now }
+
+	// The value written through another node has a timestamp ahead of the local clock.
+	_, err := service.Put(context.Background(), &proto.PutRequest{
+		Key: "key",
+		Value: &proto.VersionedValue{
+			Version:   vclock.NewEncoded(vclock.V{200: 1}),
+			Timestamp: uint64(remote),
+		},
+	})
+	require.NoError(t, err)
+
+	// The next write through this node must get a greater timestamp nevertheless.
+	res, err := service.Put(context.Background(), &proto.PutRequest{
+		Key:     "key",
+		Primary: true,
+		Value: &proto.VersionedValue{
+			Version: vclock.NewEncoded(vclock.V{200: 1}),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(remote+1), res.Timestamp)
+}
+
+func TestPut_FailsClockOffset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.UnixMilli(1000)
+	backend := mock.NewMockBackend(ctrl)
+
+	service := New(backend, 100, WithMaxClockOffset(time.Second))
+	service.now = func() time.Time { return now }
+
+	_, err := service.Put(context.Background(), &proto.PutRequest{
+		Key: "key",
+		Value: &proto.VersionedValue{
+			Version:   vclock.NewEncoded(vclock.V{200: 1}),
+			Timestamp: uint64(hlc.NewTimestamp(now.Add(time.Minute), 0)),
+		},
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, grpcutil.ErrorCode(err))
+}
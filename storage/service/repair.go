@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+// Repair stores the versions of the key sent by the coordinator of a read that found this
+// replica stale. All surviving siblings are sent at once with their original versions, so
+// that the replica ends up with the same siblings as the others. The versions that are
+// already covered by the stored ones are skipped, so a repair can be safely repeated.
+func (s *StorageService) Repair(ctx context.Context, req *proto.RepairRequest) (*proto.RepairResponse, error) {
+	if err := s.storeVersions(req.Key, req.Values); err != nil {
+		return nil, err
+	}
+
+	return &proto.RepairResponse{}, nil
+}
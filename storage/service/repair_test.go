@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxpoletaev/kv/internal/vclock"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/inmemory"
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+func TestRepair_MissingSibling(t *testing.T) {
+	engine := inmemory.New()
+	svc := New(engine, 1)
+
+	err := engine.Put("key", storage.Value{
+		Version: vclock.New(vclock.V{2: 1}),
+		Data:    []byte("first"),
+	})
+	require.NoError(t, err)
+
+	req := &proto.RepairRequest{
+		Key: "key",
+		Values: []*proto.VersionedValue{
+			{Version: vclock.NewEncoded(vclock.V{2: 1}), Data: []byte("first")},
+			{Version: vclock.NewEncoded(vclock.V{3: 1}), Data: []byte("second")},
+		},
+	}
+
+	// Repeating the repair does not change the result.
+	for i := 0; i < 2; i++ {
+		_, err = svc.Repair(context.Background(), req)
+		require.NoError(t, err)
+	}
+
+	values, err := engine.Get("key")
+	require.NoError(t, err)
+	require.Len(t, values, 2)
+
+	data := []string{string(values[0].Data), string(values[1].Data)}
+	assert.ElementsMatch(t, []string{"first", "second"}, data)
+}
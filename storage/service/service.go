@@ -1,6 +1,12 @@
 package service
 
 import (
+	"time"
+
+	"github.com/maxpoletaev/kv/hints"
+	"github.com/maxpoletaev/kv/internal/hlc"
+	"github.com/maxpoletaev/kv/internal/lockmap"
+	"github.com/maxpoletaev/kv/internal/vclock"
 	"github.com/maxpoletaev/kv/storage"
 	"github.com/maxpoletaev/kv/storage/proto"
 )
@@ -8,13 +14,67 @@ import (
 type StorageService struct {
 	proto.UnimplementedStorageServiceServer
 
-	storage storage.Engine
-	nodeID  uint32
+	storage   storage.Engine
+	nodeID    uint32
+	now       func() time.Time
+	clock     *hlc.Clock
+	maxOffset time.Duration
+	pruning   *vclock.PruneConfig
+	keyspaces storage.Keyspaces
+	locks     *lockmap.Map[string]
+	hints     *hints.Log
+}
+
+type serviceOption func(s *StorageService)
+
+// WithKeyspaces sets the per-keyspace settings of the keys written through this node.
+func WithKeyspaces(keyspaces storage.Keyspaces) serviceOption {
+	return func(s *StorageService) {
+		s.keyspaces = keyspaces
+	}
+}
+
+// WithMaxClockOffset sets how far ahead of the local clock the timestamps of the values
+// received from the other nodes may be. The values with the timestamps further ahead are
+// rejected, so that a node with a broken clock cannot push the clocks of the other nodes
+// into the future. Zero offset disables the check.
+func WithMaxClockOffset(offset time.Duration) serviceOption {
+	return func(s *StorageService) {
+		s.maxOffset = offset
+	}
+}
+
+// WithVersionPruning enables pruning of the versions written through this node. The dotted
+// versions are not pruned, since the update times of their entries are not known.
+func WithVersionPruning(conf vclock.PruneConfig) serviceOption {
+	return func(s *StorageService) {
+		s.pruning = &conf
+	}
+}
+
+// WithHints enables accepting the writes meant for the unavailable replicas. Such writes are
+// kept in the hint log rather than in the storage, until they are replayed to the replicas.
+func WithHints(hintLog *hints.Log) serviceOption {
+	return func(s *StorageService) {
+		s.hints = hintLog
+	}
 }
 
-func New(s storage.Engine, nodeID uint32) *StorageService {
-	return &StorageService{
+func New(s storage.Engine, nodeID uint32, opts ...serviceOption) *StorageService {
+	svc := &StorageService{
 		storage: s,
 		nodeID:  nodeID,
+		now:     time.Now,
+		locks:   lockmap.New[string](),
 	}
+
+	for _, opt := range opts {
+		opt(svc)
+	}
+
+	svc.clock = hlc.NewClock(func() time.Time {
+		return svc.now()
+	}, svc.maxOffset)
+
+	return svc
 }
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+// Stats returns the internal statistics of the storage engine. The engines that do not
+// implement storage.StatsProvider respond with codes.Unimplemented.
+func (s *StorageService) Stats(ctx context.Context, req *proto.StatsRequest) (*proto.StatsResponse, error) {
+	provider, ok := s.storage.(storage.StatsProvider)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "storage engine does not provide stats")
+	}
+
+	return toStatsResponse(provider.Stats()), nil
+}
+
+func toStatsResponse(stats storage.Stats) *proto.StatsResponse {
+	levels := make([]*proto.LevelStats, 0, len(stats.Levels))
+
+	for _, level := range stats.Levels {
+		levels = append(levels, &proto.LevelStats{
+			Level:     int32(level.Level),
+			NumTables: int64(level.NumTables),
+			Bytes:     level.Bytes,
+		})
+	}
+
+	return &proto.StatsResponse{
+		Levels:                 levels,
+		MemtableBytes:          stats.MemtableBytes,
+		MemtableEntries:        int64(stats.MemtableEntries),
+		FlushQueueLength:       int64(stats.FlushQueueLength),
+		FlushQueueBytes:        stats.FlushQueueBytes,
+		Gets:                   stats.Gets,
+		TablesRead:             stats.TablesRead,
+		TablesPerGet:           stats.TablesPerGet(),
+		BloomFalsePositives:    stats.BloomFalsePositives,
+		PendingCompactionBytes: stats.PendingCompactionBytes,
+		UserBytesWritten:       stats.UserBytesWritten,
+		WalBytesWritten:        stats.WALBytesWritten,
+		FlushBytesWritten:      stats.FlushBytesWritten,
+		WriteAmplification:     stats.WriteAmplification,
+		IoRateLimit:            stats.IORateLimit,
+		IoThrottled:            stats.IOThrottled,
+		IoThrottledMs:          stats.IOThrottledTime.Milliseconds(),
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/maxpoletaev/kv/internal/grpcutil"
+	"github.com/maxpoletaev/kv/storage"
+	"github.com/maxpoletaev/kv/storage/mock"
+	"github.com/maxpoletaev/kv/storage/proto"
+)
+
+type engineWithStats struct {
+	storage.Engine
+	stats storage.Stats
+}
+
+func (e *engineWithStats) Stats() storage.Stats {
+	return e.stats
+}
+
+func TestStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	backend := &engineWithStats{
+		Engine: mock.NewMockBackend(ctrl),
+		stats: storage.Stats{
+			Levels:                 []storage.LevelStats{{Level: 0, NumTables: 2, Bytes: 100}},
+			Gets:                   4,
+			TablesRead:             2,
+			BloomFalsePositives:    1,
+			PendingCompactionBytes: 100,
+			WALBytesWritten:        120,
+			IORateLimit:            1000,
+			IOThrottled:            3,
+			IOThrottledTime:        2 * time.Second,
+		},
+	}
+
+	service := New(backend, 0)
+	res, err := service.Stats(context.Background(), &proto.StatsRequest{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []*proto.LevelStats{{Level: 0, NumTables: 2, Bytes: 100}}, res.Levels)
+	assert.Equal(t, int64(4), res.Gets)
+	assert.Equal(t, 0.5, res.TablesPerGet)
+	assert.Equal(t, int64(1), res.BloomFalsePositives)
+	assert.Equal(t, int64(100), res.PendingCompactionBytes)
+	assert.Equal(t, int64(120), res.WalBytesWritten)
+	assert.Equal(t, int64(1000), res.IoRateLimit)
+	assert.Equal(t, int64(3), res.IoThrottled)
+	assert.Equal(t, int64(2000), res.IoThrottledMs)
+}
+
+func TestStats_Unimplemented(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	backend := mock.NewMockBackend(ctrl)
+
+	service := New(backend, 0)
+	_, err := service.Stats(context.Background(), &proto.StatsRequest{})
+	require.Error(t, err)
+
+	assert.Equal(t, codes.Unimplemented, grpcutil.ErrorCode(err))
+}
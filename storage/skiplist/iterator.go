@@ -11,6 +11,10 @@ type Iterator[K any, V any] struct {
 func newIterator[K any, V any](node *listNode[K, V], level int,
 	comparator Comparator[K], stopAt *K) *Iterator[K, V] {
 
+	if node != nil && stopAt != nil && comparator(node.key, *stopAt) > 0 {
+		node = nil
+	}
+
 	return &Iterator[K, V]{
 		next:    node,
 		level:   level,
@@ -35,7 +39,7 @@ func (it *Iterator[K, V]) Next() (key K, value V) {
 
 	it.next = node.loadNext(it.level)
 
-	if it.stopAt != nil && it.compare(it.next.key, *it.stopAt) > 0 {
+	if it.next != nil && it.stopAt != nil && it.compare(it.next.key, *it.stopAt) > 0 {
 		it.next = nil
 	}
 
@@ -38,6 +38,18 @@ func TestIterator(t *testing.T) {
 			stopAt:       intPtr(3),
 			wantSequence: []int{1, 2, 3},
 		},
+		"StopAtLastKey": {
+			level:        0,
+			node:         head,
+			stopAt:       intPtr(10),
+			wantSequence: []int{1, 2, 3, 4, 5},
+		},
+		"StopBeforeFirstKey": {
+			level:        0,
+			node:         head,
+			stopAt:       intPtr(0),
+			wantSequence: []int{},
+		},
 		"EmptyList": {
 			level:        0,
 			node:         nil,
@@ -185,7 +185,13 @@ func (l *Skiplist[K, V]) ScanFrom(key K) *Iterator[K, V] {
 	return newIterator(node, 0, l.compareKeys, nil)
 }
 
-// ScanRange returns an iterator that scans the list from the given start key to the given end key.
+// ScanTo returns an iterator that scans the list from the beginning up to the given key, inclusive.
+// Note that the list may change while the iterator is in use.
+func (l *Skiplist[K, V]) ScanTo(end K) *Iterator[K, V] {
+	return newIterator(l.head.loadNext(0), 0, l.compareKeys, &end)
+}
+
+// ScanRange returns an iterator that scans the list from the given start key to the given end key, inclusive.
 // Note that the list may change while the iterator is in use.
 func (l *Skiplist[K, V]) ScanRange(start, end K) *Iterator[K, V] {
 	var node *listNode[K, V]
@@ -631,3 +631,28 @@ func TestSkiplist_Scan(t *testing.T) {
 		})
 	}
 }
+
+func TestSkiplist_ScanBounds(t *testing.T) {
+	list := New[int, string](IntComparator)
+	for i := 1; i <= 5; i++ {
+		list.Insert(i, "")
+	}
+
+	collect := func(it *Iterator[int, string]) []int {
+		keys := make([]int, 0)
+
+		for it.HasNext() {
+			key, _ := it.Next()
+			keys = append(keys, key)
+		}
+
+		return keys
+	}
+
+	assert.Equal(t, []int{3, 4, 5}, collect(list.ScanFrom(3)))
+	assert.Equal(t, []int{1, 2, 3}, collect(list.ScanTo(3)))
+	assert.Equal(t, []int{2, 3, 4}, collect(list.ScanRange(2, 4)))
+	assert.Equal(t, []int{4, 5}, collect(list.ScanRange(4, 10)))
+	assert.Equal(t, []int{}, collect(list.ScanRange(4, 2)))
+	assert.Equal(t, []int{}, collect(list.ScanTo(0)))
+}
@@ -0,0 +1,62 @@
+package storage
+
+import "time"
+
+// LevelStats describes the tables on a single level of the storage.
+type LevelStats struct {
+	Level     int
+	NumTables int
+	Bytes     int64
+}
+
+// Stats is a point-in-time snapshot of the internal state of the storage engine. The
+// engines fill in the fields that apply to them, and leave the rest zeroed.
+type Stats struct {
+	// Levels contains the number and the size of the tables on each level.
+	Levels []LevelStats
+	// MemtableBytes and MemtableEntries describe the active memtable.
+	MemtableBytes   int64
+	MemtableEntries int
+	// FlushQueueLength and FlushQueueBytes describe the memtables waiting to be flushed.
+	FlushQueueLength int
+	FlushQueueBytes  int64
+	// Gets is the number of lookups served by the storage.
+	Gets int64
+	// TablesRead is the number of tables read from disk by all lookups, so
+	// that TablesRead/Gets is the average number of tables read per lookup.
+	TablesRead int64
+	// BloomFalsePositives is the number of times a bloom filter reported that a
+	// key may be in the table, but the table did not contain it.
+	BloomFalsePositives int64
+	// PendingCompactionBytes is the amount of data to be rewritten by the compaction.
+	PendingCompactionBytes int64
+	// UserBytesWritten is the size of the entries written to the storage.
+	UserBytesWritten int64
+	// WALBytesWritten is the number of bytes appended to the write-ahead logs.
+	WALBytesWritten int64
+	// FlushBytesWritten is the number of bytes written to disk by the flushes.
+	FlushBytesWritten int64
+	// WriteAmplification is the ratio of bytes written to disk to the bytes written
+	// by the user, including both the WAL and the tables.
+	WriteAmplification float64
+	// IORateLimit is the current rate limit of the background writes in bytes per second.
+	IORateLimit int64
+	// IOThrottled is the number of background writes delayed by the rate limit,
+	// and IOThrottledTime is the total time they have been delayed for.
+	IOThrottled     int64
+	IOThrottledTime time.Duration
+}
+
+// TablesPerGet returns the average number of tables read per lookup.
+func (s Stats) TablesPerGet() float64 {
+	if s.Gets == 0 {
+		return 0
+	}
+
+	return float64(s.TablesRead) / float64(s.Gets)
+}
+
+// StatsProvider is a storage that is able to report its internal statistics.
+type StatsProvider interface {
+	Stats() Stats
+}
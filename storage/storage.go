@@ -5,6 +5,8 @@ package storage
 import (
 	"errors"
 
+	"github.com/maxpoletaev/kv/internal/dvv"
+	"github.com/maxpoletaev/kv/internal/hlc"
 	"github.com/maxpoletaev/kv/internal/vclock"
 )
 
@@ -20,10 +22,33 @@ var (
 	ErrNoMoreItems = errors.New("no more items in the iterator")
 )
 
-// Value represents a single value associated with a key.
+// Value represents a single value associated with a key. A deleted key is represented
+// by a tombstone value, which is versioned the same way as the regular values, so that
+// the deletes are replicated and resolved against the concurrent writes. The timestamp
+// is the hybrid logical clock time the value was written at, assigned by the node that
+// accepted the write, and the origin is the ID of that node. Neither affects the causality,
+// but they are used to pick a winner when the concurrent versions have to be resolved
+// automatically.
+// In the keyspaces with dotted versions enabled, the version is the causal context
+// of the write, and the dot identifies the write itself (see dvv.Version).
 type Value struct {
-	Version *vclock.Vector
-	Data    []byte
+	Version   *vclock.Vector
+	Dot       dvv.Dot
+	Data      []byte
+	Tombstone bool
+	Timestamp hlc.Timestamp
+	Origin    uint32
+}
+
+// DottedVersion returns the version of the value as a dotted version vector.
+func (v Value) DottedVersion() *dvv.Version {
+	return dvv.New(v.Dot, v.Version)
+}
+
+// Clock returns the vector clock that covers all writes known to the value,
+// including its own dot.
+func (v Value) Clock() *vclock.Vector {
+	return v.DottedVersion().Join()
 }
 
 // Engine is the interface that wraps the basic storage operations. It is implemented by
@@ -47,23 +72,46 @@ type Scannable interface {
 	ScanRange(from, to string) ScanIterator
 }
 
+// Discardable is a storage that is able to drop the local copy of a key, which is needed
+// when the key is handed off to the nodes that own it now. Unlike a delete, a discard does
+// not leave a tombstone and is not replicated, the key simply disappears from this node.
+// Only the given versions and the versions older than them are dropped, so that a write
+// that arrives after the key has been copied elsewhere is not lost.
+type Discardable interface {
+	Discard(key string, values []Value) error
+}
+
+// Restorable is a storage that accepts the versions copied from other nodes, such as the
+// keys handed off by the rebalancing or repaired by the anti-entropy. These versions have
+// been accepted by the cluster already, and rejecting them would only make the sender retry
+// forever, so they are never rejected due to the sibling limits. The siblings exceeding the
+// limits are folded instead.
+type Restorable interface {
+	Restore(key string, value Value) error
+}
+
 // ScanIterator is the interface for iterating over the key-value pairs in the storage,
 // in lexicographical order. It is not usually safe for concurrent use, so we must create
-// a new iterator for each goroutine.
+// a new iterator for each goroutine. Once HasNext returns false, Err tells whether the
+// iteration is complete, or has been stopped early by an error.
 type ScanIterator interface {
 	Next() (key string, value Value)
 	HasNext() bool
+	Err() error
 }
 
 // AppendVersion appends a new version to the list of versions. In case the new version
 // overtakes the existing ones, the older existing versions are discarded. If the new
 // version is older than the existing ones, an ErrObsoleteWrite is returned. In case
-// of concurrent versions, the new version is added to the list.
+// of concurrent versions, the new version is added to the list. The versions are compared
+// as dotted version vectors, which is the same as comparing the vector clocks unless the
+// values have dots.
 func AppendVersion(values []Value, newValue Value) ([]Value, error) {
 	merged := make([]Value, 0, len(values))
+	newVersion := newValue.DottedVersion()
 
 	for _, val := range values {
-		switch vclock.Compare(newValue.Version, val.Version) {
+		switch dvv.Compare(newVersion, val.DottedVersion()) {
 		case vclock.Before, vclock.Equal:
 			return nil, ErrObsoleteWrite
 		case vclock.Concurrent:
@@ -75,3 +123,27 @@ func AppendVersion(values []Value, newValue Value) ([]Value, error) {
 
 	return merged, nil
 }
+
+// DiscardVersions removes the versions that are older than or equal to any of the
+// discarded ones from the list, and returns the versions that are left.
+func DiscardVersions(values, discarded []Value) []Value {
+	kept := make([]Value, 0, len(values))
+
+	for _, val := range values {
+		version := val.DottedVersion()
+		covered := false
+
+		for _, d := range discarded {
+			switch dvv.Compare(version, d.DottedVersion()) {
+			case vclock.Before, vclock.Equal:
+				covered = true
+			}
+		}
+
+		if !covered {
+			kept = append(kept, val)
+		}
+	}
+
+	return kept
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"testing"
 
+	"github.com/maxpoletaev/kv/internal/dvv"
 	"github.com/maxpoletaev/kv/internal/vclock"
 	"github.com/stretchr/testify/require"
 )
@@ -121,6 +122,68 @@ func TestAddVersion(t *testing.T) {
 			},
 			wantErr: ErrObsoleteWrite,
 		},
+		"DottedWritesThroughSameNodeAreConcurrent": {
+			currentValues: []Value{
+				{
+					Data:    []byte("first"),
+					Version: vclock.New(),
+					Dot:     dvv.Dot{Node: 1, Counter: 1},
+				},
+			},
+			incomingValue: Value{
+				Data:    []byte("second"),
+				Version: vclock.New(),
+				Dot:     dvv.Dot{Node: 1, Counter: 2},
+			},
+			wantResult: []Value{
+				{
+					Data:    []byte("first"),
+					Version: vclock.New(),
+					Dot:     dvv.Dot{Node: 1, Counter: 1},
+				},
+				{
+					Data:    []byte("second"),
+					Version: vclock.New(),
+					Dot:     dvv.Dot{Node: 1, Counter: 2},
+				},
+			},
+		},
+		"DottedWriteOvertakesValueInContext": {
+			currentValues: []Value{
+				{
+					Data:    []byte("first"),
+					Version: vclock.New(),
+					Dot:     dvv.Dot{Node: 1, Counter: 1},
+				},
+			},
+			incomingValue: Value{
+				Data:    []byte("second"),
+				Version: vclock.New(vclock.V{1: 1}),
+				Dot:     dvv.Dot{Node: 1, Counter: 2},
+			},
+			wantResult: []Value{
+				{
+					Data:    []byte("second"),
+					Version: vclock.New(vclock.V{1: 1}),
+					Dot:     dvv.Dot{Node: 1, Counter: 2},
+				},
+			},
+		},
+		"DottedWriteIsTheSameAsCurrentValue": {
+			currentValues: []Value{
+				{
+					Data:    []byte("value"),
+					Version: vclock.New(),
+					Dot:     dvv.Dot{Node: 1, Counter: 1},
+				},
+			},
+			incomingValue: Value{
+				Data:    []byte("value"),
+				Version: vclock.New(),
+				Dot:     dvv.Dot{Node: 1, Counter: 1},
+			},
+			wantErr: ErrObsoleteWrite,
+		},
 	}
 
 	for name, tt := range tests {
@@ -131,3 +194,42 @@ func TestAddVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestDiscardVersions(t *testing.T) {
+	v := func(data string, version vclock.V) Value {
+		return Value{Data: []byte(data), Version: vclock.New(version)}
+	}
+
+	tests := map[string]struct {
+		values    []Value
+		discarded []Value
+		want      []Value
+	}{
+		"SameVersions": {
+			values:    []Value{v("a", vclock.V{1: 1}), v("b", vclock.V{2: 1})},
+			discarded: []Value{v("a", vclock.V{1: 1}), v("b", vclock.V{2: 1})},
+			want:      []Value{},
+		},
+		"NewerVersionKept": {
+			values:    []Value{v("new", vclock.V{1: 2})},
+			discarded: []Value{v("old", vclock.V{1: 1})},
+			want:      []Value{v("new", vclock.V{1: 2})},
+		},
+		"ConcurrentVersionKept": {
+			values:    []Value{v("a", vclock.V{1: 1}), v("b", vclock.V{2: 1})},
+			discarded: []Value{v("a", vclock.V{1: 1})},
+			want:      []Value{v("b", vclock.V{2: 1})},
+		},
+		"OlderVersionDropped": {
+			values:    []Value{v("old", vclock.V{1: 1})},
+			discarded: []Value{v("new", vclock.V{1: 2})},
+			want:      []Value{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, DiscardVersions(tt.values, tt.discarded))
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package vfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrCrashed is returned by all operations on a CrashFS after the simulated crash.
+var ErrCrashed = errors.New("simulated crash")
+
+// inode is the content of an in-memory file. The data written to the file is kept
+// separately from the data that has been synced, so that the unsynced data can be
+// dropped on crash, as it would happen with the OS page cache on power loss.
+type inode struct {
+	data   []byte
+	synced []byte
+}
+
+// CrashFS is an in-memory file system that simulates a crash after a given number of
+// mutating operations (writes, truncates, file creations, renames and removals). Once
+// crashed, all operations fail with ErrCrashed, and Recover returns the state of the
+// file system as it would be found after a restart: the contents of the files are rolled
+// back to the last sync. Files opened with O_SYNC are synced on every write. Directory
+// operations are considered durable as soon as they complete, which corresponds to
+// the behaviour of the journaling file systems.
+type CrashFS struct {
+	mut     sync.Mutex
+	files   map[string]*inode
+	ops     int
+	crashAt int
+	crashed bool
+}
+
+// NewCrashFS returns an empty file system that crashes right after the crashAt-th
+// mutating operation. Zero means that the file system never crashes.
+func NewCrashFS(crashAt int) *CrashFS {
+	return &CrashFS{
+		files:   make(map[string]*inode),
+		crashAt: crashAt,
+	}
+}
+
+// Ops returns the number of mutating operations performed so far.
+func (fs *CrashFS) Ops() int {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	return fs.ops
+}
+
+// Crashed returns true if the simulated crash has happened.
+func (fs *CrashFS) Crashed() bool {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	return fs.crashed
+}
+
+// Crash makes the file system crash immediately.
+func (fs *CrashFS) Crash() {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	fs.crashed = true
+}
+
+// Recover returns a new file system with the durable state of this one, which
+// crashes after the crashAt-th operation. The original file system is not modified.
+func (fs *CrashFS) Recover(crashAt int) *CrashFS {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	recovered := NewCrashFS(crashAt)
+
+	for name, ino := range fs.files {
+		recovered.files[name] = &inode{
+			data:   append([]byte(nil), ino.synced...),
+			synced: append([]byte(nil), ino.synced...),
+		}
+	}
+
+	return recovered
+}
+
+// mutate runs the operation if the file system has not crashed yet, and
+// crashes it right after the operation, if the limit has been reached.
+// Must be called with the lock held.
+func (fs *CrashFS) mutate(op func() error) error {
+	if fs.crashed {
+		return ErrCrashed
+	}
+
+	if err := op(); err != nil {
+		return err
+	}
+
+	fs.ops++
+
+	if fs.crashAt > 0 && fs.ops >= fs.crashAt {
+		fs.crashed = true
+	}
+
+	return nil
+}
+
+func (fs *CrashFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	if fs.crashed {
+		return nil, ErrCrashed
+	}
+
+	ino, exists := fs.files[name]
+
+	switch {
+	case exists && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case !exists && flag&os.O_CREATE == 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	case !exists:
+		ino = &inode{}
+
+		if err := fs.mutate(func() error {
+			fs.files[name] = ino
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	case flag&os.O_TRUNC != 0:
+		if err := fs.mutate(func() error {
+			ino.data = ino.data[:0]
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &crashFile{fs: fs, ino: ino, flag: flag}, nil
+}
+
+func (fs *CrashFS) Rename(oldname, newname string) error {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	return fs.mutate(func() error {
+		ino, ok := fs.files[oldname]
+		if !ok {
+			return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrNotExist}
+		}
+
+		delete(fs.files, oldname)
+		fs.files[newname] = ino
+
+		return nil
+	})
+}
+
+func (fs *CrashFS) Remove(name string) error {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	return fs.mutate(func() error {
+		if _, ok := fs.files[name]; !ok {
+			return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+		}
+
+		delete(fs.files, name)
+
+		return nil
+	})
+}
+
+func (fs *CrashFS) List() ([]string, error) {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	if fs.crashed {
+		return nil, ErrCrashed
+	}
+
+	names := make([]string, 0, len(fs.files))
+	for name := range fs.files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Mmap returns a read-only view of the current contents of the file. Since the files
+// are already in memory, the view keeps working after the crash, as a real mapping would.
+func (fs *CrashFS) Mmap(name string) (ReaderAtCloser, error) {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	if fs.crashed {
+		return nil, ErrCrashed
+	}
+
+	ino, ok := fs.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "mmap", Path: name, Err: os.ErrNotExist}
+	}
+
+	return &mappedFile{Reader: bytes.NewReader(ino.data)}, nil
+}
+
+type mappedFile struct {
+	*bytes.Reader
+}
+
+func (f *mappedFile) Close() error {
+	return nil
+}
+
+type crashFile struct {
+	fs     *CrashFS
+	ino    *inode
+	flag   int
+	offset int64
+}
+
+func (f *crashFile) Read(p []byte) (int, error) {
+	f.fs.mut.Lock()
+	defer f.fs.mut.Unlock()
+
+	if f.fs.crashed {
+		return 0, ErrCrashed
+	}
+
+	if f.offset >= int64(len(f.ino.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.ino.data[f.offset:])
+	f.offset += int64(n)
+
+	return n, nil
+}
+
+func (f *crashFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fs.mut.Lock()
+	defer f.fs.mut.Unlock()
+
+	if f.fs.crashed {
+		return 0, ErrCrashed
+	}
+
+	if off >= int64(len(f.ino.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.ino.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (f *crashFile) Write(p []byte) (int, error) {
+	f.fs.mut.Lock()
+	defer f.fs.mut.Unlock()
+
+	err := f.fs.mutate(func() error {
+		if f.flag&os.O_APPEND != 0 {
+			f.offset = int64(len(f.ino.data))
+		}
+
+		if end := f.offset + int64(len(p)); end > int64(len(f.ino.data)) {
+			f.ino.data = append(f.ino.data, make([]byte, end-int64(len(f.ino.data)))...)
+		}
+
+		copy(f.ino.data[f.offset:], p)
+		f.offset += int64(len(p))
+
+		if f.flag&os.O_SYNC != 0 {
+			f.ino.synced = append(f.ino.synced[:0], f.ino.data...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (f *crashFile) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mut.Lock()
+	defer f.fs.mut.Unlock()
+
+	if f.fs.crashed {
+		return 0, ErrCrashed
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.ino.data))
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	f.offset = offset
+
+	return offset, nil
+}
+
+func (f *crashFile) Sync() error {
+	f.fs.mut.Lock()
+	defer f.fs.mut.Unlock()
+
+	if f.fs.crashed {
+		return ErrCrashed
+	}
+
+	f.ino.synced = append(f.ino.synced[:0], f.ino.data...)
+
+	return nil
+}
+
+func (f *crashFile) Truncate(size int64) error {
+	f.fs.mut.Lock()
+	defer f.fs.mut.Unlock()
+
+	return f.fs.mutate(func() error {
+		if size < int64(len(f.ino.data)) {
+			f.ino.data = f.ino.data[:size]
+		} else {
+			f.ino.data = append(f.ino.data, make([]byte, size-int64(len(f.ino.data)))...)
+		}
+
+		if f.flag&os.O_SYNC != 0 {
+			f.ino.synced = append(f.ino.synced[:0], f.ino.data...)
+		}
+
+		return nil
+	})
+}
+
+func (f *crashFile) Close() error {
+	return nil
+}
@@ -0,0 +1,83 @@
+package vfs
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, fs FS, name string) string {
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestCrashFS_DropsUnsyncedData(t *testing.T) {
+	fs := NewCrashFS(0)
+
+	f, err := fs.OpenFile("file", os.O_CREATE|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("synced"))
+	require.NoError(t, err)
+	require.NoError(t, f.Sync())
+
+	_, err = f.Write([]byte("unsynced"))
+	require.NoError(t, err)
+	require.Equal(t, "syncedunsynced", readAll(t, fs, "file"))
+
+	fs.Crash()
+
+	_, err = f.Write([]byte("more"))
+	require.ErrorIs(t, err, ErrCrashed)
+
+	recovered := fs.Recover(0)
+	require.Equal(t, "synced", readAll(t, recovered, "file"))
+}
+
+func TestCrashFS_SyncFlag(t *testing.T) {
+	fs := NewCrashFS(0)
+
+	f, err := fs.OpenFile("file", os.O_CREATE|os.O_WRONLY|os.O_SYNC, 0o644)
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("data"))
+	require.NoError(t, err)
+
+	fs.Crash()
+
+	require.Equal(t, "data", readAll(t, fs.Recover(0), "file"))
+}
+
+func TestCrashFS_CrashAt(t *testing.T) {
+	fs := NewCrashFS(3)
+
+	f, err := fs.OpenFile("file", os.O_CREATE|os.O_WRONLY, 0o644) // op 1
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("data")) // op 2
+	require.NoError(t, err)
+	require.NoError(t, f.Sync())
+
+	err = fs.Rename("file", "renamed") // op 3
+	require.NoError(t, err)
+	require.True(t, fs.Crashed())
+	require.Equal(t, 3, fs.Ops())
+
+	err = fs.Remove("renamed")
+	require.ErrorIs(t, err, ErrCrashed)
+
+	recovered := fs.Recover(0)
+	require.Equal(t, "data", readAll(t, recovered, "renamed"))
+
+	_, err = recovered.OpenFile("file", os.O_RDONLY, 0)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
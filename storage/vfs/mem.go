@@ -0,0 +1,8 @@
+package vfs
+
+// NewMem returns an empty in-memory file system. It is a CrashFS that never crashes,
+// so the data written to it is kept until the file system is garbage collected. It is
+// useful for tests and for the embedded users who do not need the data to persist.
+func NewMem() FS {
+	return NewCrashFS(0)
+}
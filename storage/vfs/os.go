@@ -0,0 +1,59 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+
+	"golang.org/x/exp/mmap"
+)
+
+type osFS struct {
+	root string
+}
+
+// NewOS returns a file system backed by the given directory of the OS file system.
+func NewOS(root string) FS {
+	return &osFS{root: root}
+}
+
+func (fs *osFS) path(name string) string {
+	return filepath.Join(fs.root, name)
+}
+
+func (fs *osFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := os.OpenFile(fs.path(name), flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (fs *osFS) Rename(oldname, newname string) error {
+	return os.Rename(fs.path(oldname), fs.path(newname))
+}
+
+func (fs *osFS) Remove(name string) error {
+	return os.Remove(fs.path(name))
+}
+
+func (fs *osFS) List() ([]string, error) {
+	entries, err := os.ReadDir(fs.root)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+func (fs *osFS) Mmap(name string) (ReaderAtCloser, error) {
+	return mmap.Open(fs.path(name))
+}
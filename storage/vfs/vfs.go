@@ -0,0 +1,45 @@
+// Package vfs provides a minimal file system abstraction for the storage engines, so
+// that they can run on top of the real file system as well as in memory, which is
+// mostly useful for testing the behaviour of the engines in case of crashes.
+package vfs
+
+import (
+	"io"
+	"os"
+)
+
+// FS is a file system rooted at some directory. All names are relative to the root.
+type FS interface {
+	// OpenFile opens the named file with the given flags, as os.OpenFile does.
+	// Files are created by passing the os.O_CREATE flag.
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	// Rename atomically renames the file, replacing the target if it exists.
+	Rename(oldname, newname string) error
+	// Remove removes the named file.
+	Remove(name string) error
+	// List returns the sorted names of all files in the root directory.
+	List() ([]string, error)
+	// Mmap maps the named file into memory for reading. The file must not be
+	// modified while it is mapped.
+	Mmap(name string) (ReaderAtCloser, error)
+}
+
+// File is an open file. It is implemented by *os.File.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.Seeker
+	io.Closer
+
+	// Sync commits the contents of the file to stable storage.
+	Sync() error
+	// Truncate changes the size of the file.
+	Truncate(size int64) error
+}
+
+// ReaderAtCloser is a read-only view of a file, such as a memory-mapped file.
+type ReaderAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
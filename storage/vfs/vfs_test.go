@@ -0,0 +1,58 @@
+package vfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS(t *testing.T) {
+	tests := map[string]func(t *testing.T) FS{
+		"OS": func(t *testing.T) FS {
+			return NewOS(t.TempDir())
+		},
+		"Mem": func(t *testing.T) FS {
+			return NewMem()
+		},
+	}
+
+	for name, newFS := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs := newFS(t)
+
+			for _, name := range []string{"b", "a"} {
+				f, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0o644)
+				require.NoError(t, err)
+
+				_, err = f.Write([]byte("data-" + name))
+				require.NoError(t, err)
+				require.NoError(t, f.Sync())
+				require.NoError(t, f.Close())
+			}
+
+			names, err := fs.List()
+			require.NoError(t, err)
+			require.Equal(t, []string{"a", "b"}, names)
+
+			mapped, err := fs.Mmap("a")
+			require.NoError(t, err)
+
+			buf := make([]byte, 4)
+			_, err = mapped.ReadAt(buf, 2)
+			require.NoError(t, err)
+			require.Equal(t, "ta-a", string(buf))
+			require.NoError(t, mapped.Close())
+
+			require.NoError(t, fs.Rename("a", "c"))
+			require.NoError(t, fs.Remove("b"))
+
+			names, err = fs.List()
+			require.NoError(t, err)
+			require.Equal(t, []string{"c"}, names)
+
+			_, err = fs.OpenFile("a", os.O_RDONLY, 0)
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}